@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prober
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// unsetValue is printed for optional fields which have not been set.
+	unsetValue = "<unset>"
+	// redactedValue is printed instead of the value of a field which references sensitive data.
+	redactedValue = "<redacted>"
+)
+
+// String returns a human-readable summary of the configuration. Dependent resources are summarized by their number and the
+// number of their scaling levels. Since the summary contains the name of the kubeconfig secret, use Redacted if it is logged.
+func (c Config) String() string {
+	return c.summary(false)
+}
+
+// Redacted returns the same summary as String except for references to sensitive data, i.e. the name of the kubeconfig
+// secret and the label selectors, which are replaced by a placeholder.
+func (c Config) Redacted() string {
+	return c.summary(true)
+}
+
+func (c Config) summary(redact bool) string {
+	kubeConfigSecretName := c.KubeConfigSecretName
+	externallyManagedSelector := formatLabelSelector(c.ExternallyManagedSelector)
+	dependentResourceSelector := formatLabelSelector(c.DependentResourceSelector)
+	if redact {
+		kubeConfigSecretName = redactedValue
+		if c.ExternallyManagedSelector != nil {
+			externallyManagedSelector = redactedValue
+		}
+		if c.DependentResourceSelector != nil {
+			dependentResourceSelector = redactedValue
+		}
+	}
+	fields := []string{
+		fmt.Sprintf("name: %s", formatString(c.Name)),
+		fmt.Sprintf("kubeConfigSecretName: %s", formatString(kubeConfigSecretName)),
+		fmt.Sprintf("probeInterval: %s", formatDuration(c.ProbeInterval)),
+		fmt.Sprintf("initialDelay: %s", formatDuration(c.InitialDelay)),
+		fmt.Sprintf("probeTimeout: %s", formatDuration(c.ProbeTimeout)),
+		fmt.Sprintf("failureThreshold: %s", formatValue(c.FailureThreshold)),
+		fmt.Sprintf("successThreshold: %s", formatValue(c.SuccessThreshold)),
+		fmt.Sprintf("scaleUpStabilizationWindow: %s", formatDuration(c.ScaleUpStabilizationWindow)),
+		fmt.Sprintf("scaleUpDisabled: %t", c.ScaleUpDisabled),
+		fmt.Sprintf("levelTimeout: %s", formatDuration(c.LevelTimeout)),
+		fmt.Sprintf("continueOnLevelTimeout: %t", c.ContinueOnLevelTimeout),
+		fmt.Sprintf("externallyManagedSelector: %s", externallyManagedSelector),
+		fmt.Sprintf("waitOnReplicasStatusField: %s", formatString(string(c.WaitOnReplicasStatusField))),
+		fmt.Sprintf("verifyScaleDownTermination: %t", c.VerifyScaleDownTermination),
+		fmt.Sprintf("strictSerialLevels: %t", c.StrictSerialLevels),
+		fmt.Sprintf("scalePausedDeployments: %t", c.ScalePausedDeployments),
+		fmt.Sprintf("scaleCallTimeout: %s", formatDuration(c.ScaleCallTimeout)),
+		fmt.Sprintf("additionalKubeConfigSecrets: %d", len(c.AdditionalKubeConfigSecretNames)),
+		fmt.Sprintf("apiServerFailureQuorum: %s", formatValue(c.APIServerFailureQuorum)),
+		fmt.Sprintf("ignoreScalingAnnotationKey: %s", formatString(c.IgnoreScalingAnnotationKey)),
+		fmt.Sprintf("removeIgnoreScalingAnnotationOnScaleUp: %t", c.RemoveIgnoreScalingAnnotationOnScaleUp),
+		fmt.Sprintf("dependentsImpactedDuration: %s", formatDuration(c.DependentsImpactedDuration)),
+		fmt.Sprintf("awaitScaleDownTermination: %t", c.AwaitScaleDownTermination),
+		fmt.Sprintf("dependentResourceSelector: %s", dependentResourceSelector),
+		fmt.Sprintf("fastScaleUp: %t", c.FastScaleUp),
+		fmt.Sprintf("allowZeroScaleUpReplicas: %t", c.AllowZeroScaleUpReplicas),
+		fmt.Sprintf("dependentResources: %d", len(c.DependentResourceInfos)),
+		fmt.Sprintf("scaleUpLevels: %d", countLevels(c.DependentResourceInfos, func(info DependentResourceInfo) *ScaleInfo { return info.ScaleUpInfo })),
+		fmt.Sprintf("scaleDownLevels: %d", countLevels(c.DependentResourceInfos, func(info DependentResourceInfo) *ScaleInfo { return info.ScaleDownInfo })),
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// countLevels returns the number of distinct levels of the ScaleInfo selected from each of the dependent resources.
+func countLevels(resourceInfos []DependentResourceInfo, scaleInfoFn func(DependentResourceInfo) *ScaleInfo) int {
+	levels := make(map[int]struct{})
+	for _, resInfo := range resourceInfos {
+		if scaleInfo := scaleInfoFn(resInfo); scaleInfo != nil {
+			levels[scaleInfo.Level] = struct{}{}
+		}
+	}
+	return len(levels)
+}
+
+func formatLabelSelector(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return unsetValue
+	}
+	return metav1.FormatLabelSelector(selector)
+}
+
+func formatString(s string) string {
+	if s == "" {
+		return unsetValue
+	}
+	return s
+}
+
+func formatDuration(d *metav1.Duration) string {
+	if d == nil {
+		return unsetValue
+	}
+	return d.Duration.String()
+}
+
+func formatValue[T any](v *T) string {
+	if v == nil {
+		return unsetValue
+	}
+	return fmt.Sprintf("%v", *v)
+}
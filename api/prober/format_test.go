@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package prober
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSampleConfig() Config {
+	failureThreshold := 3
+	return Config{
+		Name:                      "default",
+		KubeConfigSecretName:      "dwd-api-server-probe-secret",
+		ProbeInterval:             &metav1.Duration{Duration: 20 * time.Second},
+		FailureThreshold:          &failureThreshold,
+		LevelTimeout:              &metav1.Duration{Duration: 2 * time.Minute},
+		ExternallyManagedSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"reconciling": "true"}},
+		WaitOnReplicasStatusField: ReplicasStatusFieldReadyReplicas,
+		DependentResourceInfos: []DependentResourceInfo{
+			newSampleResourceInfo("kube-controller-manager", 0, 1),
+			newSampleResourceInfo("machine-controller-manager", 1, 0),
+			newSampleResourceInfo("cluster-autoscaler", 1, 0),
+		},
+	}
+}
+
+func newSampleResourceInfo(name string, scaleUpLevel, scaleDownLevel int) DependentResourceInfo {
+	return DependentResourceInfo{
+		Ref:           &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: name, APIVersion: "apps/v1"},
+		ScaleUpInfo:   &ScaleInfo{Level: scaleUpLevel},
+		ScaleDownInfo: &ScaleInfo{Level: scaleDownLevel},
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	g := NewWithT(t)
+	config := newSampleConfig()
+	expected := "{name: default, kubeConfigSecretName: dwd-api-server-probe-secret, probeInterval: 20s, initialDelay: <unset>, probeTimeout: <unset>, " +
+		"failureThreshold: 3, successThreshold: <unset>, scaleUpStabilizationWindow: <unset>, scaleUpDisabled: false, levelTimeout: 2m0s, continueOnLevelTimeout: false, " +
+		"externallyManagedSelector: reconciling=true, waitOnReplicasStatusField: readyReplicas, verifyScaleDownTermination: false, strictSerialLevels: false, scalePausedDeployments: false, scaleCallTimeout: <unset>, additionalKubeConfigSecrets: 0, apiServerFailureQuorum: <unset>, ignoreScalingAnnotationKey: <unset>, removeIgnoreScalingAnnotationOnScaleUp: false, dependentsImpactedDuration: <unset>, awaitScaleDownTermination: false, dependentResourceSelector: <unset>, fastScaleUp: false, allowZeroScaleUpReplicas: false, dependentResources: 3, scaleUpLevels: 2, scaleDownLevels: 2}"
+	g.Expect(config.String()).To(Equal(expected))
+	g.Expect(fmt.Sprintf("%v", &config)).To(Equal(expected), "a pointer to the config should be formatted the same way")
+}
+
+func TestConfigRedacted(t *testing.T) {
+	g := NewWithT(t)
+	config := newSampleConfig()
+	expected := "{name: default, kubeConfigSecretName: <redacted>, probeInterval: 20s, initialDelay: <unset>, probeTimeout: <unset>, " +
+		"failureThreshold: 3, successThreshold: <unset>, scaleUpStabilizationWindow: <unset>, scaleUpDisabled: false, levelTimeout: 2m0s, continueOnLevelTimeout: false, " +
+		"externallyManagedSelector: <redacted>, waitOnReplicasStatusField: readyReplicas, verifyScaleDownTermination: false, strictSerialLevels: false, scalePausedDeployments: false, scaleCallTimeout: <unset>, additionalKubeConfigSecrets: 0, apiServerFailureQuorum: <unset>, ignoreScalingAnnotationKey: <unset>, removeIgnoreScalingAnnotationOnScaleUp: false, dependentsImpactedDuration: <unset>, awaitScaleDownTermination: false, dependentResourceSelector: <unset>, fastScaleUp: false, allowZeroScaleUpReplicas: false, dependentResources: 3, scaleUpLevels: 2, scaleDownLevels: 2}"
+	g.Expect(config.Redacted()).To(Equal(expected))
+}
+
+func TestEmptyConfigString(t *testing.T) {
+	g := NewWithT(t)
+	expected := "{name: <unset>, kubeConfigSecretName: <unset>, probeInterval: <unset>, initialDelay: <unset>, probeTimeout: <unset>, " +
+		"failureThreshold: <unset>, successThreshold: <unset>, scaleUpStabilizationWindow: <unset>, scaleUpDisabled: false, levelTimeout: <unset>, continueOnLevelTimeout: false, " +
+		"externallyManagedSelector: <unset>, waitOnReplicasStatusField: <unset>, verifyScaleDownTermination: false, strictSerialLevels: false, scalePausedDeployments: false, scaleCallTimeout: <unset>, additionalKubeConfigSecrets: 0, apiServerFailureQuorum: <unset>, ignoreScalingAnnotationKey: <unset>, removeIgnoreScalingAnnotationOnScaleUp: false, dependentsImpactedDuration: <unset>, awaitScaleDownTermination: false, dependentResourceSelector: <unset>, fastScaleUp: false, allowZeroScaleUpReplicas: false, dependentResources: 0, scaleUpLevels: 0, scaleDownLevels: 0}"
+	g.Expect(Config{}.String()).To(Equal(expected))
+}
@@ -11,6 +11,8 @@ import (
 
 // Config provides typed access to prober configuration
 type Config struct {
+	// Name is an optional name for the prober configuration. It allows a prober to be identified without knowing the namespace it runs in.
+	Name string `json:"name,omitempty"`
 	// KubeConfigSecretName is the name of the kubernetes secret which has the kubeconfig to connect to the shoot control plane API server via internal domain
 	KubeConfigSecretName string `json:"kubeConfigSecretName"`
 	// ProbeInterval is the interval with which the probe will be run
@@ -27,8 +29,105 @@ type Config struct {
 	KCMNodeMonitorGraceDuration *metav1.Duration `json:"kcmNodeMonitorGraceDuration,omitempty"`
 	// NodeLeaseFailureFraction is used to determine the maximum number of leases that can be expired for a lease probe to succeed.
 	NodeLeaseFailureFraction *float64 `json:"nodeLeaseFailureFraction,omitempty"`
+	// FailureThreshold is the number of consecutive failed probes after which the dependent resources will be scaled down.
+	FailureThreshold *int `json:"failureThreshold,omitempty"`
+	// SuccessThreshold is the number of consecutive successful probes after which the dependent resources will be scaled up.
+	SuccessThreshold *int `json:"successThreshold,omitempty"`
+	// ScaleUpDisabled if set to true will only scale down the dependent resources. Restoring them once the shoot control plane
+	// API server is reachable again is left to another actor. If this field is not specified, scale-up is enabled.
+	ScaleUpDisabled bool `json:"scaleUpDisabled,omitempty"`
+	// LevelTimeout is the maximum time it may take to scale all dependent resources of a level. If it is not specified,
+	// a level which does not finish scaling blocks the subsequent levels till the scaling flow itself is cancelled.
+	LevelTimeout *metav1.Duration `json:"levelTimeout,omitempty"`
+	// ScaleUpStabilizationWindow is the duration for which the shoot control plane has to have been probed as healthy without
+	// interruption before the dependent resources are scaled up, in addition to reaching SuccessThreshold. A single unhealthy or
+	// failed probe within the window restarts it, which prevents a brief re-failure after a recovery from causing a scale-up
+	// followed by a scale-down. If this field is not specified, the scale-up is only held till SuccessThreshold is reached.
+	ScaleUpStabilizationWindow *metav1.Duration `json:"scaleUpStabilizationWindow,omitempty"`
+	// ContinueOnLevelTimeout if set to true will proceed with the subsequent levels once a level has timed out. If this field
+	// is not specified, the scaling flow is aborted. It is only applicable if LevelTimeout is specified.
+	ContinueOnLevelTimeout bool `json:"continueOnLevelTimeout,omitempty"`
+	// ExternallyManagedSelector selects the dependent resources which are actively being reconciled by another controller,
+	// typically identified via a label that the controller sets while it reconciles the resource. Such resources are not
+	// scaled down, so that DWD does not fight over the replicas with the other controller. If this field is not specified,
+	// all dependent resources are scaled down.
+	ExternallyManagedSelector *metav1.LabelSelector `json:"externallyManagedSelector,omitempty"`
+	// WaitOnReplicasStatusField is the status field of a dependent resource which is compared against its minimum target replicas
+	// when waiting for a scaled resource, before the resources depending on it are scaled. If this field is not specified,
+	// ReplicasStatusFieldReadyReplicas is used.
+	WaitOnReplicasStatusField ReplicasStatusField `json:"waitOnReplicasStatusField,omitempty"`
+	// VerifyScaleDownTermination if set to true will verify after a scale-down that status.replicas of a dependent resource
+	// reaches its target replicas within the timeout of the resource. A resource whose pods do not terminate, e.g. due to
+	// finalizers, is reported via a warning and a metric. It does not fail the scaling flow.
+	VerifyScaleDownTermination bool `json:"verifyScaleDownTermination,omitempty"`
+	// StrictSerialLevels if set to true will reject a configuration in which more than one dependent resource is at the same
+	// scale-up or scale-down level. Resources at the same level are scaled in parallel, which is thereby only possible if
+	// this field is not specified.
+	StrictSerialLevels bool `json:"strictSerialLevels,omitempty"`
+	// ScalePausedDeployments if set to true will also scale dependent Deployments whose spec.paused is set. If this field is
+	// not specified, paused Deployments are skipped, as they have been deliberately frozen.
+	ScalePausedDeployments bool `json:"scalePausedDeployments,omitempty"`
+	// ScaleCallTimeout is the maximum time a single call to the scale subresource of a dependent resource may take. A call
+	// which does not complete within it fails the attempt, so that a hung call cannot stall the scaling flow. If this field
+	// is not specified, every call is only bounded by the timeout of the dependent resource.
+	ScaleCallTimeout *metav1.Duration `json:"scaleCallTimeout,omitempty"`
+	// AdditionalKubeConfigSecretNames optionally lists further secrets in the shoot namespace, each holding a kubeconfig which
+	// targets another endpoint of the Kube ApiServer of the shoot, e.g. a single replica of a highly available Kube ApiServer.
+	// Their endpoints are probed in addition to the endpoint of the kubeconfig in KubeConfigSecretName.
+	AdditionalKubeConfigSecretNames []string `json:"additionalKubeConfigSecretNames,omitempty"`
+	// APIServerFailureQuorum is the number of Kube ApiServer endpoints which have to fail their probe for the Kube ApiServer
+	// to be considered unhealthy. It must be between 1 and the number of endpoints, e.g. a majority of the endpoints. If this
+	// field is not specified, all endpoints have to fail. It is only applicable if AdditionalKubeConfigSecretNames is specified.
+	APIServerFailureQuorum *int `json:"apiServerFailureQuorum,omitempty"`
+	// IgnoreScalingAnnotationKey is the key of the annotation which suspends the scaling of a dependent resource if it is set to
+	// true. Multiple DWD instances which scale the same resources can thereby be suspended independently of each other. If this
+	// field is not specified, dependency-watchdog.gardener.cloud/ignore-scaling is used.
+	IgnoreScalingAnnotationKey string `json:"ignoreScalingAnnotationKey,omitempty"`
+	// RemoveIgnoreScalingAnnotationOnScaleUp if set to true will remove the ignore-scaling annotation, see IgnoreScalingAnnotationKey,
+	// from every dependent resource which is scaled up, so that a scaling suspended by an operator is resumed by the next scale-up.
+	// If this field is not specified, the annotation is left in place and the resource is skipped till it is removed.
+	RemoveIgnoreScalingAnnotationOnScaleUp bool `json:"removeIgnoreScalingAnnotationOnScaleUp,omitempty"`
+	// DependentsImpactedDuration if specified makes a scale-down additionally require that at least one dependent resource has
+	// been impacted without interruption for this duration, while the probes find the shoot control plane unhealthy. A dependent
+	// resource is impacted if fewer of its replicas are ready than specified in its spec.replicas. Dependent resources which cope
+	// with a brief disruption are thereby not scaled down. If this field is not specified, the dependent resources are scaled
+	// down as soon as FailureThreshold is reached.
+	DependentsImpactedDuration *metav1.Duration `json:"dependentsImpactedDuration,omitempty"`
+	// AwaitScaleDownTermination if set to true will wait after a scale-down till status.replicas of a dependent resource has
+	// reached its target replicas, bounded by the timeout of the resource, before the resources at the subsequent scale-down
+	// levels are scaled down. A resource whose pods do not terminate in time fails the scaling flow, so that the resources it
+	// depends on are not scaled down while its pods are still running. It takes precedence over VerifyScaleDownTermination.
+	AwaitScaleDownTermination bool `json:"awaitScaleDownTermination,omitempty"`
+	// DependentResourceSelector optionally selects Deployments in the shoot namespace which are scaled in addition to the
+	// DependentResourceInfos, without having to list them. The Deployments are discovered whenever a scaling flow is run and are
+	// scaled at level 0 of both the scale-up and the scale-down with the default initial delay and timeout. They are optional,
+	// i.e. a discovered Deployment which has been deleted in the meantime is skipped. A Deployment which is also listed in the
+	// DependentResourceInfos is scaled as configured there. If this field is specified, DependentResourceInfos may be empty.
+	DependentResourceSelector *metav1.LabelSelector `json:"dependentResourceSelector,omitempty"`
+	// FastScaleUp if set to true will scale up all dependent resources in parallel, irrespective of their scale-up levels and
+	// their DependsOn, which brings up a shoot control plane as fast as possible, e.g. during a disaster recovery. A resource is
+	// then possibly scaled up before the resources it depends on are ready. If this field is not specified, the scale-up honours
+	// the levels and the dependencies of the resources. The scale-down is not affected.
+	FastScaleUp bool `json:"fastScaleUp,omitempty"`
+	// AllowZeroScaleUpReplicas if set to true will accept a scale-up Replicas of zero, which leaves a dependent resource whose
+	// replicas prior to its scale-down have not been recorded at zero replicas on scale-up. If this field is not specified, such
+	// a configuration is rejected as it is most likely a mistake.
+	AllowZeroScaleUpReplicas bool `json:"allowZeroScaleUpReplicas,omitempty"`
 }
 
+// ReplicasStatusField is the name of a field in the status of a scalable resource which holds a number of replicas.
+type ReplicasStatusField string
+
+const (
+	// ReplicasStatusFieldReplicas refers to status.replicas, the number of created replicas irrespective of their readiness.
+	ReplicasStatusFieldReplicas ReplicasStatusField = "replicas"
+	// ReplicasStatusFieldReadyReplicas refers to status.readyReplicas, the number of replicas which are ready.
+	ReplicasStatusFieldReadyReplicas ReplicasStatusField = "readyReplicas"
+	// ReplicasStatusFieldAvailableReplicas refers to status.availableReplicas, the number of replicas which have been ready
+	// for at least the minReadySeconds of the resource.
+	ReplicasStatusFieldAvailableReplicas ReplicasStatusField = "availableReplicas"
+)
+
 // DependentResourceInfo captures a dependent resource which should be scaled
 type DependentResourceInfo struct {
 	// Ref identifies a resource
@@ -40,6 +139,26 @@ type DependentResourceInfo struct {
 	ScaleUpInfo *ScaleInfo `json:"scaleUp,omitempty"`
 	// ScaleDownInfo captures the configuration to scale down the resource identified by Ref
 	ScaleDownInfo *ScaleInfo `json:"scaleDown,omitempty"`
+	// RetryInfo optionally overrides how often and with which backoff a failed scaling of the resource identified by Ref is
+	// retried. If it is not specified, the defaults of DWD are used.
+	RetryInfo *RetryInfo `json:"retry,omitempty"`
+	// Selector optionally selects the resource identified by Ref among the resources of the same kind in the shoot namespace.
+	// The resource is resolved via the Selector whenever it is scaled, so that it is still scaled if it has been recreated
+	// with a different name. If nothing matches the Selector then the resource is looked up by the name of Ref. If this field
+	// is not specified, the resource is only looked up by the name of Ref.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// RetryInfo captures the retries of a failed scaling of a dependent resource. Every field which is not specified falls back to its default.
+type RetryInfo struct {
+	// Attempts is the maximum number of attempts to scale the resource, including the first one. If it is not specified, 3 attempts are made.
+	Attempts *int `json:"attempts,omitempty"`
+	// Backoff is the time to wait for between two attempts. If it is not specified, 100ms are waited for.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// RetryOnNotFound if set to false will not retry the scaling of a resource which has not been found, e.g. as its CRD has been
+	// removed. If it is not specified, a resource which has not been found is retried, e.g. to tolerate its CRD being installed.
+	// A resource which is optional is never retried if it has not been found.
+	RetryOnNotFound *bool `json:"retryOnNotFound,omitempty"`
 }
 
 // ScaleInfo captures the configuration required to scale a dependent resource
@@ -51,4 +170,46 @@ type ScaleInfo struct {
 	InitialDelay *metav1.Duration `json:"initialDelay,omitempty"`
 	// ScaleTimeout is the time timeout duration to wait for when attempting to update the scaling sub-resource.
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// DependsOn optionally lists the resources which have to finish their scaling operation before this resource is scaled. Every referenced
+	// resource must be configured as a dependent resource and must be at a lower level. If it is not specified then this resource will wait
+	// for all resources in the previous levels.
+	DependsOn []autoscalingv1.CrossVersionObjectReference `json:"dependsOn,omitempty"`
+	// Steps optionally defines intermediate replicas for a scale-up. The resource is scaled to each step which is less than the target replicas
+	// and DWD waits for the resource to have as many ready replicas before proceeding with the next step. Steps must be positive and strictly
+	// increasing. They are only applicable to a scale-up.
+	Steps []int32 `json:"steps,omitempty"`
+	// ReplicaDelta optionally scales the resource relative to its current spec replicas instead of scaling it to zero. It must be negative
+	// and the resulting replicas are clamped at 0. It is only applicable to a scale-down. A resource which still has replicas after the
+	// scale-down is scaled up to its replicas prior to the scale-down.
+	ReplicaDelta *int32 `json:"replicaDelta,omitempty"`
+	// HPA optionally scales the HorizontalPodAutoscaler which targets the resource along with the resource. Otherwise an HPA would override
+	// the replicas of the resource within seconds. The HPA has to exist, the scaling of the resource fails if it does not.
+	HPA *HPAScaleInfo `json:"hpa,omitempty"`
+	// Replicas optionally sets the replicas to which the resource is scaled up if its replicas prior to the scale-down have not
+	// been recorded, e.g. 2 for a highly available resource. If it is not specified then such a resource is scaled up to 1 replica.
+	// It must be positive, unless zero replicas are allowed via Config.AllowZeroScaleUpReplicas. It is only applicable to a scale-up.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// ReplicasFromHPA optionally scales the resource up to status.desiredReplicas of a HorizontalPodAutoscaler which targets it, which
+	// is read whenever the resource is scaled up. If the HPA does not exist or has not yet computed its desired replicas, the resource
+	// is scaled up as if this field was not specified. It is only applicable to a scale-up.
+	ReplicasFromHPA *HPAReference `json:"replicasFromHPA,omitempty"`
+}
+
+// HPAReference refers to the HorizontalPodAutoscaler which targets a dependent resource.
+type HPAReference struct {
+	// Name is the name of the HPA. If it is not specified, the HPA in the namespace of the resource whose spec.scaleTargetRef refers to the
+	// resource is used.
+	Name string `json:"name,omitempty"`
+}
+
+// HPAScaleInfo captures how the HorizontalPodAutoscaler which targets a dependent resource is scaled. Its spec.minReplicas is set to the
+// target replicas of the resource before the resource itself is scaled, so that the HPA does not scale the resource back. A HorizontalPodAutoscaler
+// does not act on a resource which has zero replicas, it is therefore left as is when the resource is scaled to zero.
+type HPAScaleInfo struct {
+	// Name is the name of the HPA. If it is not specified, the HPA in the namespace of the resource whose spec.scaleTargetRef refers to the
+	// resource is used.
+	Name string `json:"name,omitempty"`
+	// PinReplicas if set to true also sets spec.maxReplicas of the HPA to the target replicas, which pins the resource to exactly the target
+	// replicas. If it is not specified, spec.maxReplicas is only raised to the target replicas if it is lower.
+	PinReplicas bool `json:"pinReplicas,omitempty"`
 }
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package weeder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// unsetValue is printed for optional fields which have not been set.
+	unsetValue = "<unset>"
+	// redactedValue is printed instead of values which identify the services of the weeder.
+	redactedValue = "<redacted>"
+)
+
+// String returns a human-readable summary of the configuration. Services are listed in sorted order along with the number
+// of their pod selectors, the allowlist and the denylist are summarized by their number of entries. Services in WeedingModeObserve
+// are summarized by their number.
+func (c Config) String() string {
+	return c.summary(false)
+}
+
+// Redacted returns the same summary as String except that the names of the services are replaced by a placeholder.
+func (c Config) Redacted() string {
+	return c.summary(true)
+}
+
+func (c Config) summary(redact bool) string {
+	services := make([]string, 0, len(c.ServicesAndDependantSelectors))
+	var numPodSelectors, numAdditionalNamespaces, numObservedServices int
+	for service, ds := range c.ServicesAndDependantSelectors {
+		services = append(services, fmt.Sprintf("%s(podSelectors: %d)", service, len(ds.PodSelectors)))
+		numPodSelectors += len(ds.PodSelectors)
+		numAdditionalNamespaces += len(ds.AdditionalNamespaces)
+		if ds.Mode == WeedingModeObserve {
+			numObservedServices++
+		}
+	}
+	slices.Sort(services)
+	formattedServices := "[" + strings.Join(services, ", ") + "]"
+	if redact {
+		formattedServices = redactedValue
+	}
+	fields := []string{
+		fmt.Sprintf("watchDuration: %s", formatDuration(c.WatchDuration)),
+		fmt.Sprintf("services: %d", len(c.ServicesAndDependantSelectors)),
+		fmt.Sprintf("serviceNames: %s", formattedServices),
+		fmt.Sprintf("podSelectors: %d", numPodSelectors),
+		fmt.Sprintf("additionalNamespaces: %d", numAdditionalNamespaces),
+		fmt.Sprintf("allowlist: %d", len(c.Allowlist)),
+		fmt.Sprintf("denylist: %d", len(c.Denylist)),
+		fmt.Sprintf("restartCountThreshold: %s", formatInt32(c.RestartCountThreshold)),
+		fmt.Sprintf("endpointStabilityDuration: %s", formatDuration(c.EndpointStabilityDuration)),
+		fmt.Sprintf("notReadyThreshold: %s", formatDuration(c.NotReadyThreshold)),
+		fmt.Sprintf("dryRun: %t", c.DryRun),
+		fmt.Sprintf("restartOwningDeployment: %t", c.RestartOwningDeployment),
+		fmt.Sprintf("requireAllContainersCrashLooping: %t", c.RequireAllContainersCrashLooping),
+		fmt.Sprintf("maxPodsPerTransition: %s", formatInt32(c.MaxPodsPerTransition)),
+		fmt.Sprintf("weedingCooldown: %s", formatDuration(c.WeedingCooldown)),
+		fmt.Sprintf("observedServices: %d", numObservedServices),
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+func formatDuration(d *metav1.Duration) string {
+	if d == nil {
+		return unsetValue
+	}
+	return d.Duration.String()
+}
+
+func formatInt32(v *int32) string {
+	if v == nil {
+		return unsetValue
+	}
+	return fmt.Sprintf("%d", *v)
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package weeder
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newSampleConfig() Config {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane"}}
+	return Config{
+		WatchDuration: &metav1.Duration{Duration: 5 * time.Minute},
+		ServicesAndDependantSelectors: map[string]DependantSelectors{
+			"kube-apiserver": {PodSelectors: []*metav1.LabelSelector{selector, selector}, AdditionalNamespaces: []string{"garden"}},
+			"etcd-main":      {PodSelectors: []*metav1.LabelSelector{selector}, Mode: WeedingModeObserve},
+		},
+		Denylist:              []ServiceMatcher{{Namespace: "garden"}},
+		RestartCountThreshold: pointer.Int32(5),
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	g := NewWithT(t)
+	expected := "{watchDuration: 5m0s, services: 2, serviceNames: [etcd-main(podSelectors: 1), kube-apiserver(podSelectors: 2)], podSelectors: 3, " +
+		"additionalNamespaces: 1, allowlist: 0, denylist: 1, restartCountThreshold: 5, endpointStabilityDuration: <unset>, notReadyThreshold: <unset>, dryRun: false, restartOwningDeployment: false, requireAllContainersCrashLooping: false, maxPodsPerTransition: <unset>, weedingCooldown: <unset>, observedServices: 1}"
+	for range 5 {
+		g.Expect(newSampleConfig().String()).To(Equal(expected), "services should be listed in a stable order")
+	}
+}
+
+func TestConfigRedacted(t *testing.T) {
+	g := NewWithT(t)
+	expected := "{watchDuration: 5m0s, services: 2, serviceNames: <redacted>, podSelectors: 3, " +
+		"additionalNamespaces: 1, allowlist: 0, denylist: 1, restartCountThreshold: 5, endpointStabilityDuration: <unset>, notReadyThreshold: <unset>, dryRun: false, restartOwningDeployment: false, requireAllContainersCrashLooping: false, maxPodsPerTransition: <unset>, weedingCooldown: <unset>, observedServices: 1}"
+	g.Expect(newSampleConfig().Redacted()).To(Equal(expected))
+}
@@ -15,6 +15,62 @@ type Config struct {
 	WatchDuration *metav1.Duration `json:"watchDuration,omitempty"`
 	// ServicesAndDependantSelectors is a map whose key is the service name and the value is a DependantSelectors
 	ServicesAndDependantSelectors map[string]DependantSelectors `json:"servicesAndDependantSelectors"`
+	// Allowlist optionally restricts weeding to the services matched by at least one of its entries. If it is empty then
+	// all services in ServicesAndDependantSelectors are weeded.
+	Allowlist []ServiceMatcher `json:"allowlist,omitempty"`
+	// Denylist lists services which are never weeded, even if they are matched by the Allowlist.
+	Denylist []ServiceMatcher `json:"denylist,omitempty"`
+	// RestartCountThreshold optionally enables weeding of dependant pods which have a container whose restart count exceeds
+	// the threshold, even if the container is not in CrashLoopBackOff at the time the pod is inspected. Since the weeder only
+	// watches dependant pods for WatchDuration after the service has recovered, the restart count is only considered within this window.
+	RestartCountThreshold *int32 `json:"restartCountThreshold,omitempty"`
+	// EndpointStabilityDuration optionally defers weeding till the endpoint of a service has been ready continuously for this duration.
+	// An endpoint which turns not ready within this duration restarts it, so that a flapping endpoint does not trigger repeated weeding.
+	// If it is not set then weeding starts as soon as the endpoint is ready.
+	EndpointStabilityDuration *metav1.Duration `json:"endpointStabilityDuration,omitempty"`
+	// NotReadyThreshold optionally enables weeding of dependant pods whose containers are all running but whose Ready condition
+	// is false, e.g. due to a failing readiness gate, for at least this duration. Only the time since the endpoint of the service
+	// has become ready is counted. It must be less than WatchDuration, as dependant pods are only watched for WatchDuration.
+	NotReadyThreshold *metav1.Duration `json:"notReadyThreshold,omitempty"`
+	// DryRun if set to true will only log and record an event for every dependant pod which would have been weeded, without
+	// deleting it. Dependant pods are watched and inspected as usual, which allows to validate a configuration. If this field is
+	// not specified, dependant pods are weeded.
+	DryRun bool `json:"dryRun,omitempty"`
+	// RestartOwningDeployment if set to true will trigger a rolling restart of the Deployment owning a dependant pod which needs
+	// weeding, like `kubectl rollout restart` does, instead of deleting the pod. A dependant pod which is not owned by a Deployment
+	// is deleted as usual. If this field is not specified, dependant pods are deleted.
+	RestartOwningDeployment bool `json:"restartOwningDeployment,omitempty"`
+	// RequireAllContainersCrashLooping if set to true will only weed a dependant pod with several containers once all of its
+	// containers are in CrashLoopBackOff, which spares the healthy containers of a pod from being restarted along with a single
+	// crash-looping one. If this field is not specified, a dependant pod is weeded as soon as any of its containers is in CrashLoopBackOff.
+	RequireAllContainersCrashLooping bool `json:"requireAllContainersCrashLooping,omitempty"`
+	// MaxPodsPerTransition optionally caps the number of dependant pods which are weeded after the endpoint of a service has
+	// become ready, which prevents a mass-deletion when its recovery coincides with many crash-looping pods. The remaining pods
+	// are left to the weeders of subsequent transitions. If it is not set then all dependant pods which need weeding are weeded.
+	MaxPodsPerTransition *int32 `json:"maxPodsPerTransition,omitempty"`
+	// WeedingCooldown optionally suppresses weeding of dependant pods of a service for this duration after a dependant pod of
+	// the service has been weeded, regardless of further transitions of its endpoint. This gives the dependant pods of a flapping
+	// endpoint a chance to stabilize instead of being deleted on every transition. The weeder which has weeded the pod is not
+	// affected, it weeds all of its dependant pods which need weeding. If it is not set then every transition triggers weeding.
+	WeedingCooldown *metav1.Duration `json:"weedingCooldown,omitempty"`
+}
+
+// ServiceMatcher matches services by their namespace and name. A field which is not set matches any value.
+type ServiceMatcher struct {
+	// Namespace is the namespace of the service.
+	Namespace string `json:"namespace,omitempty"`
+	// Service is the name of the service.
+	Service string `json:"service,omitempty"`
+}
+
+// Matches checks if the service identified by namespace and name is matched by the ServiceMatcher.
+func (m ServiceMatcher) Matches(namespace, name string) bool {
+	return (m.Namespace == "" || m.Namespace == namespace) && (m.Service == "" || m.Service == name)
+}
+
+// Covers checks if every service matched by other is also matched by the ServiceMatcher.
+func (m ServiceMatcher) Covers(other ServiceMatcher) bool {
+	return (m.Namespace == "" || m.Namespace == other.Namespace) && (m.Service == "" || m.Service == other.Service)
 }
 
 // DependantSelectors encapsulates LabelSelector's used to identify dependants for a service.
@@ -22,4 +78,21 @@ type Config struct {
 type DependantSelectors struct {
 	// PodSelectors is a slice of LabelSelector's used to identify dependant pods
 	PodSelectors []*metav1.LabelSelector `json:"podSelectors"`
+	// AdditionalNamespaces is an optional list of namespaces, other than the namespace of the service, in which dependant pods
+	// identified by PodSelectors will also be watched and weeded out. Namespaces which the weeder is not permitted to watch are skipped.
+	AdditionalNamespaces []string `json:"additionalNamespaces,omitempty"`
+	// Mode is the WeedingMode of the service. In WeedingModeObserve the dependant pods of the service which need weeding are
+	// only logged and counted, which allows to observe over time what the weeder would act on before enforcing it. Unlike
+	// DryRun it applies to a single service and no events are recorded. If it is not set then WeedingModeEnforce is used.
+	Mode WeedingMode `json:"mode,omitempty"`
 }
+
+// WeedingMode determines whether the dependant pods of a service which need weeding are weeded or only observed.
+type WeedingMode string
+
+const (
+	// WeedingModeEnforce weeds the dependant pods which need weeding.
+	WeedingModeEnforce WeedingMode = "enforce"
+	// WeedingModeObserve only logs the dependant pods which need weeding and counts them in the dwd_weeder_observed_pods_total metric.
+	WeedingModeObserve WeedingMode = "observe"
+)
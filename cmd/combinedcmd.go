@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
+	"github.com/gardener/dependency-watchdog/internal/prober"
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
+	"github.com/gardener/dependency-watchdog/internal/weeder"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const combinedLeaderElectionID = "dwd-leader-election"
+
+var (
+	// CombinedCmd stores info about the combined command which runs both the prober and the weeder
+	CombinedCmd = &Command{
+		Name:      "combined",
+		UsageLine: "",
+		ShortDesc: "Runs both the prober and the weeder within a single controller manager",
+		LongDesc: `Runs the prober and the weeder within a single controller manager. Both share the client, the metrics server,
+the health probes and, if enabled, a single leader election lease. This avoids running two deployments with two leader elections.
+
+Flags:
+	--prober-config-file
+		Path of the configuration file containing probe configuration and scaling controller-reference information
+	--weeder-config-file
+		Path of the configuration file containing the services and the selectors of their dependant pods
+	--kubeconfig
+		Path to the kubeconfig file. If not specified, then it will default to the service account token to connect to the kube-api-server
+	--concurrent-reconciles
+		Maximum number of concurrent reconciles which can be run by each of the controllers. <optional>
+	--leader-election-namespace
+		Namespace in which leader election namespace will be created. This is typically the same namespace where DWD controllers are deployed.
+	--enable-leader-election
+		Determines if the leader election needs to be enabled.
+	--leader-elect-renew-deadline
+		Interval between attempts by the acting master to renew a leadership slot
+	--leader-elect-retry-period
+		The duration the clients should wait between attempting acquisition and renewal
+	--kube-api-qps
+		Maximum QPS to the API server from this client.
+	--kube-api-burst
+		Maximum burst over the QPS
+	--metrics-bind-address
+		TCP address that the controller should bind to for serving prometheus metrics
+	--health-bind-address
+		TCP address that the controller should bind to for serving health probes
+	--max-concurrent-scaling-flows
+		Maximum number of scaling flows that can run concurrently across all probers. If it is 0 then the number is not bounded. <optional>
+	--scale-with-server-side-apply
+		Update the scale subresource of dependent resources via server-side apply. <optional>
+	--scaler-read-qps
+		Maximum QPS of the reads of dependent resources, shared by the scalers of all probers. If it is 0 then the reads are not throttled by the scalers. <optional>
+	--scaler-read-burst
+		Maximum burst over the scaler-read-qps. <optional>
+	--enable-probe-trigger
+		Serve a debug endpoint on the metrics server which runs a probe of a shoot namespace right away. <optional>
+	--endpoint-resync-period
+		Period after which ready endpoints are reconciled again so that any missed CrashLooping dependent pods are weeded. A non-positive value, which is the default, disables the resync. <optional>
+`,
+		AddFlags: addCombinedFlags,
+		Run:      startCombinedControllerMgr,
+	}
+	combinedOpts = combinedOptions{}
+)
+
+type combinedOptions struct {
+	// ProberConfigFile is the path of the prober configuration file
+	ProberConfigFile string
+	// WeederConfigFile is the path of the weeder configuration file
+	WeederConfigFile string
+	// prober holds the shared and the prober specific options, its config file is not used
+	prober proberOptions
+	// EndpointResyncPeriod is the period after which ready endpoints are reconciled again
+	EndpointResyncPeriod time.Duration
+}
+
+func addCombinedFlags(fs *flag.FlagSet) {
+	bindSharedFlags(fs, &combinedOpts.prober.SharedOpts)
+	fs.StringVar(&combinedOpts.ProberConfigFile, "prober-config-file", "", "Path of the config file containing the prober configuration")
+	fs.StringVar(&combinedOpts.WeederConfigFile, "weeder-config-file", "", "Path of the config file containing the weeder configuration")
+	fs.IntVar(&combinedOpts.prober.MaxConcurrentScalingFlows, "max-concurrent-scaling-flows", 0, "Maximum number of scaling flows that can run concurrently across all probers. If it is 0 then the number is not bounded")
+	fs.BoolVar(&combinedOpts.prober.ScaleWithServerSideApply, "scale-with-server-side-apply", false, "Update the scale subresource of dependent resources via server-side apply with the field manager "+scaler.DefaultFieldManager)
+	bindScalerReadRateLimitFlags(fs, &combinedOpts.prober)
+	bindProbeTriggerFlag(fs, &combinedOpts.prober)
+	fs.DurationVar(&combinedOpts.EndpointResyncPeriod, "endpoint-resync-period", defaultEndpointResyncPeriod, "period after which ready endpoints are reconciled again. A non-positive value disables the resync")
+}
+
+func (o combinedOptions) sharedOpts() SharedOpts {
+	return o.prober.SharedOpts
+}
+
+func (o combinedOptions) proberOptions() proberOptions {
+	return o.prober
+}
+
+func (o combinedOptions) weederOptions() weederOptions {
+	return weederOptions{
+		SharedOpts:           o.prober.SharedOpts,
+		EndpointResyncPeriod: o.EndpointResyncPeriod,
+	}
+}
+
+// loadCombinedConfigs loads the prober and the weeder configuration, both of which are mandatory for the combined command.
+func loadCombinedConfigs(opts combinedOptions) (*papi.Config, *wapi.Config, error) {
+	var errs []error
+	if opts.ProberConfigFile == "" {
+		errs = append(errs, errors.New("prober-config-file must be provided"))
+	}
+	if opts.WeederConfigFile == "" {
+		errs = append(errs, errors.New("weeder-config-file must be provided"))
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	proberConfig, err := prober.LoadConfig(opts.ProberConfigFile, scheme)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load prober config file %s: %w", opts.ProberConfigFile, err)
+	}
+	weederConfig, err := weeder.LoadConfig(opts.WeederConfigFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load weeder config file %s: %w", opts.WeederConfigFile, err)
+	}
+	return proberConfig, weederConfig, nil
+}
+
+func startCombinedControllerMgr(logger logr.Logger) (manager.Manager, error) {
+	proberConfig, weederConfig, err := loadCombinedConfigs(combinedOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, err := newControllerManager(newRestConfig(combinedOpts.sharedOpts()), combinedOpts.sharedOpts(), combinedLeaderElectionID, logger.WithName("combined-controller"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the combined controller manager %w", err)
+	}
+
+	scalesGetter, err := createScalesGetter(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err = setupClusterController(mgr, scalesGetter, proberConfig, combinedOpts.proberOptions(), logger.WithName("cluster-controller")); err != nil {
+		return nil, err
+	}
+	if err = setupEndpointsController(mgr, weederConfig, combinedOpts.weederOptions(), logger.WithName("endpoints-controller")); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package cmd
+
+import (
+	"testing"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// runnableRecordingManager records every runnable which is added to the manager.
+type runnableRecordingManager struct {
+	manager.Manager
+	runnables []manager.Runnable
+}
+
+func (m *runnableRecordingManager) Add(r manager.Runnable) error {
+	m.runnables = append(m.runnables, r)
+	return m.Manager.Add(r)
+}
+
+func TestLoadCombinedConfigsShouldRequireBothConfigFiles(t *testing.T) {
+	table := []struct {
+		description      string
+		proberConfigFile string
+		weederConfigFile string
+		expectedErrors   []string
+	}{
+		{"no config file", "", "", []string{"prober-config-file must be provided", "weeder-config-file must be provided"}},
+		{"only the prober config file", "../internal/prober/testdata/valid_config.yaml", "", []string{"weeder-config-file must be provided"}},
+		{"only the weeder config file", "", "../internal/weeder/testdata/valid_config.yaml", []string{"prober-config-file must be provided"}},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			proberConfig, weederConfig, err := loadCombinedConfigs(combinedOptions{ProberConfigFile: entry.proberConfigFile, WeederConfigFile: entry.weederConfigFile})
+			g.Expect(err).To(HaveOccurred())
+			for _, expectedError := range entry.expectedErrors {
+				g.Expect(err.Error()).To(ContainSubstring(expectedError))
+			}
+			g.Expect(proberConfig).To(BeNil())
+			g.Expect(weederConfig).To(BeNil())
+		})
+	}
+}
+
+func TestLoadCombinedConfigsShouldReturnConfigErrorForMissingConfigFile(t *testing.T) {
+	g := NewWithT(t)
+	_, _, err := loadCombinedConfigs(combinedOptions{
+		ProberConfigFile: "../internal/prober/testdata/valid_config.yaml",
+		WeederConfigFile: "../internal/weeder/testdata/notfound.yaml",
+	})
+	g.Expect(err).To(MatchError(util.ErrConfigNotFound))
+	g.Expect(ExitCode(err)).To(Equal(ExitCodeConfigError))
+}
+
+func TestLoadCombinedConfigsShouldLoadBothConfigFiles(t *testing.T) {
+	g := NewWithT(t)
+	proberConfig, weederConfig, err := loadCombinedConfigs(combinedOptions{
+		ProberConfigFile: "../internal/prober/testdata/valid_config.yaml",
+		WeederConfigFile: "../internal/weeder/testdata/valid_config.yaml",
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(proberConfig).ToNot(BeNil())
+	g.Expect(weederConfig).ToNot(BeNil())
+}
+
+func TestCombinedControllersShouldBeRegisteredOnSharedManager(t *testing.T) {
+	g := NewWithT(t)
+	// the manager is never started, therefore it does not connect to the kube-api-server.
+	restConf := &rest.Config{Host: "https://127.0.0.1:6443"}
+	mgr, err := ctrl.NewManager(restConf, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                server.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+		Controller:             config.Controller{SkipNameValidation: pointer.Bool(true)},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	recordingMgr := &runnableRecordingManager{Manager: mgr}
+	opts := combinedOptions{prober: proberOptions{SharedOpts: SharedOpts{ConcurrentReconciles: 1}}}
+
+	g.Expect(setupClusterController(recordingMgr, nil, &papi.Config{}, opts.proberOptions(), logr.Discard())).To(Succeed())
+	g.Expect(setupEndpointsController(recordingMgr, &wapi.Config{}, opts.weederOptions(), logr.Discard())).To(Succeed())
+
+	var numControllers int
+	for _, r := range recordingMgr.runnables {
+		if _, ok := r.(controller.Controller); ok {
+			numControllers++
+		}
+	}
+	g.Expect(numControllers).To(Equal(3), "the cluster, the scaling pause and the endpoint reconciler should be registered on the shared manager")
+}
@@ -5,11 +5,23 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	"github.com/gardener/dependency-watchdog/controllers/cluster"
+	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/go-logr/logr"
 	"k8s.io/client-go/rest"
 )
@@ -24,11 +36,20 @@ const (
 	defaultRetryPeriod          = 2 * time.Second
 )
 
+const (
+	// ExitCodeFailure is the exit code of a command which has failed.
+	ExitCodeFailure = 1
+	// ExitCodeConfigError is the exit code of a command whose configuration file does not exist, cannot be parsed or is invalid.
+	// Unlike other failures, restarting the command does not help till the configuration has been fixed.
+	ExitCodeConfigError = 3
+)
+
 var (
 	// Commands is a list of possible commands that could be run
 	Commands = []*Command{
 		ProberCmd,
 		WeederCmd,
+		CombinedCmd,
 	}
 )
 
@@ -42,6 +63,14 @@ type Command struct {
 	Run       func(logger logr.Logger) (manager.Manager, error)
 }
 
+// ExitCode returns the exit code for an error returned by the Run function of a Command.
+func ExitCode(err error) int {
+	if errors.Is(err, util.ErrConfigNotFound) || errors.Is(err, util.ErrConfigParse) || errors.Is(err, util.ErrConfigInvalid) {
+		return ExitCodeConfigError
+	}
+	return ExitCodeFailure
+}
+
 // SharedOpts are the flags which bother prober and weeder have in common
 type SharedOpts struct {
 	// ConfigFile is the command specific configuration file path which is typically a mounted config-map YAML file
@@ -80,8 +109,8 @@ type LeaderElectionOpts struct {
 	LeaseDuration time.Duration
 	// RenewDeadline is the interval between attempts by the acting leader to
 	// renew a leadership slot before it stops leading. This must be less
-	// than or equal to the lease duration. This is only applicable if leader
-	// election is enabled.
+	// than the lease duration and greater than the retry period. This is only
+	// applicable if leader election is enabled.
 	RenewDeadline time.Duration
 	// RetryPeriod is the duration the clients should wait between attempting
 	// acquisition and renewal of a leadership. This is only applicable if
@@ -89,9 +118,46 @@ type LeaderElectionOpts struct {
 	RetryPeriod time.Duration
 }
 
+// setDefaults sets the lease duration, renew deadline and retry period which have not been set to their defaults.
+func (o *LeaderElectionOpts) setDefaults() {
+	if o.LeaseDuration == 0 {
+		o.LeaseDuration = defaultLeaseDuration
+	}
+	if o.RenewDeadline == 0 {
+		o.RenewDeadline = defaultRenewDeadline
+	}
+	if o.RetryPeriod == 0 {
+		o.RetryPeriod = defaultRetryPeriod
+	}
+}
+
+// validate checks that the lease duration is greater than the renew deadline, which in turn is greater than the retry
+// period, as the leader would otherwise lose the lease before it has had a chance to renew it. The durations are only
+// validated if leader election is enabled.
+func (o LeaderElectionOpts) validate() error {
+	if !o.Enable {
+		return nil
+	}
+	if o.RetryPeriod <= 0 {
+		return fmt.Errorf("leader-elect-retry-period must be positive, got %s", o.RetryPeriod)
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		return fmt.Errorf("leader-elect-lease-duration %s must be greater than leader-elect-renew-deadline %s", o.LeaseDuration, o.RenewDeadline)
+	}
+	if o.RenewDeadline <= o.RetryPeriod {
+		return fmt.Errorf("leader-elect-renew-deadline %s must be greater than leader-elect-retry-period %s", o.RenewDeadline, o.RetryPeriod)
+	}
+	return nil
+}
+
 // SetSharedOpts helps in defining the location where the command flag values would be stored, it also defines default values for the flags.
 func SetSharedOpts(fs *flag.FlagSet, opts *SharedOpts) {
 	fs.StringVar(&opts.ConfigFile, "config-file", "", "Path of the config file containing the configuration")
+	bindSharedFlags(fs, opts)
+}
+
+// bindSharedFlags binds all shared flags except the config file, which is command specific.
+func bindSharedFlags(fs *flag.FlagSet, opts *SharedOpts) {
 	fs.IntVar(&opts.ConcurrentReconciles, "concurrent-reconciles", defaultConcurrentReconciles, "Maximum number of concurrent reconciles")
 	fs.IntVar(&opts.KubeApiBurst, "kube-api-burst", rest.DefaultBurst, "Maximum burst to throttle the calls to the API server.")
 	fs.Float64Var(&opts.KubeApiQps, "kube-api-qps", float64(rest.DefaultQPS), "Maximum QPS (queries per second) allowed from the client to the API server")
@@ -101,6 +167,54 @@ func SetSharedOpts(fs *flag.FlagSet, opts *SharedOpts) {
 	bindLeaderElectionFlags(fs, opts)
 }
 
+// pprofEnabled returns true if the profiling endpoint is served, controller-runtime disables it for an empty address or "0".
+func (o SharedOpts) pprofEnabled() bool {
+	return o.PprofBindAddress != "" && o.PprofBindAddress != "0"
+}
+
+// newControllerManager creates a controller manager configured via the shared options, which acquires the lease identified
+// by leaderElectionID if leader election is enabled.
+func newControllerManager(restConf *rest.Config, opts SharedOpts, leaderElectionID string, logger logr.Logger) (manager.Manager, error) {
+	opts.LeaderElection.setDefaults()
+	if err := opts.LeaderElection.validate(); err != nil {
+		return nil, err
+	}
+	return ctrl.NewManager(restConf, ctrl.Options{
+		Scheme:                     scheme,
+		Metrics:                    server.Options{BindAddress: opts.MetricsBindAddress},
+		HealthProbeBindAddress:     opts.HealthBindAddress,
+		LeaderElection:             opts.LeaderElection.Enable,
+		LeaseDuration:              &opts.LeaderElection.LeaseDuration,
+		RenewDeadline:              &opts.LeaderElection.RenewDeadline,
+		RetryPeriod:                &opts.LeaderElection.RetryPeriod,
+		LeaderElectionNamespace:    opts.LeaderElection.Namespace,
+		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
+		LeaderElectionID:           leaderElectionID,
+		Logger:                     logger,
+		PprofBindAddress:           opts.PprofBindAddress,
+		Cache:                      cache.Options{ByObject: sentinelCacheByObject(opts.LeaderElection.Namespace)},
+	})
+}
+
+// sentinelCacheByObject restricts the cache of ConfigMaps to the sentinel which pauses the scaling of all probers, as it is
+// the only ConfigMap read via the cache. Otherwise, the ConfigMaps of all namespaces of the seed would be cached.
+func sentinelCacheByObject(namespace string) map[client.Object]cache.ByObject {
+	return map[client.Object]cache.ByObject{
+		&corev1.ConfigMap{}: {
+			Namespaces: map[string]cache.Config{namespace: {}},
+			Field:      fields.OneTermEqualSelector("metadata.name", cluster.ScalingPauseConfigMapName),
+		},
+	}
+}
+
+// newRestConfig creates the rest config to connect to the kube-api-server, throttled as configured via the shared options.
+func newRestConfig(opts SharedOpts) *rest.Config {
+	restConf := ctrl.GetConfigOrDie()
+	restConf.QPS = float32(opts.KubeApiQps)
+	restConf.Burst = opts.KubeApiBurst
+	return restConf
+}
+
 func bindLeaderElectionFlags(fs *flag.FlagSet, opts *SharedOpts) {
 	fs.BoolVar(&opts.LeaderElection.Enable, "enable-leader-election", false, "Start a leader election client and gain leadership before "+
 		"executing the main loop. Enable this when running replicated "+
@@ -112,8 +226,17 @@ func bindLeaderElectionFlags(fs *flag.FlagSet, opts *SharedOpts) {
 		"before it is replaced by another candidate. This is only applicable if leader "+
 		"election is enabled.")
 	fs.DurationVar(&opts.LeaderElection.RenewDeadline, "leader-elect-renew-deadline", defaultRenewDeadline, "The interval between attempts by the acting master to renew a leadership slot "+
-		"before it stops leading. This must be less than or equal to the lease duration. "+
+		"before it stops leading. This must be less than the lease duration and greater than the retry period. "+
 		"This is only applicable if leader election is enabled.")
 	fs.DurationVar(&opts.LeaderElection.RetryPeriod, "leader-elect-retry-period", defaultRetryPeriod, "The duration the clients should wait between attempting acquisition and renewal "+
 		"of a leadership. This is only applicable if leader election is enabled.")
 }
+
+// addShutdownHook adds a runnable to the manager which calls shutdownFn once the manager has been asked to stop.
+func addShutdownHook(mgr manager.Manager, shutdownFn func()) error {
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		shutdownFn()
+		return nil
+	}))
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/internal/util"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderElectionOptsShouldDefaultUnsetDurations(t *testing.T) {
+	g := NewWithT(t)
+	opts := LeaderElectionOpts{Enable: true, RenewDeadline: 20 * time.Second}
+	opts.setDefaults()
+	g.Expect(opts.LeaseDuration).To(Equal(defaultLeaseDuration))
+	g.Expect(opts.RenewDeadline).To(Equal(20*time.Second), "a set duration should not be defaulted")
+	g.Expect(opts.RetryPeriod).To(Equal(defaultRetryPeriod))
+}
+
+func TestLeaderElectionFlagsShouldDefaultToValidDurations(t *testing.T) {
+	g := NewWithT(t)
+	opts := SharedOpts{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	SetSharedOpts(fs, &opts)
+	g.Expect(fs.Parse([]string{"--enable-leader-election"})).To(Succeed())
+	g.Expect(opts.LeaderElection.LeaseDuration).To(Equal(15 * time.Second))
+	g.Expect(opts.LeaderElection.RenewDeadline).To(Equal(10 * time.Second))
+	g.Expect(opts.LeaderElection.RetryPeriod).To(Equal(2 * time.Second))
+	g.Expect(opts.LeaderElection.validate()).To(Succeed())
+}
+
+func TestLeaderElectionOptsValidation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		opts          LeaderElectionOpts
+		expectedError string
+	}{
+		{name: "defaults should be valid", opts: LeaderElectionOpts{Enable: true, LeaseDuration: defaultLeaseDuration, RenewDeadline: defaultRenewDeadline, RetryPeriod: defaultRetryPeriod}},
+		{name: "lease duration equal to renew deadline should be rejected", opts: LeaderElectionOpts{Enable: true, LeaseDuration: 10 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second}, expectedError: "leader-elect-lease-duration 10s must be greater than leader-elect-renew-deadline 10s"},
+		{name: "lease duration less than renew deadline should be rejected", opts: LeaderElectionOpts{Enable: true, LeaseDuration: 5 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second}, expectedError: "must be greater than leader-elect-renew-deadline"},
+		{name: "renew deadline not greater than retry period should be rejected", opts: LeaderElectionOpts{Enable: true, LeaseDuration: 15 * time.Second, RenewDeadline: 2 * time.Second, RetryPeriod: 2 * time.Second}, expectedError: "leader-elect-renew-deadline 2s must be greater than leader-elect-retry-period 2s"},
+		{name: "negative retry period should be rejected", opts: LeaderElectionOpts{Enable: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: -time.Second}, expectedError: "leader-elect-retry-period must be positive"},
+		{name: "invalid durations should be ignored if leader election is disabled", opts: LeaderElectionOpts{LeaseDuration: 5 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second}},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := entry.opts.validate()
+			if entry.expectedError == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(entry.expectedError)))
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name             string
+		err              error
+		expectedExitCode int
+	}{
+		{"config not found", fmt.Errorf("failed to load weeder config file: %w", util.ErrConfigNotFound), ExitCodeConfigError},
+		{"config cannot be parsed", fmt.Errorf("failed to load prober config file: %w", util.ErrConfigParse), ExitCodeConfigError},
+		{"invalid config", fmt.Errorf("failed to load prober config file: %w", util.ErrConfigInvalid), ExitCodeConfigError},
+		{"other failure", errors.New("failed to start the controller manager"), ExitCodeFailure},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ExitCode(tc.err)).To(Equal(tc.expectedExitCode))
+		})
+	}
+}
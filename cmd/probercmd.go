@@ -5,27 +5,35 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
-	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	papi "github.com/gardener/dependency-watchdog/api/prober"
 	"github.com/gardener/dependency-watchdog/controllers/cluster"
 	"github.com/gardener/dependency-watchdog/internal/prober"
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
 	"github.com/gardener/dependency-watchdog/internal/util"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/leaderelection/resourcelock"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/util/flowcontrol"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 const (
+	// probeTriggerPath is the path of the metrics server under which probes can be triggered, if enabled.
+	probeTriggerPath = "/debug/probe-trigger"
+	// flowGraphsPath is the path of the metrics server under which the scaling flow graphs are served if profiling is enabled.
+	flowGraphsPath         = "/debug/flow-graphs"
 	proberLeaderElectionID = "dwd-prober-leader-election"
 	weederLeaderElectionID = "dwd-weeder-leader-election"
+	defaultScalerReadBurst = 10
 )
 
 var (
@@ -61,6 +69,21 @@ Flags:
 		TCP address that the controller should bind to for serving prometheus metrics
 	--health-bind-address
 		TCP address that the controller should bind to for serving health probes
+	--max-concurrent-scaling-flows
+		Maximum number of scaling flows that can run concurrently across all probers. If it is 0 then the number is not bounded. <optional>
+	--scale-with-server-side-apply
+		Update the scale subresource of dependent resources via server-side apply. <optional>
+	--scaler-read-qps
+		Maximum QPS of the reads of dependent resources, shared by the scalers of all probers. If it is 0 then the reads are not throttled by the scalers. <optional>
+	--scaler-read-burst
+		Maximum burst over the scaler-read-qps. <optional>
+	--enable-probe-trigger
+		Serve a debug endpoint on the metrics server which runs a probe of a shoot namespace right away. <optional>
+	--pprof-bind-addr
+		TCP address that the controller should bind to for serving profiling endpoint. Unless it is empty or "0", the scaling
+		flow graphs of a shoot namespace are served on the metrics server as well. <optional>
+
+The scaling of all probers is paused while the ConfigMap dependency-watchdog-scaling-pause exists in the leader-election-namespace.
 `,
 		AddFlags: addProbeFlags,
 		Run:      startClusterControllerMgr,
@@ -71,6 +94,16 @@ Flags:
 
 type proberOptions struct {
 	SharedOpts
+	// MaxConcurrentScalingFlows is the maximum number of scaling flows that can run concurrently across all probers
+	MaxConcurrentScalingFlows int
+	// ScaleWithServerSideApply determines if the scale subresource of dependent resources is updated via server-side apply
+	ScaleWithServerSideApply bool
+	// ScalerReadQPS is the maximum QPS of the reads of dependent resources shared by the scalers of all probers
+	ScalerReadQPS float64
+	// ScalerReadBurst is the maximum burst over the ScalerReadQPS
+	ScalerReadBurst int
+	// EnableProbeTrigger determines if probes can be triggered via a debug endpoint of the metrics server
+	EnableProbeTrigger bool
 }
 
 func init() {
@@ -84,51 +117,115 @@ func init() {
 
 func addProbeFlags(fs *flag.FlagSet) {
 	SetSharedOpts(fs, &proberOpts.SharedOpts)
+	fs.IntVar(&proberOpts.MaxConcurrentScalingFlows, "max-concurrent-scaling-flows", 0, "Maximum number of scaling flows that can run concurrently across all probers. If it is 0 then the number is not bounded")
+	fs.BoolVar(&proberOpts.ScaleWithServerSideApply, "scale-with-server-side-apply", false, "Update the scale subresource of dependent resources via server-side apply with the field manager "+scaler.DefaultFieldManager)
+	bindScalerReadRateLimitFlags(fs, &proberOpts)
+	bindProbeTriggerFlag(fs, &proberOpts)
+}
+
+func bindScalerReadRateLimitFlags(fs *flag.FlagSet, opts *proberOptions) {
+	fs.Float64Var(&opts.ScalerReadQPS, "scaler-read-qps", 0, "Maximum QPS of the reads of dependent resources, shared by the scalers of all probers. If it is 0 then the reads are not throttled by the scalers")
+	fs.IntVar(&opts.ScalerReadBurst, "scaler-read-burst", defaultScalerReadBurst, "Maximum burst over the scaler-read-qps")
+}
+
+func bindProbeTriggerFlag(fs *flag.FlagSet, opts *proberOptions) {
+	fs.BoolVar(&opts.EnableProbeTrigger, "enable-probe-trigger", false, "Serve the debug endpoint "+probeTriggerPath+" on the metrics server, which runs a probe of the shoot namespace given via the namespace query parameter right away")
+}
+
+// scalerReadRateLimiter creates the rate limiter shared by the scalers of all probers. It returns nil if the reads should not be throttled.
+func (o proberOptions) scalerReadRateLimiter() flowcontrol.RateLimiter {
+	if o.ScalerReadQPS <= 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(o.ScalerReadQPS), o.ScalerReadBurst)
 }
 
 func startClusterControllerMgr(logger logr.Logger) (manager.Manager, error) {
 	proberLogger := logger.WithName("cluster-controller")
 	proberConfig, err := prober.LoadConfig(proberOpts.ConfigFile, scheme)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse prober config file %s : %w", proberOpts.ConfigFile, err)
+		return nil, fmt.Errorf("failed to load prober config file %s: %w", proberOpts.ConfigFile, err)
 	}
 
-	restConf := ctrl.GetConfigOrDie()
-	restConf.QPS = float32(proberOpts.KubeApiQps)
-	restConf.Burst = proberOpts.KubeApiBurst
-
-	mgr, err := ctrl.NewManager(restConf, ctrl.Options{
-		Scheme:                     scheme,
-		Metrics:                    server.Options{BindAddress: proberOpts.SharedOpts.MetricsBindAddress},
-		HealthProbeBindAddress:     proberOpts.SharedOpts.HealthBindAddress,
-		LeaderElection:             proberOpts.SharedOpts.LeaderElection.Enable,
-		LeaseDuration:              &proberOpts.SharedOpts.LeaderElection.LeaseDuration,
-		RenewDeadline:              &proberOpts.SharedOpts.LeaderElection.RenewDeadline,
-		RetryPeriod:                &proberOpts.SharedOpts.LeaderElection.RetryPeriod,
-		LeaderElectionNamespace:    proberOpts.SharedOpts.LeaderElection.Namespace,
-		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
-		LeaderElectionID:           proberLeaderElectionID,
-		Logger:                     proberLogger,
-		PprofBindAddress:           proberOpts.SharedOpts.PprofBindAddress,
-	})
+	mgr, err := newControllerManager(newRestConfig(proberOpts.SharedOpts), proberOpts.SharedOpts, proberLeaderElectionID, proberLogger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start the prober controller manager %w", err)
 	}
 
-	scalesGetter, err := util.CreateScalesGetter(ctrl.GetConfigOrDie())
+	scalesGetter, err := createScalesGetter(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err = setupClusterController(mgr, scalesGetter, proberConfig, proberOpts, proberLogger); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// createScalesGetter creates the ScalesGetter with which the probers scale the dependent resources. It fails at startup
+// if the rest config is unusable, as the probers would otherwise only fail once they scale a resource.
+func createScalesGetter(restConf *rest.Config) (scale.ScalesGetter, error) {
+	scalesGetter, err := util.CreateScalesGetter(restConf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create clientSet for scalesGetter %w", err)
+		return nil, fmt.Errorf("failed to create the scales getter from the rest config of host %q: %w", restConf.Host, err)
 	}
+	return scalesGetter, nil
+}
 
+// setupClusterController registers the cluster reconciler, which starts a prober per shoot cluster, with the manager.
+func setupClusterController(mgr manager.Manager, scalesGetter scale.ScalesGetter, proberConfig *papi.Config, opts proberOptions, logger logr.Logger) error {
+	proberMgr := prober.NewManager(prober.WithMaxConcurrentScalingFlows(opts.MaxConcurrentScalingFlows))
 	if err := (&cluster.Reconciler{
-		Client:                  mgr.GetClient(),
-		Scheme:                  mgr.GetScheme(),
-		ScaleGetter:             scalesGetter,
-		ProberMgr:               prober.NewManager(),
-		DefaultProbeConfig:      proberConfig,
-		MaxConcurrentReconciles: proberOpts.ConcurrentReconciles,
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		ScaleGetter:              scalesGetter,
+		ProberMgr:                proberMgr,
+		DefaultProbeConfig:       proberConfig,
+		MaxConcurrentReconciles:  opts.ConcurrentReconciles,
+		ScaleWithServerSideApply: opts.ScaleWithServerSideApply,
+		ScalerReadRateLimiter:    opts.scalerReadRateLimiter(),
 	}).SetupWithManager(mgr); err != nil {
-		return nil, fmt.Errorf("failed to register cluster reconciler with the prober controller manager %w", err)
+		return fmt.Errorf("failed to register cluster reconciler with the prober controller manager %w", err)
 	}
-	return mgr, nil
+	if err := (&cluster.ScalingPauseReconciler{
+		Client:       mgr.GetClient(),
+		Namespace:    opts.LeaderElection.Namespace,
+		ScalingPause: proberMgr.GetScalingPause(),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("failed to register scaling pause reconciler with the prober controller manager %w", err)
+	}
+	if opts.EnableProbeTrigger {
+		if err := mgr.AddMetricsServerExtraHandler(probeTriggerPath, prober.NewProbeTriggerHandler(proberMgr, logger)); err != nil {
+			return fmt.Errorf("failed to add the probe trigger endpoint to the prober controller manager %w", err)
+		}
+	}
+	if opts.pprofEnabled() {
+		if err := mgr.AddMetricsServerExtraHandler(flowGraphsPath, prober.NewFlowGraphHandler(proberMgr, logger)); err != nil {
+			return fmt.Errorf("failed to add the flow graphs endpoint to the prober controller manager %w", err)
+		}
+	}
+	if err := addScalePermissionCheck(mgr, proberConfig, opts.ScaleWithServerSideApply, logger); err != nil {
+		return fmt.Errorf("failed to add the scale permission check to the prober controller manager %w", err)
+	}
+	if err := addShutdownHook(mgr, func() { proberMgr.Shutdown(logger) }); err != nil {
+		return fmt.Errorf("failed to add the prober shutdown hook to the prober controller manager %w", err)
+	}
+	return nil
+}
+
+// addScalePermissionCheck adds a runnable to the manager which checks once the manager has been started whether DWD is permitted
+// to scale the dependent resources of the prober config and logs a single warning listing all of those it cannot scale. The
+// probers are started regardless, as the permissions might be granted later on.
+func addScalePermissionCheck(mgr manager.Manager, proberConfig *papi.Config, useServerSideApply bool, logger logr.Logger) error {
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		unscalable, err := scaler.FindUnscalableResources(ctx, mgr.GetClient(), mgr.GetRESTMapper(), proberConfig.DependentResourceInfos, useServerSideApply)
+		if err != nil {
+			logger.Error(err, "Failed to check the permissions to scale the dependent resources")
+			return nil
+		}
+		if len(unscalable) > 0 {
+			logger.Info("WARNING: Not permitted to scale dependent resources, scaling them fails without being retried till the permissions have been granted", "resources", unscalable)
+		}
+		return nil
+	}))
 }
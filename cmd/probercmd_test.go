@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+)
+
+func TestCreateScalesGetterShouldFailForUnusableRestConfig(t *testing.T) {
+	g := NewWithT(t)
+	restConf := &rest.Config{
+		Host:            "https://127.0.0.1:6443",
+		TLSClientConfig: rest.TLSClientConfig{CAFile: filepath.Join(t.TempDir(), "does-not-exist.crt")},
+	}
+
+	scalesGetter, err := createScalesGetter(restConf)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("failed to create the scales getter"))
+	g.Expect(scalesGetter).To(BeNil())
+}
+
+func TestCreateScalesGetterShouldSucceedForUsableRestConfig(t *testing.T) {
+	g := NewWithT(t)
+	// the scales getter discovers the API lazily, therefore it does not connect to the kube-api-server.
+	scalesGetter, err := createScalesGetter(&rest.Config{Host: "https://127.0.0.1:6443"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(scalesGetter).ToNot(BeNil())
+}
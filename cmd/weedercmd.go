@@ -7,18 +7,19 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"time"
 
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
 	"github.com/gardener/dependency-watchdog/controllers/endpoint"
 	internalutils "github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/gardener/dependency-watchdog/internal/weeder"
-	"k8s.io/client-go/tools/leaderelection/resourcelock"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/go-logr/logr"
 )
 
+const defaultEndpointResyncPeriod time.Duration = 0
+
 var (
 	// WeederCmd stores info about the weeder command
 	WeederCmd = &Command{
@@ -53,6 +54,8 @@ Flags:
 		TCP address that the controller should bind to for serving prometheus metrics
 	--health-bind-address
 		TCP address that the controller should bind to for serving health probes
+	--endpoint-resync-period
+		Period after which ready endpoints are reconciled again so that any missed CrashLooping dependent pods are weeded. A non-positive value, which is the default, disables the resync. <optional>
 `,
 		AddFlags: addWeederFlags,
 		Run:      startEndpointsControllerMgr,
@@ -62,9 +65,11 @@ Flags:
 
 type weederOptions struct {
 	SharedOpts
+	EndpointResyncPeriod time.Duration
 }
 
 func addWeederFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&weederOpts.EndpointResyncPeriod, "endpoint-resync-period", defaultEndpointResyncPeriod, "period after which ready endpoints are reconciled again. A non-positive value disables the resync")
 	SetSharedOpts(fs, &weederOpts.SharedOpts)
 }
 
@@ -72,43 +77,39 @@ func startEndpointsControllerMgr(logger logr.Logger) (manager.Manager, error) {
 	weederLogger := logger.WithName("endpoints-controller")
 	weederConfig, err := weeder.LoadConfig(weederOpts.ConfigFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse weeder config file %s : %w", weederOpts.ConfigFile, err)
+		return nil, fmt.Errorf("failed to load weeder config file %s: %w", weederOpts.ConfigFile, err)
 	}
 
-	restConf := ctrl.GetConfigOrDie()
-	restConf.QPS = float32(weederOpts.KubeApiQps)
-	restConf.Burst = weederOpts.KubeApiBurst
-	mgr, err := ctrl.NewManager(restConf, ctrl.Options{
-		Scheme:                     scheme,
-		Metrics:                    server.Options{BindAddress: weederOpts.SharedOpts.MetricsBindAddress},
-		HealthProbeBindAddress:     weederOpts.SharedOpts.HealthBindAddress,
-		LeaderElection:             weederOpts.SharedOpts.LeaderElection.Enable,
-		LeaseDuration:              &weederOpts.SharedOpts.LeaderElection.LeaseDuration,
-		RenewDeadline:              &weederOpts.SharedOpts.LeaderElection.RenewDeadline,
-		RetryPeriod:                &weederOpts.SharedOpts.LeaderElection.RetryPeriod,
-		LeaderElectionNamespace:    weederOpts.SharedOpts.LeaderElection.Namespace,
-		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
-		LeaderElectionID:           weederLeaderElectionID,
-		Logger:                     weederLogger,
-		PprofBindAddress:           weederOpts.SharedOpts.PprofBindAddress,
-	})
+	mgr, err := newControllerManager(newRestConfig(weederOpts.SharedOpts), weederOpts.SharedOpts, weederLeaderElectionID, weederLogger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start the weeder controller manager %w", err)
 	}
+	if err = setupEndpointsController(mgr, weederConfig, weederOpts, weederLogger); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
 
+// setupEndpointsController registers the endpoint reconciler, which starts a weeder for every endpoint that becomes ready, with the manager.
+func setupEndpointsController(mgr manager.Manager, weederConfig *wapi.Config, opts weederOptions, logger logr.Logger) error {
 	// create clientSet
-	clientSet, err := internalutils.CreateClientSetFromRestConfig(restConf)
+	clientSet, err := internalutils.CreateClientSetFromRestConfig(mgr.GetConfig())
 	if err != nil {
-		return nil, fmt.Errorf("failed creating clientset for dwd-weeder %w", err)
+		return fmt.Errorf("failed creating clientset for dwd-weeder %w", err)
 	}
 
+	weederMgr := weeder.NewManager()
 	if err := (&endpoint.Reconciler{
 		Client:       mgr.GetClient(),
 		SeedClient:   clientSet,
 		WeederConfig: weederConfig,
-		WeederMgr:    weeder.NewManager(),
+		WeederMgr:    weederMgr,
+		ResyncPeriod: opts.EndpointResyncPeriod,
 	}).SetupWithManager(mgr); err != nil {
-		return nil, fmt.Errorf("failed to register endpoint reconciler with weeder controller manager %w", err)
+		return fmt.Errorf("failed to register endpoint reconciler with weeder controller manager %w", err)
 	}
-	return mgr, nil
+	if err := addShutdownHook(mgr, func() { weederMgr.Shutdown(logger) }); err != nil {
+		return fmt.Errorf("failed to add the weeder shutdown hook to weeder controller manager %w", err)
+	}
+	return nil
 }
@@ -7,6 +7,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/gardener/dependency-watchdog/internal/util"
 
@@ -28,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/scale"
+	"k8s.io/client-go/util/flowcontrol"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -50,10 +52,17 @@ type Reconciler struct {
 	DefaultProbeConfig *papi.Config
 	// MaxConcurrentReconciles is the maximum number of concurrent Reconciles which can be run. Defaults to 1.
 	MaxConcurrentReconciles int
+	// ScaleWithServerSideApply if set to true will make the scalers update the scale subresource of dependent resources via server-side apply.
+	ScaleWithServerSideApply bool
+	// ScalerReadRateLimiter if set is shared by the scalers of all probers and throttles their reads of dependent resources.
+	ScalerReadRateLimiter flowcontrol.RateLimiter
 }
 
 //+kubebuilder:rbac:groups=gardener.cloud,resources=clusters,verbs=get;list;watch
 //+kubebuilder:rbac:groups=gardener.cloud,resources=clusters/status,verbs=get
+//+kubebuilder:rbac:resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=selfsubjectaccessreviews,verbs=create
 
 // Reconcile listens to create/update/delete events for `Cluster` resources and
 // manages probes for the shoot control namespace for these clusters by looking at the cluster state.
@@ -121,9 +130,39 @@ func (r *Reconciler) startProber(ctx context.Context, shootControlNs string, sho
 
 func (r *Reconciler) createAndRunProber(ctx context.Context, shootNamespace string, shoot *v1beta1.Shoot, workerNodeConditions map[string][]string, logger logr.Logger) {
 	probeConfig := r.getEffectiveProbeConfig(shoot, logger)
-	deploymentScaler := scaler.NewScaler(shootNamespace, probeConfig.DependentResourceInfos, r.Client, r.ScaleGetter, logger)
+	var levelTimeout time.Duration
+	if probeConfig.LevelTimeout != nil {
+		levelTimeout = probeConfig.LevelTimeout.Duration
+	}
+	var scaleCallTimeout time.Duration
+	if probeConfig.ScaleCallTimeout != nil {
+		scaleCallTimeout = probeConfig.ScaleCallTimeout.Duration
+	}
+	deploymentScaler := scaler.NewScaler(shootNamespace, probeConfig.DependentResourceInfos, r.Client, r.ScaleGetter, logger,
+		scaler.WithServerSideApply(r.ScaleWithServerSideApply),
+		scaler.WithScaleUpDisabled(probeConfig.ScaleUpDisabled),
+		scaler.WithFastScaleUp(probeConfig.FastScaleUp),
+		scaler.WithLevelTimeout(levelTimeout),
+		scaler.WithContinueOnLevelTimeout(probeConfig.ContinueOnLevelTimeout),
+		scaler.WithExternallyManagedSelector(probeConfig.ExternallyManagedSelector),
+		scaler.WithDependentResourceSelector(probeConfig.DependentResourceSelector),
+		scaler.WithWaitOnReplicasStatusField(probeConfig.WaitOnReplicasStatusField),
+		scaler.WithScaleDownTerminationCheck(probeConfig.VerifyScaleDownTermination),
+		scaler.WithAwaitScaleDownTermination(probeConfig.AwaitScaleDownTermination),
+		scaler.WithScalePausedDeployments(probeConfig.ScalePausedDeployments),
+		scaler.WithScaleCallTimeout(scaleCallTimeout),
+		scaler.WithIgnoreScalingAnnotationKey(probeConfig.IgnoreScalingAnnotationKey),
+		scaler.WithRemoveIgnoreScalingAnnotationOnScaleUp(probeConfig.RemoveIgnoreScalingAnnotationOnScaleUp),
+		scaler.WithReadRateLimiter(r.ScalerReadRateLimiter),
+		scaler.WithScalingPause(r.ProberMgr.GetScalingPause()))
 	shootClientCreator := shootclient.NewClientCreator(shootNamespace, probeConfig.KubeConfigSecretName, r.Client)
-	p := prober.NewProber(ctx, r.Client, shootNamespace, probeConfig, workerNodeConditions, deploymentScaler, shootClientCreator, logger)
+	endpointClientCreators := make([]shootclient.ClientCreator, 0, len(probeConfig.AdditionalKubeConfigSecretNames))
+	for _, secretName := range probeConfig.AdditionalKubeConfigSecretNames {
+		endpointClientCreators = append(endpointClientCreators, shootclient.NewClientCreator(shootNamespace, secretName, r.Client))
+	}
+	p := prober.NewProber(ctx, r.Client, shootNamespace, probeConfig, workerNodeConditions, deploymentScaler, shootClientCreator, logger,
+		prober.WithScalingFlowLimiter(r.ProberMgr.GetScalingFlowLimiter()),
+		prober.WithAPIServerEndpointClientCreators(endpointClientCreators...))
 	r.ProberMgr.Register(*p)
 	logger.Info("Starting a new prober")
 	go p.Run()
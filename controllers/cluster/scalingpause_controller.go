@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	scalingPauseControllerName = "scaling-pause"
+	// ScalingPauseConfigMapName is the name of the sentinel ConfigMap which pauses the scaling of all probers while it exists.
+	// It is looked up in the namespace in which DWD is deployed.
+	ScalingPauseConfigMapName = "dependency-watchdog-scaling-pause"
+)
+
+// ScalingPauseReconciler reconciles the sentinel ConfigMap ScalingPauseConfigMapName and pauses the scaling of all probers
+// while it exists, e.g. during a large maintenance of the seed. The probers keep probing, but their scale-ups and scale-downs are no-ops.
+type ScalingPauseReconciler struct {
+	Client client.Client
+	// Namespace is the namespace of the sentinel ConfigMap.
+	Namespace string
+	// ScalingPause is the switch shared by the scalers of all probers, see prober.Manager.GetScalingPause.
+	ScalingPause *scaler.ScalingPause
+}
+
+//+kubebuilder:rbac:resources=configmaps,verbs=get;list;watch
+
+// Reconcile pauses the scaling if the sentinel ConfigMap exists and is not being deleted, otherwise it resumes the scaling.
+func (r *ScalingPauseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	cm := &corev1.ConfigMap{}
+	paused := true
+	if err := r.Client.Get(ctx, req.NamespacedName, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("unable to get the scaling pause configmap: %w", err)
+		}
+		paused = false
+	} else if cm.DeletionTimestamp != nil {
+		paused = false
+	}
+	if r.ScalingPause.SetPaused(paused) {
+		if paused {
+			log.Info("Scaling of all probers has been paused via the sentinel configmap")
+		} else {
+			log.Info("Scaling of all probers has been resumed as the sentinel configmap has been removed")
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only the sentinel ConfigMap is reconciled.
+func (r *ScalingPauseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	sentinel := types.NamespacedName{Namespace: r.Namespace, Name: ScalingPauseConfigMapName}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(scalingPauseControllerName).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetNamespace() == sentinel.Namespace && obj.GetName() == sentinel.Name
+		}))).
+		Complete(r)
+}
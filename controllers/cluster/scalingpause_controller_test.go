@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const scalingPauseTestNamespace = "garden"
+
+func TestScalingPauseReconcilerShouldReflectSentinelConfigMap(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	sentinel := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ScalingPauseConfigMapName, Namespace: scalingPauseTestNamespace}}
+	cl := fake.NewClientBuilder().Build()
+	r := &ScalingPauseReconciler{Client: cl, Namespace: scalingPauseTestNamespace, ScalingPause: scaler.NewScalingPause()}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: ScalingPauseConfigMapName, Namespace: scalingPauseTestNamespace}}
+
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.ScalingPause.IsPaused()).To(BeFalse(), "scaling should not be paused without the sentinel configmap")
+
+	g.Expect(cl.Create(ctx, sentinel)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.ScalingPause.IsPaused()).To(BeTrue(), "scaling should be paused once the sentinel configmap has been created")
+
+	g.Expect(cl.Delete(ctx, sentinel)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.ScalingPause.IsPaused()).To(BeFalse(), "scaling should be resumed once the sentinel configmap has been deleted")
+}
+
+func TestScalingPauseReconcilerShouldResumeScalingWhileSentinelIsBeingDeleted(t *testing.T) {
+	g := NewWithT(t)
+	now := metav1.Now()
+	sentinel := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ScalingPauseConfigMapName, Namespace: scalingPauseTestNamespace, DeletionTimestamp: &now, Finalizers: []string{"test"}}}
+	r := &ScalingPauseReconciler{Client: fake.NewClientBuilder().WithObjects(sentinel).Build(), Namespace: scalingPauseTestNamespace, ScalingPause: scaler.NewScalingPause()}
+	r.ScalingPause.SetPaused(true)
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: ScalingPauseConfigMapName, Namespace: scalingPauseTestNamespace}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.ScalingPause.IsPaused()).To(BeFalse())
+}
@@ -6,6 +6,7 @@ package endpoint
 
 import (
 	wapi "github.com/gardener/dependency-watchdog/api/weeder"
+	"github.com/gardener/dependency-watchdog/internal/weeder"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -22,10 +23,8 @@ func ReadyEndpoints(logger logr.Logger) predicate.Predicate {
 		if !ok || ep == nil {
 			return false
 		}
-		for _, subset := range ep.Subsets {
-			if len(subset.Addresses) > 0 {
-				return true
-			}
+		if IsEndpointReady(ep) {
+			return true
 		}
 		log.Info("Endpoint does not have any IP address. Skipping processing this endpoint", "namespace", ep.Namespace, "endpoint", ep.Name)
 		return false
@@ -57,6 +56,46 @@ func ReadyEndpoints(logger logr.Logger) predicate.Predicate {
 	}
 }
 
+// EndpointsTurningNotReady is a predicate to allow only update events for endpoints which transition from ready to not ready.
+func EndpointsTurningNotReady() predicate.Predicate {
+	isEndpointReady := func(obj runtime.Object) bool {
+		ep, ok := obj.(*v1.Endpoints)
+		return ok && ep != nil && IsEndpointReady(ep)
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(_ event.CreateEvent) bool {
+			return false
+		},
+
+		UpdateFunc: func(event event.UpdateEvent) bool {
+			newEp, ok := event.ObjectNew.(*v1.Endpoints)
+			if !ok || newEp == nil {
+				return false
+			}
+			return isEndpointReady(event.ObjectOld) && !IsEndpointReady(newEp)
+		},
+
+		DeleteFunc: func(_ event.DeleteEvent) bool {
+			return false
+		},
+
+		GenericFunc: func(_ event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// IsEndpointReady checks if there is at least a single endpoint subset that has at least one IP address assigned.
+func IsEndpointReady(ep *v1.Endpoints) bool {
+	for _, subset := range ep.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // MatchingEndpoints is a predicate to allow events for only configured endpoints
 func MatchingEndpoints(epMap map[string]wapi.DependantSelectors) predicate.Predicate {
 	isMatchingEndpoints := func(obj runtime.Object, epMap map[string]wapi.DependantSelectors) bool {
@@ -86,3 +125,34 @@ func MatchingEndpoints(epMap map[string]wapi.DependantSelectors) predicate.Predi
 		},
 	}
 }
+
+// PermittedEndpoints is a predicate to allow events for only those endpoints whose service is permitted to be weeded by the
+// allowlist and denylist of the config, see weeder.IsServicePermitted. Unlike MatchingEndpoints it also considers the namespace
+// of an endpoint, so that endpoints of configured services in namespaces which are not weeded do not cause any reconcile.
+func PermittedEndpoints(config *wapi.Config) predicate.Predicate {
+	isPermittedEndpoints := func(obj runtime.Object) bool {
+		ep, ok := obj.(*v1.Endpoints)
+		if !ok || ep == nil {
+			return false
+		}
+		return weeder.IsServicePermitted(config, ep.Namespace, ep.Name)
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(event event.CreateEvent) bool {
+			return isPermittedEndpoints(event.Object)
+		},
+
+		UpdateFunc: func(event event.UpdateEvent) bool {
+			return isPermittedEndpoints(event.ObjectNew)
+		},
+
+		DeleteFunc: func(_ event.DeleteEvent) bool {
+			return false
+		},
+
+		GenericFunc: func(event event.GenericEvent) bool {
+			return isPermittedEndpoints(event.Object)
+		},
+	}
+}
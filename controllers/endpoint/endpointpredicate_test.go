@@ -127,6 +127,38 @@ func TestReadyEndpoints(t *testing.T) {
 	}
 }
 
+func TestEndpointsTurningNotReady(t *testing.T) {
+	g := NewWithT(t)
+	predicate := EndpointsTurningNotReady()
+
+	readyEp := &v1.Endpoints{}
+	turnReady(readyEp)
+
+	notReadyEp := &v1.Endpoints{}
+
+	testcases := []struct {
+		name                            string
+		ep                              *v1.Endpoints
+		oldEp                           *v1.Endpoints
+		expectedUpdateEventFilterOutput bool
+	}{
+		{name: "Ready ep -> NotReady ep", ep: notReadyEp, oldEp: readyEp, expectedUpdateEventFilterOutput: true},
+		{name: "NotReady ep -> Ready ep", ep: readyEp, oldEp: notReadyEp, expectedUpdateEventFilterOutput: false},
+		{name: "Ready ep -> Ready ep", ep: readyEp, oldEp: readyEp, expectedUpdateEventFilterOutput: false},
+		{name: "NotReady ep -> NotReady ep", ep: notReadyEp, oldEp: notReadyEp, expectedUpdateEventFilterOutput: false},
+		{name: "Ready ep -> no ep", oldEp: readyEp, expectedUpdateEventFilterOutput: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(_ *testing.T) {
+			g.Expect(predicate.Create(event.CreateEvent{Object: tc.ep})).To(BeFalse())
+			g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: tc.oldEp, ObjectNew: tc.ep})).To(Equal(tc.expectedUpdateEventFilterOutput))
+			g.Expect(predicate.Delete(event.DeleteEvent{Object: tc.ep})).To(BeFalse())
+			g.Expect(predicate.Generic(event.GenericEvent{Object: tc.ep})).To(BeFalse())
+		})
+	}
+}
+
 func TestMatchingEndpointsPredicate(t *testing.T) {
 	g := NewWithT(t)
 
@@ -250,3 +282,43 @@ func TestMatchingEndpointsPredicate(t *testing.T) {
 		})
 	}
 }
+
+func TestPermittedEndpointsPredicate(t *testing.T) {
+	config := &v12.Config{
+		ServicesAndDependantSelectors: map[string]v12.DependantSelectors{"kube-apiserver": {}, "etcd-main-client": {}},
+		Allowlist:                     []v12.ServiceMatcher{{Namespace: "shoot--dev--a"}, {Namespace: "shoot--dev--b"}},
+		Denylist:                      []v12.ServiceMatcher{{Namespace: "shoot--dev--b", Service: "etcd-main-client"}},
+	}
+	predicate := PermittedEndpoints(config)
+
+	testcases := []struct {
+		name           string
+		namespace      string
+		service        string
+		expectedOutput bool
+	}{
+		{name: "allowlisted namespace", namespace: "shoot--dev--a", service: "kube-apiserver", expectedOutput: true},
+		{name: "namespace which is not allowlisted", namespace: "shoot--dev--c", service: "kube-apiserver", expectedOutput: false},
+		{name: "denylisted service in allowlisted namespace", namespace: "shoot--dev--b", service: "etcd-main-client", expectedOutput: false},
+		{name: "service which is not denylisted in allowlisted namespace", namespace: "shoot--dev--b", service: "kube-apiserver", expectedOutput: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ep := &v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace, Name: tc.service}}
+			g.Expect(predicate.Create(event.CreateEvent{Object: ep})).To(Equal(tc.expectedOutput))
+			g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: ep, ObjectNew: ep})).To(Equal(tc.expectedOutput))
+			g.Expect(predicate.Delete(event.DeleteEvent{Object: ep})).To(BeFalse())
+			g.Expect(predicate.Generic(event.GenericEvent{Object: ep})).To(Equal(tc.expectedOutput))
+		})
+	}
+}
+
+func TestPermittedEndpointsPredicateShouldAllowAllEndpointsWithoutAllowlistAndDenylist(t *testing.T) {
+	g := NewWithT(t)
+	predicate := PermittedEndpoints(&v12.Config{ServicesAndDependantSelectors: map[string]v12.DependantSelectors{"kube-apiserver": {}}})
+	ep := &v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "shoot--dev--a", Name: "kube-apiserver"}}
+	g.Expect(predicate.Create(event.CreateEvent{Object: ep})).To(BeTrue())
+	g.Expect(predicate.Update(event.UpdateEvent{ObjectOld: ep, ObjectNew: ep})).To(BeTrue())
+}
@@ -6,13 +6,18 @@ package endpoint
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	wapi "github.com/gardener/dependency-watchdog/api/weeder"
 	"github.com/gardener/dependency-watchdog/internal/weeder"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -22,7 +27,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-const controllerName = "endpoint"
+const (
+	controllerName    = "endpoint"
+	eventRecorderName = "dependency-watchdog-weeder"
+)
 
 // Reconciler EndpointReconciler reconciles an Endpoints object
 type Reconciler struct {
@@ -31,10 +39,29 @@ type Reconciler struct {
 	WeederConfig            *wapi.Config
 	WeederMgr               weeder.Manager
 	MaxConcurrentReconciles int
+	// ResyncPeriod is the period after which a ready endpoint is reconciled again, even if it has not changed. This
+	// ensures that dependent pods which were missed (e.g. due to a restart of the controller) are eventually weeded.
+	// A resync only starts a new weeder if the weeder of the endpoint has finished watching, a running weeder is not
+	// replaced unless the endpoint has changed. If it is not positive then endpoints are only reconciled when they transition to ready.
+	ResyncPeriod time.Duration
+	// EventRecorder is used to record events for pods deleted by the weeders. If it is not set then an event recorder
+	// will be obtained from the controller manager.
+	EventRecorder record.EventRecorder
+
+	// clock is used to measure for how long endpoints have been ready. If it is not set then the real clock is used.
+	clock clock.PassiveClock
+	// readySinceMu guards readySince.
+	readySinceMu sync.Mutex
+	// readySince records since when each endpoint has been observed to be ready continuously. It is only maintained if an
+	// EndpointStabilityDuration is configured.
+	readySince map[types.NamespacedName]time.Time
 }
 
 // +kubebuilder:rbac:resources=endpoints,verbs=get;list;watch
 // +kubebuilder:rbac:resources=pods,verbs=get;list;watch;delete
+// +kubebuilder:rbac:resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;patch
 
 // Reconcile listens to create/update events for `Endpoints` resources and manages weeder which shoot the dependent pods of the configured services, if necessary
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -43,16 +70,95 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	var ep v1.Endpoints
 	err := r.Client.Get(ctx, req.NamespacedName, &ep)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forgetReadySince(req.NamespacedName)
+		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
+	if !weeder.IsServicePermitted(r.WeederConfig, req.Namespace, ep.Name) {
+		log.Info("Skipping starting a weeder for endpoint as weeding of its service is not permitted by the allowlist/denylist", "namespace", req.Namespace, "endpoint", ep.Name)
+		return ctrl.Result{}, nil
+	}
+	// A periodic resync bypasses the predicates, so the readiness of the endpoint has to be checked again.
+	if !IsEndpointReady(&ep) {
+		r.forgetReadySince(req.NamespacedName)
+		log.Info("Endpoint does not have any IP address. Skipping starting a weeder for this endpoint", "namespace", req.Namespace, "endpoint", ep.Name)
+		return r.resyncResult(), nil
+	}
+	if remaining := r.remainingStabilityDuration(req.NamespacedName); remaining > 0 {
+		log.Info("Endpoint has not been ready for the endpoint stability duration yet. Deferring starting a weeder for this endpoint", "namespace", req.Namespace, "endpoint", ep.Name, "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	if r.isWeederRunningFor(&ep) {
+		log.Info("Weeder for endpoint is still running and the endpoint has not changed. Skipping starting a new weeder for this endpoint", "namespace", req.Namespace, "endpoint", ep.Name)
+		return r.resyncResult(), nil
+	}
 	log.Info("Starting a new weeder for endpoint, replacing old weeder, if any exists", "namespace", req.Namespace, "endpoint", ep.Name)
 	r.startWeeder(ctx, log, req.Namespace, &ep)
-	return ctrl.Result{}, nil
+	return r.resyncResult(), nil
+}
+
+// isWeederRunningFor returns true if the weeder registered for the endpoint has not finished watching yet and has been
+// started for the same resource version of the endpoint, which is the case when an unchanged endpoint is resynced. Replacing
+// such a weeder would restart its watch duration and reset its per-transition limits.
+func (r *Reconciler) isWeederRunningFor(ep *v1.Endpoints) bool {
+	registration, ok := r.WeederMgr.GetWeederRegistration(ep.Namespace + "/" + ep.Name)
+	return ok && !registration.IsClosed() && registration.EndpointsResourceVersion() == ep.ResourceVersion
+}
+
+// resyncResult returns a result which requeues the endpoint after the configured ResyncPeriod, if any.
+func (r *Reconciler) resyncResult() ctrl.Result {
+	if r.ResyncPeriod <= 0 {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}
+}
+
+// endpointStabilityDuration returns the duration for which an endpoint has to be ready before a weeder is started for it.
+func (r *Reconciler) endpointStabilityDuration() time.Duration {
+	if r.WeederConfig.EndpointStabilityDuration == nil {
+		return 0
+	}
+	return r.WeederConfig.EndpointStabilityDuration.Duration
+}
+
+// remainingStabilityDuration returns for how much longer the endpoint identified by key has to remain ready before a weeder is
+// started for it. The first call for an endpoint which is ready starts its stability duration.
+func (r *Reconciler) remainingStabilityDuration(key types.NamespacedName) time.Duration {
+	stabilityDuration := r.endpointStabilityDuration()
+	if stabilityDuration <= 0 {
+		return 0
+	}
+	var clk clock.PassiveClock = clock.RealClock{}
+	if r.clock != nil {
+		clk = r.clock
+	}
+	now := clk.Now()
+	r.readySinceMu.Lock()
+	defer r.readySinceMu.Unlock()
+	if r.readySince == nil {
+		r.readySince = make(map[types.NamespacedName]time.Time)
+	}
+	readySince, ok := r.readySince[key]
+	if !ok {
+		readySince = now
+		r.readySince[key] = now
+	}
+	return stabilityDuration - now.Sub(readySince)
+}
+
+// forgetReadySince restarts the stability duration of the endpoint identified by key once it is ready again. It is also
+// called once the endpoint has been deleted, so that its entry does not outlive it.
+func (r *Reconciler) forgetReadySince(key types.NamespacedName) {
+	r.readySinceMu.Lock()
+	defer r.readySinceMu.Unlock()
+	delete(r.readySince, key)
 }
 
-// startWeeder starts a new weeder for the endpoint
+// startWeeder starts a new weeder for the endpoint. Registering it closes the weeder previously started for the endpoint,
+// if any, which stops its pod watches. The new weeder only starts watching pods once they have stopped.
 func (r *Reconciler) startWeeder(ctx context.Context, logger logr.Logger, namespace string, ep *v1.Endpoints) {
-	w := weeder.NewWeeder(ctx, namespace, r.WeederConfig, r.Client, r.SeedClient, ep, logger)
+	w := weeder.NewWeeder(ctx, namespace, r.WeederConfig, r.Client, r.SeedClient, r.EventRecorder, ep, logger, weeder.WithPodRemediators(r.WeederMgr.GetPodRemediators()...))
 	// Register the weeder
 	r.WeederMgr.Register(*w)
 	go w.Run()
@@ -60,6 +166,9 @@ func (r *Reconciler) startWeeder(ctx context.Context, logger logr.Logger, namesp
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.EventRecorder == nil {
+		r.EventRecorder = mgr.GetEventRecorderFor(eventRecorderName)
+	}
 	c, err := controller.New(
 		controllerName,
 		mgr,
@@ -70,13 +179,19 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return err
 	}
+	readinessPredicate := ReadyEndpoints(c.GetLogger())
+	if r.endpointStabilityDuration() > 0 {
+		// endpoints turning not ready have to be reconciled as well, otherwise the stability duration of a flapping endpoint is not restarted.
+		readinessPredicate = predicate.Or[client.Object](EndpointsTurningNotReady(), readinessPredicate)
+	}
 	return c.Watch(
 		source.Kind[client.Object](mgr.GetCache(), &v1.Endpoints{},
 			&handler.EnqueueRequestForObject{},
 			predicate.And[client.Object](
 				predicate.ResourceVersionChangedPredicate{},
 				MatchingEndpoints(r.WeederConfig.ServicesAndDependantSelectors),
-				ReadyEndpoints(c.GetLogger()),
+				PermittedEndpoints(r.WeederConfig),
+				readinessPredicate,
 			),
 		),
 	)
@@ -17,7 +17,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/rand"
 
 	testutil "github.com/gardener/dependency-watchdog/internal/test"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 
 	internalutils "github.com/gardener/dependency-watchdog/internal/util"
 	v1 "k8s.io/api/core/v1"
@@ -25,12 +27,15 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
 	weederpackage "github.com/gardener/dependency-watchdog/internal/weeder"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	testingclock "k8s.io/utils/clock/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 )
 
@@ -41,6 +46,7 @@ const (
 	crashingPod                   = "pod-c"
 	testPodName                   = "test-pod"
 	testdataPath                  = "testdata"
+	testResyncPeriod              = 20 * time.Second
 )
 
 var (
@@ -53,7 +59,7 @@ var (
 	}
 )
 
-func setupWeederEnv(ctx context.Context, t *testing.T, kubeApiServerFlags map[string]string) (*envtest.Environment, *Reconciler) {
+func setupWeederEnv(ctx context.Context, t *testing.T, kubeApiServerFlags map[string]string, resyncPeriod time.Duration) (*envtest.Environment, *Reconciler) {
 	s := scheme.Scheme
 	g := NewWithT(t)
 
@@ -78,6 +84,7 @@ func setupWeederEnv(ctx context.Context, t *testing.T, kubeApiServerFlags map[st
 		SeedClient:              clientSet,
 		WeederMgr:               weederpackage.NewManager(),
 		MaxConcurrentReconciles: maxConcurrentReconcilesWeeder,
+		ResyncPeriod:            resyncPeriod,
 	}
 
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
@@ -113,10 +120,185 @@ func TestEndpointsControllerSuite(t *testing.T) {
 	}
 }
 
+func TestReconcileShouldNotStartWeederForDenylistedService(t *testing.T) {
+	const namespace = "shoot--dev--sensitive"
+	tests := []struct {
+		description         string
+		allowlist           []wapi.ServiceMatcher
+		denylist            []wapi.ServiceMatcher
+		expectWeederStarted bool
+	}{
+		{"weeder should be started for a configured service", nil, nil, true},
+		{"weeder should not be started for a denylisted service", nil, []wapi.ServiceMatcher{{Namespace: namespace}}, false},
+		{"weeder should not be started for a denylisted service even if it is allowlisted", []wapi.ServiceMatcher{{Service: epName}}, []wapi.ServiceMatcher{{Namespace: namespace, Service: epName}}, false},
+		{"weeder should not be started for a service which is not allowlisted", []wapi.ServiceMatcher{{Service: "kube-apiserver"}}, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+			weederConfig, err := weederpackage.LoadConfig(filepath.Join(testdataPath, "weeder-config.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			weederConfig.Allowlist = test.allowlist
+			weederConfig.Denylist = test.denylist
+			ep := newEndpoint(epName, namespace)
+			reconciler := &Reconciler{
+				Client:        fakeclient.NewClientBuilder().WithObjects(ep).Build(),
+				SeedClient:    fakeclientset.NewSimpleClientset(),
+				WeederConfig:  weederConfig,
+				WeederMgr:     weederpackage.NewManager(),
+				EventRecorder: record.NewFakeRecorder(1),
+			}
+			defer reconciler.WeederMgr.UnregisterAll()
+
+			_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ep)})
+			g.Expect(err).ToNot(HaveOccurred())
+			_, weederStarted := reconciler.WeederMgr.GetWeederRegistration(namespace + "/" + epName)
+			g.Expect(weederStarted).To(Equal(test.expectWeederStarted))
+		})
+	}
+}
+
+func TestReconcileShouldNotStartWeederForFlappingEndpoint(t *testing.T) {
+	const (
+		namespace         = "shoot--dev--flapping"
+		stabilityDuration = time.Minute
+	)
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	weederConfig, err := weederpackage.LoadConfig(filepath.Join(testdataPath, "weeder-config.yaml"))
+	g.Expect(err).ToNot(HaveOccurred())
+	weederConfig.EndpointStabilityDuration = &metav1.Duration{Duration: stabilityDuration}
+	ep := newEndpoint(epName, namespace)
+	cl := fakeclient.NewClientBuilder().WithObjects(ep).Build()
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	reconciler := &Reconciler{
+		Client:        cl,
+		SeedClient:    fakeclientset.NewSimpleClientset(),
+		WeederConfig:  weederConfig,
+		WeederMgr:     weederpackage.NewManager(),
+		EventRecorder: record.NewFakeRecorder(1),
+		clock:         fakeClock,
+	}
+	defer reconciler.WeederMgr.UnregisterAll()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ep)}
+	expectWeederStarted := func(started bool) {
+		_, weederStarted := reconciler.WeederMgr.GetWeederRegistration(namespace + "/" + epName)
+		g.Expect(weederStarted).To(Equal(started))
+	}
+
+	// the endpoint turns ready, the weeder is deferred till it has been ready for the stability duration.
+	result, err := reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(stabilityDuration))
+	expectWeederStarted(false)
+
+	// the endpoint flaps within the stability duration.
+	fakeClock.SetTime(fakeClock.Now().Add(stabilityDuration / 2))
+	turnEndpointToNotReady(ctx, g, cl, ep)
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	expectWeederStarted(false)
+	g.Expect(cl.Get(ctx, req.NamespacedName, ep)).To(Succeed())
+	ep.Subsets = newEndpoint(epName, namespace).Subsets
+	g.Expect(cl.Update(ctx, ep)).To(Succeed())
+	result, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(stabilityDuration), "the stability duration should restart once the endpoint is ready again")
+	expectWeederStarted(false)
+
+	// the requeue for the first time the endpoint turned ready must not start a weeder prematurely.
+	fakeClock.SetTime(fakeClock.Now().Add(stabilityDuration / 2))
+	result, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(stabilityDuration / 2))
+	expectWeederStarted(false)
+
+	fakeClock.SetTime(fakeClock.Now().Add(stabilityDuration / 2))
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	expectWeederStarted(true)
+}
+
+func TestReconcileShouldForgetReadySinceOfDeletedEndpoint(t *testing.T) {
+	const namespace = "shoot--dev--deleted"
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	weederConfig, err := weederpackage.LoadConfig(filepath.Join(testdataPath, "weeder-config.yaml"))
+	g.Expect(err).ToNot(HaveOccurred())
+	weederConfig.EndpointStabilityDuration = &metav1.Duration{Duration: time.Minute}
+	ep := newEndpoint(epName, namespace)
+	cl := fakeclient.NewClientBuilder().WithObjects(ep).Build()
+	reconciler := &Reconciler{
+		Client:        cl,
+		SeedClient:    fakeclientset.NewSimpleClientset(),
+		WeederConfig:  weederConfig,
+		WeederMgr:     weederpackage.NewManager(),
+		EventRecorder: record.NewFakeRecorder(1),
+	}
+	defer reconciler.WeederMgr.UnregisterAll()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ep)}
+
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reconciler.readySince).To(HaveKey(req.NamespacedName))
+
+	g.Expect(cl.Delete(ctx, ep)).To(Succeed())
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	g.Expect(reconciler.readySince).To(BeEmpty(), "the endpoint should be forgotten once it has been deleted")
+}
+
+func TestResyncOfUnchangedEndpointShouldNotReplaceRunningWeeder(t *testing.T) {
+	const namespace = "shoot--dev--resync"
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	weederConfig, err := weederpackage.LoadConfig(filepath.Join(testdataPath, "weeder-config.yaml"))
+	g.Expect(err).ToNot(HaveOccurred())
+	ep := newEndpoint(epName, namespace)
+	cl := fakeclient.NewClientBuilder().WithObjects(ep).Build()
+	reconciler := &Reconciler{
+		Client:        cl,
+		SeedClient:    fakeclientset.NewSimpleClientset(),
+		WeederConfig:  weederConfig,
+		WeederMgr:     weederpackage.NewManager(),
+		EventRecorder: record.NewFakeRecorder(1),
+		ResyncPeriod:  testResyncPeriod,
+	}
+	defer reconciler.WeederMgr.UnregisterAll()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ep)}
+	key := namespace + "/" + epName
+
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	firstRegistration, ok := reconciler.WeederMgr.GetWeederRegistration(key)
+	g.Expect(ok).To(BeTrue())
+
+	// a resync of the unchanged endpoint keeps the running weeder.
+	result, err := reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(testResyncPeriod))
+	g.Expect(firstRegistration.IsClosed()).To(BeFalse(), "the running weeder should not be replaced on resync")
+	registration, _ := reconciler.WeederMgr.GetWeederRegistration(key)
+	g.Expect(registration.Stopped()).To(Equal(firstRegistration.Stopped()))
+
+	// a change of the endpoint replaces the running weeder.
+	g.Expect(cl.Get(ctx, req.NamespacedName, ep)).To(Succeed())
+	ep.Subsets[0].Addresses = append(ep.Subsets[0].Addresses, v1.EndpointAddress{IP: "10.1.0.53"})
+	g.Expect(cl.Update(ctx, ep)).To(Succeed())
+	_, err = reconciler.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(firstRegistration.IsClosed()).To(BeTrue(), "the weeder should be replaced once the endpoint has changed")
+}
+
 func testWeederSharedEnvTest(t *testing.T) {
 	g := NewWithT(t)
 	ctx, cancelFn := context.WithCancel(context.Background())
-	testEnv, reconciler := setupWeederEnv(ctx, t, nil)
+	testEnv, reconciler := setupWeederEnv(ctx, t, nil, 0)
 	defer testutil.TeardownEnv(g, testEnv, cancelFn)
 
 	tests := []struct {
@@ -150,13 +332,15 @@ func testWeederDedicatedEnvTest(t *testing.T) {
 		description    string
 		run            func(ctx context.Context, cancelFn context.CancelFunc, g *WithT, reconciler *Reconciler, namespace string)
 		apiServerFlags map[string]string
+		resyncPeriod   time.Duration
 	}{
-		{"testPodWatchEndsAbruptlyBeforeSpecifiedWatchDuration", "single Crashlooping pod should be deleted even when watch on pods times-out in the middle", testPodWatchEndsAbruptlyBeforeSpecifiedWatchDuration, map[string]string{"min-request-timeout": "5"}},
-		{"testNoCLBFPodDeletionOnContextCancellation", "No pod termination happens when main context is cancelled", testNoCLBFPodDeletionOnContextCancellation, nil},
+		{"testPodWatchEndsAbruptlyBeforeSpecifiedWatchDuration", "single Crashlooping pod should be deleted even when watch on pods times-out in the middle", testPodWatchEndsAbruptlyBeforeSpecifiedWatchDuration, map[string]string{"min-request-timeout": "5"}, 0},
+		{"testNoCLBFPodDeletionOnContextCancellation", "No pod termination happens when main context is cancelled", testNoCLBFPodDeletionOnContextCancellation, nil, 0},
+		{"testPodTurningCLBFAfterWatchDurationIsDeletedOnResync", "Single healthy pod turning to CrashLoopBackoff after watchDuration should be deleted when the endpoint is resynced", testPodTurningCLBFAfterWatchDurationIsDeletedOnResync, nil, testResyncPeriod},
 	}
 	for _, test := range tests {
 		ctx, cancelFn := context.WithCancel(context.Background())
-		testEnv, reconciler := setupWeederEnv(ctx, t, test.apiServerFlags)
+		testEnv, reconciler := setupWeederEnv(ctx, t, test.apiServerFlags, test.resyncPeriod)
 		testNs := rand.String(4)
 		testutil.CreateTestNamespace(ctx, g, reconciler.Client, testNs)
 		t.Run(test.description, func(_ *testing.T) {
@@ -174,6 +358,7 @@ func testWeederDedicatedEnvTest(t *testing.T) {
 // case 5: deletion of CLBF pod shouldn't happen if endpoint is not ready (means the serving pod is not present/not ready)
 // case 6: cancelling the context should mean no deletion of CLBF pod happens
 // case 7: watch cancelled by API server, should lead to create of new watch (#dedicated env test)
+// case 8: pod turned CLBF after the watch duration is deleted once the endpoint is resynced (#dedicated env test)
 func testOnlyCLBFPodDeletion(ctx context.Context, _ context.CancelFunc, g *WithT, reconciler *Reconciler, namespace string) {
 	createEp(ctx, g, reconciler, namespace, true)
 	pC := newPod(crashingPod, namespace, "node-0", correctLabels)
@@ -321,6 +506,26 @@ func testPodWatchEndsAbruptlyBeforeSpecifiedWatchDuration(ctx context.Context, _
 	g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "CrashLooping pod should be deleted due to recreation of watch even after cancellation")
 }
 
+func testPodTurningCLBFAfterWatchDurationIsDeletedOnResync(ctx context.Context, _ context.CancelFunc, g *WithT, reconciler *Reconciler, namespace string) {
+	createEp(ctx, g, reconciler, namespace, true)
+	pod := newPod(testPodName, namespace, "node-0", correctLabels)
+
+	err := reconciler.Client.Create(ctx, pod)
+	g.Expect(err).ToNot(HaveOccurred())
+	turnPodToHealthy(ctx, g, reconciler.Client, pod)
+
+	// wait for the weeder started on endpoint creation to stop watching
+	time.Sleep(reconciler.WeederConfig.WatchDuration.Duration + 2*time.Second)
+
+	turnPodToCrashLoop(ctx, g, reconciler.Client, pod)
+
+	// the endpoint has not changed, only a resync will start a new weeder
+	g.Eventually(func() bool {
+		currentPod := v1.Pod{}
+		return apierrors.IsNotFound(reconciler.Client.Get(ctx, client.ObjectKeyFromObject(pod), &currentPod))
+	}).WithTimeout(reconciler.ResyncPeriod+10*time.Second).WithPolling(time.Second).Should(BeTrue(), "CrashLooping pod should be deleted by the weeder started on resync")
+}
+
 func deleteAllPods(ctx context.Context, g *WithT, crClient client.Client) {
 	pl := &v1.PodList{}
 	select {
@@ -58,7 +58,7 @@ func main() {
 	mgr, err := command.Run(logger)
 	if err != nil {
 		logger.Error(err, fmt.Sprintf("failed to run command %s", command.Name))
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 
 	// starting manager
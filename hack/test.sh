@@ -16,4 +16,5 @@ export KUBEBUILDER_ATTACH_CONTROL_PLANE_OUTPUT=true
 # see https://github.com/kubernetes-sigs/controller-runtime/issues/1363 which remains unresolved.
 go test -json -cover ./controllers/cluster | gotestfmt -hide empty-packages
 go test -json -cover ./controllers/endpoint | gotestfmt -hide empty-packages
-go test -json -cover `go list ./internal/... | grep -v fakes | grep -v test` | gotestfmt -hide empty-packages
+go test -json -cover ./internal/prober/scaler | gotestfmt -hide empty-packages
+go test -json -cover `go list ./internal/... | grep -v fakes | grep -v test | grep -v internal/prober/scaler$` | gotestfmt -hide empty-packages
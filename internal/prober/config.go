@@ -5,12 +5,17 @@
 package prober
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
 	"github.com/gardener/dependency-watchdog/internal/util"
+	multierr "github.com/hashicorp/go-multierror"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -36,19 +41,28 @@ const (
 	// See https://kubernetes.io/docs/reference/command-line-tools-reference/kube-controller-manager/#:~:text=%2D%2Dnode%2Dmonitor%2Dgrace%2Dperiod%20duration
 	// Note: Make sure to keep this value in sync with default value of nodeMonitorGracePeriod in KCM.
 	DefaultKCMNodeMonitorGraceDuration = 40 * time.Second
+	// DefaultFailureThreshold is the default number of consecutive failed probes after which a scale down is triggered.
+	DefaultFailureThreshold = 1
+	// DefaultSuccessThreshold is the default number of consecutive successful probes after which a scale up is triggered.
+	DefaultSuccessThreshold = 1
+	// maxScaleDuration is the upper bound for the initial delay and the timeout of scaling a dependent resource. Larger
+	// values are most likely a misconfiguration, e.g. a missing unit, and would stall a scaling flow.
+	maxScaleDuration = time.Hour
 )
 
 // LoadConfig reads the prober configuration from a file, unmarshalls it, fills in the default values and
 // validates the unmarshalled configuration If all validations pass it will return papi.Config else it will return an error.
+// The configuration is unmarshalled strictly, a misspelled or an unknown field is therefore rejected instead of being ignored.
+// The returned error matches util.ErrConfigNotFound, util.ErrConfigParse or util.ErrConfigInvalid, see errors.Is.
 func LoadConfig(file string, scheme *runtime.Scheme) (*papi.Config, error) {
-	config, err := util.ReadAndUnmarshall[papi.Config](file)
+	config, err := util.ReadAndUnmarshallStrict[papi.Config](file)
 	if err != nil {
 		return nil, err
 	}
 	fillDefaultValues(config)
 	err = validate(config, scheme)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", util.ErrConfigInvalid, err)
 	}
 	return config, nil
 }
@@ -60,11 +74,44 @@ func validate(c *papi.Config, scheme *runtime.Scheme) error {
 	if c.KCMNodeMonitorGraceDuration != nil {
 		v.MustNotBeZeroDuration("KCMNodeMonitorGraceDuration", *c.KCMNodeMonitorGraceDuration)
 	}
-	v.MustNotBeEmpty("ScaleResourceInfos", c.DependentResourceInfos)
+	v.DurationMustBePositive("ProbeInterval", c.ProbeInterval)
+	v.DurationMustBePositive("ProbeTimeout", c.ProbeTimeout)
+	v.DurationMustBePositive("LevelTimeout", c.LevelTimeout)
+	v.DurationMustBePositive("ScaleUpStabilizationWindow", c.ScaleUpStabilizationWindow)
+	v.DurationMustBePositive("ScaleCallTimeout", c.ScaleCallTimeout)
+	v.DurationMustBePositive("DependentsImpactedDuration", c.DependentsImpactedDuration)
+	validateLabelSelector(v, "externallyManagedSelector", c.ExternallyManagedSelector)
+	validateLabelSelector(v, "dependentResourceSelector", c.DependentResourceSelector)
+	validateAPIServerEndpoints(v, c.AdditionalKubeConfigSecretNames, c.APIServerFailureQuorum)
+	validateWaitOnReplicasStatusField(v, c.WaitOnReplicasStatusField)
+	validateIgnoreScalingAnnotationKey(v, c.IgnoreScalingAnnotationKey)
+	if c.RemoveIgnoreScalingAnnotationOnScaleUp && c.ScaleUpDisabled {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("removeIgnoreScalingAnnotationOnScaleUp has no effect as scaleUpDisabled is set"))
+	}
+	v.MustBePositive("FailureThreshold", *c.FailureThreshold)
+	v.MustBePositive("SuccessThreshold", *c.SuccessThreshold)
+	if c.DependentResourceSelector == nil {
+		// the dependent resources are otherwise discovered via the selector.
+		v.MustNotBeEmpty("ScaleResourceInfos", c.DependentResourceInfos)
+	}
 	for _, resInfo := range c.DependentResourceInfos {
 		v.ResourceRefMustBeValid(resInfo.Ref, scheme)
 		v.MustNotBeNil("scaleUp", resInfo.ScaleUpInfo)
 		v.MustNotBeNil("scaleDown", resInfo.ScaleDownInfo)
+		validateScaleInfoDurations(v, "scaleUp", resInfo.ScaleUpInfo)
+		validateScaleInfoDurations(v, "scaleDown", resInfo.ScaleDownInfo)
+		validateRetryInfo(v, resInfo.RetryInfo)
+		validateLabelSelector(v, "selector", resInfo.Selector)
+	}
+	validateDependsOn(v, c.DependentResourceInfos)
+	validateSteps(v, c.DependentResourceInfos)
+	validateReplicaDelta(v, c.DependentResourceInfos)
+	validateScaleUpReplicas(v, c.DependentResourceInfos, c.AllowZeroScaleUpReplicas)
+	validateHPA(v, c.DependentResourceInfos)
+	validateReplicasFromHPA(v, c.DependentResourceInfos)
+	if c.StrictSerialLevels {
+		validateSerialLevels(v, "scaleUp", c.DependentResourceInfos, func(resInfo papi.DependentResourceInfo) *papi.ScaleInfo { return resInfo.ScaleUpInfo })
+		validateSerialLevels(v, "scaleDown", c.DependentResourceInfos, func(resInfo papi.DependentResourceInfo) *papi.ScaleInfo { return resInfo.ScaleDownInfo })
 	}
 	if v.Error != nil {
 		return v.Error
@@ -72,6 +119,245 @@ func validate(c *papi.Config, scheme *runtime.Scheme) error {
 	return nil
 }
 
+// validateAPIServerEndpoints checks that the names of the additional kubeconfig secrets are not empty and that the failure
+// quorum can be reached by the Kube ApiServer endpoints, which are the endpoint of KubeConfigSecretName and those of the additional secrets.
+func validateAPIServerEndpoints(v *util.Validator, additionalSecretNames []string, failureQuorum *int) {
+	for _, secretName := range additionalSecretNames {
+		v.MustNotBeEmpty("additionalKubeConfigSecretNames", secretName)
+	}
+	if failureQuorum == nil {
+		return
+	}
+	numEndpoints := 1 + len(additionalSecretNames)
+	if *failureQuorum < 1 || *failureQuorum > numEndpoints {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("apiServerFailureQuorum %d must be between 1 and the number of API server endpoints %d", *failureQuorum, numEndpoints))
+	}
+}
+
+// validateWaitOnReplicasStatusField checks that the status field refers to one of the supported replica counts.
+func validateWaitOnReplicasStatusField(v *util.Validator, field papi.ReplicasStatusField) {
+	switch field {
+	case papi.ReplicasStatusFieldReplicas, papi.ReplicasStatusFieldReadyReplicas, papi.ReplicasStatusFieldAvailableReplicas:
+	default:
+		v.Error = multierr.Append(v.Error, fmt.Errorf("waitOnReplicasStatusField %q is not supported, must be one of %q, %q or %q", field,
+			papi.ReplicasStatusFieldReplicas, papi.ReplicasStatusFieldReadyReplicas, papi.ReplicasStatusFieldAvailableReplicas))
+	}
+}
+
+// validateIgnoreScalingAnnotationKey checks that the ignore scaling annotation key, if specified, is a valid annotation key.
+func validateIgnoreScalingAnnotationKey(v *util.Validator, key string) {
+	if key == "" {
+		return
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("ignoreScalingAnnotationKey %q is not a valid annotation key: %s", key, strings.Join(errs, "; ")))
+	}
+}
+
+// validateScaleInfoDurations checks that the timeout of a scale operation is positive and that neither the timeout nor the
+// initial delay is negative or exceeds maxScaleDuration.
+func validateScaleInfoDurations(v *util.Validator, scaleInfoKey string, scaleInfo *papi.ScaleInfo) {
+	if scaleInfo == nil {
+		return
+	}
+	if v.DurationMustBePositive(scaleInfoKey+".timeout", scaleInfo.Timeout) {
+		v.DurationMustBeWithin(scaleInfoKey+".timeout", scaleInfo.Timeout, 0, maxScaleDuration)
+	}
+	v.DurationMustBeWithin(scaleInfoKey+".initialDelay", scaleInfo.InitialDelay, 0, maxScaleDuration)
+}
+
+// validateRetryInfo checks that the number of attempts and the backoff of the retries of a dependent resource are positive
+// and that the backoff does not exceed maxScaleDuration.
+func validateRetryInfo(v *util.Validator, retryInfo *papi.RetryInfo) {
+	if retryInfo == nil {
+		return
+	}
+	if retryInfo.Attempts != nil {
+		v.MustBePositive("retry.attempts", *retryInfo.Attempts)
+	}
+	if v.DurationMustBePositive("retry.backoff", retryInfo.Backoff) {
+		v.DurationMustBeWithin("retry.backoff", retryInfo.Backoff, 0, maxScaleDuration)
+	}
+}
+
+// validateLabelSelector checks that the selector can be parsed and that it is not empty. An empty externallyManagedSelector
+// would select every dependent resource and thereby disable scale-down altogether, an empty dependentResourceSelector would
+// scale every Deployment in the shoot namespace and the empty selector of a dependent resource would match any resource of its kind.
+func validateLabelSelector(v *util.Validator, fieldName string, selector *metav1.LabelSelector) {
+	if selector == nil {
+		return
+	}
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("%s must not be empty", fieldName))
+		return
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("%s is invalid: %w", fieldName, err))
+	}
+}
+
+// validateDependsOn checks that every resource that is explicitly referenced as a dependency is configured as a
+// dependent resource and that it is at a lower level for the respective scale operation.
+func validateDependsOn(v *util.Validator, resourceInfos []papi.DependentResourceInfo) {
+	scaleUpLevels := make(map[string]int, len(resourceInfos))
+	scaleDownLevels := make(map[string]int, len(resourceInfos))
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		key := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleUpInfo != nil {
+			scaleUpLevels[key] = resInfo.ScaleUpInfo.Level
+		}
+		if resInfo.ScaleDownInfo != nil {
+			scaleDownLevels[key] = resInfo.ScaleDownInfo.Level
+		}
+	}
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		validateScaleInfoDependsOn(v, "scaleUp", *resInfo.Ref, resInfo.ScaleUpInfo, scaleUpLevels)
+		validateScaleInfoDependsOn(v, "scaleDown", *resInfo.Ref, resInfo.ScaleDownInfo, scaleDownLevels)
+	}
+}
+
+func validateScaleInfoDependsOn(v *util.Validator, scaleInfoKey string, ref autoscalingv1.CrossVersionObjectReference, scaleInfo *papi.ScaleInfo, levels map[string]int) {
+	if scaleInfo == nil {
+		return
+	}
+	refKey := util.ResourceRefKey(ref)
+	for _, dep := range scaleInfo.DependsOn {
+		depKey := util.ResourceRefKey(dep)
+		depLevel, ok := levels[depKey]
+		if !ok {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("%s.dependsOn of %s references %s which is not a configured dependent resource", scaleInfoKey, refKey, depKey))
+			continue
+		}
+		if depLevel >= scaleInfo.Level {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("%s.dependsOn of %s references %s which is not at a lower level", scaleInfoKey, refKey, depKey))
+		}
+	}
+}
+
+// validateSteps checks that the intermediate replicas of a staged scale-up are positive and strictly increasing. Staged
+// scaling is not supported for a scale-down.
+func validateSteps(v *util.Validator, resourceInfos []papi.DependentResourceInfo) {
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		refKey := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleDownInfo != nil && len(resInfo.ScaleDownInfo.Steps) > 0 {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleDown.steps of %s is not supported, steps are only applicable to a scale-up", refKey))
+		}
+		if resInfo.ScaleUpInfo == nil {
+			continue
+		}
+		var previous int32
+		for _, step := range resInfo.ScaleUpInfo.Steps {
+			if step <= previous {
+				v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.steps of %s must be positive and strictly increasing, found %v", refKey, resInfo.ScaleUpInfo.Steps))
+				break
+			}
+			previous = step
+		}
+	}
+}
+
+// validateSerialLevels checks that no two dependent resources share a level of the scale operation selected by scaleInfoFn,
+// as resources at the same level are scaled in parallel. It is only applicable if strictSerialLevels is set.
+func validateSerialLevels(v *util.Validator, scaleInfoKey string, resourceInfos []papi.DependentResourceInfo, scaleInfoFn func(papi.DependentResourceInfo) *papi.ScaleInfo) {
+	refKeysByLevel := make(map[int][]string)
+	for _, resInfo := range resourceInfos {
+		scaleInfo := scaleInfoFn(resInfo)
+		if resInfo.Ref == nil || scaleInfo == nil {
+			continue
+		}
+		refKeysByLevel[scaleInfo.Level] = append(refKeysByLevel[scaleInfo.Level], util.ResourceRefKey(*resInfo.Ref))
+	}
+	for _, level := range util.SortedKeys(refKeysByLevel) {
+		if refKeys := refKeysByLevel[level]; len(refKeys) > 1 {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("%s.level %d is shared by %s which would be scaled in parallel, strictSerialLevels requires a distinct level for every resource",
+				scaleInfoKey, level, strings.Join(refKeys, ", ")))
+		}
+	}
+}
+
+// validateScaleUpReplicas checks that the replicas to which a resource is scaled up are positive, or zero if allowZero is set.
+// A scale-up to zero replicas is rejected by default as it does not bring up the resource.
+func validateScaleUpReplicas(v *util.Validator, resourceInfos []papi.DependentResourceInfo, allowZero bool) {
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		refKey := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleDownInfo != nil && resInfo.ScaleDownInfo.Replicas != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleDown.replicas of %s is not supported, replicas are only applicable to a scale-up", refKey))
+		}
+		if resInfo.ScaleUpInfo == nil || resInfo.ScaleUpInfo.Replicas == nil {
+			continue
+		}
+		switch replicas := *resInfo.ScaleUpInfo.Replicas; {
+		case replicas < 0:
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.replicas of %s must not be negative, found %d", refKey, replicas))
+		case replicas == 0 && !allowZero:
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.replicas of %s is zero, which does not bring up the resource. Set allowZeroScaleUpReplicas if this is intended", refKey))
+		}
+	}
+}
+
+// validateReplicaDelta checks that a scale-down by a replica delta removes replicas. Scaling by a replica delta is not
+// supported for a scale-up, which restores the replicas prior to the scale-down instead.
+func validateReplicaDelta(v *util.Validator, resourceInfos []papi.DependentResourceInfo) {
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		refKey := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleUpInfo != nil && resInfo.ScaleUpInfo.ReplicaDelta != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.replicaDelta of %s is not supported, a replica delta is only applicable to a scale-down", refKey))
+		}
+		if resInfo.ScaleDownInfo != nil && resInfo.ScaleDownInfo.ReplicaDelta != nil && *resInfo.ScaleDownInfo.ReplicaDelta >= 0 {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleDown.replicaDelta of %s must be negative, found %d", refKey, *resInfo.ScaleDownInfo.ReplicaDelta))
+		}
+	}
+}
+
+// validateHPA checks that the HorizontalPodAutoscaler of a dependent resource is only scaled along with a resource which has
+// replicas. A CronJob is suspended and resumed instead of being scaled.
+func validateHPA(v *util.Validator, resourceInfos []papi.DependentResourceInfo) {
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil || resInfo.Ref.Kind != "CronJob" {
+			continue
+		}
+		refKey := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleUpInfo != nil && resInfo.ScaleUpInfo.HPA != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.hpa of %s is not supported, a CronJob does not have replicas", refKey))
+		}
+		if resInfo.ScaleDownInfo != nil && resInfo.ScaleDownInfo.HPA != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleDown.hpa of %s is not supported, a CronJob does not have replicas", refKey))
+		}
+	}
+}
+
+// validateReplicasFromHPA checks that the replicas of a scale-up are only read from a HorizontalPodAutoscaler for a resource which
+// has replicas. The replicas of a scale-down are not read from an HPA, which does not act on a resource with zero replicas.
+func validateReplicasFromHPA(v *util.Validator, resourceInfos []papi.DependentResourceInfo) {
+	for _, resInfo := range resourceInfos {
+		if resInfo.Ref == nil {
+			continue
+		}
+		refKey := util.ResourceRefKey(*resInfo.Ref)
+		if resInfo.ScaleDownInfo != nil && resInfo.ScaleDownInfo.ReplicasFromHPA != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleDown.replicasFromHPA of %s is not supported, replicas are only read from an HPA for a scale-up", refKey))
+		}
+		if resInfo.Ref.Kind == "CronJob" && resInfo.ScaleUpInfo != nil && resInfo.ScaleUpInfo.ReplicasFromHPA != nil {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("scaleUp.replicasFromHPA of %s is not supported, a CronJob does not have replicas", refKey))
+		}
+	}
+}
+
 func fillDefaultValues(c *papi.Config) {
 	c.ProbeInterval = util.GetValOrDefault(c.ProbeInterval, metav1.Duration{Duration: DefaultProbeInterval})
 	c.InitialDelay = util.GetValOrDefault(c.InitialDelay, metav1.Duration{Duration: DefaultProbeInitialDelay})
@@ -79,6 +365,11 @@ func fillDefaultValues(c *papi.Config) {
 	c.BackoffJitterFactor = util.GetValOrDefault(c.BackoffJitterFactor, DefaultBackoffJitterFactor)
 	c.NodeLeaseFailureFraction = util.GetValOrDefault(c.NodeLeaseFailureFraction, DefaultNodeLeaseFailureFraction)
 	c.KCMNodeMonitorGraceDuration = util.GetValOrDefault(c.KCMNodeMonitorGraceDuration, metav1.Duration{Duration: DefaultKCMNodeMonitorGraceDuration})
+	c.FailureThreshold = util.GetValOrDefault(c.FailureThreshold, DefaultFailureThreshold)
+	c.SuccessThreshold = util.GetValOrDefault(c.SuccessThreshold, DefaultSuccessThreshold)
+	if c.WaitOnReplicasStatusField == "" {
+		c.WaitOnReplicasStatusField = papi.ReplicasStatusFieldReadyReplicas
+	}
 	fillDefaultValuesForResourceInfos(c.DependentResourceInfos)
 }
 
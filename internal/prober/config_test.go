@@ -7,11 +7,17 @@
 package prober
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	papi "github.com/gardener/dependency-watchdog/api/prober"
 	testutil "github.com/gardener/dependency-watchdog/internal/test"
+	"github.com/gardener/dependency-watchdog/internal/util"
 	multierr "github.com/hashicorp/go-multierror"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -30,6 +36,32 @@ func TestProberConfigSuite(t *testing.T) {
 	}{
 		{"test default values for all missing/optional fields", testCheckIfDefaultValuesAreSetForAllOptionalMissingValues},
 		{"missing mandatory fields should error out", testMissingConfigValuesShouldReturnErrorAndNilConfig},
+		{"invalid threshold values should error out", testInvalidThresholdsShouldReturnErrorAndNilConfig},
+		{"invalid explicit dependencies should error out", testInvalidDependsOnShouldReturnErrorAndNilConfig},
+		{"invalid scale up steps should error out", testInvalidStepsShouldReturnErrorAndNilConfig},
+		{"invalid replica delta should error out", testInvalidReplicaDeltaShouldReturnErrorAndNilConfig},
+		{"empty externally managed selector should error out", testEmptyExternallyManagedSelectorShouldReturnErrorAndNilConfig},
+		{"empty dependent resource selector should error out", testEmptyDependentResourceSelectorShouldReturnErrorAndNilConfig},
+		{"empty selector of a dependent resource should error out", testEmptySelectorOfDependentResourceShouldReturnErrorAndNilConfig},
+		{"dependent resource selector without dependent resources should pass validation", testDependentResourceSelectorWithoutDependentResourceInfosShouldPassValidation},
+		{"invalid scale durations should error out", testInvalidScaleDurationsShouldReturnErrorAndNilConfig},
+		{"invalid scale up replicas should error out", testInvalidScaleUpReplicasShouldReturnErrorAndNilConfig},
+		{"zero scale up replicas should be accepted if allowed", testZeroScaleUpReplicasShouldBeAcceptedIfAllowed},
+		{"unsupported wait on replicas status field should error out", testUnsupportedWaitOnReplicasStatusFieldShouldReturnErrorAndNilConfig},
+		{"invalid retry should error out", testInvalidRetryInfoShouldReturnErrorAndNilConfig},
+		{"non positive scale up stabilization window should error out", testNonPositiveScaleUpStabilizationWindowShouldReturnErrorAndNilConfig},
+		{"non positive dependents impacted duration should error out", testNonPositiveDependentsImpactedDurationShouldReturnErrorAndNilConfig},
+		{"shared levels with strict serial levels should error out", testSharedLevelsWithStrictSerialLevelsShouldReturnErrorAndNilConfig},
+		{"unknown field should error out", testUnknownFieldShouldReturnErrorAndNilConfig},
+		{"unreachable api server failure quorum should error out", testUnreachableAPIServerFailureQuorumShouldReturnErrorAndNilConfig},
+		{"hpa of a cronjob should error out", testHPAForCronJobShouldReturnErrorAndNilConfig},
+		{"replicas from hpa of a cronjob or for a scale-down should error out", testInvalidReplicasFromHPAShouldReturnErrorAndNilConfig},
+		{"invalid ignore scaling annotation key should error out", testInvalidIgnoreScalingAnnotationKeyShouldReturnErrorAndNilConfig},
+		{"removing the ignore scaling annotation on scale up should be loaded", testRemoveIgnoreScalingAnnotationOnScaleUpShouldBeLoaded},
+		{"removing the ignore scaling annotation with scale up disabled should error out", testRemoveIgnoreScalingAnnotationWithScaleUpDisabledShouldReturnErrorAndNilConfig},
+		{"durations given as duration strings should be parsed", testDurationStringsShouldBeParsed},
+		{"invalid duration string should error out", testInvalidDurationStringShouldReturnErrorAndNilConfig},
+		{"duration given as bare number should error out", testBareNumberDurationShouldReturnErrorAndNilConfig},
 		{"config file not found", testConfigFileNotFound},
 		{"invalid configuration yaml", testErrorInUnMarshallingYaml},
 		{"valid configuration yaml", testValidConfigShouldPassAllValidations},
@@ -61,6 +93,9 @@ func testCheckIfDefaultValuesAreSetForAllOptionalMissingValues(t *testing.T, s *
 	g.Expect(*config.BackoffJitterFactor).To(Equal(DefaultBackoffJitterFactor), "LoadConfig should set jitter factor to DefaultJitterFactor if not set in the config file")
 	g.Expect(*config.NodeLeaseFailureFraction).To(Equal(DefaultNodeLeaseFailureFraction), "LoadConfig should set lease failure threshold fraction to DefaultNodeLeaseFailureFraction if not set in the config file")
 	g.Expect(config.KCMNodeMonitorGraceDuration.Milliseconds()).To(Equal(DefaultKCMNodeMonitorGraceDuration.Milliseconds()), "LoadConfig should set kcmNodeMonitorGraceDuration to DefaultKCMNodeMonitorGraceDuration if not set in the config file")
+	g.Expect(*config.FailureThreshold).To(Equal(DefaultFailureThreshold), "LoadConfig should set failureThreshold to DefaultFailureThreshold if not set in the config file")
+	g.Expect(*config.SuccessThreshold).To(Equal(DefaultSuccessThreshold), "LoadConfig should set successThreshold to DefaultSuccessThreshold if not set in the config file")
+	g.Expect(config.WaitOnReplicasStatusField).To(Equal(papi.ReplicasStatusFieldReadyReplicas), "LoadConfig should set waitOnReplicasStatusField to readyReplicas if not set in the config file")
 	for _, resInfo := range config.DependentResourceInfos {
 		g.Expect(resInfo.ScaleUpInfo.InitialDelay.Milliseconds()).To(Equal(DefaultScaleInitialDelay.Milliseconds()), fmt.Sprintf("LoadConfig should set scale up initial delay for %v to DefaultInitialDelay if not set in the config file", resInfo.Ref.Name))
 		g.Expect(resInfo.ScaleUpInfo.Timeout.Milliseconds()).To(Equal(DefaultScaleUpdateTimeout.Milliseconds()), fmt.Sprintf("LoadConfig should set scale up timeout for %v to DefaultScaleUpTimeout if not set in the config file", resInfo.Ref.Name))
@@ -88,19 +123,376 @@ func testMissingConfigValuesShouldReturnErrorAndNilConfig(t *testing.T, s *runti
 
 		g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with missing mandatory values")
 		g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with missing mandatory values")
-		if merr, ok := err.(*multierr.Error); ok {
+		var merr *multierr.Error
+		if errors.As(err, &merr) {
 			g.Expect(merr.Errors).To(HaveLen(entry.expectedErrCount), "LoadConfig did not return all the errors for a faulty config")
 		}
 	}
 	t.Log("All the missing mandatory values are identified")
 }
 
+func testInvalidThresholdsShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_thresholds.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with non-positive thresholds")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with non-positive thresholds")
+	g.Expect(err.Error()).To(ContainSubstring("FailureThreshold"))
+	g.Expect(err.Error()).To(ContainSubstring("SuccessThreshold"))
+	g.Expect(errors.Is(err, util.ErrConfigInvalid)).To(BeTrue(), "a config failing its validation should result in an ErrConfigInvalid")
+}
+
+func testInvalidDependsOnShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_depends_on.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with invalid explicit dependencies")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with invalid explicit dependencies")
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(2))
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.dependsOn of apps/v1/Deployment/kube-controller-manager references apps/v1/Deployment/machine-controller-manager which is not at a lower level"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.dependsOn of apps/v1/Deployment/machine-controller-manager references apps/v1/Deployment/cluster-autoscaler which is not a configured dependent resource"))
+}
+
+func testEmptyExternallyManagedSelectorShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_empty_externally_managed_selector.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an empty externallyManagedSelector")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an empty externallyManagedSelector")
+	g.Expect(err.Error()).To(ContainSubstring("externallyManagedSelector must not be empty"))
+}
+
+func testEmptyDependentResourceSelectorShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_empty_dependent_resource_selector.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an empty dependentResourceSelector")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an empty dependentResourceSelector")
+	g.Expect(err.Error()).To(ContainSubstring("dependentResourceSelector must not be empty"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("ScaleResourceInfos"), "dependent resources are not required if a dependentResourceSelector is set")
+}
+
+func testEmptySelectorOfDependentResourceShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_empty_dependent_resource_info_selector.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an empty selector of a dependent resource")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an empty selector of a dependent resource")
+	g.Expect(err.Error()).To(ContainSubstring("selector must not be empty"))
+}
+
+func testDependentResourceSelectorWithoutDependentResourceInfosShouldPassValidation(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_dependent_resource_selector.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).ToNot(HaveOccurred(), "LoadConfig should not return error for a config which discovers its dependent resources")
+	g.Expect(config.DependentResourceInfos).To(BeEmpty())
+	g.Expect(config.DependentResourceSelector.MatchLabels).To(HaveKeyWithValue("dependency-watchdog.gardener.cloud/scale", "true"))
+}
+
+func testInvalidStepsShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_steps.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with invalid steps")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with invalid steps")
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.steps of apps/v1/Deployment/kube-controller-manager must be positive and strictly increasing"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.steps of apps/v1/Deployment/kube-controller-manager is not supported"))
+}
+
+func testInvalidReplicaDeltaShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_replica_delta.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an invalid replica delta")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an invalid replica delta")
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.replicaDelta of apps/v1/Deployment/kube-controller-manager is not supported"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.replicaDelta of apps/v1/Deployment/kube-controller-manager must be negative, found 1"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("machine-controller-manager"))
+}
+
+func testInvalidScaleDurationsShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_scale_durations.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with negative or too large scale durations")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with negative or too large scale durations")
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(3))
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.initialDelay"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.timeout"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.initialDelay"))
+}
+
+func testInvalidScaleUpReplicasShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_scale_up_replicas.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with invalid scale up replicas")
+	g.Expect(config).To(BeNil())
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(3))
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.replicas of apps/v1/Deployment/kube-controller-manager is zero"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.replicas of apps/v1/Deployment/machine-controller-manager must not be negative, found -1"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.replicas of apps/v1/Deployment/machine-controller-manager is not supported"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("cluster-autoscaler"))
+}
+
+func testZeroScaleUpReplicasShouldBeAcceptedIfAllowed(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_allowed_zero_scale_up_replicas.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).ToNot(HaveOccurred(), "LoadConfig should accept zero scale up replicas if allowZeroScaleUpReplicas is set")
+	g.Expect(*config.DependentResourceInfos[0].ScaleUpInfo.Replicas).To(BeZero())
+}
+
+func testUnsupportedWaitOnReplicasStatusFieldShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_unsupported_wait_on_replicas_status_field.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an unsupported waitOnReplicasStatusField")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an unsupported waitOnReplicasStatusField")
+	g.Expect(err.Error()).To(ContainSubstring(`waitOnReplicasStatusField "updatedReplicas" is not supported`))
+}
+
+func testInvalidRetryInfoShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_retry.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with invalid retries")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with invalid retries")
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(3))
+	g.Expect(err.Error()).To(ContainSubstring("value for key retry.attempts must be greater than zero"))
+	g.Expect(err.Error()).To(ContainSubstring("value for key retry.backoff must be within [0s, 1h0m0s], found 2h0m0s"))
+	g.Expect(err.Error()).To(ContainSubstring("value for key retry.backoff must be greater than zero, found 0s"))
+}
+
+func testNonPositiveScaleUpStabilizationWindowShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_non_positive_scale_up_stabilization_window.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non positive scaleUpStabilizationWindow")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with a non positive scaleUpStabilizationWindow")
+	g.Expect(err.Error()).To(ContainSubstring("ScaleUpStabilizationWindow"))
+}
+
+func testNonPositiveDependentsImpactedDurationShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_non_positive_dependents_impacted_duration.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non positive dependentsImpactedDuration")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with a non positive dependentsImpactedDuration")
+	g.Expect(err.Error()).To(ContainSubstring("DependentsImpactedDuration"))
+}
+
+func testSharedLevelsWithStrictSerialLevelsShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_shared_levels_strict_serial.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with shared levels if strictSerialLevels is set")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with shared levels if strictSerialLevels is set")
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(1), "only the shared scaleUp level should be rejected")
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.level 1 is shared by apps/v1/Deployment/kube-controller-manager, apps/v1/Deployment/machine-controller-manager"))
+
+	// the same levels are permitted, and scaled in parallel, without strictSerialLevels.
+	rawConfig, err := os.ReadFile(configPath)
+	g.Expect(err).ToNot(HaveOccurred())
+	nonStrictConfigPath := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(nonStrictConfigPath, []byte(strings.Replace(string(rawConfig), "strictSerialLevels: true", "strictSerialLevels: false", 1)), 0600)).To(Succeed())
+	config, err = LoadConfig(nonStrictConfigPath, s)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.StrictSerialLevels).To(BeFalse())
+}
+
+func testUnknownFieldShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_unknown_field.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a misspelled field")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with a misspelled field")
+	g.Expect(err.Error()).To(ContainSubstring(`unknown field "intialDelay"`))
+	g.Expect(errors.Is(err, util.ErrConfigParse)).To(BeTrue(), "a misspelled field should result in an ErrConfigParse")
+}
+
+func testUnreachableAPIServerFailureQuorumShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_unreachable_api_server_failure_quorum.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config whose apiServerFailureQuorum exceeds the number of endpoints")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file whose apiServerFailureQuorum exceeds the number of endpoints")
+	g.Expect(err.Error()).To(ContainSubstring("apiServerFailureQuorum 4 must be between 1 and the number of API server endpoints 3"))
+}
+
+func testHPAForCronJobShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_hpa_for_cronjob.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config which scales the hpa of a CronJob")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file which scales the hpa of a CronJob")
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.hpa of batch/v1/CronJob/etcd-backup-compaction is not supported"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("machine-controller-manager"), "the hpa of a Deployment should be permitted")
+}
+
+func testInvalidReplicasFromHPAShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_replicas_from_hpa.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config which reads the replicas of a CronJob or of a scale-down from an hpa")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file which reads the replicas of a CronJob or of a scale-down from an hpa")
+	g.Expect(err.Error()).To(ContainSubstring("scaleUp.replicasFromHPA of batch/v1/CronJob/etcd-backup-compaction is not supported"))
+	g.Expect(err.Error()).To(ContainSubstring("scaleDown.replicasFromHPA of apps/v1/Deployment/machine-controller-manager is not supported"))
+	g.Expect(err.Error()).ToNot(ContainSubstring("scaleUp.replicasFromHPA of apps/v1/Deployment/machine-controller-manager"))
+}
+
+func testInvalidIgnoreScalingAnnotationKeyShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_ignore_scaling_annotation_key.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an invalid ignoreScalingAnnotationKey")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an invalid ignoreScalingAnnotationKey")
+	g.Expect(err.Error()).To(ContainSubstring(`ignoreScalingAnnotationKey "dwd/ignore scaling" is not a valid annotation key`))
+}
+
+func testRemoveIgnoreScalingAnnotationOnScaleUpShouldBeLoaded(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_remove_ignore_scaling_annotation.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).ToNot(HaveOccurred(), "LoadConfig should not give any error for a config which removes the ignore scaling annotation on scale up")
+	g.Expect(config.RemoveIgnoreScalingAnnotationOnScaleUp).To(BeTrue())
+}
+
+func testRemoveIgnoreScalingAnnotationWithScaleUpDisabledShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_remove_ignore_scaling_annotation_scale_up_disabled.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config which removes the ignore scaling annotation on scale up while scale up is disabled")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file which removes the ignore scaling annotation on scale up while scale up is disabled")
+	g.Expect(err.Error()).To(ContainSubstring("removeIgnoreScalingAnnotationOnScaleUp has no effect as scaleUpDisabled is set"))
+}
+
+func testDurationStringsShouldBeParsed(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_duration_strings.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).ToNot(HaveOccurred(), "LoadConfig should not give any error for durations given as duration strings")
+	g.Expect(config.ProbeInterval.Duration).To(Equal(30 * time.Second))
+	g.Expect(config.InitialDelay.Duration).To(Equal(2*time.Minute + 30*time.Second))
+	g.Expect(config.ProbeTimeout.Duration).To(Equal(500 * time.Millisecond))
+	g.Expect(config.DependentResourceInfos[0].ScaleUpInfo.InitialDelay.Duration).To(Equal(2 * time.Minute))
+}
+
+func testInvalidDurationStringShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_duration_string.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an invalid duration string")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with an invalid duration string")
+	g.Expect(err.Error()).To(ContainSubstring(`unknown unit " minutes" in duration "2 minutes"`))
+}
+
+func testBareNumberDurationShouldReturnErrorAndNilConfig(t *testing.T, s *runtime.Scheme) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	// a bare number would be ambiguous, e.g. 30 could be meant as seconds while time.Duration counts nanoseconds.
+	configPath := filepath.Join(testdataPath, "config_bare_number_duration.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath, s)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a duration given as a bare number")
+	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with a duration given as a bare number")
+	g.Expect(err.Error()).To(ContainSubstring("cannot unmarshal number"))
+}
+
 func testConfigFileNotFound(t *testing.T, s *runtime.Scheme) {
 	g := NewWithT(t)
 	config, err := LoadConfig(filepath.Join(testdataPath, "notfound.yaml"), s)
 	g.Expect(err).To(HaveOccurred(), "LoadConfig should give error if config file is not found")
 	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config if config file is not found")
 	g.Expect(err.Error()).To(ContainSubstring("no such file or directory"), "LoadConfig did not load all the dependent resources")
+	g.Expect(errors.Is(err, util.ErrConfigNotFound)).To(BeTrue(), "a missing config file should result in an ErrConfigNotFound")
 }
 
 func testErrorInUnMarshallingYaml(t *testing.T, s *runtime.Scheme) {
@@ -113,6 +505,7 @@ func testErrorInUnMarshallingYaml(t *testing.T, s *runtime.Scheme) {
 	g.Expect(err).To(HaveOccurred(), "LoadConfig should not give error for a valid config")
 	g.Expect(config).To(BeNil(), "LoadConfig should got nil config for a valid file")
 	g.Expect(err.Error()).To(ContainSubstring("cannot unmarshal string into Go struct field DependentResourceInfo.dependentResourceInfos.scaleUp"), "Wrong error recieved")
+	g.Expect(errors.Is(err, util.ErrConfigParse)).To(BeTrue(), "a config with invalid syntax should result in an ErrConfigParse")
 }
 
 func testValidConfigShouldPassAllValidations(t *testing.T, s *runtime.Scheme) {
@@ -6,6 +6,7 @@ package scale
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
 	"github.com/gardener/dependency-watchdog/internal/test"
@@ -70,6 +71,24 @@ func (f *fakeScaler) ScaleDown(ctx context.Context) error {
 	return nil
 }
 
+func (f *fakeScaler) ScaleResource(ctx context.Context, refName string, direction scaler.ScaleDirection) error {
+	replicas, err := int32(1), f.scaleUpErr
+	if direction == scaler.ScaleDirectionDown {
+		replicas, err = 0, f.scaleDownErr
+	}
+	if err != nil {
+		return err
+	}
+	for _, scalingTargetRef := range f.scalingTargetRefs {
+		if scalingTargetRef.Name == refName {
+			return f.doScale(ctx, scalingTargetRef, replicas)
+		}
+	}
+	return fmt.Errorf("%w: %s", scaler.ErrResourceNotConfigured, refName)
+}
+
+func (f *fakeScaler) Close() {}
+
 func (f *fakeScaler) doScale(ctx context.Context, ref client.ObjectKey, replicas int32) error {
 	deploy := &appsv1.Deployment{}
 	if err := f.client.Get(ctx, ref, deploy); err != nil {
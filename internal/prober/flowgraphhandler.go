@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-logr/logr"
+)
+
+// NewFlowGraphHandler creates an http.Handler which responds with the graphs of the scale-up and scale-down flows, see
+// scaler.FlowGraphs, of the prober registered for the shoot namespace given via the namespace query parameter. It only
+// accepts GET requests and never scales the dependent resources.
+func NewFlowGraphHandler(mgr Manager, logger logr.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		namespace := r.URL.Query().Get(namespaceQueryParam)
+		if namespace == "" {
+			http.Error(w, "query parameter namespace must be provided", http.StatusBadRequest)
+			return
+		}
+		graphs, err := mgr.GetFlowGraphs(r.Context(), namespace)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrProberNotFound) || errors.Is(err, ErrProberClosed) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(graphs); err != nil {
+			logger.Error(err, "Failed to write flow graphs response", "shootNamespace", namespace)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prober
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "dwd"
+	metricsSubsystem = "prober"
+	namespaceLabel   = "namespace"
+)
+
+var (
+	// proberHealth captures the result of the latest probe of a shoot control plane, 1 if it is healthy and 0 otherwise.
+	proberHealth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "health",
+			Help:      "Health of the shoot control plane as determined by the latest probe, 1 if healthy and 0 if unhealthy.",
+		},
+		[]string{namespaceLabel},
+	)
+	// proberLastTransitionTimestampSeconds captures the time at which the health of a shoot control plane last changed.
+	proberLastTransitionTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "last_transition_timestamp_seconds",
+			Help:      "Unix timestamp in seconds at which the health of the shoot control plane has last changed.",
+		},
+		[]string{namespaceLabel},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(proberHealth, proberLastTransitionTimestampSeconds)
+}
+
+// deleteHealthMetrics removes the health metrics of the given namespace so that no stale values are reported for a
+// namespace which is no longer probed.
+func deleteHealthMetrics(namespace string) {
+	proberHealth.DeleteLabelValues(namespace)
+	proberLastTransitionTimestampSeconds.DeleteLabelValues(namespace)
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package prober
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const metricsTestNamespace = "shoot--test--metrics"
+
+func TestProbeShouldUpdateHealthMetrics(t *testing.T) {
+	g := NewWithT(t)
+	var (
+		healthy  bool
+		probeErr error
+	)
+	probeFn := func(_ context.Context) (bool, error) {
+		return healthy, probeErr
+	}
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	// the thresholds are never reached so that no scaling flow is run.
+	config.FailureThreshold = pointer.Int(100)
+	config.SuccessThreshold = pointer.Int(100)
+	p := NewProber(context.Background(), nil, metricsTestNamespace, config, nil, &concurrencyTrackingScaler{}, nil, logr.Discard(), WithProbeFn(probeFn))
+	defer p.Close()
+	health := proberHealth.WithLabelValues(metricsTestNamespace)
+	lastTransition := proberLastTransitionTimestampSeconds.WithLabelValues(metricsTestNamespace)
+
+	healthy = true
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(health)).To(Equal(float64(1)))
+	firstTransition := testutil.ToFloat64(lastTransition)
+	g.Expect(firstTransition).To(BeNumerically(">", 0))
+
+	// another healthy probe should not be recorded as a transition.
+	time.Sleep(10 * time.Millisecond)
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(health)).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(lastTransition)).To(Equal(firstTransition))
+
+	healthy = false
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(health)).To(BeZero())
+	secondTransition := testutil.ToFloat64(lastTransition)
+	g.Expect(secondTransition).To(BeNumerically(">", firstTransition))
+
+	// a probe which has failed with an error is also unhealthy.
+	healthy, probeErr = true, errors.New("test probe error")
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(health)).To(BeZero())
+	g.Expect(testutil.ToFloat64(lastTransition)).To(Equal(secondTransition))
+
+	// a probe which has skipped scaling does not determine the health.
+	probeErr = errSkipScaling
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(health)).To(BeZero())
+}
+
+func TestCloseShouldDeleteHealthMetrics(t *testing.T) {
+	g := NewWithT(t)
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.FailureThreshold = pointer.Int(100)
+	p := NewProber(context.Background(), nil, metricsTestNamespace, config, nil, &concurrencyTrackingScaler{}, nil, logr.Discard(), WithProbeFn(func(_ context.Context) (bool, error) {
+		return false, nil
+	}))
+	p.probe(p.ctx)
+	g.Expect(testutil.ToFloat64(proberHealth.WithLabelValues(metricsTestNamespace))).To(BeZero())
+
+	p.Close()
+	// DeleteLabelValues returns false if there was no metric for the namespace.
+	g.Expect(proberHealth.DeleteLabelValues(metricsTestNamespace)).To(BeFalse(), "health should have been deleted when the prober was closed")
+	g.Expect(proberLastTransitionTimestampSeconds.DeleteLabelValues(metricsTestNamespace)).To(BeFalse(), "last transition timestamp should have been deleted when the prober was closed")
+}
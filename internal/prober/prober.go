@@ -6,8 +6,12 @@ package prober
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	"reflect"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/internal/prober/errors"
@@ -20,11 +24,12 @@ import (
 	dwdScaler "github.com/gardener/dependency-watchdog/internal/prober/scaler"
 	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/go-logr/logr"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	coordinationv1 "k8s.io/api/coordination/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 )
 
 const (
@@ -39,6 +44,60 @@ const (
 	nodeLeaseNamespace   = "kube-node-lease"
 )
 
+// errSkipScaling is returned by a ProbeFn when the probe has succeeded but no scaling decision should be taken.
+var errSkipScaling = fmt.Errorf("skip scaling")
+
+// ErrProberClosed is returned when a probe is triggered for a prober which has been closed.
+var ErrProberClosed = stderrors.New("prober has been closed")
+
+// ProbeDecision is the scaling decision which has been taken for a probe.
+type ProbeDecision string
+
+const (
+	// ProbeDecisionScaleUp is the decision to scale up the dependent resources as the shoot is healthy.
+	ProbeDecisionScaleUp ProbeDecision = "ScaleUp"
+	// ProbeDecisionScaleDown is the decision to scale down the dependent resources as the shoot is unhealthy.
+	ProbeDecisionScaleDown ProbeDecision = "ScaleDown"
+	// ProbeDecisionNone is the decision to leave the dependent resources as they are, e.g. as the probe has failed or as the
+	// failure or success threshold has not been reached yet.
+	ProbeDecisionNone ProbeDecision = "None"
+)
+
+// probeResult is the outcome of a triggered probe.
+type probeResult struct {
+	decision ProbeDecision
+	err      error
+}
+
+// ProbeFn probes a shoot control plane. It returns true if the shoot is healthy, in which case dependent resources
+// are scaled up, and false if they should be scaled down. If an error is returned then no scaling decision is taken.
+type ProbeFn func(ctx context.Context) (bool, error)
+
+type proberOption func(p *Prober)
+
+// WithProbeFn replaces the default probe of the Kube ApiServer and node leases of a shoot with the given ProbeFn.
+func WithProbeFn(fn ProbeFn) proberOption {
+	return func(p *Prober) {
+		p.probeFn = fn
+	}
+}
+
+// WithScalingFlowLimiter sets the ScalingFlowLimiter from which the prober has to acquire a slot before running a scaling flow.
+func WithScalingFlowLimiter(limiter ScalingFlowLimiter) proberOption {
+	return func(p *Prober) {
+		p.scalingFlowLimiter = limiter
+	}
+}
+
+// WithAPIServerEndpointClientCreators sets the client creators of further endpoints of the Kube ApiServer of the shoot, which
+// are probed in addition to the endpoint of the shootClientCreator passed to NewProber. The API server probe then only fails
+// if at least papi.Config.APIServerFailureQuorum of all endpoints fail, see Prober.probeAPIServer.
+func WithAPIServerEndpointClientCreators(clientCreators ...shoot.ClientCreator) proberOption {
+	return func(p *Prober) {
+		p.apiServerEndpointClientCreators = clientCreators
+	}
+}
+
 // Prober represents a probe to the Kube ApiServer of a shoot
 type Prober struct {
 	namespace            string
@@ -47,18 +106,37 @@ type Prober struct {
 	scaler               dwdScaler.Scaler
 	seedClient           client.Client
 	shootClientCreator   shoot.ClientCreator
+	probeFn              ProbeFn
+	scalingFlowLimiter   ScalingFlowLimiter
+	scalingFlowRunning   *atomic.Bool
 	backOff              *time.Timer
 	ctx                  context.Context
 	cancelFn             context.CancelFunc
 	l                    logr.Logger
 	lastErr              error // this is currently used only for unit tests
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	// lastHealthy is the health determined by the previous probe, it is nil till the first probe has determined the health.
+	lastHealthy *bool
+	// healthySince is the time since which every probe has found the shoot to be healthy. It is zero if the last probe has not.
+	healthySince time.Time
+	clock        clock.PassiveClock
+	// apiServerEndpointClientCreators are the client creators of the further endpoints of the Kube ApiServer which are probed.
+	apiServerEndpointClientCreators []shoot.ClientCreator
+	// probeMu serializes the periodic probes and the triggered ones.
+	probeMu *sync.Mutex
+	// triggerCh passes the triggered probes to the running prober, which sends the outcome of each of them to the given channel.
+	triggerCh chan chan<- probeResult
+	// dependentsImpactedSince is the time since which every unhealthy probe has found at least one dependent resource to be
+	// impacted. It is zero if the last probe has not, and it is only recorded if a DependentsImpactedDuration is configured.
+	dependentsImpactedSince time.Time
 }
 
 // NewProber creates a new Prober
-func NewProber(parentCtx context.Context, seedClient client.Client, namespace string, config *papi.Config, workerNodeConditions map[string][]string, scaler dwdScaler.Scaler, shootClientCreator shoot.ClientCreator, logger logr.Logger) *Prober {
+func NewProber(parentCtx context.Context, seedClient client.Client, namespace string, config *papi.Config, workerNodeConditions map[string][]string, scaler dwdScaler.Scaler, shootClientCreator shoot.ClientCreator, logger logr.Logger, opts ...proberOption) *Prober {
 	pLogger := logger.WithValues("shootNamespace", namespace)
 	ctx, cancelFn := context.WithCancel(parentCtx)
-	return &Prober{
+	p := &Prober{
 		namespace:            namespace,
 		config:               config,
 		workerNodeConditions: workerNodeConditions,
@@ -68,12 +146,26 @@ func NewProber(parentCtx context.Context, seedClient client.Client, namespace st
 		ctx:                  ctx,
 		cancelFn:             cancelFn,
 		l:                    pLogger,
+		scalingFlowLimiter:   NewScalingFlowLimiter(0),
+		scalingFlowRunning:   &atomic.Bool{},
+		clock:                clock.RealClock{},
+		probeMu:              &sync.Mutex{},
+		triggerCh:            make(chan chan<- probeResult),
 	}
+	p.probeFn = p.probeShoot
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-// Close closes a probe
+// Close closes a probe and releases the resources held by its scaler.
 func (p *Prober) Close() {
 	p.cancelFn()
+	if p.scaler != nil {
+		p.scaler.Close()
+	}
+	deleteHealthMetrics(p.namespace)
 }
 
 // IsClosed checks if the context of the prober is cancelled or not.
@@ -86,61 +178,274 @@ func (p *Prober) IsClosed() bool {
 	}
 }
 
-// Run starts a probe which will run with a configured interval and jitter.
+// Run starts a probe which will run with a configured interval and jitter. The first probe after the initial delay is delayed
+// by a random jitter as well, so that the probers of different shoots do not probe in lockstep. Probes triggered via
+// TriggerProbe are run in between, including during the initial delay.
 func (p *Prober) Run() {
+	go p.runTriggeredProbes()
 	_ = util.SleepWithContext(p.ctx, p.config.InitialDelay.Duration)
-	wait.JitterUntilWithContext(p.ctx, p.probe, p.config.ProbeInterval.Duration, *p.config.BackoffJitterFactor, true)
+	util.JitterUntil(p.ctx, func(ctx context.Context) {
+		_, _ = p.probe(ctx)
+	}, p.config.ProbeInterval.Duration, *p.config.BackoffJitterFactor)
+}
+
+// TriggerProbe runs a probe and takes the scaling decision for it right away instead of waiting for the next probe interval.
+// It waits till the scaling operation, if any, has finished and returns the decision together with the error of the probe or
+// of the scaling operation. The probe counts towards the failure and success thresholds like every other probe. It returns
+// ErrProberClosed if the prober has been closed.
+func (p *Prober) TriggerProbe(ctx context.Context) (ProbeDecision, error) {
+	resultCh := make(chan probeResult, 1)
+	select {
+	case p.triggerCh <- resultCh:
+	case <-p.ctx.Done():
+		return ProbeDecisionNone, ErrProberClosed
+	case <-ctx.Done():
+		return ProbeDecisionNone, ctx.Err()
+	}
+	select {
+	case result := <-resultCh:
+		return result.decision, result.err
+	case <-ctx.Done():
+		return ProbeDecisionNone, ctx.Err()
+	}
 }
 
-func (p *Prober) probe(ctx context.Context) {
+// FlowGraphs returns the graphs of the scaling flows of the prober's scaler, see scaler.Scaler.FlowGraphs. It returns
+// ErrProberClosed if the prober has been closed.
+func (p *Prober) FlowGraphs(ctx context.Context) (dwdScaler.FlowGraphs, error) {
+	if p.IsClosed() {
+		return dwdScaler.FlowGraphs{}, ErrProberClosed
+	}
+	if p.scaler == nil {
+		return dwdScaler.FlowGraphs{}, nil
+	}
+	return p.scaler.FlowGraphs(ctx)
+}
+
+// runTriggeredProbes runs the probes triggered via TriggerProbe till the prober is closed.
+func (p *Prober) runTriggeredProbes() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case resultCh := <-p.triggerCh:
+			decision, err := p.probe(p.ctx)
+			resultCh <- probeResult{decision: decision, err: err}
+		}
+	}
+}
+
+// probe probes the shoot and scales the dependent resources if necessary. It returns the scaling decision which has been
+// taken together with the error of the probe or of the scaling operation.
+func (p *Prober) probe(ctx context.Context) (ProbeDecision, error) {
+	p.probeMu.Lock()
+	defer p.probeMu.Unlock()
 	p.backOffIfNeeded()
+	healthy, err := p.probeFn(ctx)
+	if err != errSkipScaling {
+		p.recordHealth(err == nil && healthy)
+		p.recordHealthySince(err == nil && healthy)
+	}
+	if err != nil {
+		if err != errSkipScaling {
+			p.lastErr = err
+			return ProbeDecisionNone, err
+		}
+		return ProbeDecisionNone, nil
+	}
+	return p.checkAndTriggerScale(ctx, healthy)
+}
+
+// probeShoot is the default ProbeFn. It first probes the Kube ApiServer of the shoot and if it is reachable then it
+// probes the node leases to determine if the dependent resources should be scaled up or scaled down.
+func (p *Prober) probeShoot(ctx context.Context) (bool, error) {
 	err := p.probeAPIServer(ctx)
 	if err != nil {
-		p.recordError(err, errors.ErrProbeAPIServer, "Failed to probe API server")
 		p.l.Info("API server probe failed, Skipping lease probe and scaling operation", "err", err.Error())
-		return
+		return false, errors.WrapError(err, errors.ErrProbeAPIServer, "Failed to probe API server")
 	}
 	p.l.Info("API server probe is successful, will conduct node lease probe")
 
 	shootClient, err := p.setupProbeClient(ctx)
 	if err != nil {
-		p.recordError(err, errors.ErrSetupProbeClient, "Failed to setup probe client")
 		p.l.Error(err, "Failed to create shoot client using the KubeConfig secret, ignoring error, probe will be re-attempted")
-		return
+		return false, errors.WrapError(err, errors.ErrSetupProbeClient, "Failed to setup probe client")
 	}
 	candidateNodeLeases, err := p.probeNodeLeases(ctx, shootClient)
 	if err != nil {
-		p.recordError(err, errors.ErrProbeNodeLease, "Failed to probe node leases")
 		p.l.Error(err, "Failed to probe node leases, ignoring error, probe will be re-attempted")
-		return
+		return false, errors.WrapError(err, errors.ErrProbeNodeLease, "Failed to probe node leases")
 	}
-	if len(candidateNodeLeases) != 1 {
-		p.checkAndTriggerScale(ctx, candidateNodeLeases)
-	} else {
+	if len(candidateNodeLeases) == 1 {
 		p.l.Info("Skipping scaling operation as number of candidate node leases == 1")
+		return false, errSkipScaling
+	}
+	return p.shouldPerformScaleUp(candidateNodeLeases), nil
+}
+
+// recordHealth updates the health metrics of the prober. A probe which has failed with an error is recorded as unhealthy.
+// The last transition timestamp is only updated if the health differs from the one determined by the previous probe.
+func (p *Prober) recordHealth(healthy bool) {
+	if p.IsClosed() {
+		return
+	}
+	var healthValue float64
+	if healthy {
+		healthValue = 1
+	}
+	proberHealth.WithLabelValues(p.namespace).Set(healthValue)
+	if p.lastHealthy == nil || *p.lastHealthy != healthy {
+		proberLastTransitionTimestampSeconds.WithLabelValues(p.namespace).SetToCurrentTime()
+		p.lastHealthy = &healthy
+	}
+}
+
+// recordHealthySince records the time of the first of an uninterrupted sequence of healthy probes, which determines whether
+// the ScaleUpStabilizationWindow has elapsed. A probe which is unhealthy or has failed with an error interrupts the sequence.
+func (p *Prober) recordHealthySince(healthy bool) {
+	if !healthy {
+		p.healthySince = time.Time{}
+		return
+	}
+	if p.healthySince.IsZero() {
+		p.healthySince = p.clock.Now()
+	}
+}
+
+// isScaleUpStabilizing returns true if the shoot has not yet been healthy for the whole ScaleUpStabilizationWindow.
+func (p *Prober) isScaleUpStabilizing() bool {
+	if p.config.ScaleUpStabilizationWindow == nil {
+		return false
 	}
+	return p.healthySince.IsZero() || p.clock.Since(p.healthySince) < p.config.ScaleUpStabilizationWindow.Duration
+}
+
+// recordDependentsImpactedSince records the time of the first of an uninterrupted sequence of unhealthy probes for which at
+// least one dependent resource has been found to be impacted. A healthy probe or a probe for which no dependent resource is
+// impacted interrupts the sequence. The dependent resources are only checked if a DependentsImpactedDuration is configured.
+func (p *Prober) recordDependentsImpactedSince(ctx context.Context, healthy bool) {
+	if p.config.DependentsImpactedDuration == nil {
+		return
+	}
+	if healthy || !p.areDependentsImpacted(ctx) {
+		p.dependentsImpactedSince = time.Time{}
+		return
+	}
+	if p.dependentsImpactedSince.IsZero() {
+		p.dependentsImpactedSince = p.clock.Now()
+	}
+}
+
+// isScaleDownAwaitingImpactedDependents returns true if the dependent resources have not yet been impacted for the whole
+// DependentsImpactedDuration.
+func (p *Prober) isScaleDownAwaitingImpactedDependents() bool {
+	if p.config.DependentsImpactedDuration == nil {
+		return false
+	}
+	return p.dependentsImpactedSince.IsZero() || p.clock.Since(p.dependentsImpactedSince) < p.config.DependentsImpactedDuration.Duration
+}
+
+// areDependentsImpacted returns true if fewer replicas are ready than desired for at least one of the dependent resources.
+// A dependent resource which cannot be checked is not considered to be impacted, so that it does not cause a scale-down.
+func (p *Prober) areDependentsImpacted(ctx context.Context) bool {
+	for _, resInfo := range p.config.DependentResourceInfos {
+		impacted, err := p.isDependentImpacted(ctx, resInfo.Ref)
+		if err != nil {
+			if !resInfo.Optional || !apierrors.IsNotFound(err) {
+				p.l.Error(err, "Failed to check if dependent resource is impacted, considering it not impacted", "resource", util.ResourceRefKey(*resInfo.Ref))
+			}
+			continue
+		}
+		if impacted {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Prober) isDependentImpacted(ctx context.Context, resourceRef *autoscalingv1.CrossVersionObjectReference) (bool, error) {
+	specReplicas, err := util.GetResourceSpecReplicas(ctx, p.seedClient, p.namespace, resourceRef)
+	if err != nil {
+		return false, err
+	}
+	readyReplicas, err := util.GetResourceReadyReplicas(ctx, p.seedClient, p.namespace, resourceRef)
+	if err != nil {
+		return false, err
+	}
+	return readyReplicas < specReplicas, nil
 }
 
 func (p *Prober) recordError(err error, code errors.ErrorCode, message string) {
 	p.lastErr = errors.WrapError(err, code, message)
 }
 
-func (p *Prober) checkAndTriggerScale(ctx context.Context, candidateNodeLeases []coordinationv1.Lease) {
-	// revive:disable:early-return
-	if p.shouldPerformScaleUp(candidateNodeLeases) {
-		if err := p.scaler.ScaleUp(ctx); err != nil {
+// checkAndTriggerScale scales the dependent resources according to the health of the shoot once the thresholds have been
+// reached. It returns the scaling decision which has been taken together with the error of the scaling operation.
+func (p *Prober) checkAndTriggerScale(ctx context.Context, healthy bool) (ProbeDecision, error) {
+	p.recordDependentsImpactedSince(ctx, healthy)
+	if healthy {
+		if !p.recordProbeSuccess() {
+			p.l.Info("Skipping scale up operation as success threshold has not been reached", "consecutiveSuccesses", p.consecutiveSuccesses, "successThreshold", *p.config.SuccessThreshold)
+			return ProbeDecisionNone, nil
+		}
+		if p.isScaleUpStabilizing() {
+			p.l.Info("Holding scale up operation as the shoot has not been healthy for the stabilization window", "healthySince", p.healthySince, "scaleUpStabilizationWindow", p.config.ScaleUpStabilizationWindow.Duration)
+			return ProbeDecisionNone, nil
+		}
+		if err := p.runScalingFlow(ctx, p.scaler.ScaleUp); err != nil {
 			p.recordError(err, errors.ErrScaleUp, "Failed to scale up resources")
 			p.l.Error(err, "Failed to scale up resources")
+			return ProbeDecisionScaleUp, p.lastErr
 		}
-	} else {
-		p.l.Info("Lease probe failed, performing scale down operation if required")
-		if err := p.scaler.ScaleDown(ctx); err != nil {
-			p.recordError(err, errors.ErrScaleDown, "Failed to scale down resources")
-			p.l.Error(err, "Failed to scale down resources")
-		}
-		return
+		return ProbeDecisionScaleUp, nil
 	}
-	// revive:enable:early-return
+	if !p.recordProbeFailure() {
+		p.l.Info("Skipping scale down operation as failure threshold has not been reached", "consecutiveFailures", p.consecutiveFailures, "failureThreshold", *p.config.FailureThreshold)
+		return ProbeDecisionNone, nil
+	}
+	if p.isScaleDownAwaitingImpactedDependents() {
+		p.l.Info("Holding scale down operation as the dependent resources have not been impacted for the dependents impacted duration", "dependentsImpactedSince", p.dependentsImpactedSince, "dependentsImpactedDuration", p.config.DependentsImpactedDuration.Duration)
+		return ProbeDecisionNone, nil
+	}
+	p.l.Info("Lease probe failed, performing scale down operation if required")
+	if err := p.runScalingFlow(ctx, p.scaler.ScaleDown); err != nil {
+		p.recordError(err, errors.ErrScaleDown, "Failed to scale down resources")
+		p.l.Error(err, "Failed to scale down resources")
+		return ProbeDecisionScaleDown, p.lastErr
+	}
+	return ProbeDecisionScaleDown, nil
+}
+
+// runScalingFlow waits for a slot from the scalingFlowLimiter and runs the scaling flow once it is acquired.
+func (p *Prober) runScalingFlow(ctx context.Context, scalingFlowFn func(ctx context.Context) error) error {
+	if err := p.scalingFlowLimiter.Acquire(ctx); err != nil {
+		return err
+	}
+	defer p.scalingFlowLimiter.Release()
+	p.scalingFlowRunning.Store(true)
+	defer p.scalingFlowRunning.Store(false)
+	return scalingFlowFn(ctx)
+}
+
+// isScalingFlowRunning returns true if the prober is currently running a scaling flow.
+func (p *Prober) isScalingFlowRunning() bool {
+	return p.scalingFlowRunning != nil && p.scalingFlowRunning.Load()
+}
+
+// recordProbeSuccess records a successful lease probe and resets the consecutive failure count.
+// It returns true if the number of consecutive successful probes has reached the configured SuccessThreshold.
+func (p *Prober) recordProbeSuccess() bool {
+	p.consecutiveFailures = 0
+	p.consecutiveSuccesses++
+	return p.consecutiveSuccesses >= *p.config.SuccessThreshold
+}
+
+// recordProbeFailure records a failed lease probe and resets the consecutive success count.
+// It returns true if the number of consecutive failed probes has reached the configured FailureThreshold.
+func (p *Prober) recordProbeFailure() bool {
+	p.consecutiveSuccesses = 0
+	p.consecutiveFailures++
+	return p.consecutiveFailures >= *p.config.FailureThreshold
 }
 
 // shouldPerformScaleUp returns true if the ratio of expired node leases to valid node leases is less than
@@ -172,15 +477,60 @@ func (p *Prober) setupProbeClient(ctx context.Context) (client.Client, error) {
 	return shootClient, nil
 }
 
+// probeAPIServer probes the Kube ApiServer of the shoot. If further endpoints of the Kube ApiServer have been configured then
+// all endpoints are probed concurrently and the probe only fails if at least the failure quorum of them fail, so that a single
+// endpoint which is unavailable, e.g. during a rolling update of the Kube ApiServer, does not cause a scale-down.
 func (p *Prober) probeAPIServer(ctx context.Context) error {
-	discoveryClient, err := p.shootClientCreator.CreateDiscoveryClient(ctx, p.l, p.config.ProbeTimeout.Duration)
+	if len(p.apiServerEndpointClientCreators) == 0 {
+		err := p.probeAPIServerEndpoint(ctx, p.shootClientCreator)
+		p.setBackOffIfThrottlingError(err)
+		return err
+	}
+	clientCreators := append([]shoot.ClientCreator{p.shootClientCreator}, p.apiServerEndpointClientCreators...)
+	errs := make([]error, len(clientCreators))
+	var wg sync.WaitGroup
+	for i, clientCreator := range clientCreators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.probeAPIServerEndpoint(ctx, clientCreator)
+		}()
+	}
+	wg.Wait()
+	var failedErrs []error
+	for _, err := range errs {
+		if err != nil {
+			p.setBackOffIfThrottlingError(err)
+			failedErrs = append(failedErrs, err)
+		}
+	}
+	failureQuorum := p.apiServerFailureQuorum(len(clientCreators))
+	if len(failedErrs) >= failureQuorum {
+		return fmt.Errorf("%d of %d API server endpoints failed the probe, failure quorum is %d: %w", len(failedErrs), len(clientCreators), failureQuorum, stderrors.Join(failedErrs...))
+	}
+	if len(failedErrs) > 0 {
+		p.l.Info("API server probe failed for some endpoints, considering the API server healthy as the failure quorum has not been reached",
+			"failedEndpoints", len(failedErrs), "endpoints", len(clientCreators), "failureQuorum", failureQuorum, "err", stderrors.Join(failedErrs...).Error())
+	}
+	return nil
+}
+
+// apiServerFailureQuorum returns the number of endpoints of the Kube ApiServer which have to fail for the API server probe to
+// fail. Unless it has been configured, all endpoints have to fail.
+func (p *Prober) apiServerFailureQuorum(numEndpoints int) int {
+	if p.config.APIServerFailureQuorum != nil {
+		return *p.config.APIServerFailureQuorum
+	}
+	return numEndpoints
+}
+
+func (p *Prober) probeAPIServerEndpoint(ctx context.Context, clientCreator shoot.ClientCreator) error {
+	discoveryClient, err := clientCreator.CreateDiscoveryClient(ctx, p.l, p.config.ProbeTimeout.Duration)
 	if err != nil {
 		p.l.Error(err, "Failed to create discovery client, probe will be re-attempted")
-		p.setBackOffIfThrottlingError(err)
 		return err
 	}
 	_, err = discoveryClient.ServerVersion()
-	p.setBackOffIfThrottlingError(err)
 	return err
 }
 
@@ -16,11 +16,14 @@ import (
 	k8sfakes "github.com/gardener/dependency-watchdog/internal/prober/fakes/k8s"
 	scalefakes "github.com/gardener/dependency-watchdog/internal/prober/fakes/scale"
 	shootfakes "github.com/gardener/dependency-watchdog/internal/prober/fakes/shoot"
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
+	"github.com/gardener/dependency-watchdog/internal/prober/shoot"
 	"github.com/gardener/dependency-watchdog/internal/test"
 	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machineutils"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,6 +35,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclock "k8s.io/utils/clock/testing"
 	"k8s.io/utils/pointer"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
@@ -80,6 +84,48 @@ func TestAPIServerProbeFailure(t *testing.T) {
 	}
 }
 
+func TestAPIServerProbeWithMultipleEndpointsShouldFailOnlyOnReachingFailureQuorum(t *testing.T) {
+	testCases := []struct {
+		name             string
+		failingEndpoints int
+		failureQuorum    *int
+		expectFailure    bool
+	}{
+		{name: "unanimous quorum should tolerate all but one failing endpoint", failingEndpoints: 2, expectFailure: false},
+		{name: "unanimous quorum should fail if all endpoints fail", failingEndpoints: 3, expectFailure: true},
+		{name: "majority quorum should tolerate a single failing endpoint", failingEndpoints: 1, failureQuorum: pointer.Int(2), expectFailure: false},
+		{name: "majority quorum should fail if a majority of endpoints fail", failingEndpoints: 2, failureQuorum: pointer.Int(2), expectFailure: true},
+		{name: "no failing endpoint should succeed", failingEndpoints: 0, failureQuorum: pointer.Int(1), expectFailure: false},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			probeErr := apierrors.NewServiceUnavailable("api server replica is being rolled")
+			clientCreators := make([]shoot.ClientCreator, 3)
+			for i := range clientCreators {
+				var discoveryErr error
+				if i < entry.failingEndpoints {
+					discoveryErr = probeErr
+				}
+				clientCreators[i] = shootfakes.NewFakeShootClientBuilder(k8sfakes.NewFakeDiscoveryClient(discoveryErr), nil).Build()
+			}
+			config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+			config.APIServerFailureQuorum = entry.failureQuorum
+			p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, nil, clientCreators[0], logr.Discard(), WithAPIServerEndpointClientCreators(clientCreators[1:]...))
+			defer p.Close()
+
+			err := p.probeAPIServer(context.Background())
+			if entry.expectFailure {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err).To(MatchError(probeErr))
+				g.Expect(err.Error()).To(ContainSubstring("%d of 3 API server endpoints failed the probe", entry.failingEndpoints))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
 func TestDiscoveryClientCreationFailed(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -604,8 +650,304 @@ func TestLeaseProbeFailureShouldRunScaleDown(t *testing.T) {
 	}
 }
 
+func TestProbeThresholdTransitions(t *testing.T) {
+	g := NewWithT(t)
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.FailureThreshold = pointer.Int(3)
+	config.SuccessThreshold = pointer.Int(2)
+	p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, nil, nil, logr.Discard())
+	defer p.Close()
+
+	// 2 failures followed by a success should reset the failure count
+	g.Expect(p.recordProbeFailure()).To(BeFalse())
+	g.Expect(p.recordProbeFailure()).To(BeFalse())
+	g.Expect(p.recordProbeSuccess()).To(BeFalse())
+	g.Expect(p.consecutiveFailures).To(BeZero())
+	g.Expect(p.consecutiveSuccesses).To(Equal(1))
+
+	// a failure resets the success count, 3 consecutive failures reach the failure threshold
+	g.Expect(p.recordProbeFailure()).To(BeFalse())
+	g.Expect(p.consecutiveSuccesses).To(BeZero())
+	g.Expect(p.recordProbeFailure()).To(BeFalse())
+	g.Expect(p.recordProbeFailure()).To(BeTrue())
+
+	// 2 consecutive successes reach the success threshold
+	g.Expect(p.recordProbeSuccess()).To(BeFalse())
+	g.Expect(p.recordProbeSuccess()).To(BeTrue())
+	g.Expect(p.consecutiveFailures).To(BeZero())
+}
+
+func TestScalingDrivenByInjectedProbeFn(t *testing.T) {
+	t.Parallel()
+	probeErr := errors.New("test probe error")
+	testCases := []struct {
+		name                       string
+		healthy                    bool
+		probeErr                   error
+		initialDeploymentReplicas  int32
+		expectedDeploymentReplicas int32
+	}{
+		{name: "scale down should happen if probe reports an unhealthy shoot", healthy: false, initialDeploymentReplicas: 1, expectedDeploymentReplicas: 0},
+		{name: "scale up should happen if probe reports a healthy shoot", healthy: true, initialDeploymentReplicas: 0, expectedDeploymentReplicas: 1},
+		{name: "no scaling should happen if probe returns an error", probeErr: probeErr, initialDeploymentReplicas: 1, expectedDeploymentReplicas: 1},
+	}
+	g := NewWithT(t)
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			entry := entry
+			t.Parallel()
+			ctx := context.Background()
+			scaleTargetDeployments := generateScaleTargetDeployments(entry.initialDeploymentReplicas)
+			seedClient := initializeSeedClientBuilder(nil, scaleTargetDeployments).Build()
+			scaler := scalefakes.NewFakeScaler(seedClient, test.DefaultNamespace, nil, nil)
+			config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+			probeFn := func(_ context.Context) (bool, error) {
+				return entry.healthy, entry.probeErr
+			}
+
+			p := NewProber(ctx, seedClient, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+			g.Expect(p.IsClosed()).To(BeFalse())
+
+			err := runProber(p, testProbeTimeout.Duration)
+			g.Expect(p.IsClosed()).To(BeTrue())
+			if entry.probeErr != nil {
+				g.Expect(err).To(Equal(entry.probeErr))
+			} else {
+				g.Expect(err).To(BeNil())
+			}
+			assertScale(ctx, g, seedClient, getDeploymentRefs(scaleTargetDeployments), entry.expectedDeploymentReplicas)
+		})
+	}
+}
+
+func TestScaleUpShouldBeHeldTillShootHasBeenHealthyForStabilizationWindow(t *testing.T) {
+	testCases := []struct {
+		name             string
+		probes           []bool
+		elapsedBetween   time.Duration
+		expectedScaleUps int
+	}{
+		{name: "scale up should be held within the window", probes: []bool{true, true}, elapsedBetween: 10 * time.Second, expectedScaleUps: 0},
+		{name: "scale up should run once the shoot has been healthy for the window", probes: []bool{true, true, true, true}, elapsedBetween: 10 * time.Second, expectedScaleUps: 1},
+		{name: "re-failure within the window should cancel the pending scale up", probes: []bool{true, true, false, true, true}, elapsedBetween: 10 * time.Second, expectedScaleUps: 0},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			healthy := true
+			probeFn := func(_ context.Context) (bool, error) {
+				return healthy, nil
+			}
+			config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+			config.SuccessThreshold = pointer.Int(1)
+			config.FailureThreshold = pointer.Int(100)
+			config.ScaleUpStabilizationWindow = &metav1.Duration{Duration: 30 * time.Second}
+			scaler := &flowRecordingScaler{}
+			p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+			defer p.Close()
+			fakeClock := testclock.NewFakePassiveClock(time.Now())
+			p.clock = fakeClock
+
+			for _, probeHealthy := range entry.probes {
+				healthy = probeHealthy
+				p.probe(p.ctx)
+				fakeClock.SetTime(fakeClock.Now().Add(entry.elapsedBetween))
+			}
+			g.Expect(scaler.scaleUps).To(Equal(entry.expectedScaleUps))
+			g.Expect(scaler.scaleDowns).To(BeZero())
+		})
+	}
+}
+
+func TestFailedProbeShouldRestartScaleUpStabilizationWindow(t *testing.T) {
+	g := NewWithT(t)
+	var (
+		healthy  bool
+		probeErr error
+	)
+	probeFn := func(_ context.Context) (bool, error) {
+		return healthy, probeErr
+	}
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.SuccessThreshold = pointer.Int(1)
+	config.ScaleUpStabilizationWindow = &metav1.Duration{Duration: 30 * time.Second}
+	scaler := &flowRecordingScaler{}
+	p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+	defer p.Close()
+	fakeClock := testclock.NewFakePassiveClock(time.Now())
+	p.clock = fakeClock
+
+	healthy = true
+	p.probe(p.ctx)
+	fakeClock.SetTime(fakeClock.Now().Add(20 * time.Second))
+	// the API server is briefly unreachable.
+	probeErr = errors.New("test probe error")
+	p.probe(p.ctx)
+	g.Expect(p.healthySince.IsZero()).To(BeTrue(), "a failed probe should interrupt the healthy probes")
+	probeErr = nil
+	p.probe(p.ctx)
+	fakeClock.SetTime(fakeClock.Now().Add(20 * time.Second))
+	p.probe(p.ctx)
+	g.Expect(scaler.scaleUps).To(BeZero(), "the window should have restarted with the probe following the failed one")
+	fakeClock.SetTime(fakeClock.Now().Add(10 * time.Second))
+	p.probe(p.ctx)
+	g.Expect(scaler.scaleUps).To(Equal(1))
+}
+
+func TestScaleDownShouldBeHeldTillDependentsHaveBeenImpactedForDuration(t *testing.T) {
+	probeErr := errors.New("api server is unreachable")
+	testCases := []struct {
+		name               string
+		probeErr           error
+		dependentsImpacted []bool
+		expectedScaleDowns int
+	}{
+		{name: "unreachable api server should not scale down", probeErr: probeErr, dependentsImpacted: []bool{true, true, true, true}, expectedScaleDowns: 0},
+		{name: "unhealthy shoot with dependents which are fine should not scale down", dependentsImpacted: []bool{false, false, false, false}, expectedScaleDowns: 0},
+		{name: "dependents impacted within the duration should not scale down", dependentsImpacted: []bool{true, true}, expectedScaleDowns: 0},
+		{name: "dependents impacted for the duration should scale down", dependentsImpacted: []bool{true, true, true, true}, expectedScaleDowns: 1},
+		{name: "recovering dependents should restart the duration", dependentsImpacted: []bool{true, true, false, true, true}, expectedScaleDowns: 0},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployments := generateScaleTargetDeployments(1)
+			for _, deploy := range deployments {
+				deploy.Status.ReadyReplicas = 1
+			}
+			seedClient := initializeSeedClientBuilder(nil, deployments).Build()
+			probeFn := func(_ context.Context) (bool, error) {
+				return false, entry.probeErr
+			}
+			config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+			config.FailureThreshold = pointer.Int(1)
+			config.DependentsImpactedDuration = &metav1.Duration{Duration: 30 * time.Second}
+			for _, deploy := range deployments {
+				config.DependentResourceInfos = append(config.DependentResourceInfos, papi.DependentResourceInfo{
+					Ref: &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: deploy.Name, APIVersion: "apps/v1"},
+				})
+			}
+			scaler := &flowRecordingScaler{}
+			p := NewProber(ctx, seedClient, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+			defer p.Close()
+			fakeClock := testclock.NewFakePassiveClock(time.Now())
+			p.clock = fakeClock
+
+			for _, impacted := range entry.dependentsImpacted {
+				// only the first dependent resource is impacted, which suffices for a scale-down.
+				kcm := &appsv1.Deployment{}
+				g.Expect(seedClient.Get(ctx, client.ObjectKeyFromObject(deployments[0]), kcm)).To(Succeed())
+				kcm.Status.ReadyReplicas = 1
+				if impacted {
+					kcm.Status.ReadyReplicas = 0
+				}
+				g.Expect(seedClient.Status().Update(ctx, kcm)).To(Succeed())
+				p.probe(p.ctx)
+				fakeClock.SetTime(fakeClock.Now().Add(10 * time.Second))
+			}
+			g.Expect(scaler.scaleDowns).To(Equal(entry.expectedScaleDowns))
+			g.Expect(scaler.scaleUps).To(BeZero())
+		})
+	}
+}
+
+func TestScaleDownShouldNotAwaitImpactedDependentsByDefault(t *testing.T) {
+	g := NewWithT(t)
+	deployments := generateScaleTargetDeployments(1)
+	for _, deploy := range deployments {
+		deploy.Status.ReadyReplicas = 1
+	}
+	seedClient := initializeSeedClientBuilder(nil, deployments).Build()
+	probeFn := func(_ context.Context) (bool, error) {
+		return false, nil
+	}
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Microsecond}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.FailureThreshold = pointer.Int(1)
+	scaler := &flowRecordingScaler{}
+	p := NewProber(context.Background(), seedClient, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+	defer p.Close()
+
+	p.probe(p.ctx)
+	g.Expect(scaler.scaleDowns).To(Equal(1))
+	g.Expect(p.dependentsImpactedSince.IsZero()).To(BeTrue())
+}
+
 //---------------------------------- Helper functions ----------------------------------
 
+func TestTriggerProbeShouldRunProbeAndScalingDecisionRightAway(t *testing.T) {
+	testCases := []struct {
+		name               string
+		healthy            bool
+		probeErr           error
+		expectedDecision   ProbeDecision
+		expectedScaleUps   int
+		expectedScaleDowns int
+	}{
+		{name: "healthy shoot should be scaled up", healthy: true, expectedDecision: ProbeDecisionScaleUp, expectedScaleUps: 1},
+		{name: "unhealthy shoot should be scaled down", healthy: false, expectedDecision: ProbeDecisionScaleDown, expectedScaleDowns: 1},
+		{name: "failed probe should not be scaled", probeErr: errors.New("test probe error"), expectedDecision: ProbeDecisionNone},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			probeFn := func(_ context.Context) (bool, error) {
+				return entry.healthy, entry.probeErr
+			}
+			// the initial delay keeps the periodic probes from running during the test.
+			config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Hour}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+			config.SuccessThreshold = pointer.Int(1)
+			config.FailureThreshold = pointer.Int(1)
+			scaler := &flowRecordingScaler{}
+			p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+			defer p.Close()
+			go p.Run()
+
+			decision, err := p.TriggerProbe(context.Background())
+			if entry.probeErr != nil {
+				g.Expect(err).To(MatchError(entry.probeErr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(decision).To(Equal(entry.expectedDecision))
+			g.Expect(scaler.scaleUps).To(Equal(entry.expectedScaleUps))
+			g.Expect(scaler.scaleDowns).To(Equal(entry.expectedScaleDowns))
+		})
+	}
+}
+
+func TestTriggerProbeShouldCountTowardsThresholds(t *testing.T) {
+	g := NewWithT(t)
+	probeFn := func(_ context.Context) (bool, error) {
+		return false, nil
+	}
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Hour}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.FailureThreshold = pointer.Int(2)
+	scaler := &flowRecordingScaler{}
+	p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, scaler, nil, logr.Discard(), WithProbeFn(probeFn))
+	defer p.Close()
+	go p.Run()
+
+	decision, err := p.TriggerProbe(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(decision).To(Equal(ProbeDecisionNone), "the failure threshold should not have been reached by the first probe")
+	decision, err = p.TriggerProbe(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(decision).To(Equal(ProbeDecisionScaleDown))
+	g.Expect(scaler.scaleDowns).To(Equal(1))
+}
+
+func TestTriggerProbeOfClosedProberShouldFail(t *testing.T) {
+	g := NewWithT(t)
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Hour}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	p := NewProber(context.Background(), nil, test.DefaultNamespace, config, nil, &flowRecordingScaler{}, nil, logr.Discard())
+	p.Close()
+
+	decision, err := p.TriggerProbe(context.Background())
+	g.Expect(err).To(MatchError(ErrProberClosed))
+	g.Expect(decision).To(Equal(ProbeDecisionNone))
+}
+
 func getDeploymentRefs(deployments []*appsv1.Deployment) []client.ObjectKey {
 	refs := make([]client.ObjectKey, 0, len(deployments))
 	for _, deploy := range deployments {
@@ -685,5 +1027,34 @@ func createConfig(probeInterval metav1.Duration, initialDelay metav1.Duration, k
 		ProbeTimeout:                &testProbeTimeout,
 		KCMNodeMonitorGraceDuration: &kcmNodeMonitorGraceDuration,
 		NodeLeaseFailureFraction:    pointer.Float64(DefaultNodeLeaseFailureFraction),
+		FailureThreshold:            pointer.Int(DefaultFailureThreshold),
+		SuccessThreshold:            pointer.Int(DefaultSuccessThreshold),
 	}
 }
+
+// flowRecordingScaler records the number of scale up and scale down flows which have been run.
+type flowRecordingScaler struct {
+	scaleUps   int
+	scaleDowns int
+	flowGraphs scaler.FlowGraphs
+}
+
+func (s *flowRecordingScaler) ScaleUp(_ context.Context) error {
+	s.scaleUps++
+	return nil
+}
+
+func (s *flowRecordingScaler) ScaleDown(_ context.Context) error {
+	s.scaleDowns++
+	return nil
+}
+
+func (s *flowRecordingScaler) ScaleResource(_ context.Context, _ string, _ scaler.ScaleDirection) error {
+	return nil
+}
+
+func (s *flowRecordingScaler) FlowGraphs(_ context.Context) (scaler.FlowGraphs, error) {
+	return s.flowGraphs, nil
+}
+
+func (s *flowRecordingScaler) Close() {}
@@ -5,9 +5,19 @@
 package prober
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
 	"sync"
+
+	dwdScaler "github.com/gardener/dependency-watchdog/internal/prober/scaler"
+	"github.com/go-logr/logr"
 )
 
+// ErrProberNotFound is returned when a probe is triggered for a shoot namespace for which no prober is registered.
+var ErrProberNotFound = errors.New("no prober is registered for the namespace")
+
 // Manager is the convenience interface to manage lifecycle of probers.
 type Manager interface {
 	// Register registers the given prober with the manager. It should return false if prober is already registered.
@@ -16,20 +26,74 @@ type Manager interface {
 	Unregister(key string) bool
 	// GetProber uses the given key to get a registered prober from the manager. It returns false if prober is not found.
 	GetProber(key string) (Prober, bool)
+	// GetProberByConfigName returns the registered prober whose config has the given name. It returns false if no such prober is found.
+	// Unlike GetProber this is an O(n) lookup as it iterates over all registered probers.
+	GetProberByConfigName(name string) (Prober, bool)
 	// GetAllProbers returns a slice of all the probers registered with the manager.
 	GetAllProbers() []Prober
+	// GetProberCount returns the number of probers registered with the manager.
+	GetProberCount() int
+	// GetScalingFlowLimiter returns the ScalingFlowLimiter which is shared by all probers managed by the manager.
+	GetScalingFlowLimiter() ScalingFlowLimiter
+	// GetScalingPause returns the ScalingPause which is shared by the scalers of all probers managed by the manager. While it is
+	// paused, the probers keep probing but none of them scales its dependent resources.
+	GetScalingPause() *dwdScaler.ScalingPause
+	// ReplaceAll replaces all registered probers with the given probers in a single locked operation. A prober whose key is
+	// not among the given probers is closed and unregistered, a given prober whose key is not registered is registered, and a
+	// registered prober whose config or worker node conditions differ from the given prober is closed and replaced by it.
+	// A given prober which is identical to the registered one, or whose key occurs more than once, is closed and discarded.
+	// Probers are not run by the manager, the registered and replaced probers therefore have to be run by the caller.
+	ReplaceAll(newProbers []Prober) ReplaceSummary
+	// TriggerProbe runs a probe of the prober registered for the shoot namespace right away, see Prober.TriggerProbe, and returns
+	// the scaling decision which has been taken for it. It returns ErrProberNotFound if no prober is registered for the namespace.
+	TriggerProbe(ctx context.Context, namespace string) (ProbeDecision, error)
+	// GetFlowGraphs returns the graphs of the scaling flows of the prober registered for the shoot namespace, see Prober.FlowGraphs.
+	// It returns ErrProberNotFound if no prober is registered for the namespace.
+	GetFlowGraphs(ctx context.Context, namespace string) (dwdScaler.FlowGraphs, error)
+	// Shutdown closes and unregisters all probers. It logs a summary of the number of probers that have been
+	// unregistered and the number of scaling flows that were still running and have therefore been interrupted.
+	Shutdown(logger logr.Logger)
+}
+
+// ReplaceSummary captures the keys of the probers which have been added, removed, updated or left unchanged by Manager.ReplaceAll.
+// Every slice of keys is sorted.
+type ReplaceSummary struct {
+	Added     []string
+	Removed   []string
+	Updated   []string
+	Unchanged []string
+}
+
+type managerOption func(pm *manager)
+
+// WithMaxConcurrentScalingFlows bounds the number of scaling flows that can run concurrently across all probers
+// managed by the manager. Probers which trigger a scaling flow when the limit has been reached will wait for a slot.
+func WithMaxConcurrentScalingFlows(maxConcurrentFlows int) managerOption {
+	return func(pm *manager) {
+		pm.scalingFlowLimiter = NewScalingFlowLimiter(maxConcurrentFlows)
+	}
 }
 
 // NewManager creates a new manager to manage probers.
-func NewManager() Manager {
-	return &manager{
-		probers: make(map[string]Prober),
+func NewManager(opts ...managerOption) Manager {
+	pm := &manager{
+		probers:            make(map[string]Prober),
+		scalingFlowLimiter: NewScalingFlowLimiter(0),
+		scalingPause:       dwdScaler.NewScalingPause(),
 	}
+	for _, opt := range opts {
+		opt(pm)
+	}
+	return pm
 }
 
+// manager guards the registered probers with a RWMutex as they are registered, unregistered and looked up concurrently by
+// the reconciles of different clusters and by the debug endpoints.
 type manager struct {
-	sync.Mutex
-	probers map[string]Prober
+	sync.RWMutex
+	probers            map[string]Prober
+	scalingFlowLimiter ScalingFlowLimiter
+	scalingPause       *dwdScaler.ScalingPause
 }
 
 func (pm *manager) Unregister(key string) bool {
@@ -55,11 +119,26 @@ func (pm *manager) Register(prober Prober) bool {
 }
 
 func (pm *manager) GetProber(key string) (Prober, bool) {
+	pm.RLock()
+	defer pm.RUnlock()
 	prober, ok := pm.probers[key]
 	return prober, ok
 }
 
+func (pm *manager) GetProberByConfigName(name string) (Prober, bool) {
+	pm.RLock()
+	defer pm.RUnlock()
+	for _, p := range pm.probers {
+		if p.config != nil && p.config.Name == name {
+			return p, true
+		}
+	}
+	return Prober{}, false
+}
+
 func (pm *manager) GetAllProbers() []Prober {
+	pm.RLock()
+	defer pm.RUnlock()
 	probers := make([]Prober, 0, len(pm.probers))
 	for _, p := range pm.probers {
 		probers = append(probers, p)
@@ -67,6 +146,90 @@ func (pm *manager) GetAllProbers() []Prober {
 	return probers
 }
 
+func (pm *manager) GetProberCount() int {
+	pm.RLock()
+	defer pm.RUnlock()
+	return len(pm.probers)
+}
+
+func (pm *manager) TriggerProbe(ctx context.Context, namespace string) (ProbeDecision, error) {
+	p, ok := pm.GetProber(namespace)
+	if !ok {
+		return ProbeDecisionNone, ErrProberNotFound
+	}
+	return p.TriggerProbe(ctx)
+}
+
+func (pm *manager) GetFlowGraphs(ctx context.Context, namespace string) (dwdScaler.FlowGraphs, error) {
+	p, ok := pm.GetProber(namespace)
+	if !ok {
+		return dwdScaler.FlowGraphs{}, ErrProberNotFound
+	}
+	return p.FlowGraphs(ctx)
+}
+
+func (pm *manager) GetScalingFlowLimiter() ScalingFlowLimiter {
+	return pm.scalingFlowLimiter
+}
+
+func (pm *manager) GetScalingPause() *dwdScaler.ScalingPause {
+	return pm.scalingPause
+}
+
+func (pm *manager) ReplaceAll(newProbers []Prober) ReplaceSummary {
+	pm.Lock()
+	defer pm.Unlock()
+	var summary ReplaceSummary
+	replacedKeys := make(map[string]struct{}, len(newProbers))
+	for _, newProber := range newProbers {
+		key := createKey(newProber)
+		if _, ok := replacedKeys[key]; ok {
+			newProber.Close()
+			continue
+		}
+		replacedKeys[key] = struct{}{}
+		existingProber, ok := pm.probers[key]
+		switch {
+		case !ok:
+			summary.Added = append(summary.Added, key)
+		case reflect.DeepEqual(existingProber.config, newProber.config) && !existingProber.AreWorkerNodeConditionsStale(newProber.workerNodeConditions):
+			summary.Unchanged = append(summary.Unchanged, key)
+			newProber.Close()
+			continue
+		default:
+			summary.Updated = append(summary.Updated, key)
+			existingProber.Close()
+		}
+		pm.probers[key] = newProber
+	}
+	for key, p := range pm.probers {
+		if _, ok := replacedKeys[key]; !ok {
+			summary.Removed = append(summary.Removed, key)
+			delete(pm.probers, key)
+			p.Close()
+		}
+	}
+	for _, keys := range [][]string{summary.Added, summary.Removed, summary.Updated, summary.Unchanged} {
+		slices.Sort(keys)
+	}
+	return summary
+}
+
+func (pm *manager) Shutdown(logger logr.Logger) {
+	pm.Lock()
+	defer pm.Unlock()
+	var probersUnregistered, scalingFlowsInterrupted int
+	for key, p := range pm.probers {
+		if p.isScalingFlowRunning() {
+			scalingFlowsInterrupted++
+		}
+		delete(pm.probers, key)
+		p.Close()
+		probersUnregistered++
+	}
+	logger.Info("Prober manager has been shut down", "probersUnregistered", probersUnregistered, "scalingFlowsInterrupted", scalingFlowsInterrupted)
+}
+
 func createKey(prober Prober) string {
 	return prober.namespace // check if this would be sufficient
 }
@@ -8,12 +8,22 @@ package prober
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 const proberMgrTestNamespace = "default"
@@ -70,6 +80,56 @@ func TestProberRegistrationWithSameKeyShouldNotOverwriteExistingProber(t *testin
 	t.Log("Existing prober is not overwritten by the Register method")
 }
 
+// TestConcurrentAccessToManager is meant to be run with -race, which reports any access to the registered probers which is
+// not guarded by the manager. Every lookup is made by a goroutine of its own, so that its accesses are not ordered by the
+// locks taken for the other lookups.
+func TestConcurrentAccessToManager(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+	const numNamespaces, numIterations = 4, 100
+	namespaceOf := func(i int) string { return "concurrent-" + strconv.Itoa(i%numNamespaces) }
+
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numIterations; i++ {
+				fn(i)
+			}
+		}()
+	}
+	for w := 0; w < 2*numNamespaces; w++ {
+		run(func(i int) {
+			namespace := namespaceOf(w + i)
+			p := NewProber(context.Background(), nil, namespace, &papi.Config{Name: namespace}, nil, nil, nil, pmLogger)
+			if !mgr.Register(*p) {
+				p.Close()
+			}
+			if i%2 == 1 {
+				mgr.Unregister(namespace)
+			}
+		})
+	}
+	run(func(i int) {
+		if p, ok := mgr.GetProber(namespaceOf(i)); ok && p.namespace != namespaceOf(i) {
+			t.Errorf("mgr.GetProber returned the prober of namespace %s for namespace %s", p.namespace, namespaceOf(i))
+		}
+	})
+	run(func(i int) { _, _ = mgr.GetProberByConfigName(namespaceOf(i)) })
+	run(func(_ int) { _ = mgr.GetAllProbers() })
+	run(func(_ int) { _ = mgr.GetProberCount() })
+	wg.Wait()
+
+	probers := mgr.GetAllProbers()
+	g.Expect(mgr.GetProberCount()).To(Equal(len(probers)))
+	g.Expect(len(probers)).To(BeNumerically("<=", numNamespaces))
+	for _, p := range probers {
+		g.Expect(p.IsClosed()).To(BeFalse(), "a registered prober should not be closed")
+	}
+}
+
 func TestUnregisterExistingProberShouldCloseItAndRemoveItFromManager(t *testing.T) {
 	g := NewWithT(t)
 	mgr, tearDownTest := setupMgrTest(t)
@@ -87,6 +147,35 @@ func TestUnregisterExistingProberShouldCloseItAndRemoveItFromManager(t *testing.
 
 }
 
+// closeTrackingScaler records whether it has been closed.
+type closeTrackingScaler struct {
+	closed bool
+}
+
+func (s *closeTrackingScaler) ScaleUp(_ context.Context) error   { return nil }
+func (s *closeTrackingScaler) ScaleDown(_ context.Context) error { return nil }
+func (s *closeTrackingScaler) ScaleResource(_ context.Context, _ string, _ scaler.ScaleDirection) error {
+	return nil
+}
+func (s *closeTrackingScaler) FlowGraphs(_ context.Context) (scaler.FlowGraphs, error) {
+	return scaler.FlowGraphs{}, nil
+}
+func (s *closeTrackingScaler) Close() { s.closed = true }
+
+func TestUnregisterExistingProberShouldCloseItsScaler(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	scaler := &closeTrackingScaler{}
+	p := NewProber(context.Background(), nil, proberMgrTestNamespace, &papi.Config{}, nil, scaler, nil, pmLogger)
+	g.Expect(mgr.Register(*p)).To(BeTrue(), "mgr.Register should register a new prober")
+	g.Expect(scaler.closed).To(BeFalse())
+
+	g.Expect(mgr.Unregister(proberMgrTestNamespace)).To(BeTrue())
+	g.Expect(scaler.closed).To(BeTrue(), "mgr.Unregister should close the scaler of the unregistered prober")
+}
+
 func TestUnregisterNonExistingProberShouldNotFail(t *testing.T) {
 	g := NewWithT(t)
 	mgr, tearDownTest := setupMgrTest(t)
@@ -96,3 +185,244 @@ func TestUnregisterNonExistingProberShouldNotFail(t *testing.T) {
 	t.Log("De-registering a non existing prober did not fail")
 
 }
+
+func TestGetProberByConfigName(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	p1 := NewProber(context.Background(), nil, "shoot--foo--bar", &papi.Config{Name: "bingo"}, nil, nil, nil, pmLogger)
+	g.Expect(mgr.Register(*p1)).To(BeTrue(), "mgr.Register should register a new prober")
+	p2 := NewProber(context.Background(), nil, "shoot--foo--baz", &papi.Config{Name: "zingo"}, nil, nil, nil, pmLogger)
+	g.Expect(mgr.Register(*p2)).To(BeTrue(), "mgr.Register should register a new prober")
+
+	foundProber, ok := mgr.GetProberByConfigName("zingo")
+	g.Expect(ok).Should(BeTrue(), "mgr.GetProberByConfigName should return true for a registered prober")
+	g.Expect(foundProber.namespace).Should(Equal(p2.namespace))
+
+	_, ok = mgr.GetProberByConfigName("bazingo")
+	g.Expect(ok).Should(BeFalse(), "mgr.GetProberByConfigName should return false if no prober has a config with the given name")
+}
+
+func TestShutdownShouldCloseAllProbersAndLogSummary(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	p1 := NewProber(context.Background(), nil, "shoot--foo--bar", &papi.Config{}, nil, nil, nil, pmLogger)
+	g.Expect(mgr.Register(*p1)).To(BeTrue(), "mgr.Register should register a new prober")
+	p2 := NewProber(context.Background(), nil, "shoot--foo--baz", &papi.Config{}, nil, nil, nil, pmLogger)
+	g.Expect(mgr.Register(*p2)).To(BeTrue(), "mgr.Register should register a new prober")
+	p2.scalingFlowRunning.Store(true)
+
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	mgr.Shutdown(logger)
+
+	g.Expect(mgr.GetAllProbers()).To(BeEmpty(), "mgr.Shutdown should unregister all probers")
+	g.Expect(p1.IsClosed()).To(BeTrue(), "mgr.Shutdown should close all probers")
+	g.Expect(p2.IsClosed()).To(BeTrue(), "mgr.Shutdown should close all probers")
+	g.Expect(logs).To(ContainElement(SatisfyAll(
+		ContainSubstring(`"probersUnregistered"=2`),
+		ContainSubstring(`"scalingFlowsInterrupted"=1`),
+	)))
+}
+
+func TestReplaceAllShouldAddRemoveAndUpdateProbers(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	workerNodeConditions := map[string][]string{"worker-a": {"KernelDeadlock"}}
+	unchanged := NewProber(context.Background(), nil, "shoot--foo--unchanged", &papi.Config{Name: "unchanged"}, workerNodeConditions, nil, nil, pmLogger)
+	configChanged := NewProber(context.Background(), nil, "shoot--foo--config", &papi.Config{Name: "config"}, nil, nil, nil, pmLogger)
+	conditionsChanged := NewProber(context.Background(), nil, "shoot--foo--conditions", &papi.Config{Name: "conditions"}, workerNodeConditions, nil, nil, pmLogger)
+	removedScaler := &closeTrackingScaler{}
+	removed := NewProber(context.Background(), nil, "shoot--foo--removed", &papi.Config{Name: "removed"}, nil, removedScaler, nil, pmLogger)
+	for _, p := range []*Prober{unchanged, configChanged, conditionsChanged, removed} {
+		g.Expect(mgr.Register(*p)).To(BeTrue())
+	}
+
+	newUnchanged := NewProber(context.Background(), nil, "shoot--foo--unchanged", &papi.Config{Name: "unchanged"}, map[string][]string{"worker-a": {"KernelDeadlock"}}, nil, nil, pmLogger)
+	newConfigChanged := NewProber(context.Background(), nil, "shoot--foo--config", &papi.Config{Name: "config", ScaleUpDisabled: true}, nil, nil, nil, pmLogger)
+	newConditionsChanged := NewProber(context.Background(), nil, "shoot--foo--conditions", &papi.Config{Name: "conditions"}, nil, nil, nil, pmLogger)
+	added := NewProber(context.Background(), nil, "shoot--foo--added", &papi.Config{Name: "added"}, nil, nil, nil, pmLogger)
+	summary := mgr.ReplaceAll([]Prober{*newUnchanged, *newConfigChanged, *newConditionsChanged, *added})
+
+	g.Expect(summary).To(Equal(ReplaceSummary{
+		Added:     []string{"shoot--foo--added"},
+		Removed:   []string{"shoot--foo--removed"},
+		Updated:   []string{"shoot--foo--conditions", "shoot--foo--config"},
+		Unchanged: []string{"shoot--foo--unchanged"},
+	}))
+	g.Expect(mgr.GetAllProbers()).To(HaveLen(4))
+
+	// an unchanged prober keeps running, the identical new prober is discarded.
+	p, ok := mgr.GetProber("shoot--foo--unchanged")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p.IsClosed()).To(BeFalse())
+	g.Expect(unchanged.IsClosed()).To(BeFalse())
+	g.Expect(newUnchanged.IsClosed()).To(BeTrue(), "the identical new prober should be closed")
+
+	// updated probers are replaced by the new ones.
+	p, ok = mgr.GetProber("shoot--foo--config")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p.config.ScaleUpDisabled).To(BeTrue())
+	g.Expect(configChanged.IsClosed()).To(BeTrue(), "the replaced prober should be closed")
+	g.Expect(conditionsChanged.IsClosed()).To(BeTrue(), "the replaced prober should be closed")
+	g.Expect(newConditionsChanged.IsClosed()).To(BeFalse())
+
+	_, ok = mgr.GetProber("shoot--foo--added")
+	g.Expect(ok).To(BeTrue())
+
+	_, ok = mgr.GetProber("shoot--foo--removed")
+	g.Expect(ok).To(BeFalse())
+	g.Expect(removed.IsClosed()).To(BeTrue(), "the removed prober should be closed")
+	g.Expect(removedScaler.closed).To(BeTrue(), "the scaler of the removed prober should be closed")
+}
+
+func TestReplaceAllShouldKeepFirstOfDuplicateProbers(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	first := NewProber(context.Background(), nil, proberMgrTestNamespace, &papi.Config{Name: "first"}, nil, nil, nil, pmLogger)
+	duplicate := NewProber(context.Background(), nil, proberMgrTestNamespace, &papi.Config{Name: "duplicate"}, nil, nil, nil, pmLogger)
+	summary := mgr.ReplaceAll([]Prober{*first, *duplicate})
+
+	g.Expect(summary).To(Equal(ReplaceSummary{Added: []string{proberMgrTestNamespace}}))
+	p, ok := mgr.GetProber(proberMgrTestNamespace)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p.config.Name).To(Equal("first"))
+	g.Expect(duplicate.IsClosed()).To(BeTrue(), "the duplicate prober should be closed")
+}
+
+func TestReplaceAllWithNoProbersShouldRemoveAllProbers(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	p := NewProber(context.Background(), nil, proberMgrTestNamespace, &papi.Config{}, nil, nil, nil, pmLogger)
+	g.Expect(mgr.Register(*p)).To(BeTrue())
+
+	g.Expect(mgr.ReplaceAll(nil)).To(Equal(ReplaceSummary{Removed: []string{proberMgrTestNamespace}}))
+	g.Expect(mgr.GetAllProbers()).To(BeEmpty())
+	g.Expect(p.IsClosed()).To(BeTrue())
+}
+
+// registerTriggerableProber registers and runs a prober whose probes only run when triggered and which scales the given scaler
+// after a single probe.
+func registerTriggerableProber(mgr Manager, healthy bool, scaler *flowRecordingScaler) {
+	probeFn := func(_ context.Context) (bool, error) {
+		return healthy, nil
+	}
+	config := createConfig(testProbeInterval, metav1.Duration{Duration: time.Hour}, metav1.Duration{Duration: 40 * time.Second}, 0.2)
+	config.SuccessThreshold = pointer.Int(1)
+	config.FailureThreshold = pointer.Int(1)
+	p := NewProber(context.Background(), nil, proberMgrTestNamespace, config, nil, scaler, nil, pmLogger, WithProbeFn(probeFn))
+	mgr.Register(*p)
+	go p.Run()
+}
+
+func TestTriggerProbeShouldRunProbeOfRegisteredProber(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+	scaler := &flowRecordingScaler{}
+	registerTriggerableProber(mgr, false, scaler)
+
+	decision, err := mgr.TriggerProbe(context.Background(), proberMgrTestNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(decision).To(Equal(ProbeDecisionScaleDown))
+	g.Expect(scaler.scaleDowns).To(Equal(1))
+}
+
+func TestTriggerProbeOfUnregisteredNamespaceShouldFail(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	_, err := mgr.TriggerProbe(context.Background(), proberMgrTestNamespace)
+	g.Expect(err).To(MatchError(ErrProberNotFound))
+}
+
+func TestProbeTriggerHandler(t *testing.T) {
+	testCases := []struct {
+		name             string
+		method           string
+		target           string
+		expectedStatus   int
+		expectedDecision ProbeDecision
+	}{
+		{name: "POST for a registered prober should run a probe", method: http.MethodPost, target: "/?namespace=" + proberMgrTestNamespace, expectedStatus: http.StatusOK, expectedDecision: ProbeDecisionScaleUp},
+		{name: "POST for an unknown namespace should not be found", method: http.MethodPost, target: "/?namespace=unknown", expectedStatus: http.StatusNotFound, expectedDecision: ProbeDecisionNone},
+		{name: "POST without namespace should be rejected", method: http.MethodPost, target: "/", expectedStatus: http.StatusBadRequest},
+		{name: "GET should not be allowed", method: http.MethodGet, target: "/?namespace=" + proberMgrTestNamespace, expectedStatus: http.StatusMethodNotAllowed},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mgr, tearDownTest := setupMgrTest(t)
+			defer tearDownTest(mgr)
+			scaler := &flowRecordingScaler{}
+			registerTriggerableProber(mgr, true, scaler)
+
+			recorder := httptest.NewRecorder()
+			NewProbeTriggerHandler(mgr, pmLogger).ServeHTTP(recorder, httptest.NewRequest(entry.method, entry.target, nil))
+			g.Expect(recorder.Code).To(Equal(entry.expectedStatus))
+			if entry.expectedDecision == "" {
+				g.Expect(scaler.scaleUps).To(BeZero())
+				return
+			}
+			response := ProbeTriggerResponse{}
+			g.Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			g.Expect(response.Decision).To(Equal(entry.expectedDecision))
+			if entry.expectedDecision == ProbeDecisionScaleUp {
+				g.Expect(scaler.scaleUps).To(Equal(1))
+			}
+		})
+	}
+}
+
+func TestFlowGraphHandler(t *testing.T) {
+	flowGraphs := scaler.FlowGraphs{
+		ScaleUp: scaler.FlowGraph{Name: "scale-up-" + proberMgrTestNamespace, Tasks: []scaler.FlowGraphTask{
+			{Name: "scale:level-0:kcm", DependsOn: []string{}, WaitOnResources: []string{}},
+			{Name: "scale:level-1:mcm", DependsOn: []string{"scale:level-0:kcm"}, WaitOnResources: []string{"apps/v1/Deployment/kcm"}},
+		}},
+		ScaleDown: scaler.FlowGraph{Name: "scale-down-" + proberMgrTestNamespace, Tasks: []scaler.FlowGraphTask{
+			{Name: "scale:level-0:mcm", DependsOn: []string{}, WaitOnResources: []string{}},
+		}},
+	}
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		expectedStatus int
+	}{
+		{name: "GET for a registered prober should return its flow graphs", method: http.MethodGet, target: "/?namespace=" + proberMgrTestNamespace, expectedStatus: http.StatusOK},
+		{name: "GET for an unknown namespace should not be found", method: http.MethodGet, target: "/?namespace=unknown", expectedStatus: http.StatusNotFound},
+		{name: "GET without namespace should be rejected", method: http.MethodGet, target: "/", expectedStatus: http.StatusBadRequest},
+		{name: "POST should not be allowed", method: http.MethodPost, target: "/?namespace=" + proberMgrTestNamespace, expectedStatus: http.StatusMethodNotAllowed},
+	}
+	for _, entry := range testCases {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mgr, tearDownTest := setupMgrTest(t)
+			defer tearDownTest(mgr)
+			s := &flowRecordingScaler{flowGraphs: flowGraphs}
+			registerTriggerableProber(mgr, true, s)
+
+			recorder := httptest.NewRecorder()
+			NewFlowGraphHandler(mgr, pmLogger).ServeHTTP(recorder, httptest.NewRequest(entry.method, entry.target, nil))
+			g.Expect(recorder.Code).To(Equal(entry.expectedStatus))
+			if entry.expectedStatus != http.StatusOK {
+				return
+			}
+			g.Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+			response := scaler.FlowGraphs{}
+			g.Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			g.Expect(response).To(Equal(flowGraphs))
+		})
+	}
+}
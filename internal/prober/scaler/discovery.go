@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultScaleTimeout is the timeout of scaling a dependent resource which is not loaded from a prober config, i.e. a Deployment
+// which has been discovered via the dependent resource selector or a resource built from a ScaleInfoSpec without a timeout.
+// It matches the default timeout of the configured dependent resources.
+const defaultScaleTimeout = 30 * time.Second
+
+// discoverDependentResourceInfos lists the Deployments in the namespace which are matched by the selector and returns them
+// along with the configured dependentResourceInfos. Every discovered Deployment which is not configured already is scaled at
+// level 0 without an initial delay, it is optional as it might have been deleted by the time it is scaled.
+func discoverDependentResourceInfos(ctx context.Context, cl client.Client, namespace string, selector labels.Selector, dependentResourceInfos []papi.DependentResourceInfo) ([]papi.DependentResourceInfo, error) {
+	deployments := &metav1.PartialObjectMetadataList{}
+	deployments.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("DeploymentList"))
+	if err := cl.List(ctx, deployments, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to discover dependent deployments matching %s: %w", selector.String(), err)
+	}
+	resourceInfos := slices.Clone(dependentResourceInfos)
+	for _, deployment := range deployments.Items {
+		configured := slices.ContainsFunc(dependentResourceInfos, func(resInfo papi.DependentResourceInfo) bool {
+			return resInfo.Ref.Kind == deploymentKind && resInfo.Ref.Name == deployment.Name
+		})
+		if !configured {
+			resourceInfos = append(resourceInfos, createDiscoveredResourceInfo(deployment.Name))
+		}
+	}
+	return resourceInfos, nil
+}
+
+func createDiscoveredResourceInfo(name string) papi.DependentResourceInfo {
+	return papi.DependentResourceInfo{
+		Ref:           &autoscalingv1.CrossVersionObjectReference{Kind: deploymentKind, Name: name, APIVersion: appsv1.SchemeGroupVersion.String()},
+		Optional:      true,
+		ScaleUpInfo:   createDiscoveredScaleInfo(),
+		ScaleDownInfo: createDiscoveredScaleInfo(),
+	}
+}
+
+func createDiscoveredScaleInfo() *papi.ScaleInfo {
+	return &papi.ScaleInfo{
+		Level:        0,
+		InitialDelay: &metav1.Duration{},
+		Timeout:      &metav1.Duration{Duration: defaultScaleTimeout},
+	}
+}
+
+// resolveResourceRef resolves the reference of a dependent resource to the resource of the same kind in the namespace which is
+// matched by the selector, e.g. as the resource has been recreated with a different name. The reference is retained if nothing
+// matches the selector or if the referenced resource is among the matches. More than one other match is ambiguous.
+func resolveResourceRef(ctx context.Context, cl client.Client, namespace string, ref *autoscalingv1.CrossVersionObjectReference, selector *metav1.LabelSelector) (*autoscalingv1.CrossVersionObjectReference, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of %s %s: %w", ref.Kind, ref.Name, err)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	resources := &metav1.PartialObjectMetadataList{}
+	resources.SetGroupVersionKind(gv.WithKind(ref.Kind + "List"))
+	if err = cl.List(ctx, resources, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s %s via selector %s: %w", ref.Kind, ref.Name, labelSelector.String(), err)
+	}
+	names := make([]string, 0, len(resources.Items))
+	for _, resource := range resources.Items {
+		names = append(names, resource.Name)
+	}
+	switch {
+	case len(names) == 0, slices.Contains(names, ref.Name):
+		return ref, nil
+	case len(names) == 1:
+		resolved := *ref
+		resolved.Name = names[0]
+		return &resolved, nil
+	default:
+		slices.Sort(names)
+		return nil, fmt.Errorf("selector %s of %s %s is ambiguous, it matches %s", labelSelector.String(), ref.Kind, ref.Name, strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	scalev1 "k8s.io/client-go/scale"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeScalesGetter is an in-memory scalev1.ScalesGetter which allows to test the scaling logic without a cluster. The scale
+// subresources are kept in a map keyed by namespace and name of the resource. Every change of the replicas of a scale
+// subresource is also applied to the Deployment of the same name known to the client, whose replicas are all marked as
+// ready right away as the deployment controller would eventually do.
+type fakeScalesGetter struct {
+	mu     sync.Mutex
+	client client.Client
+	scales map[types.NamespacedName]*autoscalingv1.Scale
+	// replicaUpdates records the replicas of every successful update or patch of a scale subresource.
+	replicaUpdates map[types.NamespacedName][]int32
+	// updateErrs are returned, one after the other, for the updates and patches of a scale subresource before it is changed.
+	updateErrs []error
+	// updateOrder records the name of the resource of every successful update or patch of a scale subresource.
+	updateOrder []string
+	// terminatingReplicas holds the replicas of a Deployment whose pods do not finish terminating, status.replicas of the
+	// Deployment does not drop below them.
+	terminatingReplicas map[types.NamespacedName]int32
+}
+
+// newFakeScalesGetter creates a client which holds the given Deployments and a fakeScalesGetter which holds a scale
+// subresource for each of them.
+func newFakeScalesGetter(deployments ...*appsv1.Deployment) (*fakeScalesGetter, client.Client) {
+	objects := make([]client.Object, 0, len(deployments))
+	scales := make(map[types.NamespacedName]*autoscalingv1.Scale, len(deployments))
+	for _, deployment := range deployments {
+		objects = append(objects, deployment)
+		scales[client.ObjectKeyFromObject(deployment)] = &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: deployment.Name, Namespace: deployment.Namespace, ResourceVersion: "1"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: pointer.Int32Deref(deployment.Spec.Replicas, 0)},
+			Status:     autoscalingv1.ScaleStatus{Replicas: deployment.Status.Replicas},
+		}
+	}
+	cl := newStagedTestClient(objects...)
+	return &fakeScalesGetter{client: cl, scales: scales, replicaUpdates: make(map[types.NamespacedName][]int32), terminatingReplicas: make(map[types.NamespacedName]int32)}, cl
+}
+
+func (f *fakeScalesGetter) Scales(namespace string) scalev1.ScaleInterface {
+	return &fakeScaleInterface{scales: f, namespace: namespace}
+}
+
+// scaleOrder returns the names of the resources in the order in which their scale subresources have been updated or patched.
+func (f *fakeScalesGetter) scaleOrder() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.updateOrder)
+}
+
+// updates returns the replicas of every update or patch of the scale subresource of the named resource.
+func (f *fakeScalesGetter) updates(namespace, name string) []int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.replicaUpdates[types.NamespacedName{Namespace: namespace, Name: name}]
+}
+
+// setReplicas changes the replicas of the scale subresource and of the Deployment of the same name. A non-empty
+// resourceVersion has to match the one of the scale subresource.
+func (f *fakeScalesGetter) setReplicas(ctx context.Context, gr schema.GroupResource, key types.NamespacedName, resourceVersion string, replicas int32) (*autoscalingv1.Scale, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	scale, ok := f.scales[key]
+	if !ok {
+		return nil, apierrors.NewNotFound(gr, key.Name)
+	}
+	if len(f.updateErrs) > 0 {
+		err := f.updateErrs[0]
+		f.updateErrs = f.updateErrs[1:]
+		return nil, err
+	}
+	if resourceVersion != "" && resourceVersion != scale.ResourceVersion {
+		return nil, apierrors.NewConflict(gr, key.Name, fmt.Errorf("resourceVersion %s does not match %s", resourceVersion, scale.ResourceVersion))
+	}
+	if err := f.syncDeployment(ctx, key, replicas); err != nil {
+		return nil, err
+	}
+	currentVersion, _ := strconv.Atoi(scale.ResourceVersion)
+	scale.ResourceVersion = strconv.Itoa(currentVersion + 1)
+	scale.Spec.Replicas = replicas
+	scale.Status.Replicas = replicas
+	f.replicaUpdates[key] = append(f.replicaUpdates[key], replicas)
+	f.updateOrder = append(f.updateOrder, key.Name)
+	return scale.DeepCopy(), nil
+}
+
+// syncDeployment applies the replicas to the Deployment of the same name, if there is one, and marks them as ready. Replicas
+// which do not finish terminating are still counted in status.replicas, see terminatingReplicas.
+func (f *fakeScalesGetter) syncDeployment(ctx context.Context, key types.NamespacedName, replicas int32) error {
+	deployment := &appsv1.Deployment{}
+	if err := f.client.Get(ctx, key, deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	deployment.Spec.Replicas = pointer.Int32(replicas)
+	if err := f.client.Update(ctx, deployment); err != nil {
+		return err
+	}
+	deployment.Status.ObservedGeneration = deployment.Generation
+	deployment.Status.Replicas = max(replicas, f.terminatingReplicas[key])
+	deployment.Status.UpdatedReplicas = replicas
+	deployment.Status.ReadyReplicas = replicas
+	deployment.Status.AvailableReplicas = replicas
+	return f.client.Status().Update(ctx, deployment)
+}
+
+// fakeScaleInterface is the scalev1.ScaleInterface of a fakeScalesGetter for a single namespace.
+type fakeScaleInterface struct {
+	scales    *fakeScalesGetter
+	namespace string
+}
+
+func (s *fakeScaleInterface) Get(_ context.Context, gr schema.GroupResource, name string, _ metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	s.scales.mu.Lock()
+	defer s.scales.mu.Unlock()
+	scale, ok := s.scales.scales[types.NamespacedName{Namespace: s.namespace, Name: name}]
+	if !ok {
+		return nil, apierrors.NewNotFound(gr, name)
+	}
+	return scale.DeepCopy(), nil
+}
+
+func (s *fakeScaleInterface) Update(ctx context.Context, gr schema.GroupResource, scale *autoscalingv1.Scale, _ metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	return s.scales.setReplicas(ctx, gr, types.NamespacedName{Namespace: s.namespace, Name: scale.Name}, scale.ResourceVersion, scale.Spec.Replicas)
+}
+
+// Patch only supports patches which set spec.replicas, which are the only patches of a scale subresource made by the scaler.
+func (s *fakeScaleInterface) Patch(ctx context.Context, gvr schema.GroupVersionResource, name string, pt types.PatchType, data []byte, _ metav1.PatchOptions) (*autoscalingv1.Scale, error) {
+	if pt != types.ApplyPatchType && pt != types.MergePatchType {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("patch type %s is not supported", pt))
+	}
+	patch := &autoscalingv1.Scale{}
+	if err := json.Unmarshal(data, patch); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	return s.scales.setReplicas(ctx, gvr.GroupResource(), types.NamespacedName{Namespace: s.namespace, Name: name}, "", patch.Spec.Replicas)
+}
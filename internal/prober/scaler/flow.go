@@ -6,7 +6,9 @@ package scaler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/gardener/gardener/pkg/utils/flow"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	scalev1 "k8s.io/client-go/scale"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -24,6 +27,8 @@ const (
 
 type flowCreator interface {
 	createFlow(name string, namespace string, opType operation) *scaleFlow
+	// createResourceTaskFn creates a flow.TaskFn which only scales the dependent resource of the given name, see Scaler.ScaleResource.
+	createResourceTaskFn(namespace string, refName string, opType operation) (flow.TaskFn, error)
 }
 
 type creator struct {
@@ -46,21 +51,47 @@ func newFlowCreator(client client.Client, scaler scalev1.ScaleInterface, logger
 
 func (c *creator) createFlow(name string, namespace string, opType operation) *scaleFlow {
 	resourceInfos := createScalableResourceInfos(opType, c.dependentResourceInfos)
+	if opType == scaleUp && c.options.fastScaleUp {
+		resourceInfos = collapseLevels(resourceInfos)
+	}
 	levels := sortAndGetUniqueLevels(resourceInfos)
 	orderedResourceInfos := collectResourceInfosByLevel(resourceInfos)
 	g := flow.NewGraph(name)
 	sf := newScaleFlow()
 	var previousLevelResourceInfos []scalableResourceInfo
 	var previousTaskIDs flow.TaskIDs
+	// taskIDs and resInfosByKey are keyed by util.ResourceRefKey and are used to resolve explicit dependencies.
+	taskIDs := make(map[string]flow.TaskID, len(resourceInfos))
+	resInfosByKey := make(map[string]scalableResourceInfo, len(resourceInfos))
 	for _, level := range levels {
 		if resInfos, ok := orderedResourceInfos[level]; ok {
-			dependentTaskIDs := previousTaskIDs
+			dependentTaskIDs, waitOnResourceInfos := previousTaskIDs, previousLevelResourceInfos
+			if dependencies, explicit := collectExplicitDependencies(resInfos); explicit {
+				dependentTaskIDs = flow.NewTaskIDs()
+				waitOnResourceInfos = make([]scalableResourceInfo, 0, len(dependencies))
+				for _, dependency := range dependencies {
+					key := util.ResourceRefKey(dependency)
+					depTaskID, found := taskIDs[key]
+					if !found {
+						// the config validation rejects such a dependency, it is nevertheless flagged as nothing is waited on for it.
+						c.logger.Info("WARNING: Dependency is not a dependent resource at a lower level, it will not be waited on", "operation", opType, "level", level, "dependency", key)
+						continue
+					}
+					dependentTaskIDs.Insert(depTaskID)
+					waitOnResourceInfos = append(waitOnResourceInfos, resInfosByKey[key])
+				}
+			}
 			taskID := g.Add(flow.Task{
 				Name:         createTaskName(resInfos, level),
-				Fn:           c.createScaleTaskFn(namespace, resInfos),
+				Fn:           c.withLevelTimeout(level, c.createScaleTaskFn(namespace, resInfos)),
 				Dependencies: dependentTaskIDs,
 			})
-			sf.addScaleStepInfo(taskID, dependentTaskIDs, previousLevelResourceInfos)
+			sf.addScaleStepInfo(taskID, dependentTaskIDs, waitOnResourceInfos)
+			for _, resInfo := range resInfos {
+				key := util.ResourceRefKey(*resInfo.ref)
+				taskIDs[key] = taskID
+				resInfosByKey[key] = resInfo
+			}
 			previousLevelResourceInfos = append(previousLevelResourceInfos, resInfos...)
 			if previousTaskIDs == nil {
 				previousTaskIDs = flow.NewTaskIDs(taskID)
@@ -73,6 +104,38 @@ func (c *creator) createFlow(name string, namespace string, opType operation) *s
 	return sf
 }
 
+// collapseLevels moves all resources to level 0 and drops their explicit dependencies, so that they are all scaled in a
+// single parallel step.
+func collapseLevels(resourceInfos []scalableResourceInfo) []scalableResourceInfo {
+	collapsed := make([]scalableResourceInfo, 0, len(resourceInfos))
+	for _, resInfo := range resourceInfos {
+		resInfo.level = 0
+		resInfo.dependsOn = nil
+		collapsed = append(collapsed, resInfo)
+	}
+	return collapsed
+}
+
+// createResourceTaskFn creates the same flow.TaskFn for the dependent resource of the given name as the flow does for it,
+// bounded by the level timeout, without the tasks of the other dependent resources. The name has to identify a single
+// dependent resource.
+func (c *creator) createResourceTaskFn(namespace string, refName string, opType operation) (flow.TaskFn, error) {
+	var matches []scalableResourceInfo
+	for _, resInfo := range createScalableResourceInfos(opType, c.dependentResourceInfos) {
+		if resInfo.ref.Name == refName {
+			matches = append(matches, resInfo)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotConfigured, refName)
+	case 1:
+		return c.withLevelTimeout(matches[0].level, c.doCreateTaskFn(namespace, matches[0])), nil
+	default:
+		return nil, fmt.Errorf("name %s is ambiguous, it matches %d dependent resources of different kinds", refName, len(matches))
+	}
+}
+
 // createScaleTaskFn creates a flow.TaskFn for a slice of DependentResourceInfo. If there are more than one
 // DependentResourceInfo passed to this function, it indicates that they all are at the same level indicating that these functions
 // should be invoked concurrently. In this case it will construct a flow.Parallel. If there is only one DependentResourceInfo passed
@@ -89,6 +152,29 @@ func (c *creator) createScaleTaskFn(namespace string, resourceInfos []scalableRe
 	return flow.Parallel(taskFns...)
 }
 
+// withLevelTimeout bounds the given flow.TaskFn of a level by the configured level timeout. If the level times out then
+// either an error is returned, which aborts the flow, or the timeout is only logged so that the flow can proceed with
+// the subsequent levels.
+func (c *creator) withLevelTimeout(level int, taskFn flow.TaskFn) flow.TaskFn {
+	if c.options.levelTimeout <= 0 {
+		return taskFn
+	}
+	return func(ctx context.Context) error {
+		levelCtx, cancelFn := context.WithTimeout(ctx, c.options.levelTimeout)
+		defer cancelFn()
+		err := taskFn(levelCtx)
+		// only a timeout of the level itself is handled here, a cancelled or expired flow context is returned as is.
+		if err == nil || ctx.Err() != nil || !errors.Is(levelCtx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+		if c.options.continueOnLevelTimeout {
+			c.logger.Error(err, "Scaling of level has timed out, proceeding with the subsequent levels", "level", level, "levelTimeout", c.options.levelTimeout)
+			return nil
+		}
+		return fmt.Errorf("scaling of level %d has timed out after %s: %w", level, c.options.levelTimeout, err)
+	}
+}
+
 func (c *creator) doCreateTaskFn(namespace string, resInfo scalableResourceInfo) flow.TaskFn {
 	return func(ctx context.Context) error {
 		var operation string
@@ -98,19 +184,52 @@ func (c *creator) doCreateTaskFn(namespace string, resInfo scalableResourceInfo)
 			operation = fmt.Sprintf("scaleDown-resource-%s.%s", namespace, resInfo.ref.Name)
 		}
 		resScaler := newResourceScaler(c.client, c.scaler, c.logger, c.options, namespace, resInfo)
+		numAttempts, backOff, canRetry := c.retryTuning(resInfo)
+		// a resource whose scale-down has not terminated has already been waited on for its timeout, it is not retried. Neither
+		// is a resource which DWD is not permitted to scale, as every further attempt would be forbidden as well.
+		retryable := func(err error) bool {
+			return !errors.Is(err, errScaleDownNotTerminated) && !apierrors.IsForbidden(err) && canRetry(err)
+		}
 		result := util.Retry(ctx, c.logger,
 			operation,
 			func() (interface{}, error) {
 				err := resScaler.scale(ctx)
 				return nil, err
 			},
-			defaultMaxResourceScalingAttempts,
-			*c.options.scaleResourceBackOff,
-			util.AlwaysRetry)
+			numAttempts,
+			backOff,
+			retryable,
+			util.WithQuietRetries(true),
+			util.WithMetricsOperation(resInfo.operation.String()))
+		if apierrors.IsForbidden(result.Err) {
+			c.logger.Error(result.Err, "Not permitted to scale dependent resource, it is not retried till the permissions have been granted", "operation", operation)
+		}
 		return result.Err
 	}
 }
 
+// retryTuning returns the number of attempts, the backoff and the predicate deciding which errors are retried when scaling
+// the resource. The retry info of the resource overrides the defaults for every field it specifies.
+func (c *creator) retryTuning(resInfo scalableResourceInfo) (int, time.Duration, func(error) bool) {
+	numAttempts, backOff, canRetry := defaultMaxResourceScalingAttempts, *c.options.scaleResourceBackOff, util.AlwaysRetry
+	retryInfo := resInfo.retryInfo
+	if retryInfo == nil {
+		return numAttempts, backOff, canRetry
+	}
+	if retryInfo.Attempts != nil {
+		numAttempts = *retryInfo.Attempts
+	}
+	if retryInfo.Backoff != nil {
+		backOff = retryInfo.Backoff.Duration
+	}
+	if retryInfo.RetryOnNotFound != nil && !*retryInfo.RetryOnNotFound {
+		canRetry = func(err error) bool {
+			return !apierrors.IsNotFound(err)
+		}
+	}
+	return numAttempts, backOff, canRetry
+}
+
 type scaleFlow struct {
 	flow          *flow.Flow
 	flowStepInfos []scaleStepInfo
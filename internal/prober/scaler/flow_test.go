@@ -7,12 +7,24 @@
 package scaler
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 
 	"github.com/gardener/gardener/pkg/utils/flow"
 	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
 )
@@ -62,7 +74,8 @@ func TestCreateScaleDownSequentialAndConcurrentFlow(t *testing.T) {
 	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 0, nil, nil, false))
 	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(caObjectRef.Name, 2, 0, nil, nil, false))
 
-	expectedScaleUpResNames := map[int][]string{0: {mcmObjectRef.Name, caObjectRef.Name}, 1: {kcmObjectRef.Name}}
+	// resources within a level are sorted by name irrespective of the order in which they have been configured
+	expectedScaleUpResNames := map[int][]string{0: {caObjectRef.Name, mcmObjectRef.Name}, 1: {kcmObjectRef.Name}}
 	flowName := "testCreateSequentialAndConcurrentFlow"
 	namespace := "test-sequential-and-concurrent"
 
@@ -85,3 +98,148 @@ func TestCreateScaleDownSequentialAndConcurrentFlow(t *testing.T) {
 		previousDepTaskIDs = append(previousDepTaskIDs, currentTaskStep.taskID)
 	}
 }
+
+// Tests creation of the flow where a resource explicitly declares the resources it depends upon instead of waiting
+// on all resources in the previous levels.
+func TestCreateScaleUpFlowWithExplicitDependencies(t *testing.T) {
+	g := NewWithT(t)
+	var depResInfos []papi.DependentResourceInfo
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 2, nil, nil, false))
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 1, nil, nil, false))
+	caResInfo := createTestDeploymentDependentResourceInfo(caObjectRef.Name, 2, 0, nil, nil, false)
+	caResInfo.ScaleUpInfo.DependsOn = []autoscalingv1.CrossVersionObjectReference{kcmObjectRef}
+	depResInfos = append(depResInfos, caResInfo)
+
+	fc := newFlowCreator(nil, nil, flowTestLogger, &scalerOptions{}, depResInfos)
+	f := fc.createFlow("testCreateFlowWithExplicitDependencies", "test-explicit-dependencies", scaleUp)
+	g.Expect(f.flowStepInfos).To(HaveLen(3))
+
+	kcmStep, mcmStep, caStep := f.flowStepInfos[0], f.flowStepInfos[1], f.flowStepInfos[2]
+	// mcm does not declare its dependencies and therefore waits on all resources in the previous levels
+	g.Expect(mcmStep.dependentTaskIDs.TaskIDs()).To(ConsistOf(kcmStep.taskID))
+	g.Expect(mcmStep.waitOnResources).To(ConsistOf(kcmObjectRef))
+	// cluster-autoscaler only waits on kcm even though mcm is in a previous level
+	g.Expect(caStep.dependentTaskIDs.TaskIDs()).To(ConsistOf(kcmStep.taskID))
+	g.Expect(caStep.waitOnResources).To(ConsistOf(kcmObjectRef))
+}
+
+// Tests that explicit dependencies are only considered for the scale operation they have been declared for.
+func TestCreateScaleDownFlowShouldIgnoreScaleUpDependencies(t *testing.T) {
+	g := NewWithT(t)
+	var depResInfos []papi.DependentResourceInfo
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 0, nil, nil, false))
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 1, nil, nil, false))
+	caResInfo := createTestDeploymentDependentResourceInfo(caObjectRef.Name, 2, 2, nil, nil, false)
+	caResInfo.ScaleUpInfo.DependsOn = []autoscalingv1.CrossVersionObjectReference{kcmObjectRef}
+	depResInfos = append(depResInfos, caResInfo)
+
+	fc := newFlowCreator(nil, nil, flowTestLogger, &scalerOptions{}, depResInfos)
+	f := fc.createFlow("testCreateScaleDownFlowIgnoringScaleUpDependencies", "test-explicit-dependencies", scaleDown)
+	g.Expect(f.flowStepInfos).To(HaveLen(3))
+
+	caStep := f.flowStepInfos[2]
+	g.Expect(caStep.dependentTaskIDs.TaskIDs()).To(ConsistOf(f.flowStepInfos[0].taskID, f.flowStepInfos[1].taskID))
+	g.Expect(caStep.waitOnResources).To(ConsistOf(kcmObjectRef, mcmObjectRef))
+}
+
+// Tests that a fast scale-up collapses all scale-up levels into a single step without any dependencies, while the
+// strict scale-up and the scale-down retain a step per level.
+func TestCreateFlowShouldCollapseScaleUpLevelsOnlyForFastScaleUp(t *testing.T) {
+	var depResInfos []papi.DependentResourceInfo
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 2, nil, nil, false))
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 1, nil, nil, false))
+	caResInfo := createTestDeploymentDependentResourceInfo(caObjectRef.Name, 2, 0, nil, nil, false)
+	caResInfo.ScaleUpInfo.DependsOn = []autoscalingv1.CrossVersionObjectReference{kcmObjectRef}
+	depResInfos = append(depResInfos, caResInfo)
+
+	table := []struct {
+		description   string
+		fastScaleUp   bool
+		opType        operation
+		expectedSteps []string
+		// expectedNumDependencies is the number of tasks on which the task of each step depends.
+		expectedNumDependencies []int
+	}{
+		{"strict scale-up", false, scaleUp, []string{kcmObjectRef.Name, mcmObjectRef.Name, caObjectRef.Name}, []int{0, 1, 1}},
+		{"fast scale-up", true, scaleUp, []string{strings.Join([]string{caObjectRef.Name, kcmObjectRef.Name, mcmObjectRef.Name}, "#")}, []int{0}},
+		{"scale-down with fast scale-up", true, scaleDown, []string{caObjectRef.Name, mcmObjectRef.Name, kcmObjectRef.Name}, []int{0, 1, 2}},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			fc := newFlowCreator(nil, nil, flowTestLogger, &scalerOptions{fastScaleUp: entry.fastScaleUp}, depResInfos)
+			f := fc.createFlow("testCreateFlowWithFastScaleUp", "test-fast-scale-up", entry.opType)
+			g.Expect(f.flowStepInfos).To(HaveLen(len(entry.expectedSteps)))
+			for i, step := range f.flowStepInfos {
+				level, resourceRefNames, err := parseTaskID(string(step.taskID))
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(level).To(Equal(i))
+				g.Expect(strings.Join(resourceRefNames, "#")).To(Equal(entry.expectedSteps[i]))
+				g.Expect(step.dependentTaskIDs.TaskIDs()).To(HaveLen(entry.expectedNumDependencies[i]))
+			}
+		})
+	}
+}
+
+// Tests that a dependency which does not resolve to a configured dependent resource, e.g. due to a typo in its name, is flagged.
+func TestCreateScaleUpFlowShouldFlagDanglingDependencies(t *testing.T) {
+	g := NewWithT(t)
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	typoObjectRef := autoscalingv1.CrossVersionObjectReference{Kind: kcmObjectRef.Kind, Name: "kube-controler-manager", APIVersion: kcmObjectRef.APIVersion}
+	var depResInfos []papi.DependentResourceInfo
+	depResInfos = append(depResInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 1, nil, nil, false))
+	caResInfo := createTestDeploymentDependentResourceInfo(caObjectRef.Name, 1, 0, nil, nil, false)
+	caResInfo.ScaleUpInfo.DependsOn = []autoscalingv1.CrossVersionObjectReference{typoObjectRef}
+	depResInfos = append(depResInfos, caResInfo)
+
+	fc := newFlowCreator(nil, nil, logger, &scalerOptions{}, depResInfos)
+	f := fc.createFlow("testCreateFlowWithDanglingDependencies", "test-dangling-dependencies", scaleUp)
+	g.Expect(f.flowStepInfos).To(HaveLen(2))
+
+	caStep := f.flowStepInfos[1]
+	g.Expect(caStep.dependentTaskIDs.TaskIDs()).To(BeEmpty())
+	g.Expect(caStep.waitOnResources).To(BeEmpty())
+	g.Expect(logs).To(ContainElement(And(
+		ContainSubstring("WARNING: Dependency is not a dependent resource at a lower level"),
+		ContainSubstring(`"dependency"="apps/v1/Deployment/kube-controler-manager"`),
+	)))
+}
+
+func TestScaleTaskShouldApplyResourceSpecificRetryInfo(t *testing.T) {
+	tests := []struct {
+		name             string
+		retryInfo        *papi.RetryInfo
+		expectedAttempts int
+	}{
+		{name: "default retries should be used without retry info", retryInfo: nil, expectedAttempts: defaultMaxResourceScalingAttempts},
+		{name: "attempts should be overridden", retryInfo: &papi.RetryInfo{Attempts: pointer.Int(5), Backoff: &metav1.Duration{Duration: time.Millisecond}}, expectedAttempts: 5},
+		{name: "not found resource should not be retried if disabled", retryInfo: &papi.RetryInfo{Attempts: pointer.Int(5), RetryOnNotFound: pointer.Bool(false)}, expectedAttempts: 1},
+		{name: "not found resource should be retried if enabled", retryInfo: &papi.RetryInfo{Attempts: pointer.Int(2), RetryOnNotFound: pointer.Bool(true)}, expectedAttempts: 2},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var attempts int
+			// the deployment does not exist, therefore every attempt fails as it is not found.
+			cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					// every attempt first checks if the namespace is terminating, only the reads of the deployment are counted.
+					if _, ok := obj.(*corev1.Namespace); !ok {
+						attempts++
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}).Build()
+			depResInfo := createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, nil, false)
+			depResInfo.RetryInfo = entry.retryInfo
+			c := newFlowCreator(cl, nil, logr.Discard(), buildScalerOptions(withScaleResourceBackOff(time.Millisecond)), []papi.DependentResourceInfo{depResInfo}).(*creator)
+			resInfo := createScalableResourceInfos(scaleDown, c.dependentResourceInfos)[0]
+			resInfo.initialDelay = 0
+
+			err := c.doCreateTaskFn(stagedTestNamespace, resInfo)(context.Background())
+			g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			g.Expect(attempts).To(Equal(entry.expectedAttempts))
+		})
+	}
+}
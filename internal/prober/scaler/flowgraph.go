@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import "github.com/gardener/dependency-watchdog/internal/util"
+
+// FlowGraphs are the compiled scale-up and scale-down flows of a Scaler, see Scaler.FlowGraphs.
+type FlowGraphs struct {
+	// ScaleUp is the graph of the scale-up flow.
+	ScaleUp FlowGraph `json:"scaleUp"`
+	// ScaleDown is the graph of the scale-down flow.
+	ScaleDown FlowGraph `json:"scaleDown"`
+}
+
+// FlowGraph describes the tasks of a scaling flow in the order in which they have been added to the flow.
+type FlowGraph struct {
+	// Name is the name of the flow.
+	Name string `json:"name"`
+	// Tasks are the tasks of the flow, one per level of dependent resources.
+	Tasks []FlowGraphTask `json:"tasks"`
+}
+
+// FlowGraphTask describes a single task of a scaling flow.
+type FlowGraphTask struct {
+	// Name is the name of the task, which is also its ID within the flow.
+	Name string `json:"name"`
+	// DependsOn are the names of the tasks which have to complete before this task is run, sorted by name.
+	DependsOn []string `json:"dependsOn"`
+	// WaitOnResources are the keys of the dependent resources whose replicas this task waits on before it scales its own.
+	WaitOnResources []string `json:"waitOnResources"`
+}
+
+// graph converts the scaleFlow into its serializable FlowGraph.
+func (sf *scaleFlow) graph() FlowGraph {
+	fg := FlowGraph{Name: sf.flow.Name(), Tasks: make([]FlowGraphTask, 0, len(sf.flowStepInfos))}
+	for _, stepInfo := range sf.flowStepInfos {
+		task := FlowGraphTask{
+			Name:            string(stepInfo.taskID),
+			DependsOn:       stepInfo.dependentTaskIDs.StringList(),
+			WaitOnResources: make([]string, 0, len(stepInfo.waitOnResources)),
+		}
+		for _, ref := range stepInfo.waitOnResources {
+			task.WaitOnResources = append(task.WaitOnResources, util.ResourceRefKey(ref))
+		}
+		fg.Tasks = append(fg.Tasks, task)
+	}
+	return fg
+}
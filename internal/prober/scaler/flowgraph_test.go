@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/internal/util"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func TestFlowGraphsShouldDescribeTasksAndDependenciesOfBothFlows(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter()
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard())
+
+	graphs, err := s.FlowGraphs(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+
+	kcmTask, mcmTask, caTask := "scale:level-0:"+kcmObjectRef.Name, "scale:level-1:"+mcmObjectRef.Name, "scale:level-2:"+caObjectRef.Name
+	kcmKey, mcmKey := util.ResourceRefKey(kcmObjectRef), util.ResourceRefKey(mcmObjectRef)
+	g.Expect(graphs.ScaleUp.Name).To(Equal(flowName(scaleUp, stagedTestNamespace)))
+	g.Expect(graphs.ScaleUp.Tasks).To(Equal([]FlowGraphTask{
+		{Name: kcmTask, DependsOn: []string{}, WaitOnResources: []string{}},
+		{Name: mcmTask, DependsOn: []string{kcmTask}, WaitOnResources: []string{kcmKey}},
+		{Name: caTask, DependsOn: []string{kcmTask, mcmTask}, WaitOnResources: []string{kcmKey, mcmKey}},
+	}))
+
+	caDownTask, mcmDownTask, kcmDownTask := "scale:level-0:"+caObjectRef.Name, "scale:level-1:"+mcmObjectRef.Name, "scale:level-2:"+kcmObjectRef.Name
+	caKey := util.ResourceRefKey(caObjectRef)
+	g.Expect(graphs.ScaleDown.Name).To(Equal(flowName(scaleDown, stagedTestNamespace)))
+	g.Expect(graphs.ScaleDown.Tasks).To(Equal([]FlowGraphTask{
+		{Name: caDownTask, DependsOn: []string{}, WaitOnResources: []string{}},
+		{Name: mcmDownTask, DependsOn: []string{caDownTask}, WaitOnResources: []string{caKey}},
+		{Name: kcmDownTask, DependsOn: []string{caDownTask, mcmDownTask}, WaitOnResources: []string{caKey, mcmKey}},
+	}))
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ScaleLevelAnnotationKey is the key for an annotation whose non-negative integer value overrides the configured level of the
+// annotated dependent resource in both the scale-up and the scale-down flow, e.g. to promote a resource during an incident
+// without editing the prober config. It is evaluated whenever a flow is built, an invalid value is ignored.
+const ScaleLevelAnnotationKey = "dependency-watchdog.gardener.cloud/scale-level"
+
+// applyScaleLevelOverrides returns the dependentResourceInfos with the levels of those resources which carry the ScaleLevelAnnotationKey
+// annotation replaced by its value. The given dependentResourceInfos are not modified. It returns false if no level has been
+// overridden. A resource whose metadata cannot be read keeps its configured level, the flow is then built as configured.
+func applyScaleLevelOverrides(ctx context.Context, cl client.Client, namespace string, dependentResourceInfos []papi.DependentResourceInfo, logger logr.Logger) ([]papi.DependentResourceInfo, bool) {
+	var resourceInfos []papi.DependentResourceInfo
+	for i, resInfo := range dependentResourceInfos {
+		level, ok := getScaleLevelOverride(ctx, cl, namespace, resInfo, logger)
+		if !ok {
+			continue
+		}
+		if resourceInfos == nil {
+			resourceInfos = make([]papi.DependentResourceInfo, len(dependentResourceInfos))
+			copy(resourceInfos, dependentResourceInfos)
+		}
+		resourceInfos[i] = withScaleLevel(resInfo, level)
+		logger.Info("Overriding scaling level of dependent resource as requested via annotation", "annotation", ScaleLevelAnnotationKey, "kind", resInfo.Ref.Kind, "name", resInfo.Ref.Name, "level", level)
+	}
+	if resourceInfos == nil {
+		return dependentResourceInfos, false
+	}
+	return resourceInfos, true
+}
+
+// getScaleLevelOverride returns the level of the ScaleLevelAnnotationKey annotation of the dependent resource. It returns false
+// if the resource is not annotated, its annotation is invalid or if its metadata cannot be read.
+func getScaleLevelOverride(ctx context.Context, cl client.Client, namespace string, resInfo papi.DependentResourceInfo, logger logr.Logger) (int, bool) {
+	annotations, err := getResourceAnnotations(ctx, cl, namespace, resInfo)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to read the scaling level annotation of dependent resource, the configured level is used", "kind", resInfo.Ref.Kind, "name", resInfo.Ref.Name)
+		}
+		return 0, false
+	}
+	val, ok := annotations[ScaleLevelAnnotationKey]
+	if !ok {
+		return 0, false
+	}
+	level, err := parseScaleLevel(val)
+	if err != nil {
+		logger.Error(err, "Ignoring annotation as its value is not a valid scaling level", "annotation", ScaleLevelAnnotationKey, "kind", resInfo.Ref.Kind, "name", resInfo.Ref.Name, "value", val)
+		return 0, false
+	}
+	return level, true
+}
+
+func getResourceAnnotations(ctx context.Context, cl client.Client, namespace string, resInfo papi.DependentResourceInfo) (map[string]string, error) {
+	gv, err := schema.ParseGroupVersion(resInfo.Ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	resource := &metav1.PartialObjectMetadata{}
+	resource.SetGroupVersionKind(gv.WithKind(resInfo.Ref.Kind))
+	if err = cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: resInfo.Ref.Name}, resource); err != nil {
+		return nil, err
+	}
+	return resource.GetAnnotations(), nil
+}
+
+func parseScaleLevel(val string) (int, error) {
+	level, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if level < 0 {
+		return 0, fmt.Errorf("scaling level %d must not be negative", level)
+	}
+	return level, nil
+}
+
+// withScaleLevel returns a copy of the resInfo whose scale-up and scale-down infos are at the given level.
+func withScaleLevel(resInfo papi.DependentResourceInfo, level int) papi.DependentResourceInfo {
+	if resInfo.ScaleUpInfo != nil {
+		scaleUpInfo := *resInfo.ScaleUpInfo
+		scaleUpInfo.Level = level
+		resInfo.ScaleUpInfo = &scaleUpInfo
+	}
+	if resInfo.ScaleDownInfo != nil {
+		scaleDownInfo := *resInfo.ScaleDownInfo
+		scaleDownInfo.Level = level
+		resInfo.ScaleDownInfo = &scaleDownInfo
+	}
+	return resInfo
+}
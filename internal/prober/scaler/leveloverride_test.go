@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+func TestScaleLevelAnnotationShouldOverrideLevelInFlowGraphs(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, map[string]string{ScaleLevelAnnotationKey: "0"}),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard())
+
+	graphs, err := s.FlowGraphs(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	scaleUpLevel0Task := "scale:level-0:" + kcmObjectRef.Name + "#" + mcmObjectRef.Name
+	g.Expect(graphs.ScaleUp.Tasks).To(HaveLen(2))
+	g.Expect(graphs.ScaleUp.Tasks[0].Name).To(Equal(scaleUpLevel0Task))
+	g.Expect(graphs.ScaleUp.Tasks[1].Name).To(Equal("scale:level-2:" + caObjectRef.Name))
+	g.Expect(graphs.ScaleUp.Tasks[1].DependsOn).To(Equal([]string{scaleUpLevel0Task}))
+
+	g.Expect(graphs.ScaleDown.Tasks).To(HaveLen(2))
+	g.Expect(graphs.ScaleDown.Tasks[0].Name).To(Equal("scale:level-0:" + caObjectRef.Name + "#" + mcmObjectRef.Name))
+	g.Expect(graphs.ScaleDown.Tasks[1].Name).To(Equal("scale:level-2:" + kcmObjectRef.Name))
+}
+
+func TestScaleLevelAnnotationShouldBeEvaluatedWhenFlowIsRun(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, map[string]string{ScaleLevelAnnotationKey: "3"}))
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond))
+
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(Equal([]string{mcmObjectRef.Name, kcmObjectRef.Name, caObjectRef.Name}), "the demoted resource should be scaled down last")
+}
+
+func TestInvalidScaleLevelAnnotationShouldBeIgnored(t *testing.T) {
+	for _, value := range []string{"-1", "one", ""} {
+		t.Run(value, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(
+				createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+				createFakeScalesTestDeployment(mcmObjectRef.Name, 1, map[string]string{ScaleLevelAnnotationKey: value}),
+				createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+			ds := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard()).(*scaleFlowRunner)
+
+			fc, err := ds.currentFlowCreator(context.Background())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(fc).To(BeIdenticalTo(ds.flowCreator), "the flows of the configured dependent resources should be used")
+			graphs, err := ds.FlowGraphs(context.Background())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(graphs.ScaleUp.Tasks).To(HaveLen(3))
+		})
+	}
+}
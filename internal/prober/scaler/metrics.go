@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "dwd"
+	metricsSubsystem = "scaler"
+)
+
+// stuckScaleDownsTotal counts the scale-downs after which status.replicas of a resource has not reached its target replicas
+// within the timeout of the resource. It is only recorded if the scale-down termination check is enabled.
+var stuckScaleDownsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "stuck_scale_downs_total",
+		Help:      "Total number of scale-downs after which the replicas of the resource did not terminate within its timeout.",
+	},
+	[]string{"namespace", "kind", "name"},
+)
+
+// flowDurationSeconds observes the duration of every scale-up and scale-down flow. It is named after the prober which runs the
+// flows, an unusually long flow indicates a dependent resource which is stuck waiting to reach its target replicas.
+var flowDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "prober",
+		Name:      "flow_duration_seconds",
+		Help:      "Duration in seconds of the scale-up and scale-down flows of the dependent resources.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	},
+	[]string{"namespace", "direction"},
+)
+
+// deleteMetrics removes the metrics of a namespace once its scaler has been closed, e.g. as its shoot has been deleted.
+func deleteMetrics(namespace string) {
+	stuckScaleDownsTotal.DeletePartialMatch(prometheus.Labels{"namespace": namespace})
+	flowDurationSeconds.DeletePartialMatch(prometheus.Labels{"namespace": namespace})
+}
+
+func init() {
+	metrics.Registry.MustRegister(stuckScaleDownsTotal, flowDurationSeconds)
+}
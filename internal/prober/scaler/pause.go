@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import "sync/atomic"
+
+// ScalingPause is a switch which pauses the scaling of every scaler it has been passed to via WithScalingPause. While it is
+// paused, ScaleUp, ScaleDown and ScaleResource of these scalers are logged no-ops. A nil ScalingPause is never paused.
+type ScalingPause struct {
+	paused atomic.Bool
+}
+
+// NewScalingPause creates a ScalingPause which is not paused.
+func NewScalingPause() *ScalingPause {
+	return &ScalingPause{}
+}
+
+// SetPaused pauses or resumes the scaling. It returns true if the state of the switch has changed.
+func (p *ScalingPause) SetPaused(paused bool) bool {
+	return p.paused.Swap(paused) != paused
+}
+
+// IsPaused returns true if the scaling is paused.
+func (p *ScalingPause) IsPaused() bool {
+	return p != nil && p.paused.Load()
+}
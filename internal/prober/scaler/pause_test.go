@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestScalingPauseShouldReportWhetherItsStateHasChanged(t *testing.T) {
+	g := NewWithT(t)
+	pause := NewScalingPause()
+	g.Expect(pause.IsPaused()).To(BeFalse())
+
+	g.Expect(pause.SetPaused(true)).To(BeTrue())
+	g.Expect(pause.IsPaused()).To(BeTrue())
+	g.Expect(pause.SetPaused(true)).To(BeFalse(), "pausing an already paused switch should not change its state")
+
+	g.Expect(pause.SetPaused(false)).To(BeTrue())
+	g.Expect(pause.IsPaused()).To(BeFalse())
+}
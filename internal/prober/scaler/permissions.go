@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import (
+	"context"
+	"fmt"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// scaleSubresource is the name of the scale subresource of a scalable resource.
+const scaleSubresource = "scale"
+
+// FindUnscalableResources checks via SelfSubjectAccessReviews whether DWD is permitted to get and to update (or, if
+// useServerSideApply is set, to patch) the scale subresource of each of the dependent resources in all namespaces. It returns
+// the keys, see util.ResourceRefKey, of the dependent resources whose scaling would be forbidden. Scaling such a resource
+// fails with a Forbidden error, which is not retried.
+func FindUnscalableResources(ctx context.Context, cl client.Client, mapper meta.RESTMapper, dependentResourceInfos []papi.DependentResourceInfo, useServerSideApply bool) ([]string, error) {
+	updateVerb := "update"
+	if useServerSideApply {
+		updateVerb = "patch"
+	}
+	var unscalable []string
+	for _, resInfo := range dependentResourceInfos {
+		gvr, err := getGroupVersionResource(mapper, resInfo)
+		if err != nil {
+			return nil, err
+		}
+		for _, verb := range []string{"get", updateVerb} {
+			allowed, err := isScaleSubresourceAccessAllowed(ctx, cl, gvr, resInfo.Ref.Name, verb)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				unscalable = append(unscalable, util.ResourceRefKey(*resInfo.Ref))
+				break
+			}
+		}
+	}
+	return unscalable, nil
+}
+
+func getGroupVersionResource(mapper meta.RESTMapper, resInfo papi.DependentResourceInfo) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(resInfo.Ref.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	mapping, err := mapper.RESTMapping(gv.WithKind(resInfo.Ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("failed to map %s to its resource: %w", util.ResourceRefKey(*resInfo.Ref), err)
+	}
+	return mapping.Resource, nil
+}
+
+func isScaleSubresourceAccessAllowed(ctx context.Context, cl client.Client, gvr schema.GroupVersionResource, name, verb string) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        verb,
+				Group:       gvr.Group,
+				Version:     gvr.Version,
+				Resource:    gvr.Resource,
+				Subresource: scaleSubresource,
+				Name:        name,
+			},
+		},
+	}
+	if err := cl.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed to review access to the scale subresource of %s %s: %w", gvr.Resource, name, err)
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestFindUnscalableResourcesShouldListResourcesWhoseScaleSubresourceIsForbidden(t *testing.T) {
+	tests := []struct {
+		name               string
+		useServerSideApply bool
+		expectedVerb       string
+	}{
+		{name: "update should be reviewed", expectedVerb: "update"},
+		{name: "patch should be reviewed for server-side apply", useServerSideApply: true, expectedVerb: "patch"},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var reviewed []authorizationv1.ResourceAttributes
+			// only the scaling of machine-controller-manager is forbidden.
+			cl := newAccessReviewClient(func(attributes authorizationv1.ResourceAttributes) bool {
+				reviewed = append(reviewed, attributes)
+				return attributes.Name != mcmObjectRef.Name || attributes.Verb == "get"
+			})
+
+			unscalable, err := FindUnscalableResources(context.Background(), cl, newDeploymentRESTMapper(), createLeafToRootDependentResourceInfos(), entry.useServerSideApply)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(unscalable).To(Equal([]string{util.ResourceRefKey(mcmObjectRef)}))
+			g.Expect(reviewed).To(HaveLen(6))
+			for _, attributes := range reviewed {
+				g.Expect(attributes.Verb).To(BeElementOf("get", entry.expectedVerb))
+				g.Expect(attributes.Group).To(Equal("apps"))
+				g.Expect(attributes.Resource).To(Equal("deployments"))
+				g.Expect(attributes.Subresource).To(Equal("scale"))
+				g.Expect(attributes.Namespace).To(BeEmpty(), "the access should be reviewed for all namespaces")
+			}
+		})
+	}
+}
+
+func TestFindUnscalableResourcesShouldFailIfAccessCannotBeReviewed(t *testing.T) {
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, _ client.Object, _ ...client.CreateOption) error {
+			return errors.New("test error")
+		},
+	}).Build()
+
+	_, err := FindUnscalableResources(context.Background(), cl, newDeploymentRESTMapper(), []papi.DependentResourceInfo{createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, nil, false)}, false)
+	g.Expect(err).To(MatchError(ContainSubstring("test error")))
+}
+
+// newAccessReviewClient creates a client which answers every SelfSubjectAccessReview via the given allowed func.
+func newAccessReviewClient(allowed func(attributes authorizationv1.ResourceAttributes) bool) client.Client {
+	return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+			if !ok {
+				return c.Create(ctx, obj, opts...)
+			}
+			review.Status.Allowed = allowed(*review.Spec.ResourceAttributes)
+			return nil
+		},
+	}).Build()
+}
+
+func newDeploymentRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	return mapper
+}
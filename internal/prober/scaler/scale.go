@@ -6,31 +6,59 @@ package scaler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/go-logr/logr"
 
+	papi "github.com/gardener/dependency-watchdog/api/prober"
 	"github.com/gardener/dependency-watchdog/internal/util"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	scalev1 "k8s.io/client-go/scale"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	// ignoreScalingAnnotationKey is the key for an annotation if present on a resource will suspend any scaling action for that resource.
-	ignoreScalingAnnotationKey = "dependency-watchdog.gardener.cloud/ignore-scaling"
+	// DefaultIgnoreScalingAnnotationKey is the default key for an annotation if present on a resource will suspend any scaling action for that resource.
+	DefaultIgnoreScalingAnnotationKey = "dependency-watchdog.gardener.cloud/ignore-scaling"
+	// ScaleDownAfterAnnotationKey is the key for an annotation whose RFC3339 timestamp value defers the scale-down of a resource
+	// till the timestamp has passed, e.g. till the start of a maintenance window. A timestamp which cannot be parsed is ignored.
+	ScaleDownAfterAnnotationKey = "dependency-watchdog.gardener.cloud/scale-down-after"
 	// replicasAnnotationKey is the key for an annotation whose value captures the current spec.replicas prior to scale down for that resource.
 	// This is used when DWD attempts to restore the state of the resource it scale down.
 	replicasAnnotationKey = "dependency-watchdog.gardener.cloud/replicas"
+	// hpaMinReplicasAnnotationKey is the key for an annotation on a HorizontalPodAutoscaler whose value captures its spec.minReplicas prior
+	// to the scale-down of the resource it targets. It is only set if the HPA has spec.minReplicas.
+	hpaMinReplicasAnnotationKey = "dependency-watchdog.gardener.cloud/hpa-min-replicas"
+	// hpaMaxReplicasAnnotationKey is the key for an annotation on a HorizontalPodAutoscaler whose value captures its spec.maxReplicas prior
+	// to the scale-down of the resource it targets. It is used to restore the bounds of the HPA when the resource is scaled up.
+	hpaMaxReplicasAnnotationKey = "dependency-watchdog.gardener.cloud/hpa-max-replicas"
 	// defaultScaleUpReplicas is the default value of number of replicas for a scale-up operation by a probe when the external probe transitions from failed to success.
 	defaultScaleUpReplicas int32 = 1
 	// defaultScaleDownReplicas is the default value of number of replicas for a scale-down operation by a probe when the external probe transitions from success to failed.
 	defaultScaleDownReplicas int32 = 0
+	// deploymentKind is the kind of Deployment resources.
+	deploymentKind = "Deployment"
+	// cronJobKind is the kind of CronJob resources. CronJobs do not have a scale subresource, they are instead suspended on scale-down and resumed on scale-up.
+	cronJobKind = "CronJob"
 )
 
+// errScaleDownNotTerminated is returned if the scale-down of a resource is awaited and status.replicas of the resource has not
+// reached its target replicas within the timeout of the resource.
+var errScaleDownNotTerminated = errors.New("scaled down resource has not terminated")
+
 type resourceScaler interface {
 	scale(ctx context.Context) error
 }
@@ -42,6 +70,8 @@ type resScaler struct {
 	namespace    string
 	resourceInfo scalableResourceInfo
 	opts         *scalerOptions
+	// refResolved is set once the reference of a resource with a selector has been resolved, see resolveRef.
+	refResolved bool
 }
 
 func newResourceScaler(client client.Client, scaler scalev1.ScaleInterface, logger logr.Logger, opts *scalerOptions, namespace string, resourceInfo scalableResourceInfo) resourceScaler {
@@ -59,83 +89,295 @@ func newResourceScaler(client client.Client, scaler scalev1.ScaleInterface, logg
 func (r *resScaler) scale(ctx context.Context) error {
 	var (
 		err           error
+		resourceMeta  *metav1.ObjectMeta
 		resourceAnnot map[string]string
 	)
 	// sleep for initial delay
-	if err = util.SleepWithContext(ctx, r.resourceInfo.initialDelay); err != nil {
+	if err = util.SleepWithClock(ctx, r.opts.clock, r.resourceInfo.initialDelay); err != nil {
 		r.logger.Error(err, "Looks like the context has been cancelled. exiting scaling operation")
 		return err
 	}
 
-	if resourceAnnot, err = util.GetResourceAnnotations(ctx, r.client, r.namespace, r.resourceInfo.ref); err != nil {
+	if r.isNamespaceTerminating(ctx) {
+		r.logger.Info("Skipping scaling of resource as its namespace is terminating")
+		return nil
+	}
+
+	if err = r.resolveRef(ctx); err != nil {
+		return err
+	}
+
+	if resourceMeta, err = util.GetResourceMetadata(ctx, r.client, r.namespace, r.resourceInfo.ref); err != nil {
 		if apierrors.IsNotFound(err) && r.resourceInfo.optional {
 			r.logger.Info("Resource not found. Ignoring this resource as its existence is marked as optional")
 			return nil
 		}
-		r.logger.Error(err, "Error trying to get annotations for resource")
+		r.logger.Error(err, "Error trying to get metadata for resource")
 		return err
 	}
+	resourceAnnot = resourceMeta.Annotations
+
+	if r.resourceInfo.operation == scaleUp && r.opts.removeIgnoreScalingAnnotation {
+		if resourceAnnot, err = r.removeIgnoreScalingAnnotation(ctx, resourceAnnot); err != nil {
+			return err
+		}
+	}
+
+	if ignoreScaling(resourceAnnot, r.opts.ignoreScalingAnnotationKey) {
+		r.logger.Info("Scaling ignored due to explicit instruction via annotation", "annotation", r.opts.ignoreScalingAnnotationKey)
+		return nil
+	}
+
+	if r.resourceInfo.operation == scaleDown && r.isScaleDownDeferred(resourceAnnot) {
+		return nil
+	}
 
-	if ignoreScaling(resourceAnnot) {
-		r.logger.Info("Scaling ignored due to explicit instruction via annotation", "annotation", ignoreScalingAnnotationKey)
+	if r.resourceInfo.operation == scaleDown && r.isManagedExternally(resourceMeta.Labels) {
+		r.logger.Info("WARNING: Skipping scale-down as resource is actively managed by another controller, scaling it down would conflict with it", "externallyManagedSelector", r.opts.externallyManagedSelector.String())
 		return nil
 	}
 
-	_, scaleSubRes, err := util.GetScaleResource(ctx, r.client, r.scaler, r.logger, r.resourceInfo.ref, r.resourceInfo.timeout)
+	if r.resourceInfo.ref.Kind == cronJobKind {
+		return r.suspendOrResumeCronJob(ctx)
+	}
+
+	if skip, err := r.skipPausedDeployment(ctx); err != nil || skip {
+		return err
+	}
+
+	currentReplicas, err := r.getSpecReplicas(ctx)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			r.logger.Error(err, "Resource does not have a scale subresource. Skipping scaling of dependent resources. Invalid config file")
-		}
 		return err
 	}
 
-	if r.resourceInfo.operation.shouldScaleReplicas(scaleSubRes.Spec.Replicas) {
-		if err := r.updateResourceAndScale(ctx, scaleSubRes, resourceAnnot); err != nil {
+	if r.resourceInfo.operation == scaleUp && r.isScaledUpToZero(currentReplicas, resourceAnnot) {
+		r.logger.Info("Skipping scale-up for resource as its target replicas are zero")
+		return nil
+	}
+
+	minTargetReplicas := r.resourceInfo.operation.getMinTargetReplicas()
+	if r.resourceInfo.operation == scaleDown {
+		// a resource which is scaled down by a replica delta keeps its remaining replicas, therefore it is not waited on to reach zero.
+		minTargetReplicas, _ = r.determineTargetReplicas(currentReplicas, resourceAnnot)
+	}
+	if r.shouldScaleReplicas(currentReplicas, resourceAnnot) {
+		if err := r.updateResourceAndScale(ctx, currentReplicas, resourceAnnot); err != nil {
 			return err
 		}
 	} else {
-		if r.resourceInfo.operation == scaleUp {
+		switch {
+		case r.resourceInfo.operation == scaleUp:
 			r.logger.Info("Skipping scale-up for resource as current spec replicas > 0")
-		} else {
+		case r.resourceInfo.replicaDelta != nil:
+			r.logger.Info("Skipping scale-down for resource as it has already been scaled down by its replica delta", "replicaDelta", *r.resourceInfo.replicaDelta, "currentReplicas", currentReplicas)
+		default:
 			r.logger.Info("Skipping scale-down for resource as current spec replicas == 0")
 		}
 	}
 
-	return r.waitTillMinTargetReplicasReached(ctx)
+	if err = r.waitTillMinTargetReplicasReached(ctx, minTargetReplicas); err != nil {
+		return err
+	}
+	if r.resourceInfo.operation == scaleDown && r.opts.awaitScaleDownTermination {
+		return r.awaitScaleDownTermination(ctx, minTargetReplicas)
+	}
+	if r.resourceInfo.operation == scaleDown && r.opts.verifyScaleDownTermination {
+		r.verifyScaleDownTermination(ctx, minTargetReplicas)
+	}
+	return nil
 }
 
-func (r *resScaler) waitTillMinTargetReplicasReached(ctx context.Context) error {
-	var minTargetReplicas int32
-	if r.resourceInfo.operation == scaleUp {
-		minTargetReplicas = 1
+// resolveRef resolves the reference of a resource which has a selector to the resource which is currently matched by it, see
+// resolveResourceRef. The resolved reference is used for all subsequent attempts to scale the resource.
+func (r *resScaler) resolveRef(ctx context.Context) error {
+	if r.resourceInfo.selector == nil || r.refResolved {
+		return nil
+	}
+	ref, err := resolveResourceRef(ctx, r.client, r.namespace, r.resourceInfo.ref, r.resourceInfo.selector)
+	if err != nil {
+		r.logger.Error(err, "Error trying to resolve resource via its selector")
+		return err
+	}
+	if ref.Name != r.resourceInfo.ref.Name {
+		r.logger.Info("WARNING: Resource has been resolved via its selector to a resource of a different name, scaling it instead. Consider updating the configured name", "resolvedName", ref.Name)
+		r.logger = r.logger.WithValues("resolvedName", ref.Name)
+	}
+	r.resourceInfo.ref = ref
+	r.refResolved = true
+	return nil
+}
+
+// isNamespaceTerminating checks if the namespace of the resource is being deleted, in which case scaling its resources is
+// pointless. If the namespace cannot be read then it is not considered to be terminating, so that the resource is still scaled.
+func (r *resScaler) isNamespaceTerminating(ctx context.Context) bool {
+	terminating, err := util.IsNamespaceTerminating(ctx, r.client, r.namespace)
+	if err != nil {
+		r.logger.Error(err, "Failed to check if namespace is terminating, proceeding with scaling")
+		return false
+	}
+	return terminating
+}
+
+// skipPausedDeployment checks if the resource is a Deployment whose spec.paused is set, in which case it is not scaled
+// unless the scaler has been configured to scale paused Deployments.
+func (r *resScaler) skipPausedDeployment(ctx context.Context) (bool, error) {
+	if r.resourceInfo.ref.Kind != deploymentKind || r.opts.scalePausedDeployments {
+		return false, nil
+	}
+	paused, err := util.IsResourcePaused(ctx, r.client, r.namespace, r.resourceInfo.ref)
+	if err != nil {
+		r.logger.Error(err, "Error trying to check if the deployment is paused")
+		return false, err
+	}
+	if paused {
+		r.logger.Info("Skipping scaling of deployment as it has been paused via spec.paused", "operation", r.resourceInfo.operation)
+	}
+	return paused, nil
+}
+
+// shouldScaleReplicas checks if the resource should be scaled given its current spec replicas. Besides the resources which are
+// scaled up from or down to zero, a resource which has been scaled down by a replica delta is scaled up if it has fewer replicas
+// than were recorded prior to its scale-down.
+func (r *resScaler) shouldScaleReplicas(currentReplicas int32, annotations map[string]string) bool {
+	if r.resourceInfo.operation == scaleDown && r.resourceInfo.replicaDelta != nil {
+		targetReplicas, _ := r.determineTargetReplicas(currentReplicas, annotations)
+		return currentReplicas > targetReplicas
+	}
+	if r.resourceInfo.operation.shouldScaleReplicas(currentReplicas) {
+		return true
+	}
+	if !r.resourceInfo.restoreRecordedReplicas {
+		return false
+	}
+	recordedReplicas, err := r.determineTargetReplicas(currentReplicas, annotations)
+	return err == nil && currentReplicas < recordedReplicas
+}
+
+// isScaleDownDeferred checks if the scale-down of the resource has been deferred till a later time via the ScaleDownAfterAnnotationKey
+// annotation. The scale-down is attempted again by a subsequent scale-down flow, which runs as long as the probes keep failing.
+func (r *resScaler) isScaleDownDeferred(annotations map[string]string) bool {
+	val, ok := annotations[ScaleDownAfterAnnotationKey]
+	if !ok {
+		return false
+	}
+	scaleDownAfter, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		r.logger.Error(err, "Ignoring annotation as its value is not an RFC3339 timestamp", "annotation", ScaleDownAfterAnnotationKey, "value", val)
+		return false
+	}
+	if remaining := scaleDownAfter.Sub(r.opts.clock.Now()); remaining > 0 {
+		r.logger.Info("Deferring scale-down of resource as requested via annotation", "annotation", ScaleDownAfterAnnotationKey, "scaleDownAfter", val, "remaining", remaining)
+		return true
 	}
-	r.logger.Info("Waiting for resource to reach minimum target replicas", "minTargetReplicas", minTargetReplicas)
+	return false
+}
+
+// isScaledUpToZero checks if a scale-up of the resource would leave it at zero replicas, which is only the case if zero scale-up
+// replicas have been configured for it and its replicas prior to the scale-down have not been recorded.
+func (r *resScaler) isScaledUpToZero(currentReplicas int32, annotations map[string]string) bool {
+	if currentReplicas > 0 {
+		return false
+	}
+	targetReplicas, err := r.determineTargetReplicas(currentReplicas, annotations)
+	return err == nil && targetReplicas == 0
+}
+
+// waitTillMinTargetReplicasReached waits till the resource has at least minTargetReplicas on scale-up and at most minTargetReplicas on scale-down.
+func (r *resScaler) waitTillMinTargetReplicasReached(ctx context.Context, minTargetReplicas int32) error {
+	statusField := r.opts.waitOnReplicasStatusField
+	r.logger.Info("Waiting for resource to reach minimum target replicas", "minTargetReplicas", minTargetReplicas, "statusField", statusField)
 	opDesc := fmt.Sprintf("wait for resource to reach minimum required target replicas %d", minTargetReplicas)
-	resMinTargetReached := util.RetryUntilPredicate(ctx, r.logger, opDesc, func() bool {
-		readyReplicas, err := util.GetResourceReadyReplicas(ctx, r.client, r.namespace, r.resourceInfo.ref)
+	resMinTargetReached := util.RetryUntilPredicateWithClock(ctx, r.opts.clock, r.logger, opDesc, func() bool {
+		replicas, err := util.GetResourceStatusReplicas(ctx, r.client, r.namespace, r.resourceInfo.ref, string(statusField))
 		if err != nil {
 			return false
 		}
-		if r.resourceInfo.operation.minTargetReplicasReached(readyReplicas) {
+		if r.resourceInfo.operation.minTargetReplicasReached(replicas, minTargetReplicas) {
 			r.logger.Info("Resource has reached desired replicas", "minTargetReplicas", minTargetReplicas)
 			return true
 		}
 		return false
 	}, *r.opts.resourceCheckTimeout, *r.opts.resourceCheckInterval)
 	if !resMinTargetReached {
-		return fmt.Errorf("timed out waiting for {namespace: %s, resource: %s} to reach minTargetReplicas %d", r.namespace, r.resourceInfo.ref.Name, minTargetReplicas)
+		return fmt.Errorf("timed out waiting for {namespace: %s, resource: %s} to reach minTargetReplicas %d in status.%s", r.namespace, r.resourceInfo.ref.Name, minTargetReplicas, statusField)
 	}
 	return nil
 }
 
-func (r *resScaler) updateResourceAndScale(ctx context.Context, scaleSubRes *autoscalingv1.Scale, annot map[string]string) error {
+// verifyScaleDownTermination checks that status.replicas of the scaled down resource reaches the target replicas within
+// the timeout of the resource. If it does not then the pods of the resource are stuck terminating, which is only reported
+// as the resource has otherwise been scaled down.
+func (r *resScaler) verifyScaleDownTermination(ctx context.Context, targetReplicas int32) {
+	terminated, lastObservedReplicas := r.waitTillScaleDownTerminated(ctx, targetReplicas)
+	if !terminated && ctx.Err() == nil {
+		r.logger.Info("WARNING: Scaled down resource still has more replicas than its target, its pods might be stuck terminating, e.g. due to finalizers",
+			"targetReplicas", targetReplicas, "statusReplicas", lastObservedReplicas, "timeout", r.resourceInfo.timeout)
+		stuckScaleDownsTotal.WithLabelValues(r.namespace, r.resourceInfo.ref.Kind, r.resourceInfo.ref.Name).Inc()
+	}
+}
+
+// awaitScaleDownTermination waits till status.replicas of the scaled down resource has reached the target replicas, bounded by
+// the timeout of the resource. Unlike verifyScaleDownTermination it fails the scale-down if they have not been reached, so
+// that the resources at the subsequent scale-down levels are only scaled down once the pods of this resource are gone.
+func (r *resScaler) awaitScaleDownTermination(ctx context.Context, targetReplicas int32) error {
+	terminated, lastObservedReplicas := r.waitTillScaleDownTerminated(ctx, targetReplicas)
+	if terminated {
+		return nil
+	}
+	if ctx.Err() == nil {
+		stuckScaleDownsTotal.WithLabelValues(r.namespace, r.resourceInfo.ref.Kind, r.resourceInfo.ref.Name).Inc()
+	}
+	return fmt.Errorf("%w: {namespace: %s, resource: %s} has %d replicas in status.replicas instead of %d after %s", errScaleDownNotTerminated,
+		r.namespace, r.resourceInfo.ref.Name, lastObservedReplicas, targetReplicas, r.resourceInfo.timeout)
+}
+
+// waitTillScaleDownTerminated waits till status.replicas of the scaled down resource has reached the target replicas, bounded
+// by the timeout of the resource. It returns whether they have been reached and the last observed status.replicas, which is
+// -1 if they could not be read.
+func (r *resScaler) waitTillScaleDownTerminated(ctx context.Context, targetReplicas int32) (bool, int32) {
+	if r.opts.waitOnReplicasStatusField == papi.ReplicasStatusFieldReplicas {
+		// status.replicas has already been waited on.
+		return true, targetReplicas
+	}
+	var lastObservedReplicas int32 = -1
+	opDesc := fmt.Sprintf("verify that status.replicas of resource reaches %d", targetReplicas)
+	terminated := util.RetryUntilPredicateWithClock(ctx, r.opts.clock, r.logger, opDesc, func() bool {
+		replicas, err := util.GetResourceStatusReplicas(ctx, r.client, r.namespace, r.resourceInfo.ref, string(papi.ReplicasStatusFieldReplicas))
+		if err != nil {
+			return false
+		}
+		lastObservedReplicas = replicas
+		return replicas <= targetReplicas
+	}, r.resourceInfo.timeout, *r.opts.resourceCheckInterval)
+	return terminated, lastObservedReplicas
+}
+
+// getSpecReplicas returns the spec.replicas of the resource. It is read from the scale subresource and, if the resource
+// does not have a scale subresource, directly from the resource instead.
+func (r *resScaler) getSpecReplicas(ctx context.Context) (int32, error) {
+	_, scaleSubRes, err := util.GetScaleResource(ctx, r.client, r.scaler, r.logger, r.resourceInfo.ref, r.scaleCallTimeout())
+	if err == nil {
+		return scaleSubRes.Spec.Replicas, nil
+	}
+	if !isScaleSubresourceUnavailable(err) {
+		return 0, err
+	}
+	r.logger.Info("Resource does not have a scale subresource, falling back to its spec.replicas", "reason", err.Error())
 	childCtx, cancelFn := context.WithTimeout(ctx, r.resourceInfo.timeout)
 	defer cancelFn()
+	replicas, err := util.GetResourceSpecReplicas(childCtx, r.client, r.namespace, r.resourceInfo.ref)
+	if err != nil {
+		r.logger.Error(err, "Resource can neither be scaled via a scale subresource nor via its spec.replicas. Invalid config file")
+	}
+	return replicas, err
+}
 
+func (r *resScaler) updateResourceAndScale(ctx context.Context, currentReplicas int32, annot map[string]string) error {
 	// update the annotation capturing the current spec.replicas as the annotation value if the operation is scale down.
 	// This allows restoration of the resource to the same replica count when a subsequent scale up operation is triggered.
-	if r.resourceInfo.operation == scaleDown {
-		patchBytes := []byte(fmt.Sprintf("{\"metadata\":{\"annotations\":{\"%s\":\"%s\"}}}", replicasAnnotationKey, strconv.Itoa(int(scaleSubRes.Spec.Replicas))))
+	// A resource which has already been scaled down by its replica delta keeps the replicas recorded prior to that scale-down.
+	if r.resourceInfo.operation == scaleDown && !r.isScaledDownByReplicaDelta(currentReplicas, annot) {
+		patchBytes := []byte(fmt.Sprintf("{\"metadata\":{\"annotations\":{\"%s\":\"%s\"}}}", replicasAnnotationKey, strconv.Itoa(int(currentReplicas))))
 		err := util.PatchResourceAnnotations(ctx, r.client, r.namespace, r.resourceInfo.ref, patchBytes)
 		if err != nil {
 			r.logger.Error(err, "Failed to update annotation to capture the current replicas before scaling it down")
@@ -143,31 +385,397 @@ func (r *resScaler) updateResourceAndScale(ctx context.Context, scaleSubRes *aut
 		}
 	}
 
-	targetReplicas, err := r.determineTargetReplicas(annot)
+	targetReplicas, err := r.computeTargetReplicas(ctx, currentReplicas, annot)
 	if err != nil {
 		return err
 	}
 
-	// need the updated scale subresource
-	gr, scaleSubRes, err := util.GetScaleResource(ctx, r.client, r.scaler, r.logger, r.resourceInfo.ref, r.resourceInfo.timeout)
-	if err != nil {
-		return err
+	for _, stepReplicas := range r.intermediateSteps(currentReplicas, targetReplicas) {
+		r.logger.Info("Scaling up kubernetes resource to intermediate step", "stepReplicas", stepReplicas, "targetReplicas", targetReplicas)
+		if err = r.updateScaleReplicas(ctx, stepReplicas); err != nil {
+			return err
+		}
+		if err = r.waitTillReadyReplicasReached(ctx, stepReplicas); err != nil {
+			return err
+		}
 	}
 
-	scaleSubRes.Spec.Replicas = targetReplicas
 	if r.resourceInfo.operation == scaleUp {
 		r.logger.Info("Scaling up kubernetes resource", "targetReplicas", targetReplicas)
 	} else {
 		r.logger.Info("Scaling down kubernetes resource", "targetReplicas", targetReplicas)
 	}
-	if _, err = r.scaler.Update(childCtx, *gr, scaleSubRes, metav1.UpdateOptions{}); err != nil {
+	return r.updateScaleReplicas(ctx, targetReplicas)
+}
+
+// updateScaleReplicas sets spec.replicas of the scale subresource to the given replicas. If the resource does not have a
+// scale subresource then its spec.replicas is updated directly. If configured, the HorizontalPodAutoscaler which targets
+// the resource is scaled beforehand.
+func (r *resScaler) updateScaleReplicas(ctx context.Context, replicas int32) error {
+	if r.resourceInfo.hpa != nil {
+		if err := r.updateHPAReplicas(ctx, replicas); err != nil {
+			return err
+		}
+	}
+	// need the updated scale subresource
+	gr, scaleSubRes, err := util.GetScaleResource(ctx, r.client, r.scaler, r.logger, r.resourceInfo.ref, r.scaleCallTimeout())
+	childCtx, cancelFn := context.WithTimeout(ctx, r.resourceInfo.timeout)
+	defer cancelFn()
+	if err != nil {
+		if !isScaleSubresourceUnavailable(err) {
+			return err
+		}
+		return util.UpdateResourceSpecReplicas(childCtx, r.client, r.namespace, r.resourceInfo.ref, replicas, r.opts.fieldManager)
+	}
+	if r.opts.useServerSideApply {
+		err = r.applyScaleReplicas(childCtx, *gr, replicas)
+		if !apierrors.IsUnsupportedMediaType(err) && !apierrors.IsMethodNotSupported(err) {
+			return err
+		}
+		r.logger.Info("Scale subresource does not support server-side apply, falling back to an update", "reason", err.Error())
+	}
+	// the scale subresource is fetched again if it has been updated concurrently, as an update with its stale resourceVersion would keep conflicting.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if scaleSubRes == nil {
+			if _, scaleSubRes, err = util.GetScaleResource(childCtx, r.client, r.scaler, r.logger, r.resourceInfo.ref, r.scaleCallTimeout()); err != nil {
+				return err
+			}
+		}
+		scaleSubRes.Spec.Replicas = replicas
+		err = r.updateScale(childCtx, *gr, scaleSubRes)
+		if apierrors.IsConflict(err) {
+			r.logger.Info("Scale subresource has been updated concurrently, retrying the update with its latest state", "replicas", replicas)
+			scaleSubRes = nil
+		}
 		return err
+	})
+}
+
+// updateScale updates the scale subresource, bounding the call by the scale call timeout.
+func (r *resScaler) updateScale(ctx context.Context, gr schema.GroupResource, scaleSubRes *autoscalingv1.Scale) error {
+	callCtx, cancelFn := context.WithTimeout(ctx, r.scaleCallTimeout())
+	defer cancelFn()
+	_, err := r.scaler.Update(callCtx, gr, scaleSubRes, metav1.UpdateOptions{FieldManager: r.opts.fieldManager})
+	return err
+}
+
+// scaleCallTimeout returns the timeout of a single call to the scale subresource. Unless a scale call timeout has been
+// configured, a call is bounded by the timeout of the resource.
+func (r *resScaler) scaleCallTimeout() time.Duration {
+	if r.opts.scaleCallTimeout > 0 {
+		return r.opts.scaleCallTimeout
+	}
+	return r.resourceInfo.timeout
+}
+
+// applyScaleReplicas sets spec.replicas of the scale subresource via server-side apply. Conflicts are forced as DWD has to
+// be able to scale the resource even if the replicas are owned by another field manager.
+func (r *resScaler) applyScaleReplicas(ctx context.Context, gr schema.GroupResource, replicas int32) error {
+	gv, err := schema.ParseGroupVersion(r.resourceInfo.ref.APIVersion)
+	if err != nil {
+		return err
+	}
+	// only spec.replicas is part of the apply configuration so that DWD does not take ownership of any other field.
+	applyConfig := map[string]interface{}{
+		"apiVersion": autoscalingv1.SchemeGroupVersion.String(),
+		"kind":       "Scale",
+		"metadata":   map[string]interface{}{"name": r.resourceInfo.ref.Name, "namespace": r.namespace},
+		"spec":       map[string]interface{}{"replicas": replicas},
+	}
+	patchBytes, err := json.Marshal(applyConfig)
+	if err != nil {
+		return err
+	}
+	callCtx, cancelFn := context.WithTimeout(ctx, r.scaleCallTimeout())
+	defer cancelFn()
+	_, err = r.scaler.Patch(callCtx, gv.WithResource(gr.Resource), r.resourceInfo.ref.Name, types.ApplyPatchType, patchBytes, metav1.PatchOptions{
+		FieldManager: r.opts.fieldManager,
+		Force:        pointer.Bool(true),
+	})
+	return err
+}
+
+// intermediateSteps returns the configured steps of a staged scale-up which are greater than the currentReplicas and less than the targetReplicas.
+func (r *resScaler) intermediateSteps(currentReplicas, targetReplicas int32) []int32 {
+	if r.resourceInfo.operation != scaleUp {
+		return nil
+	}
+	steps := make([]int32, 0, len(r.resourceInfo.steps))
+	for _, step := range r.resourceInfo.steps {
+		if step >= targetReplicas {
+			break
+		}
+		if step <= currentReplicas {
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// waitTillReadyReplicasReached waits till the resource has at least the given number of ready replicas. The wait is bounded by
+// the timeout configured for the resource.
+func (r *resScaler) waitTillReadyReplicasReached(ctx context.Context, replicas int32) error {
+	if r.resourceInfo.ref.Kind == deploymentKind {
+		return util.WaitForDeploymentReadyWithClock(logr.NewContext(ctx, r.logger), r.opts.clock, r.namespace, r.resourceInfo.ref.Name, r.client, r.resourceInfo.timeout, *r.opts.resourceCheckInterval)
+	}
+	opDesc := fmt.Sprintf("wait for resource to reach %d ready replicas", replicas)
+	readyReplicasReached := util.RetryUntilPredicateWithClock(ctx, r.opts.clock, r.logger, opDesc, func() bool {
+		readyReplicas, err := util.GetResourceReadyReplicas(ctx, r.client, r.namespace, r.resourceInfo.ref)
+		return err == nil && readyReplicas >= replicas
+	}, r.resourceInfo.timeout, *r.opts.resourceCheckInterval)
+	if !readyReplicasReached {
+		return fmt.Errorf("timed out waiting for {namespace: %s, resource: %s} to reach %d ready replicas", r.namespace, r.resourceInfo.ref.Name, replicas)
 	}
 	return nil
 }
 
-func (r *resScaler) determineTargetReplicas(annotations map[string]string) (int32, error) {
+// suspendOrResumeCronJob suspends the CronJob on scale-down and resumes it on scale-up by toggling its spec.suspend.
+// Replicas are not applicable for a CronJob, therefore neither the replicas annotation is set nor is there a wait for ready replicas.
+func (r *resScaler) suspendOrResumeCronJob(ctx context.Context) error {
+	childCtx, cancelFn := context.WithTimeout(ctx, r.resourceInfo.timeout)
+	defer cancelFn()
+
+	cronJob := &batchv1.CronJob{}
+	if err := r.client.Get(childCtx, types.NamespacedName{Namespace: r.namespace, Name: r.resourceInfo.ref.Name}, cronJob); err != nil {
+		r.logger.Error(err, "Failed to get CronJob")
+		return err
+	}
+	suspend := r.resourceInfo.operation == scaleDown
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend == suspend {
+		r.logger.Info("Skipping CronJob as it already has the desired suspend state", "suspend", suspend)
+		return nil
+	}
+	patch := client.MergeFrom(cronJob.DeepCopy())
+	cronJob.Spec.Suspend = &suspend
+	if suspend {
+		r.logger.Info("Suspending CronJob")
+	} else {
+		r.logger.Info("Resuming CronJob")
+	}
+	return r.client.Patch(childCtx, cronJob, patch)
+}
+
+// updateHPAReplicas sets spec.minReplicas of the HorizontalPodAutoscaler which targets the resource to the given replicas and
+// raises its spec.maxReplicas, or sets it if the replicas are pinned, so that the HPA does not scale the resource back.
+// An HPA does not act on a resource with zero replicas and cannot have zero minReplicas, it is then left as is.
+// The bounds of the HPA prior to a scale-down are recorded in its annotations and restored when the resource is scaled up.
+func (r *resScaler) updateHPAReplicas(ctx context.Context, replicas int32) error {
+	childCtx, cancelFn := context.WithTimeout(ctx, r.resourceInfo.timeout)
+	defer cancelFn()
+	hpa, err := r.getHPA(childCtx, r.resourceInfo.hpa.Name)
+	if err != nil {
+		r.logger.Error(err, "Failed to get the HorizontalPodAutoscaler of the resource")
+		return err
+	}
+	hpaLogger := r.logger.WithValues("hpa", hpa.Name)
+	if replicas == 0 {
+		hpaLogger.Info("Leaving HorizontalPodAutoscaler as is as it does not act on a resource with zero replicas")
+		return nil
+	}
+	patch := client.MergeFrom(hpa.DeepCopy())
+	if r.resourceInfo.operation == scaleUp {
+		restored, err := restoreRecordedHPAReplicas(hpa)
+		if err != nil {
+			hpaLogger.Error(err, "Failed to restore the recorded bounds of the HorizontalPodAutoscaler")
+			return err
+		}
+		if restored {
+			hpaLogger.Info("Restoring recorded bounds of HorizontalPodAutoscaler of resource", "minReplicas", pointer.Int32Deref(hpa.Spec.MinReplicas, 1), "maxReplicas", hpa.Spec.MaxReplicas)
+			return r.client.Patch(childCtx, hpa, patch, client.FieldOwner(r.opts.fieldManager))
+		}
+	}
+	maxReplicas := hpa.Spec.MaxReplicas
+	if r.resourceInfo.hpa.PinReplicas || maxReplicas < replicas {
+		maxReplicas = replicas
+	}
+	if hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas == replicas && hpa.Spec.MaxReplicas == maxReplicas {
+		return nil
+	}
 	if r.resourceInfo.operation == scaleDown {
+		recordHPAReplicas(hpa)
+	}
+	hpa.Spec.MinReplicas = pointer.Int32(replicas)
+	hpa.Spec.MaxReplicas = maxReplicas
+	hpaLogger.Info("Scaling HorizontalPodAutoscaler of resource", "minReplicas", replicas, "maxReplicas", maxReplicas)
+	return r.client.Patch(childCtx, hpa, patch, client.FieldOwner(r.opts.fieldManager))
+}
+
+// recordHPAReplicas captures spec.minReplicas and spec.maxReplicas of the HorizontalPodAutoscaler in its annotations unless they have
+// already been recorded by a previous scale-down, which keeps the bounds of the HPA prior to the first scale-down.
+func recordHPAReplicas(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	if _, ok := hpa.Annotations[hpaMaxReplicasAnnotationKey]; ok {
+		return
+	}
+	if hpa.Annotations == nil {
+		hpa.Annotations = make(map[string]string)
+	}
+	if hpa.Spec.MinReplicas != nil {
+		hpa.Annotations[hpaMinReplicasAnnotationKey] = strconv.Itoa(int(*hpa.Spec.MinReplicas))
+	}
+	hpa.Annotations[hpaMaxReplicasAnnotationKey] = strconv.Itoa(int(hpa.Spec.MaxReplicas))
+}
+
+// restoreRecordedHPAReplicas sets spec.minReplicas and spec.maxReplicas of the HorizontalPodAutoscaler to the values recorded prior to
+// a scale-down and removes the annotations which recorded them. It returns false if no values have been recorded.
+func restoreRecordedHPAReplicas(hpa *autoscalingv2.HorizontalPodAutoscaler) (bool, error) {
+	maxReplicasStr, ok := hpa.Annotations[hpaMaxReplicasAnnotationKey]
+	if !ok {
+		return false, nil
+	}
+	maxReplicas, err := strconv.ParseInt(maxReplicasStr, 10, 32)
+	if err != nil {
+		return false, fmt.Errorf("unexpected value of annotation %s: %w", hpaMaxReplicasAnnotationKey, err)
+	}
+	var minReplicas *int32
+	if minReplicasStr, ok := hpa.Annotations[hpaMinReplicasAnnotationKey]; ok {
+		replicas, err := strconv.ParseInt(minReplicasStr, 10, 32)
+		if err != nil {
+			return false, fmt.Errorf("unexpected value of annotation %s: %w", hpaMinReplicasAnnotationKey, err)
+		}
+		minReplicas = pointer.Int32(int32(replicas))
+	}
+	hpa.Spec.MinReplicas = minReplicas
+	hpa.Spec.MaxReplicas = int32(maxReplicas)
+	delete(hpa.Annotations, hpaMinReplicasAnnotationKey)
+	delete(hpa.Annotations, hpaMaxReplicasAnnotationKey)
+	return true, nil
+}
+
+// getHPA returns the HorizontalPodAutoscaler which targets the resource. It is either the HPA with the given name or, if the name
+// is empty, the HPA whose spec.scaleTargetRef refers to the resource. An error is returned if there is no HPA which targets the resource.
+func (r *resScaler) getHPA(ctx context.Context, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	resKey := util.ResourceRefKey(*r.resourceInfo.ref)
+	if name != "" {
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: name}, hpa); err != nil {
+			return nil, err
+		}
+		if !r.isScaleTargetOf(hpa) {
+			return nil, fmt.Errorf("HorizontalPodAutoscaler %s/%s does not target resource %s", r.namespace, name, resKey)
+		}
+		return hpa, nil
+	}
+	hpaList := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := r.client.List(ctx, hpaList, client.InNamespace(r.namespace)); err != nil {
+		return nil, err
+	}
+	for i := range hpaList.Items {
+		if r.isScaleTargetOf(&hpaList.Items[i]) {
+			return &hpaList.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no HorizontalPodAutoscaler in namespace %s targets resource %s", r.namespace, resKey)
+}
+
+// isScaleTargetOf checks if the spec.scaleTargetRef of the HorizontalPodAutoscaler refers to the resource. Only the API group
+// is compared as the HPA can refer to the resource via any of its versions.
+func (r *resScaler) isScaleTargetOf(hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	targetRef, ref := hpa.Spec.ScaleTargetRef, r.resourceInfo.ref
+	if targetRef.Kind != ref.Kind || targetRef.Name != ref.Name {
+		return false
+	}
+	targetGV, err := schema.ParseGroupVersion(targetRef.APIVersion)
+	if err != nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	return err == nil && targetGV.Group == gv.Group
+}
+
+// removeIgnoreScalingAnnotation removes the ignore-scaling annotation from the resource if it is present and returns the
+// remaining annotations.
+func (r *resScaler) removeIgnoreScalingAnnotation(ctx context.Context, annotations map[string]string) (map[string]string, error) {
+	if _, ok := annotations[r.opts.ignoreScalingAnnotationKey]; !ok {
+		return annotations, nil
+	}
+	patchBytes := []byte(fmt.Sprintf("{\"metadata\":{\"annotations\":{\"%s\":null}}}", r.opts.ignoreScalingAnnotationKey))
+	if err := util.PatchResourceAnnotations(ctx, r.client, r.namespace, r.resourceInfo.ref, patchBytes); err != nil {
+		r.logger.Error(err, "Failed to remove ignore scaling annotation from resource", "annotation", r.opts.ignoreScalingAnnotationKey)
+		return annotations, err
+	}
+	r.logger.Info("Removed ignore scaling annotation from resource, scaling will be resumed", "annotation", r.opts.ignoreScalingAnnotationKey)
+	delete(annotations, r.opts.ignoreScalingAnnotationKey)
+	return annotations, nil
+}
+
+// computeTargetReplicas returns the replicas to which the resource is scaled. The target replicas of a scale-up are computed by
+// the replicas function if one is configured, else they are read from the HorizontalPodAutoscaler if replicasFromHPA is set. The
+// target replicas of determineTargetReplicas are the fallback.
+func (r *resScaler) computeTargetReplicas(ctx context.Context, currentReplicas int32, annotations map[string]string) (int32, error) {
+	if r.resourceInfo.operation == scaleUp && r.opts.replicasFunc != nil {
+		replicas, err := r.opts.replicasFunc(ctx, r.resourceInfo.ref)
+		switch {
+		case err != nil:
+			r.logger.Error(err, "Replicas function has failed, falling back to the recorded or configured scale-up replicas")
+		case replicas <= 0:
+			r.logger.Info("Replicas function has returned replicas which are not positive, falling back to the recorded or configured scale-up replicas", "replicas", replicas)
+		default:
+			return replicas, nil
+		}
+	}
+	if r.resourceInfo.operation == scaleUp && r.resourceInfo.replicasFromHPA != nil {
+		if replicas, ok := r.getHPADesiredReplicas(ctx); ok {
+			return replicas, nil
+		}
+	}
+	return r.determineTargetReplicas(currentReplicas, annotations)
+}
+
+// getHPADesiredReplicas returns status.desiredReplicas of the HorizontalPodAutoscaler referred to by replicasFromHPA. It returns
+// false if the HPA cannot be read or has not yet computed its desired replicas.
+func (r *resScaler) getHPADesiredReplicas(ctx context.Context) (int32, bool) {
+	childCtx, cancelFn := context.WithTimeout(ctx, r.resourceInfo.timeout)
+	defer cancelFn()
+	hpa, err := r.getHPA(childCtx, r.resourceInfo.replicasFromHPA.Name)
+	if err != nil {
+		r.logger.Error(err, "Failed to get the HorizontalPodAutoscaler to read the desired replicas from, falling back to the recorded or configured scale-up replicas")
+		return 0, false
+	}
+	if hpa.Status.DesiredReplicas <= 0 {
+		r.logger.Info("HorizontalPodAutoscaler has not computed its desired replicas yet, falling back to the recorded or configured scale-up replicas", "hpa", hpa.Name)
+		return 0, false
+	}
+	r.logger.Info("Scaling up to the desired replicas of the HorizontalPodAutoscaler", "hpa", hpa.Name, "desiredReplicas", hpa.Status.DesiredReplicas)
+	return hpa.Status.DesiredReplicas, true
+}
+
+// isScaledDownByReplicaDelta checks if the resource has already been scaled down by its replica delta, i.e. if it has fewer
+// replicas than have been recorded prior to a scale-down.
+func (r *resScaler) isScaledDownByReplicaDelta(currentReplicas int32, annotations map[string]string) bool {
+	if r.resourceInfo.replicaDelta == nil {
+		return false
+	}
+	recordedReplicas, ok := getRecordedReplicas(annotations)
+	return ok && currentReplicas < recordedReplicas
+}
+
+// getRecordedReplicas returns the replicas recorded in the replicas annotation prior to a scale-down. It returns false if the
+// annotation is not set or its value is not a number.
+func getRecordedReplicas(annotations map[string]string) (int32, bool) {
+	replicasStr, ok := annotations[replicasAnnotationKey]
+	if !ok {
+		return 0, false
+	}
+	replicas, err := strconv.ParseInt(replicasStr, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(replicas), true
+}
+
+// determineTargetReplicas returns the target replicas of a scale-down or the recorded or configured replicas of a scale-up.
+// The replica delta of a scale-down is applied to the replicas recorded prior to it if the resource has already been scaled down,
+// so that repeated scale-downs, which are triggered by every failed probe, do not remove further replicas.
+func (r *resScaler) determineTargetReplicas(currentReplicas int32, annotations map[string]string) (int32, error) {
+	if r.resourceInfo.operation == scaleDown {
+		if r.resourceInfo.replicaDelta != nil {
+			originalReplicas := currentReplicas
+			if r.isScaledDownByReplicaDelta(currentReplicas, annotations) {
+				originalReplicas, _ = getRecordedReplicas(annotations)
+			}
+			return max(originalReplicas+*r.resourceInfo.replicaDelta, defaultScaleDownReplicas), nil
+		}
 		return defaultScaleDownReplicas, nil
 	}
 	if replicasStr, ok := annotations[replicasAnnotationKey]; ok {
@@ -177,12 +785,29 @@ func (r *resScaler) determineTargetReplicas(annotations map[string]string) (int3
 		}
 		return int32(replicas), nil // #nosec G109 G115 -- number of replicas will not exceed MaxInt32
 	}
+	if r.resourceInfo.replicas != nil {
+		r.logger.Info("Replicas annotation not found, falling back to configured scale-up replicas", "operation", r.resourceInfo.operation, "annotationKey", replicasAnnotationKey, "replicas", *r.resourceInfo.replicas)
+		return *r.resourceInfo.replicas, nil
+	}
 	r.logger.Info("Replicas annotation not found, falling back to default scale-up replicas", "operation", r.resourceInfo.operation, "annotationKey", replicasAnnotationKey, "default-replicas", defaultScaleUpReplicas)
 	return defaultScaleUpReplicas, nil
 }
 
-func ignoreScaling(annotations map[string]string) bool {
-	if val, ok := annotations[ignoreScalingAnnotationKey]; ok {
+// isScaleSubresourceUnavailable returns true if the error returned for a scale subresource indicates that the resource
+// does not have one. The resource itself is known to exist as its annotations have been read before.
+func isScaleSubresourceUnavailable(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err)
+}
+
+// isManagedExternally checks if the labels of the resource match the configured selector for resources which are actively
+// managed by another controller.
+func (r *resScaler) isManagedExternally(resourceLabels map[string]string) bool {
+	return r.opts.externallyManagedSelector != nil && r.opts.externallyManagedSelector.Matches(labels.Set(resourceLabels))
+}
+
+// ignoreScaling checks if the annotation with the given key is set to true.
+func ignoreScaling(annotations map[string]string, annotationKey string) bool {
+	if val, ok := annotations[annotationKey]; ok {
 		b, err := strconv.ParseBool(val)
 		if err != nil {
 			return false
@@ -0,0 +1,1745 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	scalev1 "k8s.io/client-go/scale"
+	testclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	cronJobTestNamespace = "shoot--test--cronjob"
+	cronJobTestName      = "etcd-backup-compaction"
+	stagedTestNamespace  = "shoot--test--staged"
+)
+
+func TestScaleCronJob(t *testing.T) {
+	tests := []struct {
+		name            string
+		op              operation
+		initialSuspend  *bool
+		expectedSuspend bool
+	}{
+		{name: "scale down should suspend a running CronJob", op: scaleDown, initialSuspend: nil, expectedSuspend: true},
+		{name: "scale down should keep a suspended CronJob suspended", op: scaleDown, initialSuspend: pointer.Bool(true), expectedSuspend: true},
+		{name: "scale up should resume a suspended CronJob", op: scaleUp, initialSuspend: pointer.Bool(true), expectedSuspend: false},
+		{name: "scale up should keep a running CronJob running", op: scaleUp, initialSuspend: pointer.Bool(false), expectedSuspend: false},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			cronJob := createTestCronJob(entry.initialSuspend)
+			cl := fake.NewClientBuilder().WithObjects(cronJob).Build()
+
+			rs := newResourceScaler(cl, nil, logr.Discard(), buildScalerOptions(), cronJobTestNamespace, createCronJobResourceInfo(entry.op))
+			g.Expect(rs.scale(ctx)).To(Succeed())
+
+			actual := &batchv1.CronJob{}
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(cronJob), actual)).To(Succeed())
+			g.Expect(actual.Spec.Suspend).ToNot(BeNil())
+			g.Expect(*actual.Spec.Suspend).To(Equal(entry.expectedSuspend))
+			g.Expect(actual.Annotations).ToNot(HaveKey(replicasAnnotationKey), "replicas are not applicable to a CronJob")
+		})
+	}
+}
+
+func TestScaleCronJobShouldBeIgnoredWhenAnnotatedWithIgnoreScaling(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	cronJob := createTestCronJob(nil)
+	cronJob.Annotations = map[string]string{DefaultIgnoreScalingAnnotationKey: "true"}
+	cl := fake.NewClientBuilder().WithObjects(cronJob).Build()
+
+	rs := newResourceScaler(cl, nil, logr.Discard(), buildScalerOptions(), cronJobTestNamespace, createCronJobResourceInfo(scaleDown))
+	g.Expect(rs.scale(ctx)).To(Succeed())
+
+	actual := &batchv1.CronJob{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(cronJob), actual)).To(Succeed())
+	g.Expect(actual.Spec.Suspend).To(BeNil())
+}
+
+func TestScaleOptionalCronJobWhichDoesNotExist(t *testing.T) {
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().Build()
+	resInfo := createCronJobResourceInfo(scaleDown)
+	resInfo.optional = true
+
+	rs := newResourceScaler(cl, nil, logr.Discard(), buildScalerOptions(), cronJobTestNamespace, resInfo)
+	g.Expect(rs.scale(context.Background())).To(Succeed())
+}
+
+func createTestCronJob(suspend *bool) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJobTestName,
+			Namespace: cronJobTestNamespace,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			Suspend:  suspend,
+		},
+	}
+}
+
+func createCronJobResourceInfo(op operation) scalableResourceInfo {
+	return scalableResourceInfo{
+		ref:       &autoscalingv1.CrossVersionObjectReference{Kind: cronJobKind, Name: cronJobTestName, APIVersion: "batch/v1"},
+		level:     0,
+		timeout:   time.Second,
+		operation: op,
+	}
+}
+
+func TestStagedScaleUp(t *testing.T) {
+	tests := []struct {
+		name                   string
+		steps                  []int32
+		replicasAnnotation     string
+		expectedReplicaUpdates []int32
+	}{
+		{name: "scale up should walk through a single intermediate step", steps: []int32{1}, replicasAnnotation: "3", expectedReplicaUpdates: []int32{1, 3}},
+		{name: "scale up should walk through two intermediate steps", steps: []int32{1, 2}, replicasAnnotation: "4", expectedReplicaUpdates: []int32{1, 2, 4}},
+		{name: "scale up should skip steps which are not less than the target replicas", steps: []int32{1, 3, 5}, replicasAnnotation: "3", expectedReplicaUpdates: []int32{1, 3}},
+		{name: "scale up without steps should directly scale to the target replicas", replicasAnnotation: "3", expectedReplicaUpdates: []int32{3}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createStagedTestDeployment(entry.replicasAnnotation)
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, createStagedResourceInfo(entry.steps))
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal(entry.expectedReplicaUpdates))
+		})
+	}
+}
+
+func TestStagedScaleUpShouldFailWhenStepDoesNotBecomeReady(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createStagedTestDeployment("3")
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: false}
+
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, createStagedResourceInfo([]int32{1}))
+	err := rs.scale(ctx)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(SatisfyAll(ContainSubstring("timed out waiting for deployment"), ContainSubstring("readyReplicas: 0")))
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{1}), "scale up should not proceed beyond a step which has not become ready")
+}
+
+func TestScaleUpdateShouldSetFieldManager(t *testing.T) {
+	tests := []struct {
+		name                  string
+		options               []scalerOption
+		applyErr              error
+		expectedFieldManagers []string
+		expectApply           bool
+	}{
+		{name: "update should set the default field manager", expectedFieldManagers: []string{DefaultFieldManager}},
+		{name: "update should set a configured field manager", options: []scalerOption{WithFieldManager("custom-manager")}, expectedFieldManagers: []string{"custom-manager"}},
+		{name: "server-side apply should set the default field manager", options: []scalerOption{WithServerSideApply(true)}, expectedFieldManagers: []string{DefaultFieldManager}, expectApply: true},
+		{
+			name:                  "server-side apply should fall back to an update with the same field manager if it is not supported",
+			options:               []scalerOption{WithServerSideApply(true)},
+			applyErr:              apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "patch", schema.GroupResource{Group: "apps", Resource: "deployments"}, mcmObjectRef.Name, "", 0, false),
+			expectedFieldManagers: []string{DefaultFieldManager, DefaultFieldManager},
+		},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			cl := newStagedTestClient(createStagedTestDeployment("2"))
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true, applyErr: entry.applyErr}
+			options := append(entry.options, withResourceCheckInterval(10*time.Millisecond))
+
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(options...), stagedTestNamespace, createStagedResourceInfo(nil))
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{2}))
+			g.Expect(scaler.fieldManagers).To(Equal(entry.expectedFieldManagers))
+			if entry.expectApply {
+				g.Expect(scaler.appliedScale).To(HaveLen(1))
+				g.Expect(scaler.appliedScale[0]).To(MatchJSON(fmt.Sprintf(`{"apiVersion":"autoscaling/v1","kind":"Scale","metadata":{"name":"%s","namespace":"%s"},"spec":{"replicas":2}}`, mcmObjectRef.Name, stagedTestNamespace)))
+			} else {
+				g.Expect(scaler.appliedScale).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestScaleUpShouldBeSkippedWhenDisabled(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	cl := newStagedTestClient(createStagedTestDeployment("2"))
+	scaleInterface := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+	depResInfos := []papi.DependentResourceInfo{createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, nil, false)}
+
+	s := NewScaler(stagedTestNamespace, depResInfos, cl, scalesGetterFunc(func(string) scalev1.ScaleInterface { return scaleInterface }), logr.Discard(), WithScaleUpDisabled(true))
+	g.Expect(s.ScaleUp(ctx)).To(Succeed())
+	g.Expect(scaleInterface.replicaUpdates).To(BeEmpty())
+	g.Expect(scaleInterface.fieldManagers).To(BeEmpty(), "scale subresource should not have been touched")
+
+	deployment := &appsv1.Deployment{}
+	g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: stagedTestNamespace, Name: mcmObjectRef.Name}, deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(BeZero())
+}
+
+func TestScaleShouldFallBackToSpecReplicasWithoutScaleSubresource(t *testing.T) {
+	deploymentsResource := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name                string
+		op                  operation
+		initialReplicas     int32
+		replicasAnnotation  string
+		scaleSubresourceErr error
+		expectedReplicas    int32
+	}{
+		{name: "scale down should update spec.replicas if there is no scale subresource", op: scaleDown, initialReplicas: 2, scaleSubresourceErr: apierrors.NewNotFound(deploymentsResource, mcmObjectRef.Name), expectedReplicas: 0},
+		{name: "scale up should update spec.replicas if the scale subresource is not supported", op: scaleUp, replicasAnnotation: "3", scaleSubresourceErr: apierrors.NewMethodNotSupported(deploymentsResource, "get"), expectedReplicas: 3},
+		{name: "scale up should use the scale subresource if it is available", op: scaleUp, replicasAnnotation: "3", expectedReplicas: 3},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createStagedTestDeployment(entry.replicasAnnotation)
+			deployment.Spec.Replicas = pointer.Int32(entry.initialReplicas)
+			// the fallback does not go through the scale subresource which would mark the replicas as ready.
+			deployment.Status.ReadyReplicas = entry.expectedReplicas
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true, scaleSubresourceErr: entry.scaleSubresourceErr}
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = entry.op
+
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+			g.Expect(*deployment.Spec.Replicas).To(Equal(entry.expectedReplicas))
+			if entry.scaleSubresourceErr != nil {
+				g.Expect(scaler.replicaUpdates).To(BeEmpty(), "scale subresource should not have been used")
+			} else {
+				g.Expect(scaler.replicaUpdates).To(Equal([]int32{entry.expectedReplicas}))
+			}
+		})
+	}
+}
+
+func TestScaleShouldFailWithoutScaleSubresourceAndSpecReplicas(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createStagedTestDeployment("3")
+	deployment.Spec.Replicas = nil
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, scaleSubresourceErr: apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, mcmObjectRef.Name)}
+
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(), stagedTestNamespace, createStagedResourceInfo(nil))
+	err := rs.scale(ctx)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("does not have a spec.replicas field"))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Replicas).To(BeNil(), "spec.replicas should not have been added to the resource")
+}
+
+func TestScaleDownShouldHandleLevelTimeout(t *testing.T) {
+	tests := []struct {
+		name                   string
+		continueOnLevelTimeout bool
+		expectedCAReplicas     int32
+	}{
+		{name: "scale down should be aborted when a level times out", continueOnLevelTimeout: false, expectedCAReplicas: 1},
+		{name: "scale down should proceed with subsequent levels when a level times out", continueOnLevelTimeout: true, expectedCAReplicas: 0},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			// the ready replicas of the MCM are never updated as the scale interface does not mark replicas as ready,
+			// therefore the level of the MCM never finishes scaling down.
+			mcm := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 2)
+			ca := createLevelTimeoutTestDeployment(caObjectRef.Name, 1)
+			ca.Status.ReadyReplicas = 0
+			cl := newStagedTestClient(mcm, ca)
+			scaleInterface := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace}
+			depResInfos := []papi.DependentResourceInfo{
+				createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, nil, false),
+				createTestDeploymentDependentResourceInfo(caObjectRef.Name, 1, 1, nil, nil, false),
+			}
+
+			s := NewScaler(stagedTestNamespace, depResInfos, cl, scalesGetterFunc(func(string) scalev1.ScaleInterface { return scaleInterface }), logr.Discard(),
+				withResourceCheckTimeout(time.Minute),
+				withResourceCheckInterval(10*time.Millisecond),
+				WithLevelTimeout(200*time.Millisecond),
+				WithContinueOnLevelTimeout(entry.continueOnLevelTimeout))
+			err := s.ScaleDown(ctx)
+			if entry.continueOnLevelTimeout {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("scaling of level 0 has timed out"))
+			}
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ca), ca)).To(Succeed())
+			g.Expect(*ca.Spec.Replicas).To(Equal(entry.expectedCAReplicas))
+		})
+	}
+}
+
+func TestScaleShouldSkipExternallyManagedResourcesOnScaleDown(t *testing.T) {
+	const managedByLabelKey = "app.kubernetes.io/managed-by"
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{managedByLabelKey: "test-operator"}}
+	tests := []struct {
+		name             string
+		op               operation
+		labels           map[string]string
+		selector         *metav1.LabelSelector
+		initialReplicas  int32
+		expectedReplicas int32
+	}{
+		{name: "scale down should skip a resource matching the selector", op: scaleDown, labels: selector.MatchLabels, selector: selector, initialReplicas: 2, expectedReplicas: 2},
+		{name: "scale down should scale a resource not matching the selector", op: scaleDown, labels: map[string]string{managedByLabelKey: "another-operator"}, selector: selector, initialReplicas: 2, expectedReplicas: 0},
+		{name: "scale down should scale a resource if no selector is configured", op: scaleDown, labels: selector.MatchLabels, initialReplicas: 2, expectedReplicas: 0},
+		{name: "scale up should scale a resource matching the selector", op: scaleUp, labels: selector.MatchLabels, selector: selector, initialReplicas: 0, expectedReplicas: 2},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createStagedTestDeployment("2")
+			deployment.Labels = entry.labels
+			deployment.Spec.Replicas = pointer.Int32(entry.initialReplicas)
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = entry.op
+
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithExternallyManagedSelector(entry.selector)), stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+			g.Expect(*deployment.Spec.Replicas).To(Equal(entry.expectedReplicas))
+		})
+	}
+}
+
+type scalesGetterFunc func(namespace string) scalev1.ScaleInterface
+
+func TestScaleUpShouldRestoreReplicasRecordedDuringScaleDown(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	// the replicas have been set by an HPA and differ from the default scale-up replicas
+	const hpaReplicas = 5
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, hpaReplicas)
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+	opts := buildScalerOptions(withResourceCheckInterval(10 * time.Millisecond))
+
+	scaleDownResInfo := createStagedResourceInfo(nil)
+	scaleDownResInfo.operation = scaleDown
+	g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleDownResInfo).scale(ctx)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(BeZero())
+	g.Expect(deployment.Annotations).To(HaveKeyWithValue(replicasAnnotationKey, strconv.Itoa(hpaReplicas)), "scale down should record the replicas prior to scaling down")
+
+	g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, createStagedResourceInfo(nil)).scale(ctx)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(Equal(int32(hpaReplicas)), "scale up should restore the recorded replicas")
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{0, hpaReplicas}))
+}
+
+func TestScaleUpShouldFallBackToDefaultReplicasWithoutRecordedReplicas(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 0)
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, createStagedResourceInfo(nil))
+	g.Expect(rs.scale(ctx)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(Equal(int32(defaultScaleUpReplicas)))
+}
+
+func TestScaleUpShouldWaitOnConfiguredReplicasStatusField(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusField   papi.ReplicasStatusField
+		status        appsv1.DeploymentStatus
+		expectedError bool
+	}{
+		{name: "replicas which are up but not ready should suffice when waiting on status.replicas", statusField: papi.ReplicasStatusFieldReplicas, status: appsv1.DeploymentStatus{Replicas: 2}},
+		{name: "replicas which are up but not ready should not suffice by default", status: appsv1.DeploymentStatus{Replicas: 2}, expectedError: true},
+		{name: "ready replicas should suffice by default", status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2}},
+		{name: "ready replicas which are not yet available should not suffice when waiting on status.availableReplicas", statusField: papi.ReplicasStatusFieldAvailableReplicas, status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2}, expectedError: true},
+		{name: "available replicas should suffice when waiting on status.availableReplicas", statusField: papi.ReplicasStatusFieldAvailableReplicas, status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2, AvailableReplicas: 2}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createStagedTestDeployment("2")
+			deployment.Status = entry.status
+			cl := newStagedTestClient(deployment)
+			// the scale interface does not mark the replicas as ready, the status of the deployment remains as is.
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace}
+
+			opts := buildScalerOptions(withResourceCheckTimeout(200*time.Millisecond), withResourceCheckInterval(10*time.Millisecond), WithWaitOnReplicasStatusField(entry.statusField))
+			rs := newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, createStagedResourceInfo(nil))
+			err := rs.scale(ctx)
+			if entry.expectedError {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("to reach minTargetReplicas 1"))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{2}))
+		})
+	}
+}
+
+func TestScaleDownByReplicaDelta(t *testing.T) {
+	tests := []struct {
+		name             string
+		replicas         int32
+		replicaDelta     int32
+		expectedReplicas int32
+	}{
+		{name: "scale down should remove as many replicas as the delta", replicas: 3, replicaDelta: -1, expectedReplicas: 2},
+		{name: "scale down should remove all replicas if the delta equals the replicas", replicas: 2, replicaDelta: -2, expectedReplicas: 0},
+		{name: "scale down should clamp the replicas at zero if the delta exceeds the replicas", replicas: 2, replicaDelta: -5, expectedReplicas: 0},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, entry.replicas)
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = scaleDown
+			resInfo.replicaDelta = pointer.Int32(entry.replicaDelta)
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{entry.expectedReplicas}))
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+			g.Expect(deployment.Annotations).To(HaveKeyWithValue(replicasAnnotationKey, strconv.Itoa(int(entry.replicas))))
+		})
+	}
+}
+
+func TestRepeatedScaleDownsByReplicaDeltaShouldOnlyApplyDeltaOnce(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 3)
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+	opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), withResourceCheckTimeout(200*time.Millisecond))
+
+	scaleDownResInfo := createStagedResourceInfo(nil)
+	scaleDownResInfo.operation = scaleDown
+	scaleDownResInfo.replicaDelta = pointer.Int32(-1)
+	// every failed probe after the failure threshold has been reached triggers another scale-down.
+	for range 3 {
+		g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleDownResInfo).scale(ctx)).To(Succeed())
+	}
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{2}), "the replica delta should only be applied once")
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Annotations).To(HaveKeyWithValue(replicasAnnotationKey, "3"), "the replicas prior to the first scale-down should be kept")
+
+	scaleUpResInfo := createStagedResourceInfo(nil)
+	scaleUpResInfo.restoreRecordedReplicas = true
+	g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleUpResInfo).scale(ctx)).To(Succeed())
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{2, 3}), "scale up should restore the replicas prior to the first scale-down")
+}
+
+func TestScaleUpShouldRestoreResourceScaledDownByReplicaDelta(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 3)
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+	opts := buildScalerOptions(withResourceCheckInterval(10 * time.Millisecond))
+
+	scaleDownResInfo := createStagedResourceInfo(nil)
+	scaleDownResInfo.operation = scaleDown
+	scaleDownResInfo.replicaDelta = pointer.Int32(-1)
+	g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleDownResInfo).scale(ctx)).To(Succeed())
+
+	scaleUpResInfo := createStagedResourceInfo([]int32{1, 2})
+	scaleUpResInfo.restoreRecordedReplicas = true
+	g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleUpResInfo).scale(ctx)).To(Succeed())
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{2, 3}), "scale up should restore the recorded replicas without walking through steps below the current replicas")
+}
+
+func TestScaleUpShouldSkipResourceWithReplicasUnlessScaledDownByReplicaDelta(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 2)
+	deployment.Annotations = map[string]string{replicasAnnotationKey: "3"}
+	cl := newStagedTestClient(deployment)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, createStagedResourceInfo(nil))
+	g.Expect(rs.scale(ctx)).To(Succeed())
+	g.Expect(scaler.replicaUpdates).To(BeEmpty())
+}
+
+func TestScaleUpShouldScaleHPABeforeResource(t *testing.T) {
+	tests := []struct {
+		name                string
+		hpaInfo             papi.HPAScaleInfo
+		minReplicas         int32
+		maxReplicas         int32
+		expectedMaxReplicas int32
+	}{
+		{name: "max replicas should be set to the target replicas when pinned", hpaInfo: papi.HPAScaleInfo{PinReplicas: true}, minReplicas: 1, maxReplicas: 5, expectedMaxReplicas: 3},
+		{name: "max replicas should be kept if not pinned", minReplicas: 1, maxReplicas: 5, expectedMaxReplicas: 5},
+		{name: "max replicas below the target replicas should be raised", minReplicas: 1, maxReplicas: 2, expectedMaxReplicas: 3},
+		{name: "hpa with the configured name should be scaled", hpaInfo: papi.HPAScaleInfo{Name: "mcm-hpa", PinReplicas: true}, minReplicas: 1, maxReplicas: 5, expectedMaxReplicas: 3},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			hpa := createTestHPA("mcm-hpa", mcmObjectRef, entry.minReplicas, entry.maxReplicas)
+			cl := newStagedTestClient(createStagedTestDeployment("3"), createTestHPA("other-hpa", kcmObjectRef, 1, 1), hpa)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.hpa = &entry.hpaInfo
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{3}))
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(hpa), hpa)).To(Succeed())
+			g.Expect(*hpa.Spec.MinReplicas).To(Equal(int32(3)), "hpa should not scale the resource below its target replicas")
+			g.Expect(hpa.Spec.MaxReplicas).To(Equal(entry.expectedMaxReplicas))
+		})
+	}
+}
+
+func TestScaleShouldFailWithoutHPATargetingResource(t *testing.T) {
+	tests := []struct {
+		name          string
+		hpaInfo       papi.HPAScaleInfo
+		expectedError string
+	}{
+		{name: "no hpa targets the resource", expectedError: "no HorizontalPodAutoscaler in namespace " + stagedTestNamespace + " targets resource apps/v1/Deployment/machine-controller-manager"},
+		{name: "hpa with the configured name targets another resource", hpaInfo: papi.HPAScaleInfo{Name: "kcm-hpa"}, expectedError: "does not target resource"},
+		{name: "hpa with the configured name does not exist", hpaInfo: papi.HPAScaleInfo{Name: "mcm-hpa"}, expectedError: "not found"},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createStagedTestDeployment("3")
+			cl := newStagedTestClient(deployment, createTestHPA("kcm-hpa", kcmObjectRef, 1, 1))
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.hpa = &entry.hpaInfo
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, resInfo)
+			err := rs.scale(ctx)
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(entry.expectedError))
+			g.Expect(scaler.replicaUpdates).To(BeEmpty(), "resource should not be scaled without its hpa")
+		})
+	}
+}
+
+func TestScaleDownToZeroShouldLeaveHPAAsIs(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	hpa := createTestHPA("mcm-hpa", mcmObjectRef, 2, 5)
+	cl := newStagedTestClient(createLevelTimeoutTestDeployment(mcmObjectRef.Name, 2), hpa)
+	scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+	resInfo := createStagedResourceInfo(nil)
+	resInfo.operation = scaleDown
+	resInfo.hpa = &papi.HPAScaleInfo{PinReplicas: true}
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(withResourceCheckInterval(10*time.Millisecond)), stagedTestNamespace, resInfo)
+	g.Expect(rs.scale(ctx)).To(Succeed())
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{0}))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(hpa), hpa)).To(Succeed())
+	g.Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+	g.Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+}
+
+func TestScaleDownThenScaleUpShouldRestoreHPABounds(t *testing.T) {
+	tests := []struct {
+		name        string
+		minReplicas *int32
+	}{
+		{name: "hpa with min replicas", minReplicas: pointer.Int32(2)},
+		{name: "hpa without min replicas"},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			hpa := createTestHPA("mcm-hpa", mcmObjectRef, 0, 5)
+			hpa.Spec.MinReplicas = entry.minReplicas
+			deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 4)
+			cl := newStagedTestClient(deployment, hpa)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+			opts := buildScalerOptions(withResourceCheckInterval(10 * time.Millisecond))
+
+			scaleDownResInfo := createStagedResourceInfo(nil)
+			scaleDownResInfo.operation = scaleDown
+			scaleDownResInfo.replicaDelta = pointer.Int32(-1)
+			scaleDownResInfo.hpa = &papi.HPAScaleInfo{PinReplicas: true}
+			for range 2 {
+				g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleDownResInfo).scale(ctx)).To(Succeed())
+			}
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(hpa), hpa)).To(Succeed())
+			g.Expect(*hpa.Spec.MinReplicas).To(Equal(int32(3)))
+			g.Expect(hpa.Spec.MaxReplicas).To(Equal(int32(3)))
+			g.Expect(hpa.Annotations).To(HaveKeyWithValue(hpaMaxReplicasAnnotationKey, "5"))
+
+			scaleUpResInfo := createStagedResourceInfo(nil)
+			scaleUpResInfo.restoreRecordedReplicas = true
+			scaleUpResInfo.hpa = &papi.HPAScaleInfo{PinReplicas: true}
+			g.Expect(newResourceScaler(cl, scaler, logr.Discard(), opts, stagedTestNamespace, scaleUpResInfo).scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{3, 4}))
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(hpa), hpa)).To(Succeed())
+			g.Expect(hpa.Spec.MinReplicas).To(Equal(entry.minReplicas), "scale up should restore min replicas of the hpa prior to the scale-down")
+			g.Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)), "scale up should restore max replicas of the hpa prior to the scale-down")
+			g.Expect(hpa.Annotations).ToNot(HaveKey(hpaMinReplicasAnnotationKey))
+			g.Expect(hpa.Annotations).ToNot(HaveKey(hpaMaxReplicasAnnotationKey))
+		})
+	}
+}
+
+func TestScaleDownThenScaleUpShouldRestoreReplicas(t *testing.T) {
+	ignoreScaling := map[string]string{DefaultIgnoreScalingAnnotationKey: "true"}
+	tests := []struct {
+		name                   string
+		mcmReplicas            int32
+		kcmReplicas            int32
+		caReplicas             int32
+		kcmAnnotations         map[string]string
+		expectedScaledDownKCM  int32
+		expectedScaledUpMCM    int32
+		expectedScaledUpKCM    int32
+		expectedScaledUpCA     int32
+		expectedKCMUnprocessed bool
+	}{
+		{name: "resources without replicas should be scaled up to the default replicas", expectedScaledUpMCM: 1, expectedScaledUpKCM: 1, expectedScaledUpCA: 1},
+		{name: "resources should be scaled up to their replicas prior to the scale down", mcmReplicas: 2, kcmReplicas: 2, caReplicas: 2, expectedScaledUpMCM: 2, expectedScaledUpKCM: 2, expectedScaledUpCA: 2},
+		{name: "resources should be scaled up independent of each other", kcmReplicas: 1, caReplicas: 2, expectedScaledUpMCM: 1, expectedScaledUpKCM: 1, expectedScaledUpCA: 2},
+		{name: "resource with ignore scaling annotation should not be scaled", mcmReplicas: 2, kcmReplicas: 1, caReplicas: 1, kcmAnnotations: ignoreScaling, expectedScaledDownKCM: 1, expectedScaledUpMCM: 2, expectedScaledUpKCM: 1, expectedScaledUpCA: 1},
+		{name: "resource without replicas and with ignore scaling annotation should not be scaled up", mcmReplicas: 2, caReplicas: 2, kcmAnnotations: ignoreScaling, expectedScaledUpMCM: 2, expectedScaledUpCA: 2},
+		{name: "resource with invalid ignore scaling annotation should be scaled", mcmReplicas: 1, kcmReplicas: 2, kcmAnnotations: map[string]string{DefaultIgnoreScalingAnnotationKey: "foo"}, expectedScaledUpMCM: 1, expectedScaledUpKCM: 2, expectedScaledUpCA: 1},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			scales, cl := newFakeScalesGetter(
+				createFakeScalesTestDeployment(mcmObjectRef.Name, entry.mcmReplicas, nil),
+				createFakeScalesTestDeployment(kcmObjectRef.Name, entry.kcmReplicas, entry.kcmAnnotations),
+				createFakeScalesTestDeployment(caObjectRef.Name, entry.caReplicas, nil))
+			s := newFakeScalesScaler(cl, scales, createDepResourceInfoArray(nil))
+
+			g.Expect(s.ScaleDown(ctx)).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+			expectDeploymentReplicas(g, cl, kcmObjectRef.Name, entry.expectedScaledDownKCM)
+			expectDeploymentReplicas(g, cl, caObjectRef.Name, 0)
+
+			g.Expect(s.ScaleUp(ctx)).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedScaledUpMCM)
+			expectDeploymentReplicas(g, cl, kcmObjectRef.Name, entry.expectedScaledUpKCM)
+			expectDeploymentReplicas(g, cl, caObjectRef.Name, entry.expectedScaledUpCA)
+		})
+	}
+}
+
+func TestScaleShouldOnlyBeIgnoredViaConfiguredIgnoreScalingAnnotationKey(t *testing.T) {
+	const customKey = "example.com/ignore-scaling"
+	tests := []struct {
+		name             string
+		annotationKey    string
+		opts             []scalerOption
+		expectedReplicas int32
+	}{
+		{name: "scaling should be ignored via the configured annotation key", annotationKey: customKey, opts: []scalerOption{WithIgnoreScalingAnnotationKey(customKey)}, expectedReplicas: 2},
+		{name: "scaling should not be ignored via the default annotation key if another one is configured", annotationKey: DefaultIgnoreScalingAnnotationKey, opts: []scalerOption{WithIgnoreScalingAnnotationKey(customKey)}, expectedReplicas: 0},
+		{name: "scaling should not be ignored via another annotation key by default", annotationKey: customKey, expectedReplicas: 0},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			deployment := createFakeScalesTestDeployment(mcmObjectRef.Name, 2, map[string]string{entry.annotationKey: "true"})
+			scales, cl := newFakeScalesGetter(deployment)
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = scaleDown
+
+			opts := buildScalerOptions(append([]scalerOption{withResourceCheckInterval(10 * time.Millisecond)}, entry.opts...)...)
+			rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleUpShouldFallBackToConfiguredReplicas(t *testing.T) {
+	tests := []struct {
+		name             string
+		replicas         *int32
+		annotations      map[string]string
+		expectedReplicas int32
+	}{
+		{name: "resource without recorded replicas should be scaled up to the default replicas", expectedReplicas: defaultScaleUpReplicas},
+		{name: "resource without recorded replicas should be scaled up to the configured replicas", replicas: pointer.Int32(2), expectedReplicas: 2},
+		{name: "resource with recorded replicas should be scaled up to them instead of the configured replicas", replicas: pointer.Int32(2), annotations: map[string]string{replicasAnnotationKey: "3"}, expectedReplicas: 3},
+		{name: "resource without recorded replicas should not be scaled up if zero replicas are configured", replicas: pointer.Int32(0), expectedReplicas: 0},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, entry.annotations))
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.replicas = entry.replicas
+
+			opts := buildScalerOptions(withResourceCheckInterval(10 * time.Millisecond))
+			rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(context.Background())).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleUpShouldBeDrivenByReplicasFunc(t *testing.T) {
+	recordedReplicas := map[string]string{replicasAnnotationKey: "2"}
+	tests := []struct {
+		name             string
+		replicasFunc     ReplicasFunc
+		expectedReplicas int32
+	}{
+		{name: "replicas computed by the function should override the recorded replicas", replicasFunc: func(context.Context, *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+			return 5, nil
+		}, expectedReplicas: 5},
+		{name: "failing function should fall back to the recorded replicas", replicasFunc: func(context.Context, *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+			return 0, errors.New("cluster size is unknown")
+		}, expectedReplicas: 2},
+		{name: "function returning zero replicas should fall back to the recorded replicas", replicasFunc: func(context.Context, *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+			return 0, nil
+		}, expectedReplicas: 2},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, recordedReplicas))
+			var refs []string
+			replicasFunc := func(ctx context.Context, ref *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+				refs = append(refs, ref.Name)
+				return entry.replicasFunc(ctx, ref)
+			}
+
+			opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithReplicasFunc(replicasFunc))
+			rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, createStagedResourceInfo(nil))
+			g.Expect(rs.scale(context.Background())).To(Succeed())
+			g.Expect(refs).To(Equal([]string{mcmObjectRef.Name}), "the function should be consulted once for the scaled resource")
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleDownShouldNotConsultReplicasFunc(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 2, nil))
+	resInfo := createStagedResourceInfo(nil)
+	resInfo.operation = scaleDown
+	opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithReplicasFunc(func(context.Context, *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+		return 5, nil
+	}))
+
+	rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, resInfo)
+	g.Expect(rs.scale(context.Background())).To(Succeed())
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+}
+
+func TestScaleUpShouldReadReplicasFromHPA(t *testing.T) {
+	recordedReplicas := map[string]string{replicasAnnotationKey: "2"}
+	tests := []struct {
+		name             string
+		hpa              *autoscalingv2.HorizontalPodAutoscaler
+		hpaRef           papi.HPAReference
+		expectedReplicas int32
+	}{
+		{name: "desired replicas of the HPA targeting the resource should be the target", hpa: createTestHPAWithDesiredReplicas("mcm-hpa", mcmObjectRef, 4), expectedReplicas: 4},
+		{name: "desired replicas of the named HPA should be the target", hpa: createTestHPAWithDesiredReplicas("mcm-hpa", mcmObjectRef, 4), hpaRef: papi.HPAReference{Name: "mcm-hpa"}, expectedReplicas: 4},
+		{name: "HPA which has not computed its desired replicas should fall back to the recorded replicas", hpa: createTestHPAWithDesiredReplicas("mcm-hpa", mcmObjectRef, 0), expectedReplicas: 2},
+		{name: "missing HPA should fall back to the recorded replicas", expectedReplicas: 2},
+		{name: "named HPA targeting another resource should fall back to the recorded replicas", hpa: createTestHPAWithDesiredReplicas("kcm-hpa", kcmObjectRef, 4), hpaRef: papi.HPAReference{Name: "kcm-hpa"}, expectedReplicas: 2},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, recordedReplicas))
+			if entry.hpa != nil {
+				g.Expect(cl.Create(context.Background(), entry.hpa)).To(Succeed())
+			}
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.replicasFromHPA = &entry.hpaRef
+
+			opts := buildScalerOptions(withResourceCheckInterval(10 * time.Millisecond))
+			rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(context.Background())).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleDownShouldBeDeferredViaScaleDownAfterAnnotation(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		operation        operation
+		replicas         int32
+		scaleDownAfter   string
+		expectedReplicas int32
+	}{
+		{name: "scale-down should be deferred till a future timestamp", operation: scaleDown, replicas: 2, scaleDownAfter: now.Add(time.Hour).Format(time.RFC3339), expectedReplicas: 2},
+		{name: "scale-down should not be deferred by a past timestamp", operation: scaleDown, replicas: 2, scaleDownAfter: now.Add(-time.Minute).Format(time.RFC3339), expectedReplicas: 0},
+		{name: "scale-down should not be deferred by a timestamp which has just passed", operation: scaleDown, replicas: 2, scaleDownAfter: now.Format(time.RFC3339), expectedReplicas: 0},
+		{name: "scale-down should not be deferred by an invalid timestamp", operation: scaleDown, replicas: 2, scaleDownAfter: "tomorrow", expectedReplicas: 0},
+		{name: "scale-up should not be deferred by a future timestamp", operation: scaleUp, replicas: 0, scaleDownAfter: now.Add(time.Hour).Format(time.RFC3339), expectedReplicas: 2},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			deployment := createFakeScalesTestDeployment(mcmObjectRef.Name, entry.replicas, map[string]string{ScaleDownAfterAnnotationKey: entry.scaleDownAfter, replicasAnnotationKey: "2"})
+			scales, cl := newFakeScalesGetter(deployment)
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = entry.operation
+
+			opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), withClock(testclock.NewFakeClock(now)))
+			rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(context.Background())).To(Succeed())
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleUpShouldRemoveConfiguredIgnoreScalingAnnotation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	const customKey = "example.com/ignore-scaling"
+	deployment := createFakeScalesTestDeployment(mcmObjectRef.Name, 0, map[string]string{customKey: "true", DefaultIgnoreScalingAnnotationKey: "true", replicasAnnotationKey: "2"})
+	scales, cl := newFakeScalesGetter(deployment)
+
+	opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithRemoveIgnoreScalingAnnotationOnScaleUp(true), WithIgnoreScalingAnnotationKey(customKey))
+	rs := newResourceScaler(cl, scales.Scales(stagedTestNamespace), logr.Discard(), opts, stagedTestNamespace, createStagedResourceInfo(nil))
+	g.Expect(rs.scale(ctx)).To(Succeed())
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 2)
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Annotations).ToNot(HaveKey(customKey))
+	g.Expect(deployment.Annotations).To(HaveKey(DefaultIgnoreScalingAnnotationKey), "only the configured annotation should be removed")
+}
+
+func TestScalingWhenResourceNotFound(t *testing.T) {
+	tests := []struct {
+		name              string
+		op                operation
+		missingResource   string
+		initialReplicas   int32
+		expectedReplicas  map[string]int32
+		expectedErrSubstr string
+	}{
+		{name: "scale up should fail at the level of a missing mandatory resource", op: scaleUp, missingResource: kcmObjectRef.Name,
+			expectedReplicas: map[string]int32{caObjectRef.Name: 1, mcmObjectRef.Name: 0}, expectedErrSubstr: "\"" + kcmObjectRef.Name + "\" not found"},
+		{name: "scale down should fail at the level of a missing mandatory resource", op: scaleDown, missingResource: kcmObjectRef.Name, initialReplicas: 2,
+			expectedReplicas: map[string]int32{mcmObjectRef.Name: 0, caObjectRef.Name: 2}, expectedErrSubstr: "\"" + kcmObjectRef.Name + "\" not found"},
+		{name: "scale up should ignore a missing optional resource", op: scaleUp, missingResource: caObjectRef.Name,
+			expectedReplicas: map[string]int32{mcmObjectRef.Name: 1, kcmObjectRef.Name: 1}},
+		{name: "scale down should ignore a missing optional resource", op: scaleDown, missingResource: caObjectRef.Name, initialReplicas: 2,
+			expectedReplicas: map[string]int32{mcmObjectRef.Name: 0, kcmObjectRef.Name: 0}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			var deployments []*appsv1.Deployment
+			for name := range entry.expectedReplicas {
+				deployments = append(deployments, createFakeScalesTestDeployment(name, entry.initialReplicas, nil))
+			}
+			scales, cl := newFakeScalesGetter(deployments...)
+			s := newFakeScalesScaler(cl, scales, createDepResourceInfoArray(nil))
+
+			scaleFn := s.ScaleUp
+			if entry.op == scaleDown {
+				scaleFn = s.ScaleDown
+			}
+			err := scaleFn(ctx)
+			if entry.expectedErrSubstr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(entry.expectedErrSubstr))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			for name, replicas := range entry.expectedReplicas {
+				expectDeploymentReplicas(g, cl, name, replicas)
+			}
+			g.Expect(scales.updates(stagedTestNamespace, entry.missingResource)).To(BeEmpty())
+		})
+	}
+}
+
+func TestScaleUpShouldFailForInvalidReplicasAnnotation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 0, nil),
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 0, map[string]string{replicasAnnotationKey: "foo"}),
+		createFakeScalesTestDeployment(caObjectRef.Name, 0, nil))
+	s := newFakeScalesScaler(cl, scales, createDepResourceInfoArray(nil))
+
+	err := s.ScaleUp(ctx)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("invalid replicasStr"))
+	expectDeploymentReplicas(g, cl, caObjectRef.Name, 1)
+	expectDeploymentReplicas(g, cl, kcmObjectRef.Name, 0)
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+}
+
+func TestScaleShouldRetryFailedScaleSubresourceUpdates(t *testing.T) {
+	updateErr := apierrors.NewServiceUnavailable("test error")
+	tests := []struct {
+		name             string
+		updateErrs       []error
+		retryInfo        *papi.RetryInfo
+		expectedErr      bool
+		expectedReplicas int32
+	}{
+		{name: "scaling should succeed once a failed update is retried", updateErrs: []error{updateErr, updateErr}, expectedReplicas: 1},
+		{name: "scaling should fail once all attempts have failed", updateErrs: []error{updateErr, updateErr, updateErr}, expectedErr: true},
+		{name: "scaling should only make the configured attempts", updateErrs: []error{updateErr}, retryInfo: &papi.RetryInfo{Attempts: pointer.Int(1)}, expectedErr: true},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, nil))
+			scales.updateErrs = entry.updateErrs
+			depResInfo := createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, pointer.Duration(0), false)
+			depResInfo.RetryInfo = entry.retryInfo
+			s := newFakeScalesScaler(cl, scales, []papi.DependentResourceInfo{depResInfo})
+
+			err := s.ScaleUp(ctx)
+			if entry.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("test error"))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+		})
+	}
+}
+
+func TestScaleShouldNotRetryForbiddenScaleSubresourceUpdates(t *testing.T) {
+	g := NewWithT(t)
+	forbiddenErr := apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, mcmObjectRef.Name, errors.New("test error"))
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, nil))
+	scales.updateErrs = []error{forbiddenErr, forbiddenErr, forbiddenErr}
+	depResInfo := createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, pointer.Duration(0), false)
+	s := newFakeScalesScaler(cl, scales, []papi.DependentResourceInfo{depResInfo})
+
+	err := s.ScaleUp(context.Background())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("forbidden"))
+	g.Expect(scales.updateErrs).To(HaveLen(2), "a forbidden update should not be retried")
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+}
+
+func TestScalerShouldLogWithNamespaceAttached(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	cl := newStagedTestClient(createLevelTimeoutTestDeployment(mcmObjectRef.Name, 1))
+	scalesGetter := scalesGetterFunc(func(namespace string) scalev1.ScaleInterface {
+		return &readyingScaleInterface{client: cl, namespace: namespace, markReady: true}
+	})
+
+	ds := NewScaler(stagedTestNamespace, nil, cl, scalesGetter, logger, WithScaleUpDisabled(true))
+	g.Expect(ds.ScaleUp(ctx)).To(Succeed())
+	g.Expect(logs).To(ContainElement(SatisfyAll(
+		ContainSubstring("Skipping scale up of dependent resources as scale up has been disabled"),
+		ContainSubstring(fmt.Sprintf(`"shootNamespace"="%s"`, stagedTestNamespace)),
+	)))
+}
+
+func TestScalerShouldFallBackToDefaultLogger(t *testing.T) {
+	g := NewWithT(t)
+	cl := newStagedTestClient()
+	scalesGetter := scalesGetterFunc(func(namespace string) scalev1.ScaleInterface {
+		return &readyingScaleInterface{client: cl, namespace: namespace}
+	})
+
+	ds := NewScaler(stagedTestNamespace, nil, cl, scalesGetter, logr.Logger{}, WithScaleUpDisabled(true))
+	g.Expect(ds.(*scaleFlowRunner).logger.GetSink()).ToNot(BeNil())
+	g.Expect(ds.ScaleUp(context.Background())).To(Succeed())
+}
+
+func TestScaleDownShouldReportResourceWhoseReplicasDoNotTerminate(t *testing.T) {
+	tests := []struct {
+		name               string
+		verify             bool
+		statusReplicas     int32
+		expectStuckWarning bool
+	}{
+		{name: "replicas stuck terminating should be reported", verify: true, statusReplicas: 2, expectStuckWarning: true},
+		{name: "terminated replicas should not be reported", verify: true, statusReplicas: 0, expectStuckWarning: false},
+		{name: "replicas stuck terminating should not be reported if the check is disabled", verify: false, statusReplicas: 2, expectStuckWarning: false},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			var logs []string
+			logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+			deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 2)
+			// the pods of the deployment never finish terminating, therefore status.replicas does not converge.
+			deployment.Status.Replicas = entry.statusReplicas
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+			stuckScaleDowns := stuckScaleDownsTotal.WithLabelValues(stagedTestNamespace, mcmObjectRef.Kind, mcmObjectRef.Name)
+			stuckScaleDownsBefore := promtestutil.ToFloat64(stuckScaleDowns)
+
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = scaleDown
+			resInfo.timeout = 200 * time.Millisecond
+			opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithScaleDownTerminationCheck(entry.verify))
+			rs := newResourceScaler(cl, scaler, logger, opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed(), "a scale-down whose replicas do not terminate should not fail")
+			g.Expect(scaler.replicaUpdates).To(Equal([]int32{0}))
+
+			stuckWarning := ContainElement(SatisfyAll(ContainSubstring("might be stuck terminating"), ContainSubstring(`"statusReplicas"=2`)))
+			if entry.expectStuckWarning {
+				g.Expect(logs).To(stuckWarning)
+				g.Expect(promtestutil.ToFloat64(stuckScaleDowns)).To(Equal(stuckScaleDownsBefore + 1))
+			} else {
+				g.Expect(logs).ToNot(stuckWarning)
+				g.Expect(promtestutil.ToFloat64(stuckScaleDowns)).To(Equal(stuckScaleDownsBefore))
+			}
+		})
+	}
+}
+
+// createLeafToRootDependentResourceInfos creates the dependent resources of a chain in which CA depends on MCM, which in turn
+// depends on KCM. They are scaled down from the leaf to the root of the chain and scaled up the other way around.
+func createLeafToRootDependentResourceInfos() []papi.DependentResourceInfo {
+	timeout := 200 * time.Millisecond
+	return []papi.DependentResourceInfo{
+		createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 2, &timeout, nil, false),
+		createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 1, &timeout, nil, false),
+		createTestDeploymentDependentResourceInfo(caObjectRef.Name, 2, 0, &timeout, nil, false),
+	}
+}
+
+func TestAwaitedScaleDownShouldScaleLevelsFromLeafToRoot(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond),
+		WithAwaitScaleDownTermination(true))
+
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(Equal([]string{caObjectRef.Name, mcmObjectRef.Name, kcmObjectRef.Name}))
+	expectDeploymentReplicas(g, cl, caObjectRef.Name, 0)
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+	expectDeploymentReplicas(g, cl, kcmObjectRef.Name, 0)
+}
+
+func TestScalingFlowsShouldObserveFlowDuration(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond))
+	// other tests might have run flows in the same namespace without closing their scaler.
+	s.Close()
+
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(flowDurationSampleCount(g, stagedTestNamespace, ScaleDirectionDown)).To(Equal(uint64(1)))
+	g.Expect(flowDurationSampleCount(g, stagedTestNamespace, ScaleDirectionUp)).To(BeZero())
+
+	g.Expect(s.ScaleUp(context.Background())).To(Succeed())
+	g.Expect(flowDurationSampleCount(g, stagedTestNamespace, ScaleDirectionUp)).To(Equal(uint64(1)))
+
+	stuckScaleDownsTotal.WithLabelValues(stagedTestNamespace, caObjectRef.Kind, caObjectRef.Name).Inc()
+
+	s.Close()
+	g.Expect(flowDurationSampleCount(g, stagedTestNamespace, ScaleDirectionDown)).To(BeZero(), "the flow metrics of the namespace should be removed once the scaler is closed")
+	g.Expect(promtestutil.ToFloat64(stuckScaleDownsTotal.WithLabelValues(stagedTestNamespace, caObjectRef.Kind, caObjectRef.Name))).To(BeZero(), "the stuck scale-downs of the namespace should be removed once the scaler is closed")
+}
+
+// flowDurationSampleCount returns the number of flow durations which have been observed for the namespace and direction.
+func flowDurationSampleCount(g *WithT, namespace string, direction ScaleDirection) uint64 {
+	m := &dto.Metric{}
+	g.Expect(flowDurationSeconds.WithLabelValues(namespace, string(direction)).(prometheus.Histogram).Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestAwaitedScaleDownShouldNotScaleSubsequentLevelsIfResourceDoesNotTerminate(t *testing.T) {
+	tests := []struct {
+		name                     string
+		await                    bool
+		expectedScaleOrder       []string
+		expectScaleDownToSucceed bool
+	}{
+		{name: "subsequent levels should not be scaled down if the scale-down is awaited", await: true, expectedScaleOrder: []string{caObjectRef.Name}},
+		{name: "subsequent levels should be scaled down if the scale-down is not awaited", await: false, expectedScaleOrder: []string{caObjectRef.Name, mcmObjectRef.Name, kcmObjectRef.Name}, expectScaleDownToSucceed: true},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(
+				createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+				createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+				createFakeScalesTestDeployment(caObjectRef.Name, 2, nil))
+			// the pods of CA never finish terminating, e.g. due to a finalizer.
+			scales.terminatingReplicas[types.NamespacedName{Namespace: stagedTestNamespace, Name: caObjectRef.Name}] = 2
+			stuckScaleDowns := stuckScaleDownsTotal.WithLabelValues(stagedTestNamespace, caObjectRef.Kind, caObjectRef.Name)
+			stuckScaleDownsBefore := promtestutil.ToFloat64(stuckScaleDowns)
+			s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+				withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond),
+				WithAwaitScaleDownTermination(entry.await))
+
+			err := s.ScaleDown(context.Background())
+			if entry.expectScaleDownToSucceed {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(promtestutil.ToFloat64(stuckScaleDowns)).To(Equal(stuckScaleDownsBefore))
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(errScaleDownNotTerminated.Error())))
+				g.Expect(promtestutil.ToFloat64(stuckScaleDowns)).To(Equal(stuckScaleDownsBefore + 1))
+			}
+			g.Expect(scales.scaleOrder()).To(Equal(entry.expectedScaleOrder), "a scale-down which has not terminated should not be retried")
+		})
+	}
+}
+
+func TestScaleShouldBeSkippedIfNamespaceIsTerminating(t *testing.T) {
+	tests := []struct {
+		name               string
+		terminating        bool
+		expectedMCMUpdates []int32
+	}{
+		{name: "resources in a terminating namespace should not be scaled", terminating: true},
+		{name: "resources in an active namespace should be scaled", terminating: false, expectedMCMUpdates: []int32{0, 2}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 2, nil))
+			// the finalizer keeps the deleted namespace around with its deletionTimestamp set, as the namespace controller would.
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: stagedTestNamespace, Finalizers: []string{"kubernetes"}}}
+			g.Expect(cl.Create(ctx, ns)).To(Succeed())
+			if entry.terminating {
+				g.Expect(cl.Delete(ctx, ns)).To(Succeed())
+			}
+			s := newFakeScalesScaler(cl, scales, []papi.DependentResourceInfo{createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 0, nil, nil, false)})
+
+			g.Expect(s.ScaleDown(ctx)).To(Succeed())
+			g.Expect(s.ScaleUp(ctx)).To(Succeed())
+			g.Expect(scales.updates(stagedTestNamespace, mcmObjectRef.Name)).To(Equal(entry.expectedMCMUpdates))
+		})
+	}
+}
+
+func TestScaleShouldSkipPausedDeployments(t *testing.T) {
+	tests := []struct {
+		name                   string
+		op                     operation
+		paused                 bool
+		scalePausedDeployments bool
+		replicas               int32
+		expectedReplicaUpdates []int32
+	}{
+		{name: "scale down should skip a paused deployment", op: scaleDown, paused: true, replicas: 2, expectedReplicaUpdates: nil},
+		{name: "scale up should skip a paused deployment", op: scaleUp, paused: true, replicas: 0, expectedReplicaUpdates: nil},
+		{name: "scale down should scale an unpaused deployment", op: scaleDown, paused: false, replicas: 2, expectedReplicaUpdates: []int32{0}},
+		{name: "scale up should scale an unpaused deployment", op: scaleUp, paused: false, replicas: 0, expectedReplicaUpdates: []int32{1}},
+		{name: "scale down should scale a paused deployment if opted in", op: scaleDown, paused: true, scalePausedDeployments: true, replicas: 2, expectedReplicaUpdates: []int32{0}},
+		{name: "scale up should scale a paused deployment if opted in", op: scaleUp, paused: true, scalePausedDeployments: true, replicas: 0, expectedReplicaUpdates: []int32{1}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			var logs []string
+			logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+			deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, entry.replicas)
+			deployment.Spec.Paused = entry.paused
+			cl := newStagedTestClient(deployment)
+			scaler := &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true}
+
+			resInfo := createStagedResourceInfo(nil)
+			resInfo.operation = entry.op
+			opts := buildScalerOptions(withResourceCheckInterval(10*time.Millisecond), WithScalePausedDeployments(entry.scalePausedDeployments))
+			rs := newResourceScaler(cl, scaler, logger, opts, stagedTestNamespace, resInfo)
+			g.Expect(rs.scale(ctx)).To(Succeed())
+			g.Expect(scaler.replicaUpdates).To(Equal(entry.expectedReplicaUpdates))
+			skippedLog := ContainElement(ContainSubstring("Skipping scaling of deployment as it has been paused"))
+			if entry.expectedReplicaUpdates == nil {
+				g.Expect(logs).To(skippedLog)
+				g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+				g.Expect(*deployment.Spec.Replicas).To(Equal(entry.replicas), "replicas of a paused deployment should not be changed")
+			} else {
+				g.Expect(logs).ToNot(skippedLog)
+			}
+		})
+	}
+}
+
+func TestUpdateScaleReplicasShouldRefetchScaleSubresourceOnConflict(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 0)
+	cl := newStagedTestClient(deployment)
+	scaler := &conflictingScaleInterface{
+		readyingScaleInterface: &readyingScaleInterface{client: cl, namespace: stagedTestNamespace, markReady: true},
+		concurrentUpdates:      1,
+	}
+
+	resInfo := createStagedResourceInfo(nil)
+	rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(), stagedTestNamespace, resInfo).(*resScaler)
+	g.Expect(rs.updateScaleReplicas(ctx, 2)).To(Succeed())
+	g.Expect(scaler.conflicts).To(Equal(1), "the first update should have conflicted with the concurrent update")
+	g.Expect(scaler.gets).To(Equal(2), "the scale subresource should have been fetched again after the conflict")
+	g.Expect(scaler.replicaUpdates).To(Equal([]int32{2}))
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+}
+
+func TestUpdateScaleReplicasShouldBoundEveryCallByScaleCallTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		hangGet    bool
+		hangUpdate bool
+	}{
+		{name: "hanging get of the scale subresource should time out", hangGet: true},
+		{name: "hanging update of the scale subresource should time out", hangUpdate: true},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			deployment := createLevelTimeoutTestDeployment(mcmObjectRef.Name, 0)
+			cl := newStagedTestClient(deployment)
+			scaler := &hangingScaleInterface{
+				readyingScaleInterface: &readyingScaleInterface{client: cl, namespace: stagedTestNamespace},
+				hangGet:                entry.hangGet,
+				hangUpdate:             entry.hangUpdate,
+			}
+
+			resInfo := createStagedResourceInfo(nil)
+			// the resource timeout is long enough that only the scale call timeout can end a hung call within the test.
+			resInfo.timeout = time.Minute
+			rs := newResourceScaler(cl, scaler, logr.Discard(), buildScalerOptions(WithScaleCallTimeout(50*time.Millisecond)), stagedTestNamespace, resInfo).(*resScaler)
+			start := time.Now()
+			err := rs.updateScaleReplicas(context.Background(), 2)
+			g.Expect(err).To(MatchError(context.DeadlineExceeded))
+			g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			g.Expect(scaler.replicaUpdates).To(BeEmpty())
+		})
+	}
+}
+
+// hangingScaleInterface is a readyingScaleInterface whose get or update of the scale subresource hangs till its context is done.
+type hangingScaleInterface struct {
+	*readyingScaleInterface
+	hangGet    bool
+	hangUpdate bool
+}
+
+func (s *hangingScaleInterface) Get(ctx context.Context, gr schema.GroupResource, name string, opts metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	if s.hangGet {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.readyingScaleInterface.Get(ctx, gr, name, opts)
+}
+
+func (s *hangingScaleInterface) Update(ctx context.Context, gr schema.GroupResource, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	if s.hangUpdate {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.readyingScaleInterface.Update(ctx, gr, scale, opts)
+}
+
+// conflictingScaleInterface is a readyingScaleInterface which rejects an update of the scale subresource with a conflict
+// if its resourceVersion is stale. The Deployment is updated concurrently before the first concurrentUpdates updates.
+type conflictingScaleInterface struct {
+	*readyingScaleInterface
+	concurrentUpdates int
+	gets              int
+	conflicts         int
+}
+
+func (s *conflictingScaleInterface) Get(ctx context.Context, gr schema.GroupResource, name string, opts metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	s.gets++
+	return s.readyingScaleInterface.Get(ctx, gr, name, opts)
+}
+
+func (s *conflictingScaleInterface) Update(ctx context.Context, gr schema.GroupResource, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	deployment := &appsv1.Deployment{}
+	if err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: scale.Name}, deployment); err != nil {
+		return nil, err
+	}
+	if s.concurrentUpdates > 0 {
+		s.concurrentUpdates--
+		deployment.Labels = map[string]string{"updated-by": "another-actor"}
+		if err := s.client.Update(ctx, deployment); err != nil {
+			return nil, err
+		}
+	}
+	if scale.ResourceVersion != deployment.ResourceVersion {
+		s.conflicts++
+		return nil, apierrors.NewConflict(gr, scale.Name, fmt.Errorf("the object has been modified"))
+	}
+	return s.readyingScaleInterface.Update(ctx, gr, scale, opts)
+}
+
+func (f scalesGetterFunc) Scales(namespace string) scalev1.ScaleInterface {
+	return f(namespace)
+}
+
+// readyingScaleInterface is a scalev1.ScaleInterface which scales the Deployments known to the client. If markReady
+// is true then it also sets the ready replicas of a Deployment to the replicas it has been scaled to.
+type readyingScaleInterface struct {
+	scalev1.ScaleInterface
+	client         client.Client
+	namespace      string
+	markReady      bool
+	replicaUpdates []int32
+	// fieldManagers records the field manager of every update and patch of the scale subresource.
+	fieldManagers []string
+	// applyErr if set is returned for every server-side apply of the scale subresource.
+	applyErr     error
+	appliedScale []string
+	// scaleSubresourceErr if set is returned for every get and update of the scale subresource.
+	scaleSubresourceErr error
+}
+
+func (s *readyingScaleInterface) Get(ctx context.Context, _ schema.GroupResource, name string, _ metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	if s.scaleSubresourceErr != nil {
+		return nil, s.scaleSubresourceErr
+	}
+	deployment := &appsv1.Deployment{}
+	if err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: name}, deployment); err != nil {
+		return nil, err
+	}
+	return &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace, ResourceVersion: deployment.ResourceVersion},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: *deployment.Spec.Replicas},
+	}, nil
+}
+
+func (s *readyingScaleInterface) Update(ctx context.Context, _ schema.GroupResource, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	s.fieldManagers = append(s.fieldManagers, opts.FieldManager)
+	if s.scaleSubresourceErr != nil {
+		return nil, s.scaleSubresourceErr
+	}
+	if err := s.setReplicas(ctx, scale.Name, scale.Spec.Replicas); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+func (s *readyingScaleInterface) Patch(ctx context.Context, _ schema.GroupVersionResource, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*autoscalingv1.Scale, error) {
+	s.fieldManagers = append(s.fieldManagers, opts.FieldManager)
+	if s.applyErr != nil {
+		return nil, s.applyErr
+	}
+	if pt != types.ApplyPatchType || opts.Force == nil || !*opts.Force {
+		return nil, fmt.Errorf("expected a forced server-side apply, got patch type %s", pt)
+	}
+	s.appliedScale = append(s.appliedScale, string(data))
+	scale := &autoscalingv1.Scale{}
+	if err := json.Unmarshal(data, scale); err != nil {
+		return nil, err
+	}
+	if err := s.setReplicas(ctx, name, scale.Spec.Replicas); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+func (s *readyingScaleInterface) setReplicas(ctx context.Context, name string, replicas int32) error {
+	deployment := &appsv1.Deployment{}
+	if err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: name}, deployment); err != nil {
+		return err
+	}
+	deployment.Spec.Replicas = pointer.Int32(replicas)
+	if err := s.client.Update(ctx, deployment); err != nil {
+		return err
+	}
+	if s.markReady {
+		deployment.Status.ReadyReplicas = replicas
+		deployment.Status.ObservedGeneration = deployment.Generation
+		if err := s.client.Status().Update(ctx, deployment); err != nil {
+			return err
+		}
+	}
+	s.replicaUpdates = append(s.replicaUpdates, replicas)
+	return nil
+}
+
+func newStagedTestClient(objects ...client.Object) client.Client {
+	restMapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind(deploymentKind), meta.RESTScopeNamespace)
+	return fake.NewClientBuilder().WithRESTMapper(restMapper).WithObjects(objects...).Build()
+}
+
+func createStagedTestDeployment(replicasAnnotation string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        mcmObjectRef.Name,
+			Namespace:   stagedTestNamespace,
+			Annotations: map[string]string{replicasAnnotationKey: replicasAnnotation},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(0)},
+	}
+}
+
+func createLevelTimeoutTestDeployment(name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: stagedTestNamespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(replicas)},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: replicas},
+	}
+}
+
+func createStagedResourceInfo(steps []int32) scalableResourceInfo {
+	ref := mcmObjectRef
+	return scalableResourceInfo{
+		ref:       &ref,
+		timeout:   time.Second,
+		operation: scaleUp,
+		steps:     steps,
+	}
+}
+
+func createTestHPA(name string, targetRef autoscalingv1.CrossVersionObjectReference, minReplicas, maxReplicas int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: stagedTestNamespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: targetRef.Kind, Name: targetRef.Name, APIVersion: targetRef.APIVersion},
+			MinReplicas:    pointer.Int32(minReplicas),
+			MaxReplicas:    maxReplicas,
+		},
+	}
+}
+
+func createTestHPAWithDesiredReplicas(name string, targetRef autoscalingv1.CrossVersionObjectReference, desiredReplicas int32) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa := createTestHPA(name, targetRef, 1, 5)
+	hpa.Status.DesiredReplicas = desiredReplicas
+	return hpa
+}
+
+func createFakeScalesTestDeployment(name string, replicas int32, annotations map[string]string) *appsv1.Deployment {
+	deployment := createLevelTimeoutTestDeployment(name, replicas)
+	deployment.Annotations = annotations
+	return deployment
+}
+
+func TestScaleDownShouldHonourInitialDelaysMeasuredByClock(t *testing.T) {
+	g := NewWithT(t)
+	initialDelay := time.Hour
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	depResInfos := []papi.DependentResourceInfo{
+		createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 0, 1, nil, &initialDelay, false),
+		createTestDeploymentDependentResourceInfo(caObjectRef.Name, 1, 0, nil, &initialDelay, false),
+	}
+	fakeClock := testclock.NewFakeClock(time.Now())
+	s := NewScaler(stagedTestNamespace, depResInfos, cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond), withClock(fakeClock))
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ScaleDown(context.Background())
+	}()
+
+	g.Eventually(fakeClock.HasWaiters).Should(BeTrue(), "the initial delay of the resource at the first level should be awaited")
+	g.Consistently(scales.scaleOrder, 50*time.Millisecond).Should(BeEmpty(), "no resource should be scaled before the clock has advanced by the initial delay")
+	fakeClock.Step(initialDelay)
+	g.Eventually(scales.scaleOrder).Should(Equal([]string{caObjectRef.Name}))
+	g.Consistently(scales.scaleOrder, 50*time.Millisecond).Should(Equal([]string{caObjectRef.Name}), "the resource at the next level should await its own initial delay")
+	// the clock is only advanced once the initial delay of the resource at the next level is awaited.
+	g.Eventually(func() []string {
+		if fakeClock.HasWaiters() {
+			fakeClock.Step(initialDelay)
+		}
+		return scales.scaleOrder()
+	}).Should(Equal([]string{caObjectRef.Name, mcmObjectRef.Name}))
+	g.Eventually(done).Should(Receive(BeNil()))
+}
+
+func TestAwaitedScaleDownShouldTimeOutOnceClockHasAdvancedByResourceTimeout(t *testing.T) {
+	g := NewWithT(t)
+	timeout, initialDelay := time.Hour, time.Duration(0)
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(caObjectRef.Name, 2, nil))
+	// the pods of CA never finish terminating, e.g. due to a finalizer.
+	scales.terminatingReplicas[types.NamespacedName{Namespace: stagedTestNamespace, Name: caObjectRef.Name}] = 2
+	depResInfos := []papi.DependentResourceInfo{createTestDeploymentDependentResourceInfo(caObjectRef.Name, 0, 0, &timeout, &initialDelay, false)}
+	fakeClock := testclock.NewFakeClock(time.Now())
+	s := NewScaler(stagedTestNamespace, depResInfos, cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(time.Minute), withScaleResourceBackOff(10*time.Millisecond),
+		WithAwaitScaleDownTermination(true), withClock(fakeClock))
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ScaleDown(context.Background())
+	}()
+
+	g.Eventually(scales.scaleOrder).Should(Equal([]string{caObjectRef.Name}))
+	g.Consistently(done, 50*time.Millisecond).ShouldNot(Receive(), "the scale-down should not time out before the clock has advanced by the timeout of the resource")
+	g.Eventually(func() chan error {
+		fakeClock.Step(timeout)
+		return done
+	}).Should(Receive(MatchError(ContainSubstring(errScaleDownNotTerminated.Error()))))
+}
+
+func TestScaleResourceShouldOnlyScaleNamedResource(t *testing.T) {
+	tests := []struct {
+		name             string
+		direction        ScaleDirection
+		initialReplicas  int32
+		annotations      map[string]string
+		expectedReplicas int32
+	}{
+		{name: "scale down should scale the named resource down to zero", direction: ScaleDirectionDown, initialReplicas: 2, expectedReplicas: 0},
+		{name: "scale up should restore the recorded replicas of the named resource", direction: ScaleDirectionUp, initialReplicas: 0, annotations: map[string]string{replicasAnnotationKey: "3"}, expectedReplicas: 3},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			scales, cl := newFakeScalesGetter(
+				createFakeScalesTestDeployment(kcmObjectRef.Name, entry.initialReplicas, entry.annotations),
+				createFakeScalesTestDeployment(mcmObjectRef.Name, entry.initialReplicas, entry.annotations),
+				createFakeScalesTestDeployment(caObjectRef.Name, entry.initialReplicas, entry.annotations))
+			s := newFakeScalesScaler(cl, scales, createLeafToRootDependentResourceInfos())
+
+			g.Expect(s.ScaleResource(context.Background(), mcmObjectRef.Name, entry.direction)).To(Succeed())
+			g.Expect(scales.scaleOrder()).To(Equal([]string{mcmObjectRef.Name}), "only the named resource should be scaled")
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+			expectDeploymentReplicas(g, cl, kcmObjectRef.Name, entry.initialReplicas)
+			expectDeploymentReplicas(g, cl, caObjectRef.Name, entry.initialReplicas)
+		})
+	}
+}
+
+func TestScaleResourceShouldFailForUnknownResourceOrDirection(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil))
+	s := newFakeScalesScaler(cl, scales, createLeafToRootDependentResourceInfos())
+
+	err := s.ScaleResource(context.Background(), "etcd-main", ScaleDirectionDown)
+	g.Expect(err).To(MatchError(ErrResourceNotConfigured))
+	g.Expect(err).To(MatchError(ContainSubstring("etcd-main")))
+	g.Expect(s.ScaleResource(context.Background(), mcmObjectRef.Name, ScaleDirection("sideways"))).To(MatchError(ContainSubstring("invalid scale direction")))
+	g.Expect(scales.scaleOrder()).To(BeEmpty())
+}
+
+func TestScaleResourceShouldSkipScaleUpIfDisabled(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(mcmObjectRef.Name, 0, map[string]string{replicasAnnotationKey: "2"}))
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond), WithScaleUpDisabled(true))
+
+	g.Expect(s.ScaleResource(context.Background(), mcmObjectRef.Name, ScaleDirectionUp)).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(BeEmpty())
+}
+
+func TestScalingShouldBeSuppressedWhileGloballyPaused(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	pause := NewScalingPause()
+	pause.SetPaused(true)
+	s := NewScaler(stagedTestNamespace, createLeafToRootDependentResourceInfos(), cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond), WithScalingPause(pause))
+
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(s.ScaleResource(context.Background(), mcmObjectRef.Name, ScaleDirectionDown)).To(Succeed())
+	g.Expect(s.ScaleUp(context.Background())).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(BeEmpty(), "no resource should be scaled while scaling is paused")
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 1)
+
+	pause.SetPaused(false)
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(Equal([]string{caObjectRef.Name, mcmObjectRef.Name, kcmObjectRef.Name}))
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+}
+
+func TestScaleShouldIncludeDeploymentsDiscoveredViaSelector(t *testing.T) {
+	const scaleLabelKey = "dependency-watchdog.gardener.cloud/scale"
+	tests := []struct {
+		name               string
+		direction          ScaleDirection
+		initialReplicas    int32
+		annotations        map[string]string
+		expectedReplicas   int32
+		expectedScaleOrder []string
+	}{
+		{name: "scale down should scale the labeled deployments", direction: ScaleDirectionDown, initialReplicas: 1, expectedReplicas: 0},
+		{name: "scale up should scale the labeled deployments", direction: ScaleDirectionUp, initialReplicas: 0, annotations: map[string]string{replicasAnnotationKey: "1"}, expectedReplicas: 1},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			labeled := map[string]string{scaleLabelKey: "true"}
+			ca := createFakeScalesTestDeployment(caObjectRef.Name, entry.initialReplicas, entry.annotations)
+			ca.Labels = labeled
+			mcm := createFakeScalesTestDeployment(mcmObjectRef.Name, entry.initialReplicas, entry.annotations)
+			mcm.Labels = labeled
+			kcm := createFakeScalesTestDeployment(kcmObjectRef.Name, entry.initialReplicas, entry.annotations)
+			kcm.Labels = map[string]string{scaleLabelKey: "false"}
+			scales, cl := newFakeScalesGetter(ca, mcm, kcm)
+			// MCM is configured at level 1, it keeps its configuration although it is labeled as well.
+			depResInfos := []papi.DependentResourceInfo{createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 1, 1, nil, nil, false)}
+			s := NewScaler(stagedTestNamespace, depResInfos, cl, scales, logr.Discard(),
+				withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond),
+				WithDependentResourceSelector(&metav1.LabelSelector{MatchLabels: labeled}))
+
+			if entry.direction == ScaleDirectionUp {
+				g.Expect(s.ScaleUp(ctx)).To(Succeed())
+			} else {
+				g.Expect(s.ScaleDown(ctx)).To(Succeed())
+			}
+			g.Expect(scales.scaleOrder()).To(Equal([]string{caObjectRef.Name, mcmObjectRef.Name}), "the discovered deployment should be scaled at level 0")
+			expectDeploymentReplicas(g, cl, caObjectRef.Name, entry.expectedReplicas)
+			expectDeploymentReplicas(g, cl, mcmObjectRef.Name, entry.expectedReplicas)
+			expectDeploymentReplicas(g, cl, kcmObjectRef.Name, entry.initialReplicas)
+		})
+	}
+}
+
+func TestScaleShouldDiscoverDeploymentsLabeledAfterScalerCreation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	labeled := map[string]string{"dependency-watchdog.gardener.cloud/scale": "true"}
+	scales, cl := newFakeScalesGetter(createFakeScalesTestDeployment(caObjectRef.Name, 1, nil))
+	s := NewScaler(stagedTestNamespace, nil, cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond),
+		WithDependentResourceSelector(&metav1.LabelSelector{MatchLabels: labeled}))
+
+	g.Expect(s.ScaleDown(ctx)).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(BeEmpty(), "an unlabeled deployment should not be scaled")
+
+	deployment := &appsv1.Deployment{}
+	g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: stagedTestNamespace, Name: caObjectRef.Name}, deployment)).To(Succeed())
+	deployment.Labels = labeled
+	g.Expect(cl.Update(ctx, deployment)).To(Succeed())
+	g.Expect(s.ScaleDown(ctx)).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(Equal([]string{caObjectRef.Name}), "the deployment should be discovered once it has been labeled")
+	g.Expect(s.ScaleResource(ctx, caObjectRef.Name, ScaleDirectionUp)).To(Succeed())
+	expectDeploymentReplicas(g, cl, caObjectRef.Name, 1)
+}
+
+func TestScaleDownShouldScaleResourceResolvedViaSelector(t *testing.T) {
+	const renamedKCM = "kube-controller-manager-migrated"
+	kcmSelector := map[string]string{"app": "kubernetes", "role": "controller-manager"}
+	tests := []struct {
+		name string
+		// labeledDeployments are the deployments which are matched by the selector of KCM.
+		labeledDeployments   []string
+		unlabeledDeployments []string
+		expectedScaleOrder   []string
+		expectedErr          string
+	}{
+		{name: "a recreated resource should be scaled under its new name", labeledDeployments: []string{renamedKCM}, expectedScaleOrder: []string{renamedKCM}},
+		{name: "the configured name should take precedence over other matches", labeledDeployments: []string{kcmObjectRef.Name, renamedKCM}, expectedScaleOrder: []string{kcmObjectRef.Name}},
+		{name: "the configured name should be used if nothing matches", unlabeledDeployments: []string{kcmObjectRef.Name}, expectedScaleOrder: []string{kcmObjectRef.Name}},
+		{name: "an ambiguous selector should fail the scaling", labeledDeployments: []string{renamedKCM, renamedKCM + "-2"}, expectedErr: "is ambiguous"},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var deployments []*appsv1.Deployment
+			for _, name := range entry.labeledDeployments {
+				deployment := createFakeScalesTestDeployment(name, 1, nil)
+				deployment.Labels = kcmSelector
+				deployments = append(deployments, deployment)
+			}
+			for _, name := range entry.unlabeledDeployments {
+				deployments = append(deployments, createFakeScalesTestDeployment(name, 1, nil))
+			}
+			scales, cl := newFakeScalesGetter(deployments...)
+			kcmResInfo := createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 0, 0, nil, nil, false)
+			kcmResInfo.Selector = &metav1.LabelSelector{MatchLabels: kcmSelector}
+			s := newFakeScalesScaler(cl, scales, []papi.DependentResourceInfo{kcmResInfo})
+
+			err := s.ScaleDown(context.Background())
+			if entry.expectedErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(entry.expectedErr)))
+				g.Expect(scales.scaleOrder()).To(BeEmpty())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(scales.scaleOrder()).To(Equal(entry.expectedScaleOrder))
+			expectDeploymentReplicas(g, cl, entry.expectedScaleOrder[0], 0)
+		})
+	}
+}
+
+// newFakeScalesScaler creates a Scaler for the stagedTestNamespace which scales via the given fakeScalesGetter.
+func newFakeScalesScaler(cl client.Client, scales *fakeScalesGetter, depResInfos []papi.DependentResourceInfo) Scaler {
+	return NewScaler(stagedTestNamespace, depResInfos, cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond))
+}
+
+func expectDeploymentReplicas(g *WithT, cl client.Client, name string, expectedReplicas int32) {
+	deployment := &appsv1.Deployment{}
+	g.Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: stagedTestNamespace, Name: name}, deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(Equal(expectedReplicas), "unexpected spec.replicas of deployment %s", name)
+	g.Expect(deployment.Status.ReadyReplicas).To(Equal(expectedReplicas), "unexpected status.readyReplicas of deployment %s", name)
+}
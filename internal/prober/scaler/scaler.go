@@ -6,15 +6,19 @@ package scaler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/gardener/gardener/pkg/utils/flow"
 	"github.com/go-logr/logr"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	scalev1 "k8s.io/client-go/scale"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // operation denotes either a scale up or scale down action initiated by DWD.
@@ -29,45 +33,200 @@ const (
 
 //go:generate stringer -type=operation -linecomment
 
+// ScaleDirection denotes the direction in which a single dependent resource is scaled, see Scaler.ScaleResource.
+type ScaleDirection string
+
+const (
+	// ScaleDirectionUp scales a dependent resource up like a scale-up flow does.
+	ScaleDirectionUp ScaleDirection = "up"
+	// ScaleDirectionDown scales a dependent resource down like a scale-down flow does.
+	ScaleDirectionDown ScaleDirection = "down"
+)
+
+// ErrResourceNotConfigured is returned by Scaler.ScaleResource if there is no dependent resource of the given name.
+var ErrResourceNotConfigured = errors.New("resource is not a configured dependent resource")
+
 // Scaler is a facade to provide scaling operations for kubernetes scalable resources.
 type Scaler interface {
 	// ScaleUp restores the replicas of a kubernetes resource prior to scale down.
 	ScaleUp(ctx context.Context) error
 	// ScaleDown scales down a kubernetes scalable resource to 0.
 	ScaleDown(ctx context.Context) error
+	// ScaleResource scales only the dependent resource of the given name in the given direction, without running the flow
+	// of the other dependent resources. The resource is scaled and waited on as it would be within the flow.
+	ScaleResource(ctx context.Context, refName string, direction ScaleDirection) error
+	// FlowGraphs returns the graphs of the scale-up and scale-down flows. If a dependent resource selector is configured, the
+	// graphs include the dependent resources which are discovered right now, as the flows run by ScaleUp and ScaleDown do.
+	FlowGraphs(ctx context.Context) (FlowGraphs, error)
+	// Close releases any resources held by the Scaler. It is called once the prober using the Scaler has been unregistered,
+	// no scaling operation should be triggered afterwards.
+	Close()
 }
 
-// NewScaler creates an instance of Scaler.
+// NewScaler creates an instance of Scaler. All logs of the Scaler, including those of its scaling flows, are written to the
+// given logger with the namespace attached. If the logger has not been set up then the controller-runtime logger is used.
 func NewScaler(namespace string, dependentResourceInfos []papi.DependentResourceInfo, client client.Client, scalerGetter scalev1.ScalesGetter, logger logr.Logger, options ...scalerOption) Scaler {
+	if logger.GetSink() == nil {
+		logger = logf.Log.WithName("scaler")
+	}
+	logger = logger.WithValues("shootNamespace", namespace)
 	opts := buildScalerOptions(options...)
+	client = util.NewReadRateLimitedClient(client, opts.readRateLimiter)
 
-	fc := newFlowCreator(client, scalerGetter.Scales(namespace), logger, opts, dependentResourceInfos)
-	scaleUpFlow := fc.createFlow(fmt.Sprintf("scale-up-%s", namespace), namespace, scaleUp)
+	scaleInterface := scalerGetter.Scales(namespace)
+	fc := newFlowCreator(client, scaleInterface, logger, opts, dependentResourceInfos)
+	scaleUpFlow := fc.createFlow(flowName(scaleUp, namespace), namespace, scaleUp)
 	logger.V(1).Info("Created scaleUpFlow", "flowStepInfos", scaleUpFlow.flowStepInfos)
-	scaleDownFlow := fc.createFlow(fmt.Sprintf("scale-down-%s", namespace), namespace, scaleDown)
+	scaleDownFlow := fc.createFlow(flowName(scaleDown, namespace), namespace, scaleDown)
 	logger.V(1).Info("Created scaleDownFlow", "flowStepInfos", scaleDownFlow.flowStepInfos)
 
 	return &scaleFlowRunner{
-		namespace:     namespace,
-		options:       opts,
-		scaleUpFlow:   scaleUpFlow.flow,
-		scaleDownFlow: scaleDownFlow.flow,
+		namespace:              namespace,
+		logger:                 logger,
+		options:                opts,
+		client:                 client,
+		scaler:                 scaleInterface,
+		dependentResourceInfos: dependentResourceInfos,
+		flowCreator:            fc,
+		scaleUpFlow:            scaleUpFlow,
+		scaleDownFlow:          scaleDownFlow,
 	}
 }
 
 type scaleFlowRunner struct {
-	namespace     string
-	scaleDownFlow *flow.Flow
-	scaleUpFlow   *flow.Flow
-	options       *scalerOptions
+	namespace              string
+	logger                 logr.Logger
+	client                 client.Client
+	scaler                 scalev1.ScaleInterface
+	dependentResourceInfos []papi.DependentResourceInfo
+	flowCreator            flowCreator
+	scaleDownFlow          *scaleFlow
+	scaleUpFlow            *scaleFlow
+	options                *scalerOptions
 }
 
 func (ds *scaleFlowRunner) ScaleDown(ctx context.Context) error {
-	return ds.scaleDownFlow.Run(ctx, flow.Opts{})
+	if ds.options.scalingPause.IsPaused() {
+		ds.logger.Info("Skipping scale down of dependent resources as scaling has been paused globally")
+		return nil
+	}
+	f, err := ds.getFlow(ctx, scaleDown)
+	if err != nil {
+		return err
+	}
+	return ds.runFlow(ctx, f, ScaleDirectionDown)
 }
 
 func (ds *scaleFlowRunner) ScaleUp(ctx context.Context) error {
-	return ds.scaleUpFlow.Run(ctx, flow.Opts{})
+	if ds.options.scalingPause.IsPaused() {
+		ds.logger.Info("Skipping scale up of dependent resources as scaling has been paused globally")
+		return nil
+	}
+	if ds.options.scaleUpDisabled {
+		ds.logger.Info("Skipping scale up of dependent resources as scale up has been disabled")
+		return nil
+	}
+	f, err := ds.getFlow(ctx, scaleUp)
+	if err != nil {
+		return err
+	}
+	return ds.runFlow(ctx, f, ScaleDirectionUp)
+}
+
+// runFlow runs the flow and observes its duration, irrespective of whether it succeeds.
+func (ds *scaleFlowRunner) runFlow(ctx context.Context, f *flow.Flow, direction ScaleDirection) error {
+	start := ds.options.clock.Now()
+	defer func() {
+		flowDurationSeconds.WithLabelValues(ds.namespace, string(direction)).Observe(ds.options.clock.Since(start).Seconds())
+	}()
+	return f.Run(ctx, flow.Opts{})
+}
+
+func (ds *scaleFlowRunner) ScaleResource(ctx context.Context, refName string, direction ScaleDirection) error {
+	var opType operation
+	switch direction {
+	case ScaleDirectionUp:
+		opType = scaleUp
+	case ScaleDirectionDown:
+		opType = scaleDown
+	default:
+		return fmt.Errorf("invalid scale direction %q, must be one of %q, %q", direction, ScaleDirectionUp, ScaleDirectionDown)
+	}
+	if ds.options.scalingPause.IsPaused() {
+		ds.logger.Info("Skipping scaling of dependent resource as scaling has been paused globally", "name", refName, "direction", direction)
+		return nil
+	}
+	if opType == scaleUp && ds.options.scaleUpDisabled {
+		ds.logger.Info("Skipping scale up of dependent resource as scale up has been disabled", "name", refName)
+		return nil
+	}
+	fc, err := ds.currentFlowCreator(ctx)
+	if err != nil {
+		return err
+	}
+	taskFn, err := fc.createResourceTaskFn(ds.namespace, refName, opType)
+	if err != nil {
+		return err
+	}
+	return taskFn(ctx)
+}
+
+// getFlow returns the flow for the operation, see currentFlowCreator.
+func (ds *scaleFlowRunner) getFlow(ctx context.Context, opType operation) (*flow.Flow, error) {
+	fc, err := ds.currentFlowCreator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ds.scaleFlowOf(fc, opType).flow, nil
+}
+
+func (ds *scaleFlowRunner) FlowGraphs(ctx context.Context) (FlowGraphs, error) {
+	fc, err := ds.currentFlowCreator(ctx)
+	if err != nil {
+		return FlowGraphs{}, err
+	}
+	return FlowGraphs{ScaleUp: ds.scaleFlowOf(fc, scaleUp).graph(), ScaleDown: ds.scaleFlowOf(fc, scaleDown).graph()}, nil
+}
+
+// scaleFlowOf returns the flow which the flowCreator creates for the operation. The flows of the configured dependent resources
+// are created only once, those of any other flowCreator are created on every call.
+func (ds *scaleFlowRunner) scaleFlowOf(fc flowCreator, opType operation) *scaleFlow {
+	if fc == ds.flowCreator {
+		if opType == scaleUp {
+			return ds.scaleUpFlow
+		}
+		return ds.scaleDownFlow
+	}
+	sf := fc.createFlow(flowName(opType, ds.namespace), ds.namespace, opType)
+	ds.logger.V(1).Info("Created flow for the current dependent resources", "operation", opType, "flowStepInfos", sf.flowStepInfos)
+	return sf
+}
+
+// currentFlowCreator returns the flowCreator for the configured dependent resources along with those which are discovered
+// via the dependent resource selector, if one is configured. The levels of the dependent resources are overridden by their
+// ScaleLevelAnnotationKey annotations. If neither applies, the flowCreator of the configured dependent resources is returned.
+func (ds *scaleFlowRunner) currentFlowCreator(ctx context.Context) (flowCreator, error) {
+	resourceInfos := ds.dependentResourceInfos
+	if ds.options.dependentResourceSelector != nil {
+		var err error
+		if resourceInfos, err = discoverDependentResourceInfos(ctx, ds.client, ds.namespace, ds.options.dependentResourceSelector, ds.dependentResourceInfos); err != nil {
+			return nil, err
+		}
+	}
+	resourceInfos, overridden := applyScaleLevelOverrides(ctx, ds.client, ds.namespace, resourceInfos, ds.logger)
+	if !overridden && ds.options.dependentResourceSelector == nil {
+		return ds.flowCreator, nil
+	}
+	return newFlowCreator(ds.client, ds.scaler, ds.logger, ds.options, resourceInfos), nil
+}
+
+func flowName(opType operation, namespace string) string {
+	return fmt.Sprintf("%s-%s", opType, namespace)
+}
+
+// Close removes the metrics of the namespace. The scaling flows themselves do not hold any resources which outlive a single run.
+func (ds *scaleFlowRunner) Close() {
+	deleteMetrics(ds.namespace)
 }
 
 // getMinTargetReplicas gets the minimum target replicas based on the operation.
@@ -95,12 +254,11 @@ func (i operation) shouldScaleReplicas(currentReplicas int32) bool {
 // minTargetReplicasReached checks if scaling of the resource is complete based on the current and minimum target replica count.
 // This is used during the scale up for a resource which was previously scaled down by DWD. If the decision is to scale the resource
 // then this predicate checks if the wait for scaling a resource is complete.
-func (i operation) minTargetReplicasReached(currentReplicas int32) bool {
-	minTargetReplicas := i.getMinTargetReplicas()
+func (i operation) minTargetReplicasReached(currentReplicas, minTargetReplicas int32) bool {
 	if i == scaleUp {
 		return currentReplicas >= minTargetReplicas
 	} else {
-		return currentReplicas == minTargetReplicas
+		return currentReplicas <= minTargetReplicas
 	}
 }
 
@@ -112,6 +270,23 @@ type scalableResourceInfo struct {
 	initialDelay time.Duration
 	timeout      time.Duration
 	operation    operation
+	dependsOn    []autoscalingv1.CrossVersionObjectReference
+	steps        []int32
+	// replicaDelta is the change in spec replicas of a scale-down. If it is nil then the resource is scaled down to zero.
+	replicaDelta *int32
+	// restoreRecordedReplicas determines if a scale-up also restores a resource which still has replicas but fewer than were
+	// recorded prior to its scale-down. This is the case for resources which are scaled down by a replica delta.
+	restoreRecordedReplicas bool
+	// retryInfo optionally overrides the number of attempts, the backoff and the retry of a resource which has not been found.
+	retryInfo *papi.RetryInfo
+	// hpa if set scales the HorizontalPodAutoscaler which targets the resource along with it.
+	hpa *papi.HPAScaleInfo
+	// replicas if set are the replicas of a scale-up if the replicas prior to the scale-down have not been recorded.
+	replicas *int32
+	// replicasFromHPA if set refers to the HorizontalPodAutoscaler whose desired replicas are the replicas of a scale-up.
+	replicasFromHPA *papi.HPAReference
+	// selector if set resolves ref to the resource of the same kind which is matched by it, see resolveResourceRef.
+	selector *metav1.LabelSelector
 }
 
 func (r scalableResourceInfo) String() string {
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	testutil "github.com/gardener/dependency-watchdog/internal/test"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	widgetTestNamespace = "shoot--test--widget"
+	widgetTestName      = "widget"
+)
+
+var widgetGVK = schema.GroupVersionKind{Group: "example.gardener.cloud", Version: "v1alpha1", Kind: "Widget"}
+
+// TestScaleCustomResourceOfArbitraryGroup scales a namespaced custom resource, whose CustomResourceDefinition in testdata
+// has a scale subresource, via the scale client of the prober, just like the Deployments of a shoot control plane.
+func TestScaleCustomResourceOfArbitraryGroup(t *testing.T) {
+	g := NewWithT(t)
+	controllerTestEnv, err := testutil.CreateControllerTestEnv(clientgoscheme.Scheme, []string{"testdata"}, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer controllerTestEnv.Delete()
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	cl := controllerTestEnv.GetClient()
+	testutil.CreateTestNamespace(ctx, g, cl, widgetTestNamespace)
+	createWidget(ctx, g, cl, 2)
+	go syncWidgetStatus(ctx, cl)
+
+	scalesGetter, err := util.CreateScalesGetter(controllerTestEnv.GetConfig())
+	g.Expect(err).ToNot(HaveOccurred())
+	ref := &autoscalingv1.CrossVersionObjectReference{Kind: widgetGVK.Kind, Name: widgetTestName, APIVersion: widgetGVK.GroupVersion().String()}
+	gr, scale, err := util.GetScaleResource(ctx, cl, scalesGetter.Scales(widgetTestNamespace), logr.Discard(), ref, 10*time.Second)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*gr).To(Equal(schema.GroupResource{Group: widgetGVK.Group, Resource: "widgets"}))
+	g.Expect(scale.Spec.Replicas).To(Equal(int32(2)))
+
+	scaleInfo := &papi.ScaleInfo{Level: 0, InitialDelay: &metav1.Duration{}, Timeout: &metav1.Duration{Duration: 10 * time.Second}}
+	depResInfos := []papi.DependentResourceInfo{{Ref: ref, ScaleUpInfo: scaleInfo, ScaleDownInfo: scaleInfo}}
+	s := NewScaler(widgetTestNamespace, depResInfos, cl, scalesGetter, logr.Discard(),
+		withResourceCheckTimeout(10*time.Second), withResourceCheckInterval(100*time.Millisecond), withScaleResourceBackOff(100*time.Millisecond))
+
+	g.Expect(s.ScaleDown(ctx)).To(Succeed())
+	g.Expect(getWidgetSpecReplicas(ctx, g, cl)).To(Equal(int64(0)))
+	g.Expect(s.ScaleUp(ctx)).To(Succeed())
+	g.Expect(getWidgetSpecReplicas(ctx, g, cl)).To(Equal(int64(2)), "the widget should have been scaled up to the replicas it had before the scale down")
+}
+
+func createWidget(ctx context.Context, g *WithT, cl client.Client, replicas int64) {
+	widget := &unstructured.Unstructured{}
+	widget.SetGroupVersionKind(widgetGVK)
+	widget.SetNamespace(widgetTestNamespace)
+	widget.SetName(widgetTestName)
+	g.Expect(unstructured.SetNestedField(widget.Object, replicas, "spec", "replicas")).To(Succeed())
+	g.Expect(cl.Create(ctx, widget)).To(Succeed())
+}
+
+func getWidgetSpecReplicas(ctx context.Context, g *WithT, cl client.Client) int64 {
+	widget := &unstructured.Unstructured{}
+	widget.SetGroupVersionKind(widgetGVK)
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: widgetTestNamespace, Name: widgetTestName}, widget)).To(Succeed())
+	replicas, _, err := unstructured.NestedInt64(widget.Object, "spec", "replicas")
+	g.Expect(err).ToNot(HaveOccurred())
+	return replicas
+}
+
+// syncWidgetStatus acts as the controller of the widget, which envtest does not run, by marking all of its replicas as
+// ready till the context has been cancelled.
+func syncWidgetStatus(ctx context.Context, cl client.Client) {
+	for ctx.Err() == nil {
+		widget := &unstructured.Unstructured{}
+		widget.SetGroupVersionKind(widgetGVK)
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: widgetTestNamespace, Name: widgetTestName}, widget); err == nil {
+			replicas, _, _ := unstructured.NestedInt64(widget.Object, "spec", "replicas")
+			_ = unstructured.SetNestedField(widget.Object, replicas, "status", "replicas")
+			_ = unstructured.SetNestedField(widget.Object, replicas, "status", "readyReplicas")
+			_ = cl.Status().Update(ctx, widget)
+		}
+		_ = util.SleepWithContext(ctx, 50*time.Millisecond)
+	}
+}
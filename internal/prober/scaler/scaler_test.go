@@ -8,7 +8,6 @@ package scaler
 
 import (
 	"context"
-	"reflect"
 	"testing"
 	"time"
 
@@ -21,7 +20,6 @@ import (
 
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -51,12 +49,8 @@ func TestScalerSuite(t *testing.T) {
 		{"test getting scale subresource times out", testGettingScaleSubResourceTimesOut},
 		{"test scaling when kind of a resource is invalid", testScalingWhenKindOfResourceIsInvalid},
 		//{"test waitTillMinTargetReplicasReached returns an error", testWaitTillMinTargetReplicasReachedReturnsError},
-		{"test scaling when mandatory resource(optional is false in resourceInfo) is not found", testScalingWhenMandatoryResourceNotFound},
-		{"test scaling when optional resource(optional is true in resourceInfo) is not found", testScalingWhenOptionalResourceNotFound},
 		{"test scale down then scale up when ignore scaling annotation is not present", testScaleDownThenScaleUpWhenIgnoreScalingAnnotationIsNotPresent},
-		{"test scale down then scale up when ignore scaling annotation is present", testScaleDownThenScaleUpWhenIgnoreScalingAnnotationIsPresent},
-		{"test scale up should not happen if current replica count is positive", testResourceShouldNotScaleUpIfCurrentReplicaCountIsPositive},
-		{"test scale up when replica annotation has invalid value", testScaleUpShouldReturnErrorWhenReplicasAnnotationsHasInvalidValue},
+		{"test scale up removes ignore scaling annotation when configured", testScaleUpRemovesIgnoreScalingAnnotationWhenConfigured},
 	}
 	for _, test := range tests {
 		test := test
@@ -110,121 +104,30 @@ func testScaleDownThenScaleUpWhenIgnoreScalingAnnotationIsNotPresent(t *testing.
 	t.Log("scale down then scale up test finished")
 }
 
-func testScaleDownThenScaleUpWhenIgnoreScalingAnnotationIsPresent(t *testing.T) {
+func testScaleUpRemovesIgnoreScalingAnnotationWhenConfigured(t *testing.T) {
 	g := NewWithT(t)
 	probeCfg := createProbeConfig(nil)
-	ds := createDefaultScaler(g, probeCfg.DependentResourceInfos)
-	validIgnoreScalingAnnotationMap := map[string]string{ignoreScaleAnnotationKey: "true"}
-	invalidIgnoreScalingAnnotationMap := map[string]string{ignoreScaleAnnotationKey: "foo"}
-
-	table := []struct {
-		mcmReplicas                 int32
-		kcmReplicas                 int32
-		caReplicas                  int32
-		expectedScaledUpMCMReplicas int32
-		expectedScaledUpKCMReplicas int32
-		expectedScaledUpCAReplicas  int32
-		annotationsOnKCM            map[string]string
-	}{
-		{2, 2, 2, 2, 2, 2, validIgnoreScalingAnnotationMap},
-		{0, 2, 2, 1, 2, 2, validIgnoreScalingAnnotationMap},
-		{2, 1, 1, 2, 1, 1, validIgnoreScalingAnnotationMap},
-		{2, 0, 2, 2, 0, 2, validIgnoreScalingAnnotationMap},
-		{1, 2, 0, 1, 2, 1, invalidIgnoreScalingAnnotationMap},
-	}
-
-	for _, entry := range table {
-		createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, entry.mcmReplicas, nil)
-		createDeployment(g, namespace, caObjectRef.Name, deploymentImageName, entry.caReplicas, nil)
-		createDeployment(g, namespace, kcmObjectRef.Name, deploymentImageName, entry.kcmReplicas, entry.annotationsOnKCM)
-
-		err := ds.ScaleDown(context.Background())
-		g.Expect(err).ToNot(HaveOccurred())
-		checkScaleSuccess(g, scaleDown, namespace, caObjectRef.Name, expectedSpecReplicasAfterSuccessfulScaleDownTest)
-		checkScaleSuccess(g, scaleDown, namespace, mcmObjectRef.Name, expectedSpecReplicasAfterSuccessfulScaleDownTest)
-		if reflect.DeepEqual(entry.annotationsOnKCM, validIgnoreScalingAnnotationMap) {
-			matchSpecReplicas(g, namespace, kcmObjectRef.Name, entry.kcmReplicas)
-		} else {
-			checkScaleSuccess(g, scaleDown, namespace, kcmObjectRef.Name, expectedSpecReplicasAfterSuccessfulScaleDownTest)
-		}
-
-		err = ds.ScaleUp(context.Background())
-		g.Expect(err).ToNot(HaveOccurred())
-		checkScaleSuccess(g, scaleUp, namespace, mcmObjectRef.Name, entry.expectedScaledUpMCMReplicas)
-		checkScaleSuccess(g, scaleUp, namespace, caObjectRef.Name, entry.expectedScaledUpCAReplicas)
-		if reflect.DeepEqual(entry.annotationsOnKCM, validIgnoreScalingAnnotationMap) {
-			matchSpecReplicas(g, namespace, kcmObjectRef.Name, entry.kcmReplicas)
-		} else {
-			checkScaleSuccess(g, scaleUp, namespace, kcmObjectRef.Name, entry.expectedScaledUpKCMReplicas)
-		}
-
-		err = kindTestEnv.DeleteAllDeployments(namespace)
-		g.Expect(err).ToNot(HaveOccurred())
-	}
-	t.Log("scale down then scale up when ignore scaling annotation is present test finished")
-}
-
-func testScalingWhenMandatoryResourceNotFound(t *testing.T) {
-	g := NewWithT(t)
-	probeCfg := createProbeConfig(nil)
-	ds := createDefaultScaler(g, probeCfg.DependentResourceInfos)
-	table := []struct {
-		mcmReplicas                          int32
-		caReplicas                           int32
-		scalingFn                            func(ctx context.Context) error
-		op                                   operation
-		unscaledResourceName                 string
-		scaledResourceName                   string
-		expectedUnscaledResourceSpecReplicas int32
-		expectedScaledResourceSpecReplicas   int32
-	}{
-		{0, 0, ds.ScaleUp, scaleUp, mcmObjectRef.Name, caObjectRef.Name, 0, 1},
-		{2, 2, ds.ScaleDown, scaleDown, caObjectRef.Name, mcmObjectRef.Name, 2, expectedSpecReplicasAfterSuccessfulScaleDownTest},
-	}
-	for _, entry := range table {
-		createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, entry.mcmReplicas, nil)
-		createDeployment(g, namespace, caObjectRef.Name, deploymentImageName, entry.caReplicas, nil)
-
-		err := entry.scalingFn(context.Background())
-		g.Expect(err).ToNot(BeNil())
-		g.Expect(err.Error()).To(ContainSubstring("\"" + kcmObjectRef.Name + "\" not found"))
-		matchSpecReplicas(g, namespace, entry.unscaledResourceName, entry.expectedUnscaledResourceSpecReplicas)
-		checkScaleSuccess(g, entry.op, namespace, entry.scaledResourceName, entry.expectedScaledResourceSpecReplicas)
-
-		err = kindTestEnv.DeleteAllDeployments(namespace)
-		g.Expect(err).ToNot(HaveOccurred())
-	}
-	t.Log("scaling when mandatory resource not found test finished")
-}
+	cfg := kindTestEnv.GetRestConfig()
+	scalesGetter, err := util.CreateScalesGetter(cfg)
+	g.Expect(err).ToNot(HaveOccurred())
+	ds := NewScaler(namespace, probeCfg.DependentResourceInfos, kindTestEnv.GetClient(), scalesGetter, scalerTestLogger,
+		withResourceCheckTimeout(defaultTestResourceCheckTimeout), withResourceCheckInterval(defaultTestResourceCheckInterval), withScaleResourceBackOff(defaultTestScaleResourceBackoff),
+		WithRemoveIgnoreScalingAnnotationOnScaleUp(true))
 
-func testScalingWhenOptionalResourceNotFound(t *testing.T) {
-	g := NewWithT(t)
-	probeCfg := createProbeConfig(nil)
-	ds := createDefaultScaler(g, probeCfg.DependentResourceInfos)
-	table := []struct {
-		mcmReplicas               int32
-		kcmReplicas               int32
-		expectedScaledMCMReplicas int32
-		expectedScaledCAReplicas  int32
-		scalingFn                 func(context.Context) error
-		op                        operation
-	}{
-		{0, 0, 1, 1, ds.ScaleUp, scaleUp},
-		{2, 2, expectedSpecReplicasAfterSuccessfulScaleDownTest, expectedSpecReplicasAfterSuccessfulScaleDownTest, ds.ScaleDown, scaleDown},
-	}
-	for _, entry := range table {
-		createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, entry.mcmReplicas, nil)
-		createDeployment(g, namespace, kcmObjectRef.Name, deploymentImageName, entry.kcmReplicas, nil)
+	createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, 0, nil)
+	createDeployment(g, namespace, caObjectRef.Name, deploymentImageName, 0, nil)
+	createDeployment(g, namespace, kcmObjectRef.Name, deploymentImageName, 0, map[string]string{ignoreScaleAnnotationKey: "true", replicasAnnotationKey: "2"})
 
-		err := entry.scalingFn(context.Background())
-		g.Expect(err).ToNot(HaveOccurred())
-		checkScaleSuccess(g, entry.op, namespace, mcmObjectRef.Name, entry.expectedScaledMCMReplicas)
-		checkScaleSuccess(g, entry.op, namespace, kcmObjectRef.Name, entry.expectedScaledCAReplicas)
+	err = ds.ScaleUp(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	checkScaleSuccess(g, scaleUp, namespace, caObjectRef.Name, 1)
+	checkScaleSuccess(g, scaleUp, namespace, mcmObjectRef.Name, 1)
+	kcm := matchSpecReplicas(g, namespace, kcmObjectRef.Name, 2)
+	g.Expect(kcm.Annotations).ToNot(HaveKey(ignoreScaleAnnotationKey))
 
-		err = kindTestEnv.DeleteAllDeployments(namespace)
-		g.Expect(err).ToNot(HaveOccurred())
-	}
-	t.Log("scaling when optional resource not found test finished")
+	err = kindTestEnv.DeleteAllDeployments(namespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Log("scale up removes ignore scaling annotation when configured test finished")
 }
 
 func testGettingScaleSubResourceTimesOut(t *testing.T) {
@@ -345,44 +248,6 @@ func testScalingWhenKindOfResourceIsInvalid(t *testing.T) {
 //	t.Log("WaitTillMinTargetReplicasReached returns error test finished")
 //}
 
-func testResourceShouldNotScaleUpIfCurrentReplicaCountIsPositive(t *testing.T) {
-	g := NewWithT(t)
-	probeCfg := createProbeConfig(nil)
-	ds := createDefaultScaler(g, probeCfg.DependentResourceInfos)
-	createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, 0, nil)
-	createDeployment(g, namespace, caObjectRef.Name, deploymentImageName, 0, nil)
-	createDeployment(g, namespace, kcmObjectRef.Name, deploymentImageName, 1, map[string]string{replicasAnnotationKey: "2"})
-
-	err := ds.ScaleUp(context.Background())
-	g.Expect(err).ToNot(HaveOccurred())
-	checkScaleSuccess(g, scaleUp, namespace, caObjectRef.Name, 1)
-	checkScaleSuccess(g, scaleUp, namespace, kcmObjectRef.Name, 1)
-	matchSpecReplicas(g, namespace, mcmObjectRef.Name, 1)
-
-	err = kindTestEnv.DeleteAllDeployments(namespace)
-	g.Expect(err).ToNot(HaveOccurred())
-	t.Log("Resource should not scale up if current replica count is positive test finished")
-}
-
-func testScaleUpShouldReturnErrorWhenReplicasAnnotationsHasInvalidValue(t *testing.T) {
-	g := NewWithT(t)
-	probeCfg := createProbeConfig(nil)
-	ds := createDefaultScaler(g, probeCfg.DependentResourceInfos)
-	createDeployment(g, namespace, mcmObjectRef.Name, deploymentImageName, 0, nil)
-	createDeployment(g, namespace, caObjectRef.Name, deploymentImageName, 0, nil)
-	createDeployment(g, namespace, kcmObjectRef.Name, deploymentImageName, 0, map[string]string{replicasAnnotationKey: "foo"})
-
-	err := ds.ScaleUp(context.Background())
-	g.Expect(err).ToNot(BeNil())
-	checkScaleSuccess(g, scaleUp, namespace, caObjectRef.Name, 1)
-	matchSpecReplicas(g, namespace, kcmObjectRef.Name, 0)
-	matchSpecReplicas(g, namespace, mcmObjectRef.Name, 0)
-
-	err = kindTestEnv.DeleteAllDeployments(namespace)
-	g.Expect(err).ToNot(HaveOccurred())
-	t.Log("Res should not scale up if replica annotation is incorrect test finished")
-}
-
 // utility methods to be used by tests
 // ------------------------------------------------------------------------------------------------------------------
 
@@ -444,16 +309,3 @@ func matchSpecReplicas(g *WithT, namespace string, name string, expectedReplicas
 	g.Expect(*deploy.Spec.Replicas).Should(Equal(expectedReplicas))
 	return deploy
 }
-
-func createProbeConfig(timeout *time.Duration) *papi.Config {
-	dependentResourceInfos := createDepResourceInfoArray(timeout)
-	return &papi.Config{DependentResourceInfos: dependentResourceInfos}
-}
-
-func createDepResourceInfoArray(timeout *time.Duration) []papi.DependentResourceInfo {
-	var dependentResourceInfos []papi.DependentResourceInfo
-	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 2, 0, timeout, pointer.Duration(0*time.Second), false))
-	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 1, 0, timeout, pointer.Duration(0*time.Second), false))
-	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(caObjectRef.Name, 0, 1, timeout, pointer.Duration(0*time.Second), true))
-	return dependentResourceInfos
-}
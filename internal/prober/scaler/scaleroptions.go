@@ -5,8 +5,15 @@
 package scaler
 
 import (
+	"context"
 	"time"
 
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/pointer"
 )
 
@@ -14,14 +21,59 @@ const (
 	defaultResourceCheckTimeout  = 5 * time.Second
 	defaultResourceCheckInterval = 1 * time.Second
 	defaultScaleResourceBackoff  = 100 * time.Millisecond
+	// DefaultFieldManager is the name of the field manager which DWD uses when it updates the scale subresource of a resource.
+	DefaultFieldManager = "dependency-watchdog-prober"
 )
 
 type scalerOption func(options *scalerOptions)
 
+// ReplicasFunc computes the target replicas of a scale-up of the dependent resource identified by ref, e.g. from the size of the cluster.
+type ReplicasFunc func(ctx context.Context, ref *autoscalingv1.CrossVersionObjectReference) (int32, error)
+
 type scalerOptions struct {
 	resourceCheckTimeout  *time.Duration
 	resourceCheckInterval *time.Duration
 	scaleResourceBackOff  *time.Duration
+	// removeIgnoreScalingAnnotation if set to true will remove the ignore-scaling annotation from resources as part of a scale-up.
+	removeIgnoreScalingAnnotation bool
+	// useServerSideApply if set to true will update the scale subresource using server-side apply.
+	useServerSideApply bool
+	// scaleUpDisabled if set to true will make the scaler skip every scale-up.
+	scaleUpDisabled bool
+	// fieldManager is the name of the field manager which is set when updating the scale subresource.
+	fieldManager string
+	// levelTimeout if positive bounds the time it may take to scale all resources of a level.
+	levelTimeout time.Duration
+	// continueOnLevelTimeout if set to true will let a flow proceed with the subsequent levels once a level has timed out.
+	continueOnLevelTimeout bool
+	// externallyManagedSelector if set selects the resources which are actively managed by another controller and are
+	// therefore skipped during a scale-down.
+	externallyManagedSelector labels.Selector
+	// waitOnReplicasStatusField is the status field which is read when waiting for a scaled resource to reach its minimum target replicas.
+	waitOnReplicasStatusField papi.ReplicasStatusField
+	// readRateLimiter if set throttles every read of a dependent resource by the scaler.
+	readRateLimiter flowcontrol.RateLimiter
+	// verifyScaleDownTermination if set to true will verify that status.replicas of a scaled down resource reaches its target replicas.
+	verifyScaleDownTermination bool
+	// awaitScaleDownTermination if set to true will fail the scale-down of a resource whose status.replicas does not reach its
+	// target replicas, so that the resources at the subsequent scale-down levels are not scaled down.
+	awaitScaleDownTermination bool
+	// scalePausedDeployments if set to true will also scale Deployments whose spec.paused is set.
+	scalePausedDeployments bool
+	// scaleCallTimeout if positive bounds every single get, update and patch of the scale subresource.
+	scaleCallTimeout time.Duration
+	// ignoreScalingAnnotationKey is the key of the annotation which suspends the scaling of a resource if it is set to true.
+	ignoreScalingAnnotationKey string
+	// dependentResourceSelector if set selects the Deployments which are discovered as dependent resources at level 0.
+	dependentResourceSelector labels.Selector
+	// fastScaleUp if set to true will scale up all resources in parallel, ignoring their scale-up levels and dependencies.
+	fastScaleUp bool
+	// replicasFunc if set computes the target replicas of every scale-up, overriding the recorded and configured replicas.
+	replicasFunc ReplicasFunc
+	// scalingPause if set and paused makes every scaling operation a no-op.
+	scalingPause *ScalingPause
+	// clock measures the initial delays of the resources and the waits for them to reach their target replicas.
+	clock clock.Clock
 }
 
 func buildScalerOptions(options ...scalerOption) *scalerOptions {
@@ -51,6 +103,185 @@ func withScaleResourceBackOff(interval time.Duration) scalerOption {
 	}
 }
 
+// withClock replaces the real clock of the scaler, which allows tests to advance its delays and waits with a fake clock.
+func withClock(clock clock.Clock) scalerOption {
+	return func(options *scalerOptions) {
+		options.clock = clock
+	}
+}
+
+// WithRemoveIgnoreScalingAnnotationOnScaleUp configures whether the scaler removes the ignore-scaling annotation from every
+// resource that it scales up. This allows a deliberate resume of scaling, which was previously suspended by an operator
+// via the annotation, to be driven entirely by DWD.
+func WithRemoveIgnoreScalingAnnotationOnScaleUp(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.removeIgnoreScalingAnnotation = enabled
+	}
+}
+
+// WithServerSideApply configures whether the scaler updates the replicas via a server-side apply of the scale subresource,
+// which only takes ownership of spec.replicas instead of overwriting the complete scale subresource. If the scale subresource
+// of a resource does not support server-side apply then the scaler falls back to an update.
+func WithServerSideApply(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.useServerSideApply = enabled
+	}
+}
+
+// WithScaleUpDisabled configures whether the scaler skips scaling up the dependent resources, in which case it only
+// scales them down.
+func WithScaleUpDisabled(disabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.scaleUpDisabled = disabled
+	}
+}
+
+// WithFastScaleUp configures whether the scaler scales up all dependent resources in a single parallel step instead of
+// level by level, trading the ordering of the scale-up for a faster recovery. The scale-down is not affected.
+func WithFastScaleUp(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.fastScaleUp = enabled
+	}
+}
+
+// WithFieldManager configures the name of the field manager which is recorded in the managed fields of every scale
+// subresource updated by the scaler. If it is not set then DefaultFieldManager is used.
+func WithFieldManager(fieldManager string) scalerOption {
+	return func(options *scalerOptions) {
+		options.fieldManager = fieldManager
+	}
+}
+
+// WithLevelTimeout configures the maximum time it may take to scale all resources of a level, including waiting for
+// them to reach their target replicas. A level which does not finish within the timeout is cancelled so that it cannot
+// block the subsequent levels indefinitely. If the timeout is not positive then a level is only bounded by the context of the flow.
+func WithLevelTimeout(timeout time.Duration) scalerOption {
+	return func(options *scalerOptions) {
+		options.levelTimeout = timeout
+	}
+}
+
+// WithContinueOnLevelTimeout configures whether a flow proceeds with the subsequent levels once a level has timed out.
+// By default, the flow is aborted with an error. This is only applicable if a level timeout has been configured via WithLevelTimeout.
+func WithContinueOnLevelTimeout(continueOnTimeout bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.continueOnLevelTimeout = continueOnTimeout
+	}
+}
+
+// WithExternallyManagedSelector configures a label selector for resources which are actively reconciled by another
+// controller, e.g. an operator which sets a label on the resource while it reconciles it. Such resources are skipped
+// during a scale-down, as otherwise DWD and the other controller would keep undoing each other's changes to the replicas.
+// A nil or invalid selector does not select any resource. The selector is expected to have been validated beforehand.
+func WithExternallyManagedSelector(selector *metav1.LabelSelector) scalerOption {
+	return func(options *scalerOptions) {
+		if selector == nil {
+			return
+		}
+		if s, err := metav1.LabelSelectorAsSelector(selector); err == nil {
+			options.externallyManagedSelector = s
+		}
+	}
+}
+
+// WithDependentResourceSelector configures a label selector for Deployments in the namespace of the scaler which are scaled
+// in addition to the configured dependent resources. The Deployments are listed whenever a scaling flow is run and are scaled
+// at level 0, see discoverDependentResourceInfos. A nil or invalid selector does not discover any Deployment. The selector is
+// expected to have been validated beforehand.
+func WithDependentResourceSelector(selector *metav1.LabelSelector) scalerOption {
+	return func(options *scalerOptions) {
+		if selector == nil {
+			return
+		}
+		if s, err := metav1.LabelSelectorAsSelector(selector); err == nil {
+			options.dependentResourceSelector = s
+		}
+	}
+}
+
+// WithWaitOnReplicasStatusField configures the status field of a resource which is compared against its minimum target
+// replicas when waiting for the resource after it has been scaled. Only once the resource has reached its minimum target
+// replicas are the resources depending on it scaled. If it is not set then papi.ReplicasStatusFieldReadyReplicas is used.
+func WithWaitOnReplicasStatusField(field papi.ReplicasStatusField) scalerOption {
+	return func(options *scalerOptions) {
+		options.waitOnReplicasStatusField = field
+	}
+}
+
+// WithReadRateLimiter configures a client-side rate limiter which every read of a dependent resource by the scaler has to
+// wait for. The same limiter should be passed to the scalers of all probers, so that a storm of scaling flows across
+// namespaces stays within a common budget of API server requests. If it is nil then the reads are not throttled by the scaler.
+func WithReadRateLimiter(limiter flowcontrol.RateLimiter) scalerOption {
+	return func(options *scalerOptions) {
+		options.readRateLimiter = limiter
+	}
+}
+
+// WithScaleDownTerminationCheck configures whether the scaler verifies, once a scaled down resource has reached its minimum
+// target replicas, that status.replicas of the resource also reaches them within the timeout of the resource. The ready
+// replicas of a resource drop as soon as its pods start terminating, whereas pods which never finish terminating, e.g. due
+// to a finalizer, are still counted in status.replicas. A resource which does not converge is reported but does not fail the scale-down.
+func WithScaleDownTerminationCheck(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.verifyScaleDownTermination = enabled
+	}
+}
+
+// WithAwaitScaleDownTermination configures whether the scaler awaits, once a scaled down resource has reached its minimum
+// target replicas, that status.replicas of the resource also reaches them within the timeout of the resource. Unlike
+// WithScaleDownTerminationCheck a resource which does not converge fails the scale-down flow, so that the resources at the
+// subsequent scale-down levels are only scaled down once all pods of the resources at the previous levels are gone.
+func WithAwaitScaleDownTermination(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.awaitScaleDownTermination = enabled
+	}
+}
+
+// WithScalePausedDeployments configures whether the scaler scales Deployments whose spec.paused is set. By default they are
+// skipped, as a paused Deployment has been deliberately frozen by an operator.
+func WithScalePausedDeployments(enabled bool) scalerOption {
+	return func(options *scalerOptions) {
+		options.scalePausedDeployments = enabled
+	}
+}
+
+// WithScaleCallTimeout configures the maximum time a single get, update or patch of the scale subresource may take. A call
+// which hangs, e.g. as the API server does not respond, is cancelled once the timeout has expired and fails the attempt,
+// instead of stalling the scaling of the resource till its timeout has expired. If the timeout is not positive then every
+// call is bounded by the timeout of the resource.
+func WithScaleCallTimeout(timeout time.Duration) scalerOption {
+	return func(options *scalerOptions) {
+		options.scaleCallTimeout = timeout
+	}
+}
+
+// WithIgnoreScalingAnnotationKey configures the key of the annotation which suspends the scaling of a resource if it is set
+// to true. Multiple DWD instances which scale the same resources can thereby be suspended independently of each other. If it
+// is not set then DefaultIgnoreScalingAnnotationKey is used.
+func WithIgnoreScalingAnnotationKey(key string) scalerOption {
+	return func(options *scalerOptions) {
+		options.ignoreScalingAnnotationKey = key
+	}
+}
+
+// WithReplicasFunc configures a function which computes the target replicas of every scale-up of a dependent resource, overriding
+// the replicas recorded prior to its scale-down and the configured scale-up replicas. If the function fails or returns replicas
+// which are not positive then the resource is scaled up to the recorded or configured replicas as if no function was configured.
+// Scale-downs are not affected.
+func WithReplicasFunc(fn ReplicasFunc) scalerOption {
+	return func(options *scalerOptions) {
+		options.replicasFunc = fn
+	}
+}
+
+// WithScalingPause configures a switch which pauses the scaling of the scaler while it is paused. The same switch should be
+// passed to the scalers of all probers, so that all scaling across namespaces can be paused at once, e.g. during a maintenance.
+func WithScalingPause(pause *ScalingPause) scalerOption {
+	return func(options *scalerOptions) {
+		options.scalingPause = pause
+	}
+}
+
 func fillDefaultsOptions(options *scalerOptions) {
 	if options.resourceCheckTimeout == nil {
 		options.resourceCheckTimeout = pointer.Duration(defaultResourceCheckTimeout)
@@ -61,4 +292,16 @@ func fillDefaultsOptions(options *scalerOptions) {
 	if options.scaleResourceBackOff == nil {
 		options.scaleResourceBackOff = pointer.Duration(defaultScaleResourceBackoff)
 	}
+	if options.fieldManager == "" {
+		options.fieldManager = DefaultFieldManager
+	}
+	if options.ignoreScalingAnnotationKey == "" {
+		options.ignoreScalingAnnotationKey = DefaultIgnoreScalingAnnotationKey
+	}
+	if options.clock == nil {
+		options.clock = clock.RealClock{}
+	}
+	if options.waitOnReplicasStatusField == "" {
+		options.waitOnReplicasStatusField = papi.ReplicasStatusFieldReadyReplicas
+	}
 }
@@ -7,10 +7,17 @@
 package scaler
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	papi "github.com/gardener/dependency-watchdog/api/prober"
 	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/clock"
+	testclock "k8s.io/utils/clock/testing"
 )
 
 var (
@@ -42,6 +49,36 @@ func TestWithScaleResourceBackOff(t *testing.T) {
 	g.Expect(*opts.scaleResourceBackOff).To(Equal(interval))
 }
 
+func TestWithRemoveIgnoreScalingAnnotationOnScaleUp(t *testing.T) {
+	g := NewWithT(t)
+	opts := scalerOptions{}
+	g.Expect(opts.removeIgnoreScalingAnnotation).To(BeFalse())
+	fn := WithRemoveIgnoreScalingAnnotationOnScaleUp(true)
+	fn(&opts)
+	g.Expect(opts.removeIgnoreScalingAnnotation).To(BeTrue())
+}
+
+func TestWithReplicasFunc(t *testing.T) {
+	g := NewWithT(t)
+	opts := scalerOptions{}
+	WithReplicasFunc(func(context.Context, *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+		return 3, nil
+	})(&opts)
+	g.Expect(opts.replicasFunc).ToNot(BeNil())
+	replicas, err := opts.replicasFunc(context.Background(), &autoscalingv1.CrossVersionObjectReference{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(replicas).To(Equal(int32(3)))
+}
+
+func TestWithScalingPause(t *testing.T) {
+	g := NewWithT(t)
+	opts := scalerOptions{}
+	g.Expect(opts.scalingPause.IsPaused()).To(BeFalse(), "a scaler without a scaling pause should never be paused")
+	pause := NewScalingPause()
+	WithScalingPause(pause)(&opts)
+	g.Expect(opts.scalingPause).To(BeIdenticalTo(pause))
+}
+
 func TestBuildScalerOptions(t *testing.T) {
 	g := NewWithT(t)
 	opts := buildScalerOptions(withResourceCheckTimeout(timeout), withResourceCheckInterval(interval))
@@ -54,4 +91,89 @@ func TestBuildScalerOptionsShouldFillDefaultValues(t *testing.T) {
 	opts := buildScalerOptions()
 	g.Expect(*opts.resourceCheckInterval).To(Equal(defaultResourceCheckInterval))
 	g.Expect(*opts.resourceCheckTimeout).To(Equal(defaultResourceCheckTimeout))
+	g.Expect(opts.waitOnReplicasStatusField).To(Equal(papi.ReplicasStatusFieldReadyReplicas))
+}
+
+func TestWithClock(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().clock).To(Equal(clock.RealClock{}))
+	fakeClock := testclock.NewFakeClock(time.Now())
+	g.Expect(buildScalerOptions(withClock(fakeClock)).clock).To(BeIdenticalTo(fakeClock))
+}
+
+func TestWithScaleUpDisabled(t *testing.T) {
+	g := NewWithT(t)
+	opts := scalerOptions{}
+	WithScaleUpDisabled(true)(&opts)
+	g.Expect(opts.scaleUpDisabled).To(BeTrue())
+}
+
+func TestWithFastScaleUp(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().fastScaleUp).To(BeFalse())
+	g.Expect(buildScalerOptions(WithFastScaleUp(true)).fastScaleUp).To(BeTrue())
+}
+
+func TestWithLevelTimeout(t *testing.T) {
+	g := NewWithT(t)
+	opts := buildScalerOptions()
+	g.Expect(opts.levelTimeout).To(BeZero())
+	g.Expect(opts.continueOnLevelTimeout).To(BeFalse())
+	opts = buildScalerOptions(WithLevelTimeout(timeout), WithContinueOnLevelTimeout(true))
+	g.Expect(opts.levelTimeout).To(Equal(timeout))
+	g.Expect(opts.continueOnLevelTimeout).To(BeTrue())
+}
+
+func TestWithWaitOnReplicasStatusField(t *testing.T) {
+	g := NewWithT(t)
+	opts := buildScalerOptions(WithWaitOnReplicasStatusField(papi.ReplicasStatusFieldAvailableReplicas))
+	g.Expect(opts.waitOnReplicasStatusField).To(Equal(papi.ReplicasStatusFieldAvailableReplicas))
+}
+
+func TestWithReadRateLimiter(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().readRateLimiter).To(BeNil())
+	limiter := flowcontrol.NewTokenBucketRateLimiter(1, 1)
+	opts := buildScalerOptions(WithReadRateLimiter(limiter))
+	g.Expect(opts.readRateLimiter).To(BeIdenticalTo(limiter))
+}
+
+func TestWithDependentResourceSelector(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().dependentResourceSelector).To(BeNil())
+	g.Expect(buildScalerOptions(WithDependentResourceSelector(nil)).dependentResourceSelector).To(BeNil())
+	opts := buildScalerOptions(WithDependentResourceSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"dependency-watchdog.gardener.cloud/scale": "true"}}))
+	g.Expect(opts.dependentResourceSelector).ToNot(BeNil())
+	g.Expect(opts.dependentResourceSelector.String()).To(Equal("dependency-watchdog.gardener.cloud/scale=true"))
+}
+
+func TestWithScaleDownTerminationCheck(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().verifyScaleDownTermination).To(BeFalse())
+	g.Expect(buildScalerOptions(WithScaleDownTerminationCheck(true)).verifyScaleDownTermination).To(BeTrue())
+}
+
+func TestWithAwaitScaleDownTermination(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().awaitScaleDownTermination).To(BeFalse())
+	g.Expect(buildScalerOptions(WithAwaitScaleDownTermination(true)).awaitScaleDownTermination).To(BeTrue())
+}
+
+func TestWithScalePausedDeployments(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().scalePausedDeployments).To(BeFalse())
+	g.Expect(buildScalerOptions(WithScalePausedDeployments(true)).scalePausedDeployments).To(BeTrue())
+}
+
+func TestWithScaleCallTimeout(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().scaleCallTimeout).To(BeZero())
+	g.Expect(buildScalerOptions(WithScaleCallTimeout(timeout)).scaleCallTimeout).To(Equal(timeout))
+}
+
+func TestWithIgnoreScalingAnnotationKey(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(buildScalerOptions().ignoreScalingAnnotationKey).To(Equal(DefaultIgnoreScalingAnnotationKey))
+	g.Expect(buildScalerOptions(WithIgnoreScalingAnnotationKey("")).ignoreScalingAnnotationKey).To(Equal(DefaultIgnoreScalingAnnotationKey))
+	g.Expect(buildScalerOptions(WithIgnoreScalingAnnotationKey("example.com/ignore-scaling")).ignoreScalingAnnotationKey).To(Equal("example.com/ignore-scaling"))
 }
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package scaler
+
+import (
+	"fmt"
+	"time"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	multierr "github.com/hashicorp/go-multierror"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ScaleInfoSpec describes how a dependent resource is scaled in one direction. It is the programmatic counterpart of
+// papi.ScaleInfo, which allows to embed a Scaler whose dependent resources are not loaded from a prober config, see
+// NewDependentResourceInfo.
+type ScaleInfoSpec struct {
+	// Level orders the scaling of the dependent resources, see papi.ScaleInfo.
+	Level int
+	// InitialDelay is the delay before the resource is scaled. It must not be negative.
+	InitialDelay time.Duration
+	// Timeout bounds the scaling of the resource. If it is not set then the default timeout of 30s is used.
+	Timeout time.Duration
+	// DependsOn optionally lists the resources at lower levels which have to be scaled before this resource, see papi.ScaleInfo.
+	DependsOn []autoscalingv1.CrossVersionObjectReference
+}
+
+// NewDependentResourceInfo creates the dependent resource of a Scaler, see NewScaler, which is scaled up and down as described
+// by the specs. Unset durations are defaulted like those of a loaded prober config. An error is returned if the reference of
+// the resource or any of the specs is invalid.
+func NewDependentResourceInfo(ref autoscalingv1.CrossVersionObjectReference, optional bool, scaleUp, scaleDown ScaleInfoSpec) (papi.DependentResourceInfo, error) {
+	v := new(util.Validator)
+	v.MustNotBeEmpty("ref.name", ref.Name)
+	v.MustNotBeEmpty("ref.kind", ref.Kind)
+	if _, err := schema.ParseGroupVersion(ref.APIVersion); err != nil {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("ref.apiVersion %q is invalid: %w", ref.APIVersion, err))
+	}
+	scaleUpInfo := scaleUp.toScaleInfo(v, "scaleUp")
+	scaleDownInfo := scaleDown.toScaleInfo(v, "scaleDown")
+	if v.Error != nil {
+		return papi.DependentResourceInfo{}, fmt.Errorf("invalid dependent resource %s: %w", util.ResourceRefKey(ref), v.Error)
+	}
+	return papi.DependentResourceInfo{
+		Ref:           &ref,
+		Optional:      optional,
+		ScaleUpInfo:   scaleUpInfo,
+		ScaleDownInfo: scaleDownInfo,
+	}, nil
+}
+
+// toScaleInfo validates the spec and converts it into a papi.ScaleInfo with the timeout defaulted.
+func (s ScaleInfoSpec) toScaleInfo(v *util.Validator, key string) *papi.ScaleInfo {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = defaultScaleTimeout
+	}
+	scaleInfo := &papi.ScaleInfo{
+		Level:        s.Level,
+		InitialDelay: &metav1.Duration{Duration: s.InitialDelay},
+		Timeout:      &metav1.Duration{Duration: timeout},
+		DependsOn:    s.DependsOn,
+	}
+	if s.Level < 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("%s.level must not be negative, found %d", key, s.Level))
+	}
+	if s.InitialDelay < 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("%s.initialDelay must not be negative, found %s", key, s.InitialDelay))
+	}
+	v.DurationMustBePositive(key+".timeout", scaleInfo.Timeout)
+	return scaleInfo
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package scaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+
+	papi "github.com/gardener/dependency-watchdog/api/prober"
+)
+
+func TestNewDependentResourceInfoShouldDefaultTimeoutAndMapSpecs(t *testing.T) {
+	g := NewWithT(t)
+	upSpec := ScaleInfoSpec{Level: 1, InitialDelay: 10 * time.Second, Timeout: time.Minute, DependsOn: []autoscalingv1.CrossVersionObjectReference{kcmObjectRef}}
+	downSpec := ScaleInfoSpec{Level: 0}
+
+	depResInfo, err := NewDependentResourceInfo(mcmObjectRef, true, upSpec, downSpec)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*depResInfo.Ref).To(Equal(mcmObjectRef))
+	g.Expect(depResInfo.Optional).To(BeTrue())
+	g.Expect(depResInfo.ScaleDownInfo.Timeout.Duration).To(Equal(defaultScaleTimeout), "an unset timeout should be defaulted")
+	g.Expect(depResInfo.ScaleDownInfo.InitialDelay.Duration).To(BeZero())
+
+	scaleUpResInfos := createScalableResourceInfos(scaleUp, []papi.DependentResourceInfo{depResInfo})
+	g.Expect(scaleUpResInfos).To(HaveLen(1))
+	g.Expect(scaleUpResInfos[0].level).To(Equal(1))
+	g.Expect(scaleUpResInfos[0].initialDelay).To(Equal(10 * time.Second))
+	g.Expect(scaleUpResInfos[0].timeout).To(Equal(time.Minute))
+	g.Expect(scaleUpResInfos[0].dependsOn).To(ConsistOf(kcmObjectRef))
+	g.Expect(scaleUpResInfos[0].optional).To(BeTrue())
+}
+
+func TestNewDependentResourceInfoShouldRejectInvalidSpecs(t *testing.T) {
+	tests := []struct {
+		name             string
+		ref              autoscalingv1.CrossVersionObjectReference
+		scaleUp          ScaleInfoSpec
+		scaleDown        ScaleInfoSpec
+		expectedMessages []string
+	}{
+		{name: "ref without name and kind", ref: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1"}, expectedMessages: []string{"ref.name", "ref.kind"}},
+		{name: "ref with invalid apiVersion", ref: autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "kcm", APIVersion: "apps/v1/beta"}, expectedMessages: []string{"ref.apiVersion"}},
+		{name: "negative level", ref: kcmObjectRef, scaleDown: ScaleInfoSpec{Level: -1}, expectedMessages: []string{"scaleDown.level must not be negative"}},
+		{name: "negative initial delay", ref: kcmObjectRef, scaleUp: ScaleInfoSpec{InitialDelay: -time.Second}, expectedMessages: []string{"scaleUp.initialDelay must not be negative"}},
+		{name: "negative timeout", ref: kcmObjectRef, scaleUp: ScaleInfoSpec{Timeout: -time.Second}, expectedMessages: []string{"scaleUp.timeout"}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := NewDependentResourceInfo(entry.ref, false, entry.scaleUp, entry.scaleDown)
+			g.Expect(err).To(HaveOccurred())
+			for _, msg := range entry.expectedMessages {
+				g.Expect(err.Error()).To(ContainSubstring(msg))
+			}
+		})
+	}
+}
+
+func TestScalerShouldScaleDependentResourcesBuiltFromSpecs(t *testing.T) {
+	g := NewWithT(t)
+	scales, cl := newFakeScalesGetter(
+		createFakeScalesTestDeployment(kcmObjectRef.Name, 1, nil),
+		createFakeScalesTestDeployment(mcmObjectRef.Name, 1, nil))
+	kcm, err := NewDependentResourceInfo(kcmObjectRef, false, ScaleInfoSpec{Level: 0}, ScaleInfoSpec{Level: 1})
+	g.Expect(err).ToNot(HaveOccurred())
+	mcm, err := NewDependentResourceInfo(mcmObjectRef, false, ScaleInfoSpec{Level: 1}, ScaleInfoSpec{Level: 0})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	s := NewScaler(stagedTestNamespace, []papi.DependentResourceInfo{kcm, mcm}, cl, scales, logr.Discard(),
+		withResourceCheckTimeout(time.Second), withResourceCheckInterval(10*time.Millisecond), withScaleResourceBackOff(10*time.Millisecond))
+
+	g.Expect(s.ScaleDown(context.Background())).To(Succeed())
+	g.Expect(scales.scaleOrder()).To(Equal([]string{mcmObjectRef.Name, kcmObjectRef.Name}))
+	expectDeploymentReplicas(g, cl, kcmObjectRef.Name, 0)
+	expectDeploymentReplicas(g, cl, mcmObjectRef.Name, 0)
+}
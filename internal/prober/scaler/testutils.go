@@ -19,7 +19,6 @@ import (
 const (
 	defaultTimeout       = 10 * time.Second
 	defaultInitialDelay  = 10 * time.Millisecond
-	deploymentKind       = "Deployment"
 	deploymentAPIVersion = "apps/v1"
 )
 
@@ -84,3 +83,16 @@ func parseTaskID(taskID string) (int, []string, error) {
 
 	return level, resNamesSplits, nil
 }
+
+func createProbeConfig(timeout *time.Duration) *papi.Config {
+	dependentResourceInfos := createDepResourceInfoArray(timeout)
+	return &papi.Config{DependentResourceInfos: dependentResourceInfos}
+}
+
+func createDepResourceInfoArray(timeout *time.Duration) []papi.DependentResourceInfo {
+	var dependentResourceInfos []papi.DependentResourceInfo
+	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(mcmObjectRef.Name, 2, 0, timeout, pointer.Duration(0*time.Second), false))
+	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(kcmObjectRef.Name, 1, 0, timeout, pointer.Duration(0*time.Second), false))
+	dependentResourceInfos = append(dependentResourceInfos, createTestDeploymentDependentResourceInfo(caObjectRef.Name, 0, 1, timeout, pointer.Duration(0*time.Second), true))
+	return dependentResourceInfos
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 )
 
@@ -21,15 +22,29 @@ func createScalableResourceInfos(op operation, dependentResourceInfos []papi.Dep
 		var (
 			level                 int
 			initialDelay, timeout time.Duration
+			dependsOn             []autoscalingv1.CrossVersionObjectReference
+			steps                 []int32
+			replicaDelta          *int32
+			hpa                   *papi.HPAScaleInfo
+			replicas              *int32
+			replicasFromHPA       *papi.HPAReference
 		)
 		if op == scaleUp {
 			level = depResInfo.ScaleUpInfo.Level
 			initialDelay = depResInfo.ScaleUpInfo.InitialDelay.Duration
 			timeout = depResInfo.ScaleUpInfo.Timeout.Duration
+			dependsOn = depResInfo.ScaleUpInfo.DependsOn
+			steps = depResInfo.ScaleUpInfo.Steps
+			hpa = depResInfo.ScaleUpInfo.HPA
+			replicas = depResInfo.ScaleUpInfo.Replicas
+			replicasFromHPA = depResInfo.ScaleUpInfo.ReplicasFromHPA
 		} else {
+			replicaDelta = depResInfo.ScaleDownInfo.ReplicaDelta
 			level = depResInfo.ScaleDownInfo.Level
 			initialDelay = depResInfo.ScaleDownInfo.InitialDelay.Duration
 			timeout = depResInfo.ScaleDownInfo.Timeout.Duration
+			dependsOn = depResInfo.ScaleDownInfo.DependsOn
+			hpa = depResInfo.ScaleDownInfo.HPA
 		}
 		resInfo := scalableResourceInfo{
 			ref:          depResInfo.Ref,
@@ -38,6 +53,16 @@ func createScalableResourceInfos(op operation, dependentResourceInfos []papi.Dep
 			initialDelay: initialDelay,
 			timeout:      timeout,
 			operation:    op,
+			dependsOn:    dependsOn,
+			steps:        steps,
+			replicaDelta: replicaDelta,
+			// a resource which is scaled down by a replica delta keeps its remaining replicas, it has to be restored on scale-up nevertheless.
+			restoreRecordedReplicas: op == scaleUp && depResInfo.ScaleDownInfo != nil && depResInfo.ScaleDownInfo.ReplicaDelta != nil,
+			retryInfo:               depResInfo.RetryInfo,
+			hpa:                     hpa,
+			replicas:                replicas,
+			replicasFromHPA:         replicasFromHPA,
+			selector:                depResInfo.Selector,
 		}
 		resourceInfos = append(resourceInfos, resInfo)
 	}
@@ -45,18 +70,16 @@ func createScalableResourceInfos(op operation, dependentResourceInfos []papi.Dep
 }
 
 func sortAndGetUniqueLevels(resourceInfos []scalableResourceInfo) []int {
-	var levels []int
-	keys := make(map[int]bool)
+	levels := make(map[int]struct{}, len(resourceInfos))
 	for _, resInfo := range resourceInfos {
-		if _, found := keys[resInfo.level]; !found {
-			keys[resInfo.level] = true
-			levels = append(levels, resInfo.level)
-		}
+		levels[resInfo.level] = struct{}{}
 	}
-	sort.Ints(levels)
-	return levels
+	return util.SortedKeys(levels)
 }
 
+// collectResourceInfosByLevel groups the resourceInfos by their level. The resourceInfos within a level are sorted by the
+// name of the resource, and by its kind and API version if the names are equal, so that the task names and the composition
+// of a flow do not depend on the order in which the dependent resources have been configured.
 func collectResourceInfosByLevel(resourceInfos []scalableResourceInfo) map[int][]scalableResourceInfo {
 	resInfosByLevel := make(map[int][]scalableResourceInfo)
 	for _, resInfo := range resourceInfos {
@@ -69,12 +92,42 @@ func collectResourceInfosByLevel(resourceInfos []scalableResourceInfo) map[int][
 			resInfosByLevel[level] = append(resInfosByLevel[level], resInfo)
 		}
 	}
+	for _, levelResInfos := range resInfosByLevel {
+		sort.SliceStable(levelResInfos, func(i, j int) bool {
+			if levelResInfos[i].ref.Name != levelResInfos[j].ref.Name {
+				return levelResInfos[i].ref.Name < levelResInfos[j].ref.Name
+			}
+			return util.ResourceRefKey(*levelResInfos[i].ref) < util.ResourceRefKey(*levelResInfos[j].ref)
+		})
+	}
 	return resInfosByLevel
 }
 
+// collectExplicitDependencies collects the resources which the given resourceInfos explicitly depend upon. It returns false
+// if at least one of the resourceInfos does not declare its dependencies, as it then implicitly depends upon all resources
+// in the previous levels.
+func collectExplicitDependencies(resourceInfos []scalableResourceInfo) ([]autoscalingv1.CrossVersionObjectReference, bool) {
+	var dependencies []autoscalingv1.CrossVersionObjectReference
+	for _, resInfo := range resourceInfos {
+		if len(resInfo.dependsOn) == 0 {
+			return nil, false
+		}
+		dependencies = append(dependencies, resInfo.dependsOn...)
+	}
+	return dependencies, true
+}
+
+// mapToCrossVersionObjectRef collects the resource references of the given resourceInfos. A resource that is
+// referenced more than once will only be collected once.
 func mapToCrossVersionObjectRef(resourceInfos []scalableResourceInfo) []autoscalingv1.CrossVersionObjectReference {
 	refs := make([]autoscalingv1.CrossVersionObjectReference, 0, len(resourceInfos))
+	seen := make(map[string]struct{}, len(resourceInfos))
 	for _, resInfo := range resourceInfos {
+		key := util.ResourceRefKey(*resInfo.ref)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
 		refs = append(refs, *resInfo.ref)
 	}
 	return refs
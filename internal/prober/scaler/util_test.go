@@ -16,6 +16,7 @@ import (
 	"k8s.io/utils/pointer"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
+	"github.com/gardener/dependency-watchdog/internal/util"
 )
 
 func TestCreateScaleUpResourceInfos(t *testing.T) {
@@ -29,7 +30,7 @@ func TestCreateScaleUpResourceInfos(t *testing.T) {
 	g.Expect(resInfos).To(HaveLen(len(depResInfos)))
 	expectedObjectRefs := []autoscalingv1.CrossVersionObjectReference{mcmObjectRef, caObjectRef, kcmObjectRef}
 	for i, resInfo := range resInfos {
-		g.Expect(expectedObjectRefs[i]).To(Equal(*resInfo.ref))
+		g.Expect(util.RefsEqual(expectedObjectRefs[i], *resInfo.ref)).To(BeTrue())
 		g.Expect(depResInfos[i].ScaleUpInfo.Level).To(Equal(resInfo.level))
 		g.Expect(resInfo.initialDelay).To(Equal(defaultInitialDelay))
 		g.Expect(resInfo.timeout).To(Equal(defaultTimeout))
@@ -51,7 +52,7 @@ func TestCreateScaleDownResourceInfos(t *testing.T) {
 	g.Expect(resInfos).To(HaveLen(len(depResInfos)))
 	expectedObjectRefs := []autoscalingv1.CrossVersionObjectReference{mcmObjectRef, caObjectRef, kcmObjectRef}
 	for i, resInfo := range resInfos {
-		g.Expect(expectedObjectRefs[i]).To(Equal(*resInfo.ref))
+		g.Expect(util.RefsEqual(expectedObjectRefs[i], *resInfo.ref)).To(BeTrue())
 		g.Expect(depResInfos[i].ScaleDownInfo.Level).To(Equal(resInfo.level))
 		g.Expect(resInfo.initialDelay).To(Equal(initialDelay))
 		g.Expect(resInfo.timeout).To(Equal(timeout))
@@ -130,12 +131,45 @@ func TestCollectResourceInfosByLevel(t *testing.T) {
 	}
 }
 
+func TestCollectResourceInfosByLevelShouldSortResourcesWithinALevel(t *testing.T) {
+	g := NewWithT(t)
+	resInfos := []scalableResourceInfo{
+		{ref: &autoscalingv1.CrossVersionObjectReference{Kind: "StatefulSet", Name: "resource-b", APIVersion: "apps/v1"}, level: 0},
+		{ref: &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "resource-c", APIVersion: "apps/v1"}, level: 1},
+		{ref: &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "resource-b", APIVersion: "apps/v1"}, level: 0},
+		{ref: &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "resource-a", APIVersion: "apps/v1"}, level: 0},
+	}
+	expectedLevel0Refs := []autoscalingv1.CrossVersionObjectReference{*resInfos[3].ref, *resInfos[2].ref, *resInfos[0].ref}
+
+	// the same resources in the reverse order should be collected identically
+	reversedResInfos := make([]scalableResourceInfo, 0, len(resInfos))
+	for i := len(resInfos) - 1; i >= 0; i-- {
+		reversedResInfos = append(reversedResInfos, resInfos[i])
+	}
+	for _, input := range [][]scalableResourceInfo{resInfos, reversedResInfos} {
+		resInfosByLevel := collectResourceInfosByLevel(input)
+		g.Expect(mapToCrossVersionObjectRef(resInfosByLevel[0])).To(Equal(expectedLevel0Refs))
+		g.Expect(createTaskName(resInfosByLevel[0], 0)).To(Equal("scale:level-0:resource-a#resource-b#resource-b"))
+		g.Expect(resInfosByLevel[1]).To(HaveLen(1))
+	}
+}
+
 func TestMapToCrossVersionObjectRef(t *testing.T) {
 	g := NewWithT(t)
 	resInfos := createTestScalableResourceInfos(map[int]int{0: 1})
 	objRefs := mapToCrossVersionObjectRef(resInfos)
 	g.Expect(objRefs).To(HaveLen(1))
-	g.Expect(objRefs[0]).To(Equal(*resInfos[0].ref))
+	g.Expect(util.RefsEqual(objRefs[0], *resInfos[0].ref)).To(BeTrue())
+}
+
+func TestMapToCrossVersionObjectRefShouldRemoveDuplicates(t *testing.T) {
+	g := NewWithT(t)
+	resInfos := createTestScalableResourceInfos(map[int]int{0: 2})
+	resInfos = append(resInfos, resInfos[0])
+	objRefs := mapToCrossVersionObjectRef(resInfos)
+	g.Expect(objRefs).To(HaveLen(2))
+	g.Expect(util.RefsEqual(objRefs[0], *resInfos[0].ref)).To(BeTrue())
+	g.Expect(util.RefsEqual(objRefs[1], *resInfos[1].ref)).To(BeTrue())
 }
 
 func TestMapToCrossVersionObjectRefForEmptyResInfos(t *testing.T) {
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prober
+
+import (
+	"context"
+)
+
+// ScalingFlowLimiter bounds the number of scaling flows that are allowed to run concurrently across all probers.
+type ScalingFlowLimiter interface {
+	// Acquire blocks till a slot to run a scaling flow is available. It returns an error if the context is cancelled
+	// or has expired before a slot could be acquired.
+	Acquire(ctx context.Context) error
+	// Release returns a slot previously obtained via Acquire.
+	Release()
+}
+
+// NewScalingFlowLimiter creates a ScalingFlowLimiter which allows at most maxConcurrentFlows scaling flows to run
+// at the same time. If maxConcurrentFlows is not positive then the number of concurrent scaling flows is not bounded.
+func NewScalingFlowLimiter(maxConcurrentFlows int) ScalingFlowLimiter {
+	if maxConcurrentFlows <= 0 {
+		return unboundedScalingFlowLimiter{}
+	}
+	return make(semaphoreScalingFlowLimiter, maxConcurrentFlows)
+}
+
+type semaphoreScalingFlowLimiter chan struct{}
+
+func (s semaphoreScalingFlowLimiter) Acquire(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s <- struct{}{}:
+		return nil
+	}
+}
+
+func (s semaphoreScalingFlowLimiter) Release() {
+	<-s
+}
+
+type unboundedScalingFlowLimiter struct{}
+
+func (unboundedScalingFlowLimiter) Acquire(_ context.Context) error {
+	return nil
+}
+
+func (unboundedScalingFlowLimiter) Release() {}
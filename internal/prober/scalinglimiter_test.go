@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package prober
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/internal/prober/scaler"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// concurrencyTrackingScaler records the maximum number of scaling flows that have been run concurrently.
+type concurrencyTrackingScaler struct {
+	running        atomic.Int32
+	maxRunning     atomic.Int32
+	completedFlows atomic.Int32
+	flowDuration   time.Duration
+}
+
+func (s *concurrencyTrackingScaler) ScaleUp(ctx context.Context) error {
+	return s.runFlow(ctx)
+}
+
+func (s *concurrencyTrackingScaler) ScaleDown(ctx context.Context) error {
+	return s.runFlow(ctx)
+}
+
+func (s *concurrencyTrackingScaler) ScaleResource(ctx context.Context, _ string, _ scaler.ScaleDirection) error {
+	return s.runFlow(ctx)
+}
+
+func (s *concurrencyTrackingScaler) FlowGraphs(_ context.Context) (scaler.FlowGraphs, error) {
+	return scaler.FlowGraphs{}, nil
+}
+
+func (s *concurrencyTrackingScaler) Close() {}
+
+func (s *concurrencyTrackingScaler) runFlow(_ context.Context) error {
+	current := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		observedMax := s.maxRunning.Load()
+		if current <= observedMax || s.maxRunning.CompareAndSwap(observedMax, current) {
+			break
+		}
+	}
+	time.Sleep(s.flowDuration)
+	s.completedFlows.Add(1)
+	return nil
+}
+
+func TestScalingFlowLimiterShouldBoundConcurrentAcquisitions(t *testing.T) {
+	g := NewWithT(t)
+	const maxConcurrentFlows = 3
+	limiter := NewScalingFlowLimiter(maxConcurrentFlows)
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Expect(limiter.Acquire(context.Background())).To(Succeed())
+			defer limiter.Release()
+			current := running.Add(1)
+			for {
+				observedMax := maxRunning.Load()
+				if current <= observedMax || maxRunning.CompareAndSwap(observedMax, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	g.Expect(maxRunning.Load()).To(BeNumerically("<=", maxConcurrentFlows))
+}
+
+func TestScalingFlowLimiterAcquireShouldFailWhenContextIsCancelled(t *testing.T) {
+	g := NewWithT(t)
+	limiter := NewScalingFlowLimiter(1)
+	g.Expect(limiter.Acquire(context.Background())).To(Succeed())
+	defer limiter.Release()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFn()
+	g.Expect(limiter.Acquire(ctx)).To(MatchError(context.DeadlineExceeded))
+}
+
+func TestUnboundedScalingFlowLimiterShouldNeverBlock(t *testing.T) {
+	g := NewWithT(t)
+	limiter := NewScalingFlowLimiter(0)
+	for i := 0; i < 10; i++ {
+		g.Expect(limiter.Acquire(context.Background())).To(Succeed())
+	}
+}
+
+func TestConcurrentScalingFlowsAcrossProbersShouldRespectLimit(t *testing.T) {
+	g := NewWithT(t)
+	const (
+		numProbers         = 20
+		maxConcurrentFlows = 2
+	)
+	mgr := NewManager(WithMaxConcurrentScalingFlows(maxConcurrentFlows))
+	scaler := &concurrencyTrackingScaler{flowDuration: 5 * time.Millisecond}
+	unhealthyProbeFn := func(_ context.Context) (bool, error) { return false, nil }
+
+	// a long probe interval ensures that every prober triggers exactly one scale down when it starts, the negligible jitter
+	// keeps the first probe from being delayed by a fraction of the probe interval.
+	config := createConfig(metav1.Duration{Duration: time.Hour}, metav1.Duration{Duration: 0}, metav1.Duration{Duration: 40 * time.Second}, 1e-9)
+	for i := 0; i < numProbers; i++ {
+		p := NewProber(context.Background(), nil, fmt.Sprintf("shoot--test--%d", i), config, nil, scaler, nil, logr.Discard(), WithProbeFn(unhealthyProbeFn), WithScalingFlowLimiter(mgr.GetScalingFlowLimiter()))
+		g.Expect(mgr.Register(*p)).To(BeTrue())
+		go p.Run()
+	}
+	defer func() {
+		for _, p := range mgr.GetAllProbers() {
+			mgr.Unregister(p.namespace)
+		}
+	}()
+
+	g.Eventually(scaler.completedFlows.Load).Within(5 * time.Second).Should(BeEquivalentTo(numProbers))
+	g.Expect(scaler.maxRunning.Load()).To(BeNumerically("<=", maxConcurrentFlows))
+	g.Expect(scaler.maxRunning.Load()).To(BeEquivalentTo(maxConcurrentFlows), "scaling flows should have run concurrently up to the limit")
+}
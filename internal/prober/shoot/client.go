@@ -21,6 +21,8 @@ import (
 const (
 	defaultGetSecretBackoff     = 100 * time.Millisecond
 	defaultGetSecretMaxAttempts = 3
+	// getSecretMetricsOperation is the operation label of the retry metrics for getting the kubeconfig secret of a shoot.
+	getSecretMetricsOperation = "get-kubeconfig-secret"
 )
 
 // ClientCreator provides a facade to create kubernetes client targeting a shoot.
@@ -71,7 +73,8 @@ func (s *clientCreator) getKubeConfigBytesFromSecret(ctx context.Context, logger
 		},
 		defaultGetSecretMaxAttempts,
 		defaultGetSecretBackoff,
-		canRetrySecretGet)
+		canRetrySecretGet,
+		util.WithMetricsOperation(getSecretMetricsOperation))
 	if retryResult.Err != nil {
 		return nil, retryResult.Err
 	}
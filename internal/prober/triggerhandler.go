@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prober
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-logr/logr"
+)
+
+// namespaceQueryParam is the query parameter of the debug requests which holds the shoot namespace.
+const namespaceQueryParam = "namespace"
+
+// ProbeTriggerResponse is the response to a probe trigger request.
+type ProbeTriggerResponse struct {
+	// Namespace is the shoot namespace of the prober.
+	Namespace string `json:"namespace"`
+	// Decision is the scaling decision which has been taken for the probe.
+	Decision ProbeDecision `json:"decision"`
+	// Error is the error of the probe or of the scaling operation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// NewProbeTriggerHandler creates an http.Handler which runs a probe of the prober registered for the shoot namespace given
+// via the namespace query parameter right away, see Manager.TriggerProbe. It only accepts POST requests, as the probe may
+// scale the dependent resources, and responds with a ProbeTriggerResponse.
+func NewProbeTriggerHandler(mgr Manager, logger logr.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		namespace := r.URL.Query().Get(namespaceQueryParam)
+		if namespace == "" {
+			http.Error(w, "query parameter namespace must be provided", http.StatusBadRequest)
+			return
+		}
+		logger.Info("Triggering probe", "shootNamespace", namespace)
+		decision, err := mgr.TriggerProbe(r.Context(), namespace)
+		response := ProbeTriggerResponse{Namespace: namespace, Decision: decision}
+		status := http.StatusOK
+		if err != nil {
+			response.Error = err.Error()
+			status = http.StatusInternalServerError
+			if errors.Is(err, ErrProberNotFound) || errors.Is(err, ErrProberClosed) {
+				status = http.StatusNotFound
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error(err, "Failed to write probe trigger response", "shootNamespace", namespace)
+		}
+	})
+}
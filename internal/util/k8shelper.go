@@ -14,6 +14,7 @@ import (
 
 	"github.com/go-logr/logr"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -126,7 +127,7 @@ func CreateScalesGetter(config *rest.Config) (scale.ScalesGetter, error) {
 	}
 	discoveryClient := clientSet.Discovery()
 	resolver := scale.NewDiscoveryScaleKindResolver(discoveryClient)
-	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	mapper := &noMatchResettingMapper{delegate: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))}
 	return scale.New(clientSet.RESTClient(), mapper, dynamic.LegacyAPIPathResolverFunc, resolver), nil
 }
 
@@ -144,14 +145,25 @@ func GetScaleResource(ctx context.Context, client client.Client, scaler scale.Sc
 	return &gr, scaleRes, err
 }
 
-// getGroupResource returns a schema.GroupResource for the given resourceRef.
+// getGroupResource returns a schema.GroupResource for the given resourceRef. If the kind of the resourceRef is not known to
+// the RESTMapper of the client, e.g. as its CustomResourceDefinition has been installed after the mappings have been loaded,
+// then the RESTMapper is reset and the mapping is looked up once more.
 func getGroupResource(client client.Client, logger logr.Logger, resourceRef *autoscalingv1.CrossVersionObjectReference) (schema.GroupResource, error) {
-	gv, _ := schema.ParseGroupVersion(resourceRef.APIVersion) // Ignoring the error as this validation has already been done when initially validating the Config
+	gv, err := schema.ParseGroupVersion(resourceRef.APIVersion)
+	if err != nil {
+		logger.Error(err, "Failed to parse APIVersion of resource", "apiVersion", resourceRef.APIVersion)
+		return schema.GroupResource{}, fmt.Errorf("invalid apiVersion %q of resource %s: %w", resourceRef.APIVersion, resourceRef.Name, err)
+	}
 	gk := schema.GroupKind{
 		Group: gv.Group,
 		Kind:  resourceRef.Kind,
 	}
-	mapping, err := client.RESTMapper().RESTMapping(gk, gv.Version)
+	mapper := client.RESTMapper()
+	mapping, err := mapper.RESTMapping(gk, gv.Version)
+	if meta.IsNoMatchError(err) && resetRESTMapper(mapper) {
+		logger.Info("Kind is not known to the RESTMapper, retrying after resetting it", "groupKind", gk.String())
+		mapping, err = mapper.RESTMapping(gk, gv.Version)
+	}
 	if err != nil {
 		logger.Error(err, "Failed to get RESTMapping for resource")
 		return schema.GroupResource{}, err
@@ -159,19 +171,46 @@ func getGroupResource(client client.Client, logger logr.Logger, resourceRef *aut
 	return mapping.Resource.GroupResource(), nil
 }
 
+// IsNamespaceTerminating checks if the namespace is being deleted, i.e. if its deletionTimestamp is set. A namespace which is
+// not found is not considered to be terminating, the resources in it are not found either.
+func IsNamespaceTerminating(ctx context.Context, cl client.Client, namespace string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return ns.DeletionTimestamp != nil, nil
+}
+
 // GetResourceAnnotations gets the annotations for a resource identified by resourceRef withing the given namespace.
 func GetResourceAnnotations(ctx context.Context, client client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (map[string]string, error) {
+	partialObjMeta, err := getPartialObjectMetadata(ctx, client, namespace, resourceRef)
+	if err != nil {
+		return nil, fmt.Errorf("error getting annotations for resource. Err: %w", err)
+	}
+	return partialObjMeta.Annotations, nil
+}
+
+// GetResourceMetadata gets the metadata, which includes the labels and the annotations, for a resource identified by
+// resourceRef within the given namespace.
+func GetResourceMetadata(ctx context.Context, client client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (*metav1.ObjectMeta, error) {
+	partialObjMeta, err := getPartialObjectMetadata(ctx, client, namespace, resourceRef)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata for resource. Err: %w", err)
+	}
+	return &partialObjMeta.ObjectMeta, nil
+}
+
+func getPartialObjectMetadata(ctx context.Context, client client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (*metav1.PartialObjectMetadata, error) {
 	partialObjMeta := &metav1.PartialObjectMetadata{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       resourceRef.Kind,
 			APIVersion: resourceRef.APIVersion,
 		},
 	}
-	err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: resourceRef.Name}, partialObjMeta)
-	if err != nil {
-		return nil, fmt.Errorf("error getting annotations for resource. Err: %w", err)
+	if err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: resourceRef.Name}, partialObjMeta); err != nil {
+		return nil, err
 	}
-	return partialObjMeta.Annotations, nil
+	return partialObjMeta, nil
 }
 
 // PatchResourceAnnotations patches the resource annotation with patchBytes. It uses StrategicMergePatchType strategy so the consumers should only provide changes to the annotations.
@@ -192,30 +231,92 @@ func PatchResourceAnnotations(ctx context.Context, cl client.Client, namespace s
 // GetResourceReadyReplicas gets spec.replicas for any resource identified via resourceRef withing the given namespace.
 // It is an error if there is no spec.replicas or if there is an error fetching the resource.
 func GetResourceReadyReplicas(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (int32, error) {
-	resObj := unstructured.Unstructured{}
+	return GetResourceStatusReplicas(ctx, cli, namespace, resourceRef, "readyReplicas")
+}
 
-	groupVersion, err := schema.ParseGroupVersion(resourceRef.APIVersion)
+// GetResourceStatusReplicas gets the number of replicas held by the given status field, e.g. readyReplicas, for any resource
+// identified via resourceRef within the given namespace. If the resource does not have the status field then 0 is returned.
+func GetResourceStatusReplicas(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference, statusField string) (int32, error) {
+	resObj, err := getUnstructuredResource(ctx, cli, namespace, resourceRef)
 	if err != nil {
 		return 0, err
 	}
-	resObj.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   groupVersion.Group,
-		Version: groupVersion.Version,
-		Kind:    resourceRef.Kind,
-	})
-	err = cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: resourceRef.Name}, &resObj)
+	replicas, found, err := unstructured.NestedInt64(resObj.Object, "status", statusField)
+	if !found {
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	readyReplicas, found, err := unstructured.NestedInt64(resObj.Object, "status", "readyReplicas")
-	if !found {
-		return 0, nil
+
+	return int32(replicas), nil // #nosec G115 -- number of replicas will not exceed MaxInt32
+}
+
+// GetResourceSpecReplicas gets spec.replicas directly from the resource identified via resourceRef within the given namespace,
+// without going through its scale subresource. It is an error if the resource does not have a spec.replicas field.
+func GetResourceSpecReplicas(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (int32, error) {
+	resObj, err := getUnstructuredResource(ctx, cli, namespace, resourceRef)
+	if err != nil {
+		return 0, err
 	}
+	replicas, found, err := unstructured.NestedInt64(resObj.Object, "spec", "replicas")
 	if err != nil {
 		return 0, err
 	}
+	if !found {
+		return 0, fmt.Errorf("resource %s does not have a spec.replicas field", ResourceRefKey(*resourceRef))
+	}
+	return int32(replicas), nil // #nosec G115 -- number of replicas will not exceed MaxInt32
+}
+
+// IsResourcePaused checks if spec.paused is set to true for the resource identified via resourceRef within the given namespace,
+// e.g. a Deployment whose rollouts have been paused. A resource which does not have a spec.paused field is not paused.
+func IsResourcePaused(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (bool, error) {
+	resObj, err := getUnstructuredResource(ctx, cli, namespace, resourceRef)
+	if err != nil {
+		return false, err
+	}
+	paused, _, err := unstructured.NestedBool(resObj.Object, "spec", "paused")
+	return paused, err
+}
+
+// UpdateResourceSpecReplicas sets spec.replicas directly on the resource identified via resourceRef within the given namespace,
+// without going through its scale subresource. The resource is only patched if it already has a spec.replicas field, so that
+// the field is never added to a resource which cannot be scaled.
+func UpdateResourceSpecReplicas(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference, replicas int32, fieldManager string) error {
+	if _, err := GetResourceSpecReplicas(ctx, cli, namespace, resourceRef); err != nil {
+		return err
+	}
+	resObj, err := newUnstructuredResource(namespace, resourceRef)
+	if err != nil {
+		return err
+	}
+	patchBytes := []byte(fmt.Sprintf("{\"spec\":{\"replicas\":%d}}", replicas))
+	return cli.Patch(ctx, resObj, client.RawPatch(types.MergePatchType, patchBytes), client.FieldOwner(fieldManager))
+}
+
+// getUnstructuredResource gets the resource identified via resourceRef within the given namespace as unstructured.Unstructured.
+func getUnstructuredResource(ctx context.Context, cli client.Client, namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (*unstructured.Unstructured, error) {
+	resObj, err := newUnstructuredResource(namespace, resourceRef)
+	if err != nil {
+		return nil, err
+	}
+	if err = cli.Get(ctx, client.ObjectKeyFromObject(resObj), resObj); err != nil {
+		return nil, err
+	}
+	return resObj, nil
+}
 
-	return int32(readyReplicas), nil // #nosec G115 -- number of replicas will not exceed MaxInt32
+func newUnstructuredResource(namespace string, resourceRef *autoscalingv1.CrossVersionObjectReference) (*unstructured.Unstructured, error) {
+	groupVersion, err := schema.ParseGroupVersion(resourceRef.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	resObj := &unstructured.Unstructured{}
+	resObj.SetGroupVersionKind(groupVersion.WithKind(resourceRef.Kind))
+	resObj.SetNamespace(namespace)
+	resObj.SetName(resourceRef.Name)
+	return resObj, nil
 }
 
 // CreateClientSetFromRestConfig creates a kubernetes.Clientset from rest.Config.
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "dwd"
+	operationLabel   = "operation"
+	// otherMetricsOperation is the operation label of the retry metrics for operations which have not been given one via WithMetricsOperation.
+	otherMetricsOperation = "other"
+)
+
+var (
+	// retryAttemptsTotal counts every attempt made to run an operation via Retry.
+	retryAttemptsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retry_attempts_total",
+			Help:      "Total number of attempts made to run a retriable operation.",
+		},
+		[]string{operationLabel},
+	)
+	// retryExhaustedTotal counts the number of times all attempts to run an operation via Retry have been exhausted without success.
+	retryExhaustedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retry_exhausted_total",
+			Help:      "Total number of times a retriable operation has exhausted all its attempts without succeeding.",
+		},
+		[]string{operationLabel},
+	)
+)
+
+// init registers the metrics with the controller-runtime registry. Since both prober and weeder share this package,
+// registering in init guarantees that it is done exactly once per process. Prometheus collectors are safe for concurrent use.
+func init() {
+	metrics.Registry.MustRegister(retryAttemptsTotal, retryExhaustedTotal)
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// readRateLimitedClient is a client.Client whose reads wait for the rate limiter before they are sent.
+type readRateLimitedClient struct {
+	client.Client
+	limiter flowcontrol.RateLimiter
+}
+
+// NewReadRateLimitedClient wraps the given client so that every Get and List first waits for the limiter. Writes are
+// passed through as is. The limiter can be shared by several clients to enforce a common budget for all of their reads.
+// If the limiter is nil then the client is returned unchanged.
+func NewReadRateLimitedClient(cli client.Client, limiter flowcontrol.RateLimiter) client.Client {
+	if limiter == nil {
+		return cli
+	}
+	return &readRateLimitedClient{Client: cli, limiter: limiter}
+}
+
+// Get waits for the rate limiter and then gets the object. It returns an error if the context is done while waiting.
+func (c *readRateLimitedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// List waits for the rate limiter and then lists the objects. It returns an error if the context is done while waiting.
+func (c *readRateLimitedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.List(ctx, list, opts...)
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReadRateLimitedClientShouldThrottleReads(t *testing.T) {
+	g := NewWithT(t)
+	const (
+		qps      = 20
+		numReads = 6
+	)
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	cli := NewReadRateLimitedClient(fake.NewClientBuilder().WithObjects(cm).Build(), flowcontrol.NewTokenBucketRateLimiter(qps, 1))
+
+	start := time.Now()
+	for i := 0; i < numReads; i++ {
+		if i%2 == 0 {
+			g.Expect(cli.Get(context.Background(), client.ObjectKeyFromObject(cm), &corev1.ConfigMap{})).To(Succeed())
+		} else {
+			g.Expect(cli.List(context.Background(), &corev1.ConfigMapList{})).To(Succeed())
+		}
+	}
+	// the first read consumes the burst, every subsequent read has to wait for a token
+	g.Expect(time.Since(start)).To(BeNumerically(">=", (numReads-1)*time.Second/qps-10*time.Millisecond))
+}
+
+func TestReadRateLimitedClientShouldNotThrottleWrites(t *testing.T) {
+	g := NewWithT(t)
+	limiter := flowcontrol.NewTokenBucketRateLimiter(0.001, 1)
+	cli := NewReadRateLimitedClient(fake.NewClientBuilder().Build(), limiter)
+
+	for i := 0; i < 3; i++ {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-", Namespace: "default"}}
+		g.Expect(cli.Create(context.Background(), cm)).To(Succeed())
+	}
+	g.Expect(limiter.TryAccept()).To(BeTrue(), "writes should not have consumed any token")
+}
+
+func TestReadRateLimitedClientShouldStopWaitingWhenContextIsDone(t *testing.T) {
+	g := NewWithT(t)
+	cli := NewReadRateLimitedClient(fake.NewClientBuilder().Build(), flowcontrol.NewTokenBucketRateLimiter(0.001, 1))
+	ctx, cancelFn := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelFn()
+
+	// the first read consumes the only token, the second one would have to wait far longer than the context allows
+	_ = cli.List(ctx, &corev1.ConfigMapList{})
+	g.Expect(cli.List(ctx, &corev1.ConfigMapList{})).ToNot(Succeed())
+}
+
+func TestNewReadRateLimitedClientWithoutLimiter(t *testing.T) {
+	g := NewWithT(t)
+	cli := fake.NewClientBuilder().Build()
+	g.Expect(NewReadRateLimitedClient(cli, nil)).To(BeIdenticalTo(cli))
+}
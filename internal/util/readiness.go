@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaitForDeploymentReady waits till the deployment identified by namespace and name has observed its latest generation and
+// has as many ready replicas as its desired replicas. It returns an error containing the last observed status of the
+// deployment if it is not ready within the timeout or if the context has been cancelled.
+func WaitForDeploymentReady(ctx context.Context, namespace, name string, cli client.Client, timeout, interval time.Duration) error {
+	return WaitForDeploymentReadyWithClock(ctx, clock.RealClock{}, namespace, name, cli, timeout, interval)
+}
+
+// WaitForDeploymentReadyWithClock is WaitForDeploymentReady measuring timeout and interval with the given clock.
+func WaitForDeploymentReadyWithClock(ctx context.Context, clk clock.Clock, namespace, name string, cli client.Client, timeout, interval time.Duration) error {
+	var lastObserved string
+	ready := RetryUntilPredicateWithClock(ctx, clk, logr.FromContextOrDiscard(ctx), fmt.Sprintf("wait for deployment %s/%s to be ready", namespace, name), func() bool {
+		deployment := &appsv1.Deployment{}
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, deployment); err != nil {
+			lastObserved = fmt.Sprintf("error getting deployment: %v", err)
+			return false
+		}
+		desiredReplicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			desiredReplicas = *deployment.Spec.Replicas
+		}
+		lastObserved = fmt.Sprintf("{replicas: %d, readyReplicas: %d, generation: %d, observedGeneration: %d}", desiredReplicas, deployment.Status.ReadyReplicas, deployment.Generation, deployment.Status.ObservedGeneration)
+		return deployment.Status.ObservedGeneration >= deployment.Generation && deployment.Status.ReadyReplicas >= desiredReplicas
+	}, timeout, interval)
+	if !ready {
+		return fmt.Errorf("timed out waiting for deployment %s/%s to be ready, last observed status: %s", namespace, name, lastObserved)
+	}
+	return nil
+}
+
+// WaitForPodReady waits till the pod identified by namespace and name has its Ready condition set to true. It returns an
+// error containing the last observed status of the pod if it is not ready within the timeout or if the context has been cancelled.
+func WaitForPodReady(ctx context.Context, namespace, name string, cli client.Client, timeout, interval time.Duration) error {
+	var lastObserved string
+	ready := RetryUntilPredicate(ctx, logr.FromContextOrDiscard(ctx), fmt.Sprintf("wait for pod %s/%s to be ready", namespace, name), func() bool {
+		pod := &corev1.Pod{}
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pod); err != nil {
+			lastObserved = fmt.Sprintf("error getting pod: %v", err)
+			return false
+		}
+		readyCondition := corev1.ConditionUnknown
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady {
+				readyCondition = condition.Status
+				break
+			}
+		}
+		lastObserved = fmt.Sprintf("{phase: %s, ready: %s}", pod.Status.Phase, readyCondition)
+		return readyCondition == corev1.ConditionTrue
+	}, timeout, interval)
+	if !ready {
+		return fmt.Errorf("timed out waiting for pod %s/%s to be ready, last observed status: %s", namespace, name, lastObserved)
+	}
+	return nil
+}
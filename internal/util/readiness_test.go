@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	testutil "github.com/gardener/dependency-watchdog/internal/test"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	readinessTestTimeout  = 5 * time.Second
+	readinessTestInterval = 100 * time.Millisecond
+	readinessTestImage    = "nginx:latest"
+)
+
+func TestReadinessSuite(t *testing.T) {
+	g := NewWithT(t)
+	controllerTestEnv, err := testutil.CreateDefaultControllerTestEnv(scheme.Scheme, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer controllerTestEnv.Delete()
+	cli := controllerTestEnv.GetClient()
+
+	tests := []struct {
+		title string
+		run   func(ctx context.Context, g *WithT, cli client.Client, namespace string)
+	}{
+		{"deployment which becomes ready", testWaitForDeploymentReadyWhenDeploymentBecomesReady},
+		{"deployment which never becomes ready", testWaitForDeploymentReadyShouldTimeOut},
+		{"deployment which does not exist", testWaitForDeploymentReadyForNonExistingDeployment},
+		{"pod which becomes ready", testWaitForPodReadyWhenPodBecomesReady},
+		{"pod which never becomes ready", testWaitForPodReadyShouldTimeOut},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			ctx := context.Background()
+			namespace := rand.String(4)
+			testutil.CreateTestNamespace(ctx, g, cli, namespace)
+			test.run(ctx, NewWithT(t), cli, namespace)
+		})
+	}
+}
+
+func testWaitForDeploymentReadyWhenDeploymentBecomesReady(ctx context.Context, g *WithT, cli client.Client, namespace string) {
+	deployment := testutil.GenerateDeployment("test-deployment", namespace, readinessTestImage, 2, nil)
+	g.Expect(cli.Create(ctx, deployment)).To(Succeed())
+
+	// there is no deployment controller in the test environment, the status is updated instead once the wait has started.
+	go func() {
+		time.Sleep(5 * readinessTestInterval)
+		deployment.Status.Replicas = 2
+		deployment.Status.ReadyReplicas = 2
+		deployment.Status.ObservedGeneration = deployment.Generation
+		g.Expect(cli.Status().Update(ctx, deployment)).To(Succeed())
+	}()
+
+	g.Expect(WaitForDeploymentReady(ctx, namespace, deployment.Name, cli, readinessTestTimeout, readinessTestInterval)).To(Succeed())
+}
+
+func testWaitForDeploymentReadyShouldTimeOut(ctx context.Context, g *WithT, cli client.Client, namespace string) {
+	deployment := testutil.GenerateDeployment("test-deployment", namespace, readinessTestImage, 2, nil)
+	g.Expect(cli.Create(ctx, deployment)).To(Succeed())
+	deployment.Status.Replicas = 2
+	deployment.Status.ReadyReplicas = 1
+	deployment.Status.ObservedGeneration = deployment.Generation
+	g.Expect(cli.Status().Update(ctx, deployment)).To(Succeed())
+
+	err := WaitForDeploymentReady(ctx, namespace, deployment.Name, cli, time.Second, readinessTestInterval)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("timed out waiting for deployment"))
+	g.Expect(err.Error()).To(ContainSubstring("replicas: 2, readyReplicas: 1"))
+}
+
+func testWaitForDeploymentReadyForNonExistingDeployment(ctx context.Context, g *WithT, cli client.Client, namespace string) {
+	err := WaitForDeploymentReady(ctx, namespace, "does-not-exist", cli, time.Second, readinessTestInterval)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("not found"))
+}
+
+func testWaitForPodReadyWhenPodBecomesReady(ctx context.Context, g *WithT, cli client.Client, namespace string) {
+	pod := newReadinessTestPod(namespace)
+	g.Expect(cli.Create(ctx, pod)).To(Succeed())
+
+	go func() {
+		time.Sleep(5 * readinessTestInterval)
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		g.Expect(cli.Status().Update(ctx, pod)).To(Succeed())
+	}()
+
+	g.Expect(WaitForPodReady(ctx, namespace, pod.Name, cli, readinessTestTimeout, readinessTestInterval)).To(Succeed())
+}
+
+func testWaitForPodReadyShouldTimeOut(ctx context.Context, g *WithT, cli client.Client, namespace string) {
+	pod := newReadinessTestPod(namespace)
+	g.Expect(cli.Create(ctx, pod)).To(Succeed())
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+	g.Expect(cli.Status().Update(ctx, pod)).To(Succeed())
+
+	err := WaitForPodReady(ctx, namespace, pod.Name, cli, time.Second, readinessTestInterval)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("timed out waiting for pod"))
+	g.Expect(err.Error()).To(ContainSubstring("phase: Running, ready: False"))
+}
+
+func newReadinessTestPod(namespace string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "test-container", Image: readinessTestImage}},
+		},
+	}
+}
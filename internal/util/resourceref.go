@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+)
+
+// ResourceRefKey returns a key which uniquely identifies the resource referenced by ref. It can be used as a map key
+// to de-duplicate or look up resource references.
+func ResourceRefKey(ref autoscalingv1.CrossVersionObjectReference) string {
+	return fmt.Sprintf("%s/%s/%s", ref.APIVersion, ref.Kind, ref.Name)
+}
+
+// RefsEqual returns true if both resource references point to the same resource.
+func RefsEqual(a, b autoscalingv1.CrossVersionObjectReference) bool {
+	return a.APIVersion == b.APIVersion && a.Kind == b.Kind && a.Name == b.Name
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+)
+
+var kcmRef = autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "kube-controller-manager", APIVersion: "apps/v1"}
+
+func TestResourceRefKey(t *testing.T) {
+	g := NewWithT(t)
+	table := []struct {
+		ref         autoscalingv1.CrossVersionObjectReference
+		expectedKey string
+	}{
+		{kcmRef, "apps/v1/Deployment/kube-controller-manager"},
+		{autoscalingv1.CrossVersionObjectReference{Kind: "ConfigMap", Name: "c1", APIVersion: "v1"}, "v1/ConfigMap/c1"},
+		{autoscalingv1.CrossVersionObjectReference{}, "//"},
+	}
+	for _, entry := range table {
+		g.Expect(ResourceRefKey(entry.ref)).To(Equal(entry.expectedKey))
+	}
+}
+
+func TestResourceRefKeyShouldBeUniquePerResource(t *testing.T) {
+	g := NewWithT(t)
+	refs := []autoscalingv1.CrossVersionObjectReference{
+		kcmRef,
+		{Kind: "StatefulSet", Name: "kube-controller-manager", APIVersion: "apps/v1"},
+		{Kind: "Deployment", Name: "kube-controller-manager", APIVersion: "apps/v1beta1"},
+		{Kind: "Deployment", Name: "machine-controller-manager", APIVersion: "apps/v1"},
+	}
+	keys := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		keys[ResourceRefKey(ref)] = struct{}{}
+	}
+	g.Expect(keys).To(HaveLen(len(refs)))
+}
+
+func TestRefsEqual(t *testing.T) {
+	g := NewWithT(t)
+	table := []struct {
+		a, b     autoscalingv1.CrossVersionObjectReference
+		expected bool
+	}{
+		{kcmRef, kcmRef, true},
+		{kcmRef, autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "kube-controller-manager", APIVersion: "apps/v1"}, true},
+		{kcmRef, autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "machine-controller-manager", APIVersion: "apps/v1"}, false},
+		{kcmRef, autoscalingv1.CrossVersionObjectReference{Kind: "StatefulSet", Name: "kube-controller-manager", APIVersion: "apps/v1"}, false},
+		{kcmRef, autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "kube-controller-manager", APIVersion: "v1"}, false},
+	}
+	for _, entry := range table {
+		g.Expect(RefsEqual(entry.a, entry.b)).To(Equal(entry.expected))
+		g.Expect(RefsEqual(entry.b, entry.a)).To(Equal(entry.expected))
+	}
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/scale"
+)
+
+// resetRESTMapper resets the cached mappings of the given meta.RESTMapper so that they are reloaded from discovery on the next
+// lookup. It returns false if the mapper cannot be reset.
+func resetRESTMapper(mapper meta.RESTMapper) bool {
+	resettable, ok := mapper.(meta.ResettableRESTMapper)
+	if !ok {
+		return false
+	}
+	resettable.Reset()
+	return true
+}
+
+// noMatchResettingMapper is a scale.PreferredResourceMapper which resets its cached mappings and retries once if a resource is
+// not known to it. This allows to scale resources whose CustomResourceDefinition has been installed after the mappings have
+// been loaded, which the delegate does not reload by itself once it has been populated.
+type noMatchResettingMapper struct {
+	delegate meta.ResettableRESTMapper
+}
+
+var _ scale.PreferredResourceMapper = (*noMatchResettingMapper)(nil)
+
+func (m *noMatchResettingMapper) ResourceFor(resource schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	gvr, err := m.delegate.ResourceFor(resource)
+	if meta.IsNoMatchError(err) {
+		m.delegate.Reset()
+		return m.delegate.ResourceFor(resource)
+	}
+	return gvr, err
+}
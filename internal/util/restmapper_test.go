@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package util
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var lateRegisteredGVK = schema.GroupVersionKind{Group: "example.gardener.cloud", Version: "v1alpha1", Kind: "Widget"}
+
+// lateRegisteringRESTMapper simulates a RESTMapper which has cached the mappings before the CustomResourceDefinition of
+// lateRegisteredGVK has been installed. The mapping only becomes known once the mapper has been reset.
+type lateRegisteringRESTMapper struct {
+	*meta.DefaultRESTMapper
+	resets int
+}
+
+func newLateRegisteringRESTMapper() *lateRegisteringRESTMapper {
+	return &lateRegisteringRESTMapper{DefaultRESTMapper: meta.NewDefaultRESTMapper(nil)}
+}
+
+func (m *lateRegisteringRESTMapper) Reset() {
+	m.resets++
+	m.Add(lateRegisteredGVK, meta.RESTScopeNamespace)
+}
+
+// nonResettableRESTMapper hides the Reset method of the lateRegisteringRESTMapper.
+type nonResettableRESTMapper struct {
+	meta.RESTMapper
+}
+
+func TestGetGroupResourceShouldResetRESTMapperForUnknownKind(t *testing.T) {
+	g := NewWithT(t)
+	mapper := newLateRegisteringRESTMapper()
+	cl := fake.NewClientBuilder().WithRESTMapper(mapper).Build()
+	resourceRef := &autoscalingv1.CrossVersionObjectReference{Kind: lateRegisteredGVK.Kind, Name: "widget", APIVersion: lateRegisteredGVK.GroupVersion().String()}
+
+	gr, err := getGroupResource(cl, logr.Discard(), resourceRef)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gr).To(Equal(schema.GroupResource{Group: lateRegisteredGVK.Group, Resource: "widgets"}))
+	g.Expect(mapper.resets).To(Equal(1))
+
+	_, err = getGroupResource(cl, logr.Discard(), resourceRef)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mapper.resets).To(Equal(1), "a known kind should not reset the RESTMapper")
+}
+
+func TestGetGroupResourceShouldFailForUnknownKindIfRESTMapperCannotBeReset(t *testing.T) {
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithRESTMapper(nonResettableRESTMapper{RESTMapper: newLateRegisteringRESTMapper()}).Build()
+	resourceRef := &autoscalingv1.CrossVersionObjectReference{Kind: lateRegisteredGVK.Kind, Name: "widget", APIVersion: lateRegisteredGVK.GroupVersion().String()}
+
+	_, err := getGroupResource(cl, logr.Discard(), resourceRef)
+	g.Expect(meta.IsNoMatchError(err)).To(BeTrue())
+}
+
+func TestNoMatchResettingMapperShouldResetDelegateForUnknownResource(t *testing.T) {
+	g := NewWithT(t)
+	delegate := newLateRegisteringRESTMapper()
+	mapper := &noMatchResettingMapper{delegate: delegate}
+
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Group: lateRegisteredGVK.Group, Resource: "widgets"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gvr).To(Equal(lateRegisteredGVK.GroupVersion().WithResource("widgets")))
+	g.Expect(delegate.resets).To(Equal(1))
+
+	_, err = mapper.ResourceFor(schema.GroupVersionResource{Group: "unknown.gardener.cloud", Resource: "gadgets"})
+	g.Expect(meta.IsNoMatchError(err)).To(BeTrue(), "a resource which is still unknown after the reset should not be found")
+	g.Expect(delegate.resets).To(Equal(2))
+}
+
+func TestGetGroupResourceShouldFailForInvalidAPIVersion(t *testing.T) {
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithRESTMapper(newLateRegisteringRESTMapper()).Build()
+	resourceRef := &autoscalingv1.CrossVersionObjectReference{Kind: lateRegisteredGVK.Kind, Name: "widget", APIVersion: "example.gardener.cloud/v1/alpha1"}
+
+	_, err := getGroupResource(cl, logr.Discard(), resourceRef)
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid apiVersion "example.gardener.cloud/v1/alpha1" of resource widget`)))
+}
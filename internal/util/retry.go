@@ -6,15 +6,57 @@ package util
 
 import (
 	"context"
+	"math"
+	"reflect"
 	"time"
 
 	"github.com/go-logr/logr"
+	"k8s.io/utils/clock"
 )
 
 // RetryResult captures the result of a retriable operation.
 type RetryResult[T any] struct {
 	Value T
 	Err   error
+	// contextErr is set when the retry has been stopped because the context has been cancelled or has expired.
+	contextErr bool
+}
+
+// IsContextError returns true if the retry was stopped because the context has been cancelled or has expired, in
+// which case Err is the error returned by the context. It returns false if Err has been returned by the operation itself,
+// even if that error wraps context.Canceled or context.DeadlineExceeded.
+func (r RetryResult[T]) IsContextError() bool {
+	return r.contextErr
+}
+
+// RetryOption configures a single invocation of Retry.
+type RetryOption func(options *retryOptions)
+
+type retryOptions struct {
+	quiet bool
+	// metricsOperation is the value of the operation label of the retry metrics, see WithMetricsOperation.
+	metricsOperation string
+	// keepPartialResult if set to true retains the last non-zero value of the operation when the context is done, see RetryWithPartialResult.
+	keepPartialResult bool
+}
+
+// WithQuietRetries controls whether intermediate failed attempts are logged. If set to true only the final outcome of
+// the operation is logged along with the number of attempts it took, which avoids noisy logs for operations which are
+// retried frequently.
+func WithQuietRetries(quiet bool) RetryOption {
+	return func(options *retryOptions) {
+		options.quiet = quiet
+	}
+}
+
+// WithMetricsOperation sets the value of the `operation` label of the retry metrics. Unlike the operation which is logged,
+// which usually identifies the namespace and resource it acts on, the value must be one of a small, fixed set of names, e.g.
+// `scale-up`, as every distinct value creates a time series which is never deleted. If it is not set then the attempts are
+// counted as `other`.
+func WithMetricsOperation(operation string) RetryOption {
+	return func(options *retryOptions) {
+		options.metricsOperation = operation
+	}
 }
 
 // Retry retries an operation `fn`, `numAttempts` number of times with a given `backOff` until one of the conditions is met:
@@ -22,19 +64,34 @@ type RetryResult[T any] struct {
 // 2. `canRetry` returns false.
 // 3. `numAttempts` have exhausted.
 // 4. `ctx` (context) has either been cancelled or it has expired.
-// The result is captured eventually in `RetryResult`.
-func Retry[T any](ctx context.Context, logger logr.Logger, operation string, fn func() (T, error), numAttempts int, backOff time.Duration, canRetry func(error) bool) RetryResult[T] {
-	var result T
+// The result is captured eventually in `RetryResult`. If `ctx` is done, be it before the first attempt or while backing off
+// after a failed attempt, the error of the last attempt is discarded and `ctx.Err()` is returned as the result error along
+// with the zero value, see RetryWithPartialResult to retain the last non-zero value instead.
+// `RetryResult.IsContextError` can be used to distinguish this case from a failure of the operation. Every attempt is counted in the `dwd_retry_attempts_total` metric and
+// exhausting all attempts is counted in the `dwd_retry_exhausted_total` metric, both labelled with the operation set via WithMetricsOperation.
+// By default every failed attempt is logged, see WithQuietRetries to only log the final outcome.
+func Retry[T any](ctx context.Context, logger logr.Logger, operation string, fn func() (T, error), numAttempts int, backOff time.Duration, canRetry func(error) bool, opts ...RetryOption) RetryResult[T] {
+	options := retryOptions{metricsOperation: otherMetricsOperation}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var result, partialResult T
 	var err error
 	for i := 1; i <= numAttempts; i++ {
 		select {
 		case <-ctx.Done():
-			logger.Error(ctx.Err(), "Context has been cancelled, stopping retry", "operation", operation)
-			return RetryResult[T]{Err: ctx.Err()}
+			return contextErrorResult(ctx, logger, operation, partialResult)
 		default:
 		}
+		retryAttemptsTotal.WithLabelValues(options.metricsOperation).Inc()
 		result, err = fn()
+		if options.keepPartialResult && !reflect.ValueOf(&result).Elem().IsZero() {
+			partialResult = result
+		}
 		if err == nil {
+			if options.quiet && i > 1 {
+				logger.Info("Operation succeeded after retrying", "operation", operation, "attempts", i)
+			}
 			return RetryResult[T]{Value: result, Err: err}
 		}
 		if !canRetry(err) {
@@ -43,37 +100,67 @@ func Retry[T any](ctx context.Context, logger logr.Logger, operation string, fn
 		}
 		select {
 		case <-ctx.Done():
-			logger.Error(ctx.Err(), "Context has been cancelled, stopping retry", "operation", operation)
-			return RetryResult[T]{Err: ctx.Err()}
+			return contextErrorResult(ctx, logger, operation, partialResult)
 		case <-time.After(backOff):
-			logger.Info("Will attempt to retry operation", "operation", operation, "currentAttempt", i, "error", err)
+			if !options.quiet {
+				logger.Info("Will attempt to retry operation", "operation", operation, "currentAttempt", i, "error", err)
+			}
 		}
 	}
+	retryExhaustedTotal.WithLabelValues(options.metricsOperation).Inc()
+	if options.quiet {
+		logger.Error(err, "Operation failed after exhausting all attempts", "operation", operation, "attempts", numAttempts)
+	}
 	return RetryResult[T]{Value: result, Err: err}
 }
 
+func contextErrorResult[T any](ctx context.Context, logger logr.Logger, operation string, partialResult T) RetryResult[T] {
+	logger.Error(ctx.Err(), "Context has been cancelled, stopping retry", "operation", operation)
+	return RetryResult[T]{Value: partialResult, Err: ctx.Err(), contextErr: true}
+}
+
+// RetryWithPartialResult is Retry for operations which accumulate state across attempts, e.g. an operation which returns the
+// items it has processed so far along with an error for the remaining ones. Unlike Retry, if `ctx` is done then the last non-zero
+// value returned by `fn`, be it along with an error or not, is returned as `RetryResult.Value` along with `ctx.Err()`, so that
+// the caller can act on the partial progress. `RetryResult.IsContextError` returns true in this case, as it does for Retry.
+// If `ctx` is done before `fn` has returned a non-zero value then the value is the zero value. All other outcomes are those of Retry.
+func RetryWithPartialResult[T any](ctx context.Context, logger logr.Logger, operation string, fn func() (T, error), numAttempts int, backOff time.Duration, canRetry func(error) bool, opts ...RetryOption) RetryResult[T] {
+	opts = append(opts, func(options *retryOptions) {
+		options.keepPartialResult = true
+	})
+	return Retry(ctx, logger, operation, fn, numAttempts, backOff, canRetry, opts...)
+}
+
 // RetryUntilPredicate retries an operation with a given `interval` until one of the following condition is met:
 // 1. `predicateFn` returns true.
 // 2. `timeout` expires.
 // 3. `ctx` (context) is cancelled or expires.
 // Returns true if the invocation of the `predicateFn` was successful and false otherwise.
+// `predicateFn` is checked right away, a predicate which is already satisfied is therefore returned without waiting for an
+// `interval`. Subsequent checks are made every `interval` till `timeout` expires, which bounds the total wait irrespective of
+// the `interval`. An `interval` which is not shorter than `timeout` hence results in a single check.
 func RetryUntilPredicate(ctx context.Context, logger logr.Logger, operation string, predicateFn func() bool, timeout time.Duration, interval time.Duration) bool {
-	timer := time.NewTimer(timeout)
-	for {
+	return RetryUntilPredicateWithClock(ctx, clock.RealClock{}, logger, operation, predicateFn, timeout, interval)
+}
+
+// RetryUntilPredicateWithClock is RetryUntilPredicate measuring `timeout` and `interval` with the given clock.
+func RetryUntilPredicateWithClock(ctx context.Context, clk clock.Clock, logger logr.Logger, operation string, predicateFn func() bool, timeout time.Duration, interval time.Duration) bool {
+	timer := clk.NewTimer(timeout)
+	defer timer.Stop()
+	for ctx.Err() == nil {
+		if predicateFn() {
+			return true
+		}
 		select {
 		case <-ctx.Done():
-			logger.Info("Context has been cancelled, exiting retrying operation", "operation", operation)
-			return false
-		case <-timer.C:
+		case <-timer.C():
 			logger.Info("Timed out waiting for predicateFn to be true", "operation", operation)
 			return false
-		default:
-			if predicateFn() {
-				return true
-			}
-			time.Sleep(interval)
+		case <-clk.After(interval):
 		}
 	}
+	logger.Info("Context has been cancelled, exiting retrying operation", "operation", operation)
+	return false
 }
 
 // RetryOnError retries invoking a function till either the invocation of the function does not return an error or the
@@ -97,6 +184,61 @@ func RetryOnError(ctx context.Context, logger logr.Logger, operation string, ret
 	}
 }
 
+// Backoff configures exponentially growing intervals, e.g. between the attempts of RetryOnErrorWithBackoff.
+type Backoff struct {
+	// Initial is the interval after the first failed attempt.
+	Initial time.Duration
+	// Factor multiplies the interval after every subsequent failed attempt. A factor less than 1 is treated as 1.
+	Factor float64
+	// Cap is the maximum interval between two attempts. If it is not positive then the interval is not capped.
+	Cap time.Duration
+	// Budget is the total time after which no further attempt is made. If it is not positive then attempts are made
+	// till the context is done.
+	Budget time.Duration
+}
+
+// Interval returns the interval to wait for after the given failed attempt, starting at 1.
+func (b Backoff) Interval(attempt int) time.Duration {
+	factor := max(b.Factor, 1)
+	interval := float64(b.Initial) * math.Pow(factor, float64(attempt-1))
+	if b.Cap > 0 && interval > float64(b.Cap) {
+		return b.Cap
+	}
+	return time.Duration(interval)
+}
+
+// RetryOnErrorWithBackoff retries invoking a function till the invocation does not return an error, the budget of the
+// backoff has been used up or the context has timed-out or has been cancelled. The interval between two attempts grows
+// exponentially up to the cap of the backoff, the last wait is shortened so that it does not exceed the budget. It returns
+// nil if the function eventually succeeded, the error of the context if it is done and otherwise the last error of the function.
+func RetryOnErrorWithBackoff(ctx context.Context, logger logr.Logger, operation string, retriableFn func() error, backoff Backoff) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			logger.Info("Context has either timed-out or has been cancelled", "operation", operation)
+			return ctx.Err()
+		}
+		err := retriableFn()
+		if err == nil {
+			return nil
+		}
+		interval := backoff.Interval(attempt)
+		if backoff.Budget > 0 {
+			remaining := backoff.Budget - time.Since(start)
+			if remaining <= 0 {
+				logger.Error(err, "Giving up retrying as the time budget has been used up", "operation", operation, "attempts", attempt, "budget", backoff.Budget)
+				return err
+			}
+			interval = min(interval, remaining)
+		}
+		logger.Error(err, "Error encountered during retry. Will re-attempt if possible", "operation", operation, "attempt", attempt, "backOff", interval)
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
 // AlwaysRetry always returns true irrespective of the error passed.
 func AlwaysRetry(_ error) bool {
 	return true
@@ -10,12 +10,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	testclock "k8s.io/utils/clock/testing"
 )
 
 var (
@@ -47,6 +52,48 @@ func TestErrorIfExceedsAttempts(t *testing.T) {
 	emptyList()
 }
 
+func TestRetryMetricsWhenAttemptsAreExhausted(t *testing.T) {
+	g := NewWithT(t)
+	operation := "test-exhausted-retries"
+	result := Retry(context.Background(), retryTestLogger, "exhausted-retries-of-resource", appendFail, numAttempts, backoff, AlwaysRetry, WithMetricsOperation(operation))
+	g.Expect(result.Err).To(HaveOccurred())
+	g.Expect(testutil.ToFloat64(retryAttemptsTotal.WithLabelValues(operation))).To(Equal(float64(numAttempts)))
+	g.Expect(testutil.ToFloat64(retryExhaustedTotal.WithLabelValues(operation))).To(Equal(float64(1)))
+	emptyList()
+}
+
+func TestRetryMetricsWhenTaskEventuallySucceeds(t *testing.T) {
+	g := NewWithT(t)
+	operation := "test-successful-retries"
+	result := Retry(context.Background(), retryTestLogger, "successful-retries-of-resource", passEventually(), numAttempts, backoff, AlwaysRetry, WithMetricsOperation(operation))
+	g.Expect(result.Err).ToNot(HaveOccurred())
+	g.Expect(testutil.ToFloat64(retryAttemptsTotal.WithLabelValues(operation))).To(Equal(float64(numAttempts)))
+	g.Expect(testutil.ToFloat64(retryExhaustedTotal.WithLabelValues(operation))).To(BeZero())
+	emptyList()
+}
+
+func TestRetryMetricsShouldUseOneSeriesForOperationsOnDifferentNamespaces(t *testing.T) {
+	g := NewWithT(t)
+	operation := "test-scale-up"
+	seriesBefore := testutil.CollectAndCount(retryAttemptsTotal)
+	for _, namespace := range []string{"shoot--foo--bar", "shoot--foo--baz"} {
+		result := Retry(context.Background(), retryTestLogger, fmt.Sprintf("scaleUp-resource-%s.kube-controller-manager", namespace), passEventually(), numAttempts, backoff, AlwaysRetry, WithMetricsOperation(operation))
+		g.Expect(result.Err).ToNot(HaveOccurred())
+		emptyList()
+	}
+	g.Expect(testutil.CollectAndCount(retryAttemptsTotal)).To(Equal(seriesBefore + 1))
+	g.Expect(testutil.ToFloat64(retryAttemptsTotal.WithLabelValues(operation))).To(Equal(float64(2 * numAttempts)))
+}
+
+func TestRetryMetricsShouldCountOperationWithoutMetricsOperationAsOther(t *testing.T) {
+	g := NewWithT(t)
+	attemptsBefore := testutil.ToFloat64(retryAttemptsTotal.WithLabelValues(otherMetricsOperation))
+	result := Retry(context.Background(), retryTestLogger, "unlabelled-operation", passEventually(), numAttempts, backoff, AlwaysRetry)
+	g.Expect(result.Err).ToNot(HaveOccurred())
+	g.Expect(testutil.ToFloat64(retryAttemptsTotal.WithLabelValues(otherMetricsOperation))).To(Equal(attemptsBefore + float64(numAttempts)))
+	emptyList()
+}
+
 func TestCanRetryReturnsFalse(t *testing.T) {
 	g := NewWithT(t)
 	var hasRunOnce bool
@@ -76,6 +123,7 @@ func TestContextCancelledBeforeTaskIsRun(t *testing.T) {
 		defer wg.Done()
 		result = Retry(ctx, retryTestLogger, "", appendPass, numAttempts, backoff, AlwaysRetry)
 		g.Expect(result.Err).Should(Equal(ctx.Err()))
+		g.Expect(result.IsContextError()).Should(BeTrue())
 		g.Expect(result.Value).Should(Equal(""))
 		g.Expect(len(list)).Should(BeNumerically("<=", numAttempts))
 	}()
@@ -99,6 +147,7 @@ func TestContextCancelledBeforeBackoffBegins(t *testing.T) {
 		}, numAttempts, backoff, AlwaysRetry)
 
 		g.Expect(result.Err).Should(Equal(context.Canceled))
+		g.Expect(result.IsContextError()).Should(BeTrue())
 		g.Expect(result.Value).Should(Equal(""))
 		g.Expect(list).Should(HaveLen(1))
 	}()
@@ -106,6 +155,105 @@ func TestContextCancelledBeforeBackoffBegins(t *testing.T) {
 	emptyList()
 }
 
+func TestContextErrorReturnedByOperationIsNotAContextError(t *testing.T) {
+	g := NewWithT(t)
+	result := Retry(context.Background(), retryTestLogger, "", func() (string, error) {
+		return "", fmt.Errorf("request failed: %w", context.DeadlineExceeded)
+	}, numAttempts, backoff, AlwaysRetry)
+	g.Expect(errors.Is(result.Err, context.DeadlineExceeded)).Should(BeTrue())
+	g.Expect(result.IsContextError()).Should(BeFalse(), "an error returned by the operation should not be treated as a context error")
+}
+
+func TestContextDeadlineExceededDuringBackoffIsAContextError(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), backoff/2)
+	defer cancelFn()
+	result := Retry(ctx, retryTestLogger, "", func() (string, error) {
+		return "", fmt.Errorf("appendFail")
+	}, numAttempts, backoff, AlwaysRetry)
+	g.Expect(result.Err).Should(Equal(context.DeadlineExceeded))
+	g.Expect(result.IsContextError()).Should(BeTrue())
+}
+
+func TestRetryWithPartialResultShouldReturnLastNonZeroValueOnTimeout(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*backoff/2)
+	defer cancelFn()
+	var processed []string
+	result := RetryWithPartialResult(ctx, retryTestLogger, "", func() ([]string, error) {
+		if len(processed) == 2 {
+			// an attempt which does not make any progress should not discard the progress of the previous attempts.
+			return nil, fmt.Errorf("no further progress")
+		}
+		processed = append(processed, fmt.Sprintf("item-%d", len(processed)))
+		return slices.Clone(processed), fmt.Errorf("%d items remaining", 3-len(processed))
+	}, 10, backoff, AlwaysRetry)
+	g.Expect(result.Err).Should(Equal(context.DeadlineExceeded))
+	g.Expect(result.IsContextError()).Should(BeTrue())
+	g.Expect(result.Value).Should(Equal([]string{"item-0", "item-1"}))
+}
+
+func TestRetryWithPartialResultShouldReturnZeroValueIfContextIsDoneBeforeAnyProgress(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	cancelFn()
+	result := RetryWithPartialResult(ctx, retryTestLogger, "", appendPass, numAttempts, backoff, AlwaysRetry)
+	g.Expect(result.Err).Should(Equal(context.Canceled))
+	g.Expect(result.IsContextError()).Should(BeTrue())
+	g.Expect(result.Value).Should(BeEmpty())
+	emptyList()
+}
+
+func TestRetryShouldDiscardPartialResultOnTimeout(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithTimeout(context.Background(), backoff/2)
+	defer cancelFn()
+	result := Retry(ctx, retryTestLogger, "", func() (int, error) {
+		return 1, fmt.Errorf("incomplete")
+	}, numAttempts, backoff, AlwaysRetry)
+	g.Expect(result.IsContextError()).Should(BeTrue())
+	g.Expect(result.Value).Should(BeZero(), "Retry should not return a partial result, unlike RetryWithPartialResult")
+}
+
+func TestQuietRetryShouldOnlyLogFinalOutcome(t *testing.T) {
+	g := NewWithT(t)
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	result := Retry(context.Background(), logger, "quiet-retry", func() (string, error) {
+		return "", fmt.Errorf("appendFail")
+	}, numAttempts, backoff, AlwaysRetry, WithQuietRetries(true))
+	g.Expect(result.Err).Should(HaveOccurred())
+	g.Expect(logs).Should(HaveLen(1), "only the final outcome should be logged")
+	g.Expect(logs[0]).Should(ContainSubstring("Operation failed after exhausting all attempts"))
+	g.Expect(logs[0]).Should(ContainSubstring(fmt.Sprintf(`"attempts"=%d`, numAttempts)))
+}
+
+func TestQuietRetryShouldLogAttemptsOnEventualSuccess(t *testing.T) {
+	g := NewWithT(t)
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	result := Retry(context.Background(), logger, "quiet-retry", passEventually(), numAttempts, backoff, AlwaysRetry, WithQuietRetries(true))
+	g.Expect(result.Err).ShouldNot(HaveOccurred())
+	g.Expect(logs).Should(HaveLen(1))
+	g.Expect(logs[0]).Should(ContainSubstring("Operation succeeded after retrying"))
+	g.Expect(logs[0]).Should(ContainSubstring(fmt.Sprintf(`"attempts"=%d`, numAttempts)))
+	emptyList()
+}
+
+func TestRetryShouldLogEveryFailedAttemptByDefault(t *testing.T) {
+	g := NewWithT(t)
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	result := Retry(context.Background(), logger, "noisy-retry", func() (string, error) {
+		return "", fmt.Errorf("appendFail")
+	}, numAttempts, backoff, AlwaysRetry)
+	g.Expect(result.Err).Should(HaveOccurred())
+	g.Expect(logs).Should(HaveLen(numAttempts))
+	for _, log := range logs {
+		g.Expect(log).Should(ContainSubstring("Will attempt to retry operation"))
+	}
+}
+
 func TestRetryUntilPredicateForContextCancelled(t *testing.T) {
 	g := NewWithT(t)
 	ctx, cancelFn := context.WithCancel(context.Background())
@@ -141,6 +289,92 @@ func TestRetryUntilPredicateWithBackgroundContext(t *testing.T) {
 	}
 }
 
+func TestRetryUntilPredicateShouldNotWaitForIntervalIfPredicateIsSatisfiedRightAway(t *testing.T) {
+	g := NewWithT(t)
+	checks := 0
+	start := time.Now()
+	result := RetryUntilPredicate(context.Background(), retryTestLogger, "", func() bool {
+		checks++
+		return true
+	}, time.Minute, time.Minute)
+	g.Expect(result).To(BeTrue())
+	g.Expect(checks).To(Equal(1))
+	g.Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
+
+func TestRetryUntilPredicateShouldCheckEveryIntervalTillPredicateIsSatisfied(t *testing.T) {
+	g := NewWithT(t)
+	checks := 0
+	result := RetryUntilPredicate(context.Background(), retryTestLogger, "", func() bool {
+		checks++
+		return checks == 3
+	}, time.Minute, time.Millisecond)
+	g.Expect(result).To(BeTrue())
+	g.Expect(checks).To(Equal(3))
+}
+
+func TestRetryUntilPredicateShouldNotWaitLongerThanTimeoutForLongInterval(t *testing.T) {
+	g := NewWithT(t)
+	checks := 0
+	start := time.Now()
+	result := RetryUntilPredicate(context.Background(), retryTestLogger, "", func() bool {
+		checks++
+		return false
+	}, 20*time.Millisecond, time.Minute)
+	g.Expect(result).To(BeFalse())
+	g.Expect(checks).To(Equal(1))
+	g.Expect(time.Since(start)).To(BeNumerically("<", time.Second), "the timeout should interrupt the wait for the next check")
+}
+
+func TestRetryUntilPredicateShouldNotCheckPredicateIfContextIsDone(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	cancelFn()
+	checks := 0
+	result := RetryUntilPredicate(ctx, retryTestLogger, "", func() bool {
+		checks++
+		return true
+	}, time.Minute, time.Millisecond)
+	g.Expect(result).To(BeFalse())
+	g.Expect(checks).To(BeZero())
+}
+
+func TestRetryUntilPredicateWithClockShouldCheckEveryIntervalOfFakeClock(t *testing.T) {
+	g := NewWithT(t)
+	fakeClock := testclock.NewFakeClock(time.Now())
+	var checks atomic.Int32
+	done := make(chan bool, 1)
+	go func() {
+		done <- RetryUntilPredicateWithClock(context.Background(), fakeClock, retryTestLogger, "", func() bool {
+			return checks.Add(1) == 3
+		}, time.Hour, time.Minute)
+	}()
+	// every poll advances the fake clock by an interval, which is far from the timeout.
+	g.Eventually(func() int32 {
+		fakeClock.Step(time.Minute)
+		return checks.Load()
+	}).Should(Equal(int32(3)))
+	g.Eventually(done).Should(Receive(BeTrue()))
+}
+
+func TestRetryUntilPredicateWithClockShouldTimeOutOnceFakeClockHasAdvancedByTimeout(t *testing.T) {
+	g := NewWithT(t)
+	fakeClock := testclock.NewFakeClock(time.Now())
+	var checks atomic.Int32
+	done := make(chan bool, 1)
+	go func() {
+		done <- RetryUntilPredicateWithClock(context.Background(), fakeClock, retryTestLogger, "", func() bool {
+			checks.Add(1)
+			return false
+		}, time.Hour, 2*time.Hour)
+	}()
+	g.Eventually(checks.Load).Should(Equal(int32(1)))
+	g.Consistently(done, 10*time.Millisecond).ShouldNot(Receive(), "the wait should not time out before the fake clock has advanced")
+	fakeClock.Step(time.Hour)
+	g.Eventually(done).Should(Receive(BeFalse()))
+	g.Expect(checks.Load()).To(Equal(int32(1)))
+}
+
 func TestRetryOnError(t *testing.T) {
 	g := NewWithT(t)
 	counter := 0
@@ -176,6 +410,76 @@ func TestRetryOnErrorWhenContextIsCancelled(t *testing.T) {
 	g.Expect(ctx.Err()).ToNot(Succeed())
 }
 
+func TestBackoffInterval(t *testing.T) {
+	tests := []struct {
+		description       string
+		backoff           Backoff
+		expectedIntervals []time.Duration
+	}{
+		{"interval should grow exponentially up to the cap", Backoff{Initial: 100 * time.Millisecond, Factor: 2, Cap: time.Second}, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}},
+		{"interval should not be capped without a cap", Backoff{Initial: time.Second, Factor: 3}, []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 27 * time.Second}},
+		{"interval should be constant for a factor less than 1", Backoff{Initial: time.Second, Factor: 0.5}, []time.Duration{time.Second, time.Second, time.Second}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			for i, expected := range entry.expectedIntervals {
+				g.Expect(entry.backoff.Interval(i+1)).To(Equal(expected), "interval after attempt %d", i+1)
+			}
+		})
+	}
+}
+
+func TestRetryOnErrorWithBackoffShouldBackOffBetweenAttempts(t *testing.T) {
+	g := NewWithT(t)
+	var attemptTimes []time.Time
+	fn := func() error {
+		attemptTimes = append(attemptTimes, time.Now())
+		if len(attemptTimes) < 4 {
+			return errors.New("failing attempt")
+		}
+		return nil
+	}
+	backoff := Backoff{Initial: 10 * time.Millisecond, Factor: 2, Cap: 30 * time.Millisecond}
+	g.Expect(RetryOnErrorWithBackoff(context.Background(), retryTestLogger, "", fn, backoff)).To(Succeed())
+	g.Expect(attemptTimes).To(HaveLen(4))
+	for i, minInterval := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond} {
+		g.Expect(attemptTimes[i+1].Sub(attemptTimes[i])).To(BeNumerically(">=", minInterval), "interval after attempt %d", i+1)
+	}
+}
+
+func TestRetryOnErrorWithBackoffShouldGiveUpAfterBudget(t *testing.T) {
+	g := NewWithT(t)
+	attempts := 0
+	fn := func() error {
+		attempts++
+		return fmt.Errorf("attempt %d failed", attempts)
+	}
+	backoff := Backoff{Initial: 10 * time.Millisecond, Factor: 2, Cap: 40 * time.Millisecond, Budget: 100 * time.Millisecond}
+	start := time.Now()
+	err := RetryOnErrorWithBackoff(context.Background(), retryTestLogger, "", fn, backoff)
+	elapsed := time.Since(start)
+	g.Expect(err).To(MatchError(fmt.Sprintf("attempt %d failed", attempts)), "the error of the last attempt should be returned")
+	g.Expect(elapsed).To(BeNumerically(">=", backoff.Budget))
+	g.Expect(elapsed).To(BeNumerically("<", backoff.Budget+backoff.Cap), "no attempt should be made once the budget has been used up")
+	// attempts are made after 0, 10, 30, 70 and 100 (instead of 110) milliseconds, fewer if the waits overrun.
+	g.Expect(attempts).To(BeNumerically("<=", 5))
+}
+
+func TestRetryOnErrorWithBackoffWhenContextIsCancelled(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	attempts := 0
+	fn := func() error {
+		attempts++
+		cancelFn()
+		return errors.New("failing attempt")
+	}
+	err := RetryOnErrorWithBackoff(ctx, retryTestLogger, "", fn, Backoff{Initial: time.Minute})
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(attempts).To(Equal(1))
+}
+
 func appendFail() (string, error) {
 	list = append(list, "appendFail")
 	return "appendFail", fmt.Errorf("appendFail")
@@ -5,35 +5,92 @@
 package util
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/yaml"
 )
 
+var (
+	// ErrConfigNotFound is returned when a configuration file does not exist or cannot be read.
+	ErrConfigNotFound = errors.New("config file not found")
+	// ErrConfigParse is returned when the content of a configuration file cannot be unmarshalled.
+	ErrConfigParse = errors.New("config file cannot be parsed")
+	// ErrConfigInvalid is returned when an unmarshalled configuration fails its validation.
+	ErrConfigInvalid = errors.New("config is invalid")
+)
+
 // SleepWithContext sleeps until sleepFor duration has expired or the context has been cancelled.
 func SleepWithContext(ctx context.Context, sleepFor time.Duration) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(sleepFor):
-			return nil
+	return SleepWithClock(ctx, clock.RealClock{}, sleepFor)
+}
+
+// SleepWithClock is SleepWithContext measuring sleepFor with the given clock, which allows tests to advance the sleep
+// with a fake clock instead of waiting for it. A sleepFor which is not positive does not wait for the clock at all.
+func SleepWithClock(ctx context.Context, clk clock.Clock, sleepFor time.Duration) error {
+	if sleepFor <= 0 {
+		return ctx.Err()
+	}
+	timer := clk.NewTimer(sleepFor)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// JitterUntil runs fn every interval till the context has been cancelled. Each interval, which is measured from the end of
+// the previous run of fn, is extended by a random duration of up to jitterFactor * interval, see wait.Jitter. The first run of
+// fn is delayed by such a random duration as well so that loops which are started at the same time are phase-shifted
+// against each other instead of running in lockstep.
+func JitterUntil(ctx context.Context, fn func(ctx context.Context), interval time.Duration, jitterFactor float64) {
+	if err := SleepWithContext(ctx, wait.Jitter(interval, jitterFactor)-interval); err != nil {
+		return
+	}
+	for ctx.Err() == nil {
+		fn(ctx)
+		if err := SleepWithContext(ctx, wait.Jitter(interval, jitterFactor)); err != nil {
+			return
 		}
 	}
 }
 
-// ReadAndUnmarshall reads file and Unmarshall the contents in a generic type
+// ReadAndUnmarshall reads file and Unmarshall the contents in a generic type. A file which cannot be read results in an
+// ErrConfigNotFound, contents which cannot be unmarshalled result in an ErrConfigParse.
 func ReadAndUnmarshall[T any](filename string) (*T, error) {
 	configBytes, err := os.ReadFile(filename) // #nosec G304 -- Loaded from ConfigMap
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrConfigNotFound, err)
 	}
 	t := new(T)
 	err = yaml.Unmarshal(configBytes, t)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+	return t, nil
+}
+
+// ReadAndUnmarshallStrict reads file and Unmarshall the contents in a generic type. Unlike ReadAndUnmarshall, a field which
+// is not known to the type, e.g. as its key is misspelled, or a field which is specified more than once results in an error.
+func ReadAndUnmarshallStrict[T any](filename string) (*T, error) {
+	configBytes, err := os.ReadFile(filename) // #nosec G304 -- Loaded from ConfigMap
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigNotFound, err)
+	}
+	t := new(T)
+	err = yaml.UnmarshalStrict(configBytes, t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigParse, err)
 	}
 	return t, nil
 }
@@ -58,3 +115,8 @@ func GetSliceOrDefault[T any](val []T, defaultVal []T) []T {
 	}
 	return val
 }
+
+// SortedKeys returns the keys of the map in ascending order.
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	return slices.Sorted(maps.Keys(m))
+}
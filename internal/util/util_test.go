@@ -8,11 +8,15 @@ package util
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
+	testclock "k8s.io/utils/clock/testing"
 	"k8s.io/utils/pointer"
 
 	papi "github.com/gardener/dependency-watchdog/api/prober"
@@ -50,10 +54,26 @@ func TestSleepWithContextForNonCancellableContext(t *testing.T) {
 	g.Expect(err).ShouldNot(HaveOccurred())
 }
 
+func TestSleepWithClockShouldReturnOnceFakeClockHasAdvanced(t *testing.T) {
+	g := NewWithT(t)
+	fakeClock := testclock.NewFakeClock(time.Now())
+	done := make(chan error, 1)
+	go func() {
+		done <- SleepWithClock(context.Background(), fakeClock, time.Hour)
+	}()
+	g.Eventually(fakeClock.HasWaiters).Should(BeTrue())
+	fakeClock.Step(time.Hour - time.Second)
+	g.Consistently(done, 10*time.Millisecond).ShouldNot(Receive(), "sleep should not return before the fake clock has advanced by sleepFor")
+	fakeClock.Step(time.Second)
+	g.Eventually(done).Should(Receive(BeNil()))
+}
+
 func TestReadAndUnmarshallNonExistingFile(t *testing.T) {
 	g := NewWithT(t)
 	_, err := ReadAndUnmarshall[papi.Config]("file-that-does-not-exists.yaml")
 	g.Expect(err).To(HaveOccurred())
+	g.Expect(errors.Is(err, ErrConfigNotFound)).To(BeTrue())
+	g.Expect(errors.Is(err, fs.ErrNotExist)).To(BeTrue(), "the error of reading the file should be retained")
 }
 
 func TestReadAndUnmarshall(t *testing.T) {
@@ -74,6 +94,20 @@ func TestReadAndUnmarshall(t *testing.T) {
 	}
 }
 
+func TestReadAndUnmarshallStrictShouldRejectUnknownField(t *testing.T) {
+	g := NewWithT(t)
+	type config struct {
+		Name    string
+		Version string
+	}
+	// the test config also contains a Data field which is not known to the config type.
+	configPath := filepath.Join("testdata", "test-config.yaml")
+	_, err := ReadAndUnmarshallStrict[config](configPath)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring(`unknown field "Data"`))
+	g.Expect(errors.Is(err, ErrConfigParse)).To(BeTrue())
+}
+
 func TestEqualOrBeforeNow(t *testing.T) {
 	g := NewWithT(t)
 	g.Expect(EqualOrBeforeNow(time.Now())).To(BeTrue())
@@ -112,3 +146,52 @@ func TestGetSliceOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestSortedKeys(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(SortedKeys(map[int]string{3: "c", -1: "a", 2: "b", 0: ""})).To(Equal([]int{-1, 0, 2, 3}))
+	g.Expect(SortedKeys(map[string]bool{"kube-controller-manager": true, "cluster-autoscaler": false, "machine-controller-manager": true})).
+		To(Equal([]string{"cluster-autoscaler", "kube-controller-manager", "machine-controller-manager"}))
+	g.Expect(SortedKeys(map[string]int{})).To(BeEmpty())
+	g.Expect(SortedKeys[string, int](nil)).To(BeEmpty())
+}
+
+func TestJitterUntilShouldRunTillContextIsCancelled(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	runs := 0
+	JitterUntil(ctx, func(_ context.Context) {
+		runs++
+		if runs == 3 {
+			cancelFn()
+		}
+	}, time.Millisecond, 0.1)
+	g.Expect(runs).To(Equal(3))
+}
+
+func TestJitterUntilShouldPhaseShiftLoopsStartedTogether(t *testing.T) {
+	g := NewWithT(t)
+	const (
+		numLoops = 10
+		interval = 100 * time.Millisecond
+	)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	start := time.Now()
+	firstTicks := make(chan time.Duration, numLoops)
+	for i := 0; i < numLoops; i++ {
+		loopCtx, loopCancelFn := context.WithCancel(ctx)
+		go JitterUntil(loopCtx, func(_ context.Context) {
+			firstTicks <- time.Since(start)
+			loopCancelFn()
+		}, interval, 1.0)
+	}
+	ticks := make([]time.Duration, 0, numLoops)
+	for i := 0; i < numLoops; i++ {
+		ticks = append(ticks, <-firstTicks)
+	}
+	slices.Sort(ticks)
+	// the first ticks are spread uniformly over the interval, it is very unlikely that all of them fall into a fifth of it.
+	g.Expect(ticks[numLoops-1] - ticks[0]).To(BeNumerically(">", interval/5))
+	g.Expect(ticks[numLoops-1]).To(BeNumerically("<", 2*interval))
+}
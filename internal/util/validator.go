@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -61,6 +62,41 @@ func (v *Validator) MustNotBeZeroDuration(key string, duration metav1.Duration)
 	return true
 }
 
+// DurationMustBePositive checks whether the given duration is greater than zero. It returns false if it is zero or negative.
+// A nil duration is not validated, MustNotBeNil should be used in addition if the duration is mandatory.
+func (v *Validator) DurationMustBePositive(key string, duration *metav1.Duration) bool {
+	if duration == nil {
+		return true
+	}
+	if duration.Duration <= 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("value for key %s must be greater than zero, found %s", key, duration.Duration))
+		return false
+	}
+	return true
+}
+
+// DurationMustBeWithin checks whether the given duration lies within min and max, both inclusive. It returns false if it is
+// outside of this range. A nil duration is not validated, MustNotBeNil should be used in addition if the duration is mandatory.
+func (v *Validator) DurationMustBeWithin(key string, duration *metav1.Duration, min, max time.Duration) bool {
+	if duration == nil {
+		return true
+	}
+	if duration.Duration < min || duration.Duration > max {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("value for key %s must be within [%s, %s], found %s", key, min, max, duration.Duration))
+		return false
+	}
+	return true
+}
+
+// MustBePositive checks whether the given value is greater than zero. It returns false if it is zero or negative.
+func (v *Validator) MustBePositive(key string, value int) bool {
+	if value <= 0 {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("value for key %s must be greater than zero", key))
+		return false
+	}
+	return true
+}
+
 // MustNotBeNil checks whether the given value is nil and returns false if it is nil.
 func (v *Validator) MustNotBeNil(key string, value interface{}) bool {
 	if value == nil || reflect.ValueOf(value).IsNil() {
@@ -8,6 +8,7 @@ package util
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -65,6 +66,84 @@ func TestMustNotBeZeroDuration(t *testing.T) {
 	}
 }
 
+func TestDurationMustBePositive(t *testing.T) {
+	g := NewWithT(t)
+	tests := []struct {
+		key    string
+		value  *metav1.Duration
+		result bool
+	}{
+		{"k1", nil, true},
+		{"k2", &metav1.Duration{}, false},
+		{"k3", &metav1.Duration{Duration: -time.Second}, false},
+		{"k4", &metav1.Duration{Duration: time.Second}, true},
+	}
+	for _, entry := range tests {
+		v := Validator{}
+		actualResult := v.DurationMustBePositive(entry.key, entry.value)
+		g.Expect(actualResult).To(Equal(entry.result), "unexpected result for key %s", entry.key)
+		if actualResult {
+			g.Expect(v.Error).ToNot(HaveOccurred())
+		} else {
+			g.Expect(v.Error).To(HaveOccurred())
+			g.Expect(v.Error.Error()).To(ContainSubstring(entry.key))
+		}
+	}
+}
+
+func TestDurationMustBeWithin(t *testing.T) {
+	g := NewWithT(t)
+	const (
+		minDuration = time.Duration(0)
+		maxDuration = time.Minute
+	)
+	tests := []struct {
+		key    string
+		value  *metav1.Duration
+		result bool
+	}{
+		{"k1", nil, true},
+		{"k2", &metav1.Duration{}, true},
+		{"k3", &metav1.Duration{Duration: -time.Second}, false},
+		{"k4", &metav1.Duration{Duration: 30 * time.Second}, true},
+		{"k5", &metav1.Duration{Duration: maxDuration}, true},
+		{"k6", &metav1.Duration{Duration: time.Hour}, false},
+	}
+	for _, entry := range tests {
+		v := Validator{}
+		actualResult := v.DurationMustBeWithin(entry.key, entry.value, minDuration, maxDuration)
+		g.Expect(actualResult).To(Equal(entry.result), "unexpected result for key %s", entry.key)
+		if actualResult {
+			g.Expect(v.Error).ToNot(HaveOccurred())
+		} else {
+			g.Expect(v.Error).To(HaveOccurred())
+			g.Expect(v.Error.Error()).To(ContainSubstring(entry.key))
+		}
+	}
+}
+
+func TestMustBePositive(t *testing.T) {
+	g := NewWithT(t)
+	tests := []struct {
+		key    string
+		value  int
+		result bool
+	}{
+		{"k1", -1, false},
+		{"k2", 0, false},
+		{"k3", 1, true},
+		{"k4", 5, true},
+	}
+	for _, entry := range tests {
+		v := Validator{}
+		actualResult := v.MustBePositive(entry.key, entry.value)
+		g.Expect(entry.result).To(Equal(actualResult))
+		if !actualResult {
+			g.Expect(v.Error).To(HaveOccurred())
+		}
+	}
+}
+
 func TestMustNotBeNil(t *testing.T) {
 	g := NewWithT(t)
 	var ch chan struct{}
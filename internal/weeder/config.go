@@ -5,6 +5,8 @@
 package weeder
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	wapi "github.com/gardener/dependency-watchdog/api/weeder"
@@ -21,7 +23,8 @@ const (
 )
 
 // LoadConfig reads the weeder configuration from a file, unmarshalls it, fills in the default values and
-// validates the unmarshalled configuration. If all validations pass it will return papi.Config else it will return an error.
+// validates the unmarshalled configuration. If all validations pass it will return papi.Config else it will return an error
+// which matches util.ErrConfigNotFound, util.ErrConfigParse or util.ErrConfigInvalid, see errors.Is.
 func LoadConfig(filename string) (*wapi.Config, error) {
 	config, err := util.ReadAndUnmarshall[wapi.Config](filename)
 	if err != nil {
@@ -30,7 +33,7 @@ func LoadConfig(filename string) (*wapi.Config, error) {
 	fillDefaultValues(config)
 	err = validate(config)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", util.ErrConfigInvalid, err)
 	}
 	return config, nil
 }
@@ -39,7 +42,7 @@ func validate(c *wapi.Config) error {
 	v := new(util.Validator)
 	// Check the mandatory config parameters for which a default will not be set
 	v.MustNotBeEmpty("serviceAndDependantSelectors", c.ServicesAndDependantSelectors)
-	for _, ds := range c.ServicesAndDependantSelectors {
+	for service, ds := range c.ServicesAndDependantSelectors {
 		v.MustNotBeEmpty("podSelectors", ds.PodSelectors)
 		for _, selector := range ds.PodSelectors {
 			_, err := metav1.LabelSelectorAsSelector(selector)
@@ -48,10 +51,64 @@ func validate(c *wapi.Config) error {
 				continue
 			}
 		}
+		for _, ns := range ds.AdditionalNamespaces {
+			v.MustNotBeEmpty("additionalNamespaces", ns)
+		}
+		if ds.Mode != "" && ds.Mode != wapi.WeedingModeEnforce && ds.Mode != wapi.WeedingModeObserve {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("mode %q of service %s must be one of %q, %q", ds.Mode, service, wapi.WeedingModeEnforce, wapi.WeedingModeObserve))
+		}
+	}
+	if c.RestartCountThreshold != nil {
+		v.MustBePositive("restartCountThreshold", int(*c.RestartCountThreshold))
+	}
+	if c.MaxPodsPerTransition != nil {
+		v.MustBePositive("maxPodsPerTransition", int(*c.MaxPodsPerTransition))
 	}
+	v.DurationMustBePositive("endpointStabilityDuration", c.EndpointStabilityDuration)
+	v.DurationMustBePositive("weedingCooldown", c.WeedingCooldown)
+	if c.NotReadyThreshold != nil && v.DurationMustBePositive("notReadyThreshold", c.NotReadyThreshold) && c.NotReadyThreshold.Duration >= c.WatchDuration.Duration {
+		v.Error = multierr.Append(v.Error, fmt.Errorf("notReadyThreshold %s must be less than watchDuration %s", c.NotReadyThreshold.Duration, c.WatchDuration.Duration))
+	}
+	validateServiceMatchers(v, c.Allowlist, c.Denylist)
 	return v.Error
 }
 
+// validateServiceMatchers checks that every allowlist and denylist entry matches on at least a namespace or a service and
+// that no allowlist entry is entirely covered by a denylist entry, as such an entry would never allow any service.
+func validateServiceMatchers(v *util.Validator, allowlist, denylist []wapi.ServiceMatcher) {
+	for _, m := range append(slices.Clone(allowlist), denylist...) {
+		if m.Namespace == "" && m.Service == "" {
+			v.Error = multierr.Append(v.Error, fmt.Errorf("allowlist and denylist entries must specify a namespace or a service"))
+		}
+	}
+	for _, allowed := range allowlist {
+		for _, denied := range denylist {
+			if denied.Covers(allowed) {
+				v.Error = multierr.Append(v.Error, fmt.Errorf("allowlist entry %+v overlaps with denylist entry %+v", allowed, denied))
+			}
+		}
+	}
+}
+
+// IsServicePermitted checks if weeding is permitted for the service identified by namespace and name. A service is permitted
+// if it is not matched by any denylist entry and, if an allowlist is configured, it is matched by at least one allowlist entry.
+func IsServicePermitted(c *wapi.Config, namespace, name string) bool {
+	for _, denied := range c.Denylist {
+		if denied.Matches(namespace, name) {
+			return false
+		}
+	}
+	if len(c.Allowlist) == 0 {
+		return true
+	}
+	for _, allowed := range c.Allowlist {
+		if allowed.Matches(namespace, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func fillDefaultValues(c *wapi.Config) {
 	if c.WatchDuration == nil {
 		c.WatchDuration = &metav1.Duration{
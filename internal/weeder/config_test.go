@@ -7,10 +7,13 @@
 package weeder
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
 	testutil "github.com/gardener/dependency-watchdog/internal/test"
+	"github.com/gardener/dependency-watchdog/internal/util"
 	multierr "github.com/hashicorp/go-multierror"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -25,6 +28,17 @@ func TestConfigFileNotFound(t *testing.T) {
 	g.Expect(err).To(HaveOccurred(), "LoadConfig should give error if config file is not found")
 	g.Expect(config).To(BeNil(), "LoadConfig should return a nil config if config file is not found")
 	g.Expect(err.Error()).To(ContainSubstring("no such file or directory"), "LoadConfig did not load all the dependent resources")
+	g.Expect(errors.Is(err, util.ErrConfigNotFound)).To(BeTrue(), "a missing config file should result in an ErrConfigNotFound")
+}
+
+func TestConfigWithInvalidSyntaxShouldReturnParseError(t *testing.T) {
+	g := NewWithT(t)
+	configPath := filepath.Join(testdataPath, "config_invalid_syntax.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with invalid syntax")
+	g.Expect(config).To(BeNil())
+	g.Expect(errors.Is(err, util.ErrConfigParse)).To(BeTrue(), "a config with invalid syntax should result in an ErrConfigParse")
 }
 
 func TestCheckIfDefaultValuesAreSetForAllOptionalMissingValues(t *testing.T) {
@@ -59,7 +73,8 @@ func TestMissingMandatoryFieldsShouldReturnErrorAndNilConfig(t *testing.T) {
 
 		g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with missing mandatory values")
 		g.Expect(config).To(BeNil(), "LoadConfig should return a nil config for a file with missing mandatory values")
-		if merr, ok := err.(*multierr.Error); ok {
+		var merr *multierr.Error
+		if errors.As(err, &merr) {
 			g.Expect(merr.Errors).To(HaveLen(entry.expectedErrCount), "LoadConfig did not return all the errors for a faulty config")
 		}
 	}
@@ -79,3 +94,133 @@ func TestValidConfigShouldPassAllValidations(t *testing.T) {
 
 	t.Log("Valid config is loaded correctly")
 }
+
+func TestObserveModeShouldBeLoadedPerService(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_observe_mode.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).ToNot(HaveOccurred(), "LoadConfig should not give error for a config with a service in observe mode")
+	g.Expect(config.ServicesAndDependantSelectors["kube-apiserver"].Mode).To(Equal(wapi.WeedingModeObserve))
+	g.Expect(config.ServicesAndDependantSelectors["etcd-main-client"].Mode).To(BeEmpty())
+}
+
+func TestOverlappingAllowlistAndDenylistShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_overlapping_service_matchers.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with overlapping allowlist and denylist entries")
+	g.Expect(config).To(BeNil())
+	var merr *multierr.Error
+	g.Expect(errors.As(err, &merr)).To(BeTrue())
+	g.Expect(merr.Errors).To(HaveLen(2))
+	g.Expect(err.Error()).To(ContainSubstring("must specify a namespace or a service"))
+	g.Expect(err.Error()).To(ContainSubstring("overlaps with denylist entry"))
+}
+
+func TestNonPositiveRestartCountThresholdShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_restart_count_threshold.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non-positive restartCountThreshold")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("restartCountThreshold"))
+	g.Expect(errors.Is(err, util.ErrConfigInvalid)).To(BeTrue(), "a config failing its validation should result in an ErrConfigInvalid")
+}
+
+func TestNonPositiveMaxPodsPerTransitionShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_max_pods_per_transition.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non-positive maxPodsPerTransition")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("maxPodsPerTransition"))
+}
+
+func TestNonPositiveWeedingCooldownShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_weeding_cooldown.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non-positive weedingCooldown")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("weedingCooldown"))
+}
+
+func TestInvalidModeShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_mode.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with an unknown mode")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring(`mode "audit" of service etcd-main-client`))
+}
+
+func TestNonPositiveEndpointStabilityDurationShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_endpoint_stability_duration.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a non-positive endpointStabilityDuration")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("endpointStabilityDuration"))
+}
+
+func TestNotReadyThresholdNotBelowWatchDurationShouldReturnErrorAndNilConfig(t *testing.T) {
+	g := NewWithT(t)
+	testutil.ValidateIfFileExists(testdataPath, t)
+
+	configPath := filepath.Join(testdataPath, "config_invalid_not_ready_threshold.yaml")
+	testutil.ValidateIfFileExists(configPath, t)
+	config, err := LoadConfig(configPath)
+	g.Expect(err).To(HaveOccurred(), "LoadConfig should return error for a config with a notReadyThreshold which is not less than the watchDuration")
+	g.Expect(config).To(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("notReadyThreshold"))
+}
+
+func TestIsServicePermitted(t *testing.T) {
+	const (
+		namespace = "shoot--dev--test"
+		service   = "etcd-main-client"
+	)
+	table := []struct {
+		description string
+		allowlist   []wapi.ServiceMatcher
+		denylist    []wapi.ServiceMatcher
+		expected    bool
+	}{
+		{"service should be permitted if there is neither an allowlist nor a denylist", nil, nil, true},
+		{"service should be permitted if it is allowlisted", []wapi.ServiceMatcher{{Service: service}}, nil, true},
+		{"service should be permitted if its namespace is allowlisted", []wapi.ServiceMatcher{{Namespace: namespace}}, nil, true},
+		{"service should not be permitted if it is not allowlisted", []wapi.ServiceMatcher{{Service: "kube-apiserver"}}, nil, false},
+		{"service should not be permitted if it is denylisted", nil, []wapi.ServiceMatcher{{Namespace: namespace, Service: service}}, false},
+		{"denylist should take precedence over allowlist", []wapi.ServiceMatcher{{Namespace: namespace}}, []wapi.ServiceMatcher{{Service: service}}, false},
+		{"service should be permitted if only another namespace is denylisted", nil, []wapi.ServiceMatcher{{Namespace: "garden"}}, true},
+	}
+
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			config := &wapi.Config{Allowlist: entry.allowlist, Denylist: entry.denylist}
+			g.Expect(IsServicePermitted(config, namespace, service)).To(Equal(entry.expected))
+		})
+	}
+}
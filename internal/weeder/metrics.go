@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package weeder
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "dwd"
+	metricsSubsystem = "weeder"
+	resultLabel      = "result"
+	namespaceLabel   = "namespace"
+	serviceLabel     = "service"
+	// watchCreateResultSuccess is the result of an attempt which has created a kubernetes watch.
+	watchCreateResultSuccess = "success"
+	// watchCreateResultFailure is the result of an attempt which has failed and is retried.
+	watchCreateResultFailure = "failure"
+	// watchCreateResultForbidden is the result of an attempt which has failed as the weeder is not permitted to watch pods.
+	watchCreateResultForbidden = "forbidden"
+)
+
+// watchCreateAttemptsTotal counts the attempts of the pod watchers to create a kubernetes watch by their result.
+var watchCreateAttemptsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "watch_create_attempts_total",
+		Help:      "Total number of attempts to create a kubernetes watch on dependant pods, partitioned by their result.",
+	},
+	[]string{resultLabel},
+)
+
+// observedPodsTotal counts the dependant pods which would have been weeded by the weeders of services in observe mode.
+var observedPodsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "observed_pods_total",
+		Help:      "Total number of dependant pods which would have been weeded if the service was not in observe mode, partitioned by the namespace and name of the service.",
+	},
+	[]string{namespaceLabel, serviceLabel},
+)
+
+func init() {
+	metrics.Registry.MustRegister(watchCreateAttemptsTotal, observedPodsTotal)
+}
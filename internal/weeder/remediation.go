@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package weeder
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// restartedAtAnnotationKey is the annotation of the pod template of a Deployment which `kubectl rollout restart` sets to trigger
+// a rolling restart. The weeder sets the same annotation, so that its restarts are indistinguishable from manual ones.
+const restartedAtAnnotationKey = "kubectl.kubernetes.io/restartedAt"
+
+// ErrPodRemediationSkipped is returned by a PodRemediator which has not remediated the pod as it does not need to be remediated
+// anymore, e.g. as it has already been remediated along with another pod. The pod is then not counted as weeded and the remaining
+// remediators are not invoked.
+var ErrPodRemediationSkipped = errors.New("pod remediation has been skipped")
+
+// PodRemediation captures a dependant pod which a weeder has found to need weeding.
+type PodRemediation struct {
+	// Pod is the dependant pod in its last observed state.
+	Pod *v1.Pod
+	// Reason describes why the pod needs weeding, e.g. "in CrashLoopBackOff (containers: app)".
+	Reason string
+	// ServiceNamespace is the namespace of the service whose endpoint has become ready.
+	ServiceNamespace string
+	// Service is the name of the service whose endpoint has become ready.
+	Service string
+}
+
+// PodRemediator remediates dependant pods which need weeding. Unless custom remediators are configured, see WithRemediators,
+// a pod is remediated by deleting it.
+type PodRemediator interface {
+	// Remediate remediates the pod of the remediation. The logger carries the fields of the weeder and of the pod watch which
+	// received the pod, crClient is the client of the weeder. If an error other than ErrPodRemediationSkipped is returned then
+	// the remediation is retried.
+	Remediate(ctx context.Context, log logr.Logger, crClient client.Client, remediation PodRemediation) error
+}
+
+// PodRemediatorFunc is a function which implements PodRemediator.
+type PodRemediatorFunc func(ctx context.Context, log logr.Logger, crClient client.Client, remediation PodRemediation) error
+
+// Remediate invokes the function.
+func (f PodRemediatorFunc) Remediate(ctx context.Context, log logr.Logger, crClient client.Client, remediation PodRemediation) error {
+	return f(ctx, log, crClient, remediation)
+}
+
+// NewDeletePodRemediator creates the PodRemediator which deletes the pod. Every deleted pod is recorded as an event via the
+// eventRecorder, if one is provided. It is used by default and can be combined with custom remediators via WithRemediators.
+func NewDeletePodRemediator(eventRecorder record.EventRecorder) PodRemediator {
+	return newDeletePodRemediator(eventRecorder, clock.RealClock{}, false)
+}
+
+// newDefaultPodRemediator creates the PodRemediator which the weeder uses unless custom remediators are configured, and which
+// it uses instead of all other remediators in dry-run mode. It takes the time of its events and restarts from the clock of the weeder.
+func newDefaultPodRemediator(eventRecorder record.EventRecorder, clock clock.PassiveClock, restartOwningDeployment, dryRun bool) PodRemediator {
+	if restartOwningDeployment {
+		return newRestartDeploymentRemediator(eventRecorder, clock, dryRun)
+	}
+	return newDeletePodRemediator(eventRecorder, clock, dryRun)
+}
+
+// newDeletePodRemediator creates the PodRemediator which deletes the pod. In dry-run mode it only logs and records an event for
+// every pod which would have been deleted.
+func newDeletePodRemediator(eventRecorder record.EventRecorder, clock clock.PassiveClock, dryRun bool) *deletePodRemediator {
+	return &deletePodRemediator{eventRecorder: eventRecorder, clock: clock, dryRun: dryRun}
+}
+
+type deletePodRemediator struct {
+	eventRecorder record.EventRecorder
+	clock         clock.PassiveClock
+	dryRun        bool
+}
+
+func (d *deletePodRemediator) Remediate(ctx context.Context, log logr.Logger, crClient client.Client, remediation PodRemediation) error {
+	if d.dryRun {
+		log.Info("Dry run, not deleting pod which would have been deleted", "podName", remediation.Pod.Name, "reason", remediation.Reason)
+		d.recordPodWeededEvent(remediation, podWouldBeWeededEventReason, "Would have deleted")
+		return nil
+	}
+	log.Info("Deleting pod", "podName", remediation.Pod.Name, "reason", remediation.Reason)
+	if err := crClient.Delete(ctx, remediation.Pod); err != nil {
+		return err
+	}
+	d.recordPodWeededEvent(remediation, podWeededEventReason, "Deleted")
+	return nil
+}
+
+// recordPodWeededEvent records an event for a pod weeded by the weeder. Since the pod is gone, the event is recorded
+// on its controlling owner. The pod itself is only used if it does not have a controlling owner.
+func (d *deletePodRemediator) recordPodWeededEvent(remediation PodRemediation, reason, action string) {
+	if d.eventRecorder == nil {
+		return
+	}
+	pod := remediation.Pod
+	var involvedObject runtime.Object = pod
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		involvedObject = &v1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			Namespace:  pod.Namespace,
+			UID:        owner.UID,
+		}
+	}
+	d.eventRecorder.Eventf(involvedObject, v1.EventTypeNormal, reason,
+		"%s pod %s/%s %s at %s as endpoint %s/%s has become ready",
+		action, pod.Namespace, pod.Name, remediation.Reason, d.clock.Now().UTC().Format(time.RFC3339), remediation.ServiceNamespace, remediation.Service)
+}
+
+// NewRestartDeploymentRemediator creates the PodRemediator which triggers a rolling restart of the Deployment owning the pod
+// by setting the restartedAt annotation on its pod template, like `kubectl rollout restart` does. A Deployment which has
+// already been restarted since the pod was created is not restarted again, so that several weeded pods of the same Deployment
+// cause a single restart, ErrPodRemediationSkipped is returned for such a pod. A pod which is not owned by a Deployment is deleted
+// instead. Every restarted Deployment and every deleted pod is recorded as an event via the eventRecorder, if one is provided.
+func NewRestartDeploymentRemediator(eventRecorder record.EventRecorder) PodRemediator {
+	return newRestartDeploymentRemediator(eventRecorder, clock.RealClock{}, false)
+}
+
+// newRestartDeploymentRemediator creates the PodRemediator which restarts the Deployment owning the pod. In dry-run mode it only
+// logs and records an event for every Deployment which would have been restarted and for every pod which would have been deleted.
+func newRestartDeploymentRemediator(eventRecorder record.EventRecorder, clock clock.PassiveClock, dryRun bool) *restartDeploymentRemediator {
+	return &restartDeploymentRemediator{eventRecorder: eventRecorder, clock: clock, deletePodRemediator: newDeletePodRemediator(eventRecorder, clock, dryRun), dryRun: dryRun}
+}
+
+type restartDeploymentRemediator struct {
+	eventRecorder record.EventRecorder
+	clock         clock.PassiveClock
+	// deletePodRemediator remediates pods which are not owned by a Deployment.
+	deletePodRemediator *deletePodRemediator
+	dryRun              bool
+}
+
+func (r *restartDeploymentRemediator) Remediate(ctx context.Context, log logr.Logger, crClient client.Client, remediation PodRemediation) error {
+	deployment, err := getOwningDeployment(ctx, crClient, remediation.Pod)
+	if err != nil {
+		return err
+	}
+	if deployment == nil {
+		log.Info("Pod is not owned by a Deployment, deleting it instead of restarting its Deployment", "podName", remediation.Pod.Name)
+		return r.deletePodRemediator.Remediate(ctx, log, crClient, remediation)
+	}
+	// the restartedAt annotation as well as the creation timestamp of the pod only have a precision of a second, a pod created
+	// in the same second as the restart is therefore considered to be created by the restart.
+	if restartedAt, ok := deploymentRestartedAt(deployment); ok && !remediation.Pod.CreationTimestamp.Time.After(restartedAt) {
+		log.Info("Deployment has already been restarted since the pod has been created, not restarting it again", "podName", remediation.Pod.Name, "deployment", deployment.Name, "restartedAt", restartedAt)
+		return ErrPodRemediationSkipped
+	}
+	if r.dryRun {
+		log.Info("Dry run, not restarting deployment which would have been restarted", "podName", remediation.Pod.Name, "deployment", deployment.Name, "reason", remediation.Reason)
+		r.recordDeploymentRestartedEvent(deployment, remediation, deploymentWouldBeRestartedEventReason, "Would have restarted")
+		return nil
+	}
+	log.Info("Restarting deployment owning pod", "podName", remediation.Pod.Name, "deployment", deployment.Name, "reason", remediation.Reason)
+	patch := client.MergeFrom(deployment.DeepCopy())
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = make(map[string]string)
+	}
+	deployment.Spec.Template.Annotations[restartedAtAnnotationKey] = r.clock.Now().UTC().Format(time.RFC3339)
+	if err = crClient.Patch(ctx, deployment, patch); err != nil {
+		return err
+	}
+	r.recordDeploymentRestartedEvent(deployment, remediation, deploymentRestartedEventReason, "Restarted")
+	return nil
+}
+
+func (r *restartDeploymentRemediator) recordDeploymentRestartedEvent(deployment *appsv1.Deployment, remediation PodRemediation, reason, action string) {
+	if r.eventRecorder == nil {
+		return
+	}
+	r.eventRecorder.Eventf(deployment, v1.EventTypeNormal, reason,
+		"%s deployment %s/%s as its pod %s is %s at %s as endpoint %s/%s has become ready",
+		action, deployment.Namespace, deployment.Name, remediation.Pod.Name, remediation.Reason, r.clock.Now().UTC().Format(time.RFC3339), remediation.ServiceNamespace, remediation.Service)
+}
+
+// getOwningDeployment returns the Deployment which controls the ReplicaSet controlling the pod. It returns nil if the pod is not
+// owned by a Deployment, or if its ReplicaSet or Deployment no longer exists.
+func getOwningDeployment(ctx context.Context, crClient client.Client, pod *v1.Pod) (*appsv1.Deployment, error) {
+	rsOwner := metav1.GetControllerOf(pod)
+	if rsOwner == nil || rsOwner.Kind != "ReplicaSet" || rsOwner.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+	replicaSet := &appsv1.ReplicaSet{}
+	if err := crClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: rsOwner.Name}, replicaSet); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	deploymentOwner := metav1.GetControllerOf(replicaSet)
+	if deploymentOwner == nil || deploymentOwner.Kind != "Deployment" || deploymentOwner.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+	deployment := &appsv1.Deployment{}
+	if err := crClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: deploymentOwner.Name}, deployment); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return deployment, nil
+}
+
+// deploymentRestartedAt returns the time at which the Deployment has last been restarted via the restartedAt annotation, if any.
+func deploymentRestartedAt(deployment *appsv1.Deployment) (time.Time, bool) {
+	value, ok := deployment.Spec.Template.Annotations[restartedAtAnnotationKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	restartedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return restartedAt, true
+}
@@ -6,83 +6,305 @@ package weeder
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/internal/util"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const watchCreationRetryInterval = 500 * time.Millisecond
+const (
+	watchCreationRetryInterval = 500 * time.Millisecond
+	// watchCreationRetryMaxInterval caps the exponentially growing interval between attempts to create a kubernetes watch.
+	watchCreationRetryMaxInterval = 30 * time.Second
+	// watchCreationRetryBudget is the time after which a podWatcher gives up creating a kubernetes watch.
+	watchCreationRetryBudget = 2 * time.Minute
+	// watchReconnectInterval is the interval before the kubernetes watch is recreated after it has been closed shortly after
+	// its creation. It grows exponentially with every further such closure, which prevents a storm of reconnects to a flapping API server.
+	watchReconnectInterval = 500 * time.Millisecond
+	// watchReconnectMaxInterval caps the exponentially growing interval before the kubernetes watch is recreated.
+	watchReconnectMaxInterval = 30 * time.Second
+	// watchReconnectResetPeriod is the time for which a kubernetes watch has to have been open for it to be recreated
+	// without a delay once it is closed. The interval before subsequent reconnects then starts at watchReconnectInterval again.
+	watchReconnectResetPeriod = time.Minute
+	// numEventHandlerWorkers is the number of workers of a podWatcher which concurrently handle the received pod events.
+	numEventHandlerWorkers = 2
+	// maxEventHandlerRetries is the number of times the handling of a pod event is retried with a backoff if it fails.
+	maxEventHandlerRetries = 3
+)
 
+// podEventHandler handles a pod event. The log passed to it carries the fields of the podWatcher which received the event.
 type podEventHandler func(ctx context.Context, log logr.Logger, crClient client.Client, targetPod *v1.Pod) error
 
-// podWatcher watches a pod for status changes
+// selectorGroup is a set of PodSelectors whose pods are watched with a single kubernetes watch, see groupSelectors.
+type selectorGroup struct {
+	// watchSelector is the selector of the kubernetes watch. It consists of the requirements which all PodSelectors of the
+	// group have in common and thereby matches the pods of each of them.
+	watchSelector *metav1.LabelSelector
+	// podSelectors are the PodSelectors of the group, a pod received by the watch is only handled if it matches one of them.
+	// It is empty if the watchSelector is the only PodSelector of the group.
+	podSelectors []labels.Selector
+}
+
+// matches checks if the pod matches one of the PodSelectors of the group.
+func (sg selectorGroup) matches(pod *v1.Pod) bool {
+	if len(sg.podSelectors) == 0 {
+		return true
+	}
+	for _, ps := range sg.podSelectors {
+		if ps.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// podWatcher watches a pod for status changes. Received pod events are pushed onto a rate-limited work queue which is
+// processed by a pool of workers, so that a slow eventHandlerFn does not stall the receipt of subsequent events.
 type podWatcher struct {
 	weeder         *Weeder
+	namespace      string
 	selector       *metav1.LabelSelector
+	group          selectorGroup
 	eventHandlerFn podEventHandler
 	k8sWatch       watch.Interface
-	log            logr.Logger
+	// stopK8sWatchOnDone deregisters the stop of k8sWatch once the context with which it has been created is done.
+	stopK8sWatchOnDone func() bool
+	log                logr.Logger
+	numWorkers         int
+	// watchCreationBackoff is the backoff with which the creation of the kubernetes watch is retried.
+	watchCreationBackoff util.Backoff
+	// watchReconnectBackoff is the backoff with which the kubernetes watch is recreated after it has been closed.
+	watchReconnectBackoff     util.Backoff
+	watchReconnectResetPeriod time.Duration
+	// shortLivedWatches is the number of consecutive kubernetes watches which have been closed within watchReconnectResetPeriod.
+	shortLivedWatches int
+	queue             workqueue.TypedRateLimitingInterface[types.NamespacedName]
+	// pendingPods holds the latest received state of every pod whose key is in the queue. Multiple events for a pod
+	// which are received before it is handled are thereby de-duplicated into a single invocation of eventHandlerFn.
+	pendingPodsMu sync.Mutex
+	pendingPods   map[types.NamespacedName]*v1.Pod
 }
 
-func newPodWatcher(weeder *Weeder, selector *metav1.LabelSelector, eventHandlerFn podEventHandler) *podWatcher {
+func newPodWatcher(weeder *Weeder, namespace string, group selectorGroup, eventHandlerFn podEventHandler) *podWatcher {
 	return &podWatcher{
 		weeder:         weeder,
-		selector:       selector,
+		namespace:      namespace,
+		selector:       group.watchSelector,
+		group:          group,
 		eventHandlerFn: eventHandlerFn,
 		k8sWatch:       nil,
-		log:            weeder.logger,
+		log:            weeder.logger.WithValues("watchedNamespace", namespace, "selector", group.watchSelector.String()),
+		numWorkers:     numEventHandlerWorkers,
+		watchCreationBackoff: util.Backoff{
+			Initial: watchCreationRetryInterval,
+			Factor:  2,
+			Cap:     watchCreationRetryMaxInterval,
+			Budget:  watchCreationRetryBudget,
+		},
+		watchReconnectBackoff: util.Backoff{
+			Initial: watchReconnectInterval,
+			Factor:  2,
+			Cap:     watchReconnectMaxInterval,
+		},
+		watchReconnectResetPeriod: watchReconnectResetPeriod,
+		queue:                     workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[types.NamespacedName]()),
+		pendingPods:               make(map[types.NamespacedName]*v1.Pod),
 	}
 }
 
+// close stops the kubernetes watch, if any. It can be called repeatedly.
 func (pw *podWatcher) close() {
+	if pw.stopK8sWatchOnDone != nil {
+		pw.stopK8sWatchOnDone()
+		pw.stopK8sWatchOnDone = nil
+	}
 	if pw.k8sWatch != nil {
 		pw.k8sWatch.Stop()
+		pw.k8sWatch = nil
 	}
 }
 
+// watch watches for pod events till the context of the weeder is done. It only returns once the workers handling the
+// received events have stopped and the kubernetes watch has been closed.
 func (pw *podWatcher) watch() {
+	var workers sync.WaitGroup
 	defer pw.close()
-	pw.createK8sWatch(pw.weeder.ctx)
+	defer workers.Wait()
+	defer pw.queue.ShutDown()
+	if !pw.createK8sWatch(pw.weeder.ctx) {
+		return
+	}
+	for i := 0; i < pw.numWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			pw.runWorker()
+		}()
+	}
 	pw.log.Info("Watching for pods in CrashLoopBackoff")
+	watchCreatedAt := time.Now()
 	for {
 		select {
 		case <-pw.weeder.ctx.Done():
-			pw.log.Info("Exiting watch as context has timed-out or has been cancelled", "namespace", pw.weeder.namespace, "endpoint", pw.weeder.endpoints.Name, "selector", pw.selector.String())
+			pw.log.Info("Exiting watch as context has timed-out or has been cancelled")
 			return
 		case event, ok := <-pw.k8sWatch.ResultChan():
 			if !ok {
-				pw.log.V(3).Info("Watch has stopped, recreating kubernetes watch", "namespace", pw.weeder.namespace, "endpoint", pw.weeder.endpoints.Name, "selector", pw.selector.String())
-				pw.createK8sWatch(pw.weeder.ctx)
+				delay := pw.reconnectDelay(time.Since(watchCreatedAt))
+				pw.log.V(3).Info("Watch has stopped, recreating kubernetes watch", "backOff", delay)
+				if err := util.SleepWithContext(pw.weeder.ctx, delay); err != nil {
+					pw.log.Info("Exiting watch as context has timed-out or has been cancelled")
+					return
+				}
+				if !pw.createK8sWatch(pw.weeder.ctx) {
+					return
+				}
+				watchCreatedAt = time.Now()
 				continue
 			}
 			if !canProcessEvent(event) {
 				continue
 			}
-			targetPod := event.Object.(*v1.Pod)
-			if err := pw.eventHandlerFn(pw.weeder.ctx, pw.log, pw.weeder.ctrlClient, targetPod); err != nil {
-				pw.log.Error(err, "Error processing pod", "namespace", pw.weeder.namespace, "podName", targetPod.Name)
+			pod := event.Object.(*v1.Pod)
+			if !pw.group.matches(pod) {
+				continue
 			}
+			pw.enqueue(pod)
+		}
+	}
+}
+
+// reconnectDelay returns the delay before recreating a kubernetes watch which has been closed after it has been open for
+// the given duration. A watch which has been open for at least watchReconnectResetPeriod is recreated without a delay,
+// otherwise the delay grows exponentially with the number of consecutive short-lived watches.
+func (pw *podWatcher) reconnectDelay(watchDuration time.Duration) time.Duration {
+	if watchDuration >= pw.watchReconnectResetPeriod {
+		pw.shortLivedWatches = 0
+		return 0
+	}
+	pw.shortLivedWatches++
+	return pw.watchReconnectBackoff.Interval(pw.shortLivedWatches)
+}
+
+// enqueue records the pod as the latest state for its key and adds the key to the queue. The queue does not hold a key
+// more than once, so a pod which is already waiting to be handled is only handled once with its latest state.
+func (pw *podWatcher) enqueue(pod *v1.Pod) {
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	pw.pendingPodsMu.Lock()
+	pw.pendingPods[key] = pod
+	pw.pendingPodsMu.Unlock()
+	pw.queue.Add(key)
+}
+
+func (pw *podWatcher) runWorker() {
+	for pw.processNextEvent() {
+	}
+}
+
+// processNextEvent handles the next pod from the queue. It returns false once the queue has been shut down.
+func (pw *podWatcher) processNextEvent() bool {
+	key, shutdown := pw.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer pw.queue.Done(key)
+
+	pw.pendingPodsMu.Lock()
+	targetPod, ok := pw.pendingPods[key]
+	delete(pw.pendingPods, key)
+	pw.pendingPodsMu.Unlock()
+	if !ok {
+		pw.queue.Forget(key)
+		return true
+	}
+	err := pw.eventHandlerFn(pw.weeder.ctx, pw.log, pw.weeder.ctrlClient, targetPod)
+	var requeueErr *requeueAfterError
+	if errors.As(err, &requeueErr) {
+		pw.log.V(4).Info("Inspecting pod again after a delay", "podName", targetPod.Name, "after", requeueErr.after)
+		pw.retainPendingPod(key, targetPod)
+		pw.queue.Forget(key)
+		pw.queue.AddAfter(key, requeueErr.after)
+		return true
+	}
+	if err != nil {
+		pw.log.Error(err, "Error processing pod", "podName", targetPod.Name, "retries", pw.queue.NumRequeues(key))
+		if pw.queue.NumRequeues(key) < maxEventHandlerRetries {
+			pw.retainPendingPod(key, targetPod)
+			pw.queue.AddRateLimited(key)
+			return true
 		}
 	}
+	pw.queue.Forget(key)
+	return true
+}
+
+// retainPendingPod records the pod as the state to handle when its key is taken from the queue again, unless a newer state
+// of the pod has been received in the meantime, which takes precedence.
+func (pw *podWatcher) retainPendingPod(key types.NamespacedName, pod *v1.Pod) {
+	pw.pendingPodsMu.Lock()
+	defer pw.pendingPodsMu.Unlock()
+	if _, found := pw.pendingPods[key]; !found {
+		pw.pendingPods[key] = pod
+	}
 }
 
-func (pw *podWatcher) createK8sWatch(ctx context.Context) {
-	operation := fmt.Sprintf("Creating kubernetes watch for namespace %s, service %s with selector %s", pw.weeder.namespace, pw.weeder.endpoints.Name, pw.selector)
-	util.RetryOnError(ctx, pw.log, operation, func() error {
-		w, err := doCreateK8sWatch(ctx, pw.weeder.watchClient, pw.weeder.namespace, pw.selector)
+// createK8sWatch creates a kubernetes watch on pods, retrying with an exponential backoff till it succeeds, the context is done
+// or watchCreationRetryBudget has been used up. It returns false if no watch could be created, which is also the case when the
+// weeder is not permitted to watch pods in the namespace. Every attempt is counted by its result in watchCreateAttemptsTotal.
+// A created watch is stopped as soon as the context is done, irrespective of whether it is still consumed by then, and a
+// watch which has been created while the context has been done is closed right away.
+func (pw *podWatcher) createK8sWatch(ctx context.Context) bool {
+	operation := fmt.Sprintf("Creating kubernetes watch for namespace %s, service %s with selector %s", pw.namespace, pw.weeder.endpoints.Name, pw.selector)
+	pw.close()
+	forbidden := false
+	failedAttempts := 0
+	start := time.Now()
+	_ = util.RetryOnErrorWithBackoff(ctx, pw.log, operation, func() error {
+		w, err := doCreateK8sWatch(ctx, pw.weeder.watchClient, pw.namespace, pw.selector)
 		if err != nil {
+			if apierrors.IsForbidden(err) {
+				watchCreateAttemptsTotal.WithLabelValues(watchCreateResultForbidden).Inc()
+				forbidden = true
+				return nil
+			}
+			watchCreateAttemptsTotal.WithLabelValues(watchCreateResultFailure).Inc()
+			failedAttempts++
 			return err
 		}
+		watchCreateAttemptsTotal.WithLabelValues(watchCreateResultSuccess).Inc()
 		pw.k8sWatch = w
+		pw.stopK8sWatchOnDone = context.AfterFunc(ctx, w.Stop)
 		return nil
-	}, watchCreationRetryInterval)
+	}, pw.watchCreationBackoff)
+	if forbidden {
+		pw.log.Info("Skipping namespace as weeder is not permitted to watch pods in it")
+		return false
+	}
+	if pw.k8sWatch != nil && ctx.Err() != nil {
+		pw.log.Info("Closing kubernetes watch as context has timed-out or has been cancelled right after its creation")
+		pw.close()
+		return false
+	}
+	if pw.k8sWatch != nil && failedAttempts > 0 {
+		pw.log.Info("Created kubernetes watch after failed attempts", "failedAttempts", failedAttempts, "duration", time.Since(start))
+	}
+	return pw.k8sWatch != nil
 }
 
 func doCreateK8sWatch(ctx context.Context, client kubernetes.Interface, namespace string, lSelector *metav1.LabelSelector) (watch.Interface, error) {
@@ -99,6 +321,207 @@ func doCreateK8sWatch(ctx context.Context, client kubernetes.Interface, namespac
 	return w, nil
 }
 
+// groupSelectors groups the selectors which might match the same pods, so that each pod is only received by a single kubernetes
+// watch and handled once. Selectors covered by a broader selector are dropped beforehand, see collapseSelectors. A group of
+// overlapping selectors is watched with the requirements they have in common, the received pods are matched against the
+// selectors of the group. A selector only joins a group if the group keeps at least one common requirement, as an empty watch
+// selector would receive every pod of the namespace. Selectors which cannot match the same pod or have no requirement in
+// common with the selectors they overlap with, as well as nil selectors and selectors which cannot be parsed, get a group of their own.
+func groupSelectors(selectors []*metav1.LabelSelector) []selectorGroup {
+	collapsed := collapseSelectors(selectors)
+	requirements := make([]map[string]labels.Requirement, len(collapsed))
+	for i, s := range collapsed {
+		requirements[i] = parseSelectorRequirements(s)
+	}
+	type candidateGroup struct {
+		members []int
+		common  map[string]labels.Requirement
+	}
+	var candidates []*candidateGroup
+	for i := range collapsed {
+		var group *candidateGroup
+		if requirements[i] != nil {
+			for _, c := range candidates {
+				if c.common == nil || !slices.ContainsFunc(c.members, func(m int) bool { return mayMatchSamePods(requirements[i], requirements[m]) }) {
+					continue
+				}
+				if common := commonRequirements(c.common, requirements[i]); len(common) > 0 {
+					group, c.common = c, common
+					break
+				}
+			}
+		}
+		if group == nil {
+			group = &candidateGroup{common: requirements[i]}
+			candidates = append(candidates, group)
+		}
+		group.members = append(group.members, i)
+	}
+	groups := make([]selectorGroup, 0, len(candidates))
+	for _, c := range candidates {
+		if len(c.members) == 1 {
+			groups = append(groups, selectorGroup{watchSelector: collapsed[c.members[0]]})
+			continue
+		}
+		groups = append(groups, newSelectorGroup(c.members, requirements, c.common))
+	}
+	return groups
+}
+
+// commonRequirements returns the requirements which are contained in both sets of requirements.
+func commonRequirements(a, b map[string]labels.Requirement) map[string]labels.Requirement {
+	common := make(map[string]labels.Requirement)
+	for key, ra := range a {
+		if rb, ok := b[key]; ok && ra.Equal(rb) {
+			common[key] = ra
+		}
+	}
+	return common
+}
+
+// newSelectorGroup creates the group of the selectors at the given indices, which is watched with their common requirements.
+func newSelectorGroup(members []int, requirements []map[string]labels.Requirement, common map[string]labels.Requirement) selectorGroup {
+	group := selectorGroup{watchSelector: &metav1.LabelSelector{}}
+	for _, m := range members {
+		group.podSelectors = append(group.podSelectors, labels.NewSelector().Add(slices.Collect(maps.Values(requirements[m]))...))
+	}
+	for _, key := range slices.Sorted(maps.Keys(common)) {
+		group.watchSelector.MatchExpressions = append(group.watchSelector.MatchExpressions, toLabelSelectorRequirement(common[key]))
+	}
+	return group
+}
+
+// toLabelSelectorRequirement converts a requirement of a parsed label selector back into the requirement of a label selector.
+func toLabelSelectorRequirement(r labels.Requirement) metav1.LabelSelectorRequirement {
+	req := metav1.LabelSelectorRequirement{Key: r.Key(), Values: r.Values().List()}
+	switch r.Operator() {
+	case selection.In, selection.Equals, selection.DoubleEquals:
+		req.Operator = metav1.LabelSelectorOpIn
+	case selection.NotIn, selection.NotEquals:
+		req.Operator = metav1.LabelSelectorOpNotIn
+	case selection.Exists:
+		req.Operator = metav1.LabelSelectorOpExists
+	default:
+		req.Operator = metav1.LabelSelectorOpDoesNotExist
+	}
+	if req.Operator == metav1.LabelSelectorOpExists || req.Operator == metav1.LabelSelectorOpDoesNotExist {
+		req.Values = nil
+	}
+	return req
+}
+
+// mayMatchSamePods checks if there might be a pod which is matched by both selectors, i.e. if none of their requirements
+// on the same label contradict each other. Requirements which cannot be compared are assumed not to contradict.
+func mayMatchSamePods(a, b map[string]labels.Requirement) bool {
+	for _, ra := range a {
+		for _, rb := range b {
+			if ra.Key() == rb.Key() && contradicts(ra, rb) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// contradicts checks if no value of a label, including its absence, satisfies both requirements on it.
+func contradicts(a, b labels.Requirement) bool {
+	opA, opB := normalizeOperator(a.Operator()), normalizeOperator(b.Operator())
+	if opA > opB {
+		a, b = b, a
+		opA, opB = opB, opA
+	}
+	switch {
+	case opA == selection.In && opB == selection.In:
+		return !a.Values().HasAny(b.Values().UnsortedList()...)
+	case opA == selection.In && opB == selection.NotIn:
+		return b.Values().IsSuperset(a.Values())
+	case opA == selection.In && opB == selection.DoesNotExist, opA == selection.Exists && opB == selection.DoesNotExist:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeOperator maps the operators of the requirements of label selectors to In, NotIn, Exists or DoesNotExist, any other
+// operator is returned as is.
+func normalizeOperator(op selection.Operator) selection.Operator {
+	switch op {
+	case selection.Equals, selection.DoubleEquals:
+		return selection.In
+	case selection.NotEquals:
+		return selection.NotIn
+	default:
+		return op
+	}
+}
+
+// collapseSelectors drops every selector which only matches pods that are also matched by another selector, as watching
+// them would only deliver the same pod events to the weeder more than once. Of several identical selectors only the first
+// one is retained. Selectors which merely overlap are retained, see groupSelectors.
+func collapseSelectors(selectors []*metav1.LabelSelector) []*metav1.LabelSelector {
+	requirements := make([]sets.Set[string], len(selectors))
+	for i, s := range selectors {
+		requirements[i] = selectorRequirements(s)
+	}
+	collapsed := make([]*metav1.LabelSelector, 0, len(selectors))
+	for i, s := range selectors {
+		if !isSelectorRedundant(i, requirements) {
+			collapsed = append(collapsed, s)
+		}
+	}
+	return collapsed
+}
+
+// isSelectorRedundant checks if the selector at index i is matched by a broader selector, i.e. one whose requirements are
+// a subset of its own. An identical selector only makes it redundant if it comes first.
+func isSelectorRedundant(i int, requirements []sets.Set[string]) bool {
+	if requirements[i] == nil {
+		return false
+	}
+	for j, other := range requirements {
+		if j == i || other == nil || !requirements[i].IsSuperset(other) {
+			continue
+		}
+		if j < i || !other.Equal(requirements[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorRequirements returns the requirements of a label selector in their string form. It returns nil for a selector
+// which is nil or cannot be parsed, so that it is never collapsed.
+func selectorRequirements(lSelector *metav1.LabelSelector) sets.Set[string] {
+	requirements := parseSelectorRequirements(lSelector)
+	if requirements == nil {
+		return nil
+	}
+	return sets.KeySet(requirements)
+}
+
+// parseSelectorRequirements returns the requirements of a label selector keyed by their string form. It returns nil for a
+// selector which is nil or cannot be parsed.
+func parseSelectorRequirements(lSelector *metav1.LabelSelector) map[string]labels.Requirement {
+	if lSelector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(lSelector)
+	if err != nil {
+		return nil
+	}
+	requirements, _ := selector.Requirements()
+	reqs := make(map[string]labels.Requirement, len(requirements))
+	for _, r := range requirements {
+		// `key in (value)` is equivalent to `key=value` which is the requirement that results from matchLabels.
+		if r.Operator() == selection.In && r.Values().Len() == 1 {
+			reqs[fmt.Sprintf("%s=%s", r.Key(), r.Values().List()[0])] = r
+			continue
+		}
+		reqs[r.String()] = r
+	}
+	return reqs
+}
+
 func canProcessEvent(ev watch.Event) bool {
 	return ev.Type == watch.Added || ev.Type == watch.Modified
 }
@@ -0,0 +1,705 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package weeder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
+	"github.com/gardener/dependency-watchdog/internal/util"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	additionalNamespace = "waikiki"
+	forbiddenNamespace  = "lanai"
+)
+
+var (
+	testPodSelector      = &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane"}}
+	testPodSelectorGroup = selectorGroup{watchSelector: testPodSelector}
+)
+
+// recordingEventHandler captures the namespaces of all pods for which an event has been received.
+type recordingEventHandler struct {
+	mu         sync.Mutex
+	namespaces map[string]int
+}
+
+func (r *recordingEventHandler) handle(_ context.Context, _ logr.Logger, _ client.Client, targetPod *v1.Pod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespaces[targetPod.Namespace]++
+	return nil
+}
+
+func (r *recordingEventHandler) count(namespace string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.namespaces[namespace]
+}
+
+func newTestWeeder(ctx context.Context, watchClient *fake.Clientset, additionalNamespaces []string) *Weeder {
+	config := &wapi.Config{
+		WatchDuration: &metav1.Duration{Duration: testWatchDuration},
+		ServicesAndDependantSelectors: map[string]wapi.DependantSelectors{
+			epName: {
+				PodSelectors:         []*metav1.LabelSelector{testPodSelector},
+				AdditionalNamespaces: additionalNamespaces,
+			},
+		},
+	}
+	return NewWeeder(ctx, namespace, config, nil, watchClient, nil, testEp, logr.Discard())
+}
+
+func newTestPod(name, ns string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels:    testPodSelector.MatchLabels,
+		},
+	}
+}
+
+func TestWatchedNamespacesShouldIncludeServiceNamespaceAndAdditionalNamespaces(t *testing.T) {
+	g := NewWithT(t)
+	w := newTestWeeder(context.Background(), fake.NewSimpleClientset(), []string{additionalNamespace, namespace, additionalNamespace})
+	defer w.cancelFn()
+	g.Expect(w.watchedNamespaces()).To(Equal([]string{namespace, additionalNamespace}))
+}
+
+func TestPodWatchersShouldReceiveEventsFromAllWatchedNamespaces(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient := fake.NewSimpleClientset()
+	watchEstablished := make(chan string, 2)
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		gvr := action.GetResource()
+		ns := action.GetNamespace()
+		podWatch, err := watchClient.Tracker().Watch(gvr, ns)
+		if err == nil {
+			watchEstablished <- ns
+		}
+		return true, podWatch, err
+	})
+	w := newTestWeeder(ctx, watchClient, []string{additionalNamespace})
+	defer w.cancelFn()
+
+	handler := &recordingEventHandler{namespaces: make(map[string]int)}
+	for _, ns := range w.watchedNamespaces() {
+		go newPodWatcher(w, ns, testPodSelectorGroup, handler.handle).watch()
+	}
+	for range w.watchedNamespaces() {
+		g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+	}
+
+	for _, ns := range []string{namespace, additionalNamespace} {
+		_, err := watchClient.CoreV1().Pods(ns).Create(ctx, newTestPod("kube-controller-manager", ns), metav1.CreateOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Eventually(func() int { return handler.count(namespace) }).Within(time.Second).Should(Equal(1))
+	g.Eventually(func() int { return handler.count(additionalNamespace) }).Within(time.Second).Should(Equal(1))
+}
+
+func TestPodWatcherShouldSkipNamespaceWhenWatchIsForbidden(t *testing.T) {
+	g := NewWithT(t)
+	watchClient := fake.NewSimpleClientset()
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if action.GetNamespace() != forbiddenNamespace {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", nil)
+	})
+	w := newTestWeeder(context.Background(), watchClient, []string{forbiddenNamespace})
+	defer w.cancelFn()
+
+	handler := &recordingEventHandler{namespaces: make(map[string]int)}
+	done := make(chan struct{})
+	go func() {
+		newPodWatcher(w, forbiddenNamespace, testPodSelectorGroup, handler.handle).watch()
+		close(done)
+	}()
+	g.Eventually(done).Within(time.Second).Should(BeClosed(), "podWatcher should stop when it is not permitted to watch pods in a namespace")
+	g.Expect(w.ctx.Err()).ToNot(HaveOccurred(), "weeder should continue to run when one of its namespaces is forbidden")
+}
+
+func TestCollapseSelectors(t *testing.T) {
+	kcmSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "app": "kubernetes"}}
+	etcdSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etcd-statefulset"}}
+	expressionSelector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "gardener.cloud/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"controlplane"}}}}
+	tests := []struct {
+		name      string
+		selectors []*metav1.LabelSelector
+		expected  []*metav1.LabelSelector
+	}{
+		{name: "distinct selectors should be retained", selectors: []*metav1.LabelSelector{testPodSelector, etcdSelector}, expected: []*metav1.LabelSelector{testPodSelector, etcdSelector}},
+		{name: "narrower selector should be collapsed into a broader one", selectors: []*metav1.LabelSelector{kcmSelector, testPodSelector}, expected: []*metav1.LabelSelector{testPodSelector}},
+		{name: "identical selectors should be collapsed into the first one", selectors: []*metav1.LabelSelector{testPodSelector, etcdSelector, {MatchLabels: testPodSelector.MatchLabels}}, expected: []*metav1.LabelSelector{testPodSelector, etcdSelector}},
+		{name: "selectors which only partially overlap should be retained", selectors: []*metav1.LabelSelector{kcmSelector, {MatchLabels: map[string]string{"app": "kubernetes", "role": "apiserver"}}}, expected: []*metav1.LabelSelector{kcmSelector, {MatchLabels: map[string]string{"app": "kubernetes", "role": "apiserver"}}}},
+		{name: "empty selector should collapse all other selectors", selectors: []*metav1.LabelSelector{testPodSelector, {}, etcdSelector}, expected: []*metav1.LabelSelector{{}}},
+		{name: "nil selector should be retained", selectors: []*metav1.LabelSelector{nil, testPodSelector}, expected: []*metav1.LabelSelector{nil, testPodSelector}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(collapseSelectors(entry.selectors)).To(Equal(entry.expected))
+		})
+	}
+	// a label selector using matchExpressions results in the same requirement as the equivalent matchLabels.
+	g := NewWithT(t)
+	g.Expect(collapseSelectors([]*metav1.LabelSelector{testPodSelector, expressionSelector})).To(Equal([]*metav1.LabelSelector{testPodSelector}))
+}
+
+func TestGroupSelectors(t *testing.T) {
+	kcmSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "app": "kubernetes"}}
+	apiServerSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "role": "apiserver"}}
+	notApiServerSelector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "gardener.cloud/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"controlplane"}},
+		{Key: "role", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"main", "apiserver"}},
+	}}
+	etcdSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "role": "main"}}
+	appSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}
+	roleSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"role": "b"}}
+	tests := []struct {
+		name                 string
+		selectors            []*metav1.LabelSelector
+		expectedWatches      []*metav1.LabelSelector
+		expectedPodSelectors []int
+	}{
+		{name: "overlapping selectors should be watched with their common requirements", selectors: []*metav1.LabelSelector{kcmSelector, apiServerSelector},
+			expectedWatches: []*metav1.LabelSelector{{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "gardener.cloud/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"controlplane"}}}}}, expectedPodSelectors: []int{2}},
+		{name: "selectors requiring different values of a label should be watched separately", selectors: []*metav1.LabelSelector{apiServerSelector, etcdSelector},
+			expectedWatches: []*metav1.LabelSelector{apiServerSelector, etcdSelector}, expectedPodSelectors: []int{0, 0}},
+		{name: "selectors excluding the value required by another one should be watched separately", selectors: []*metav1.LabelSelector{apiServerSelector, notApiServerSelector},
+			expectedWatches: []*metav1.LabelSelector{apiServerSelector, notApiServerSelector}, expectedPodSelectors: []int{0, 0}},
+		{name: "selectors overlapping via a third one should share a watch", selectors: []*metav1.LabelSelector{apiServerSelector, kcmSelector, etcdSelector},
+			expectedWatches: []*metav1.LabelSelector{{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "gardener.cloud/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"controlplane"}}}}}, expectedPodSelectors: []int{3}},
+		{name: "selectors without a requirement in common should be watched separately", selectors: []*metav1.LabelSelector{appSelector, roleSelector},
+			expectedWatches: []*metav1.LabelSelector{appSelector, roleSelector}, expectedPodSelectors: []int{0, 0}},
+		{name: "selector without a requirement in common with a group should not widen its watch", selectors: []*metav1.LabelSelector{kcmSelector, apiServerSelector, roleSelector},
+			expectedWatches: []*metav1.LabelSelector{{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "gardener.cloud/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"controlplane"}}}}, roleSelector}, expectedPodSelectors: []int{2, 0}},
+		{name: "narrower selector should be collapsed into a broader one", selectors: []*metav1.LabelSelector{kcmSelector, testPodSelector},
+			expectedWatches: []*metav1.LabelSelector{testPodSelector}, expectedPodSelectors: []int{0}},
+		{name: "nil selector should be watched separately", selectors: []*metav1.LabelSelector{nil, testPodSelector},
+			expectedWatches: []*metav1.LabelSelector{nil, testPodSelector}, expectedPodSelectors: []int{0, 0}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			groups := groupSelectors(entry.selectors)
+			g.Expect(groups).To(HaveLen(len(entry.expectedWatches)))
+			for i, group := range groups {
+				g.Expect(group.watchSelector).To(Equal(entry.expectedWatches[i]))
+				g.Expect(group.podSelectors).To(HaveLen(entry.expectedPodSelectors[i]))
+			}
+		})
+	}
+}
+
+func TestWeederShouldCreateSingleWatchForOverlappingSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []*metav1.LabelSelector
+	}{
+		{name: "nested selectors", selectors: []*metav1.LabelSelector{
+			testPodSelector,
+			{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "app": "kubernetes"}},
+		}},
+		{name: "overlapping selectors which are not nested", selectors: []*metav1.LabelSelector{
+			{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "app": "kubernetes"}},
+			{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "role": "apiserver"}},
+		}},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+			watchClient := fake.NewSimpleClientset()
+			var numWatches atomic.Int32
+			watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				numWatches.Add(1)
+				podWatch, err := watchClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+				return true, podWatch, err
+			})
+			config := &wapi.Config{
+				WatchDuration: &metav1.Duration{Duration: testWatchDuration},
+				ServicesAndDependantSelectors: map[string]wapi.DependantSelectors{
+					epName: {PodSelectors: entry.selectors},
+				},
+			}
+			w := NewWeeder(ctx, namespace, config, nil, watchClient, nil, testEp, logr.Discard())
+			defer w.cancelFn()
+
+			go w.Run()
+			g.Eventually(numWatches.Load).Within(time.Second).Should(Equal(int32(1)))
+			g.Consistently(numWatches.Load).WithTimeout(200*time.Millisecond).Should(Equal(int32(1)), "overlapping selectors should not result in redundant watches")
+		})
+	}
+}
+
+func TestPodWatcherOfOverlappingSelectorsShouldHandleEveryMatchingPodOnce(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient := fake.NewSimpleClientset()
+	watchEstablished := make(chan struct{}, 1)
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		podWatch, err := watchClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err == nil {
+			watchEstablished <- struct{}{}
+		}
+		return true, podWatch, err
+	})
+	w := newTestWeeder(ctx, watchClient, nil)
+	defer w.cancelFn()
+	groups := groupSelectors([]*metav1.LabelSelector{
+		{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "app": "kubernetes"}},
+		{MatchLabels: map[string]string{"gardener.cloud/role": "controlplane", "role": "apiserver"}},
+	})
+	g.Expect(groups).To(HaveLen(1))
+
+	handler := &recordingEventHandler{namespaces: make(map[string]int)}
+	go newPodWatcher(w, namespace, groups[0], handler.handle).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+
+	podLabels := []map[string]string{
+		{"gardener.cloud/role": "controlplane", "app": "kubernetes", "role": "apiserver"},
+		{"gardener.cloud/role": "controlplane", "app": "kubernetes"},
+		// only matches the common requirements of the selectors, it is received by the watch but must not be handled.
+		{"gardener.cloud/role": "controlplane", "role": "main"},
+	}
+	for i, l := range podLabels {
+		pod := newTestPod(fmt.Sprintf("pod-%d", i), namespace)
+		pod.Labels = l
+		_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Eventually(func() int { return handler.count(namespace) }).Within(time.Second).Should(Equal(2))
+	g.Consistently(func() int { return handler.count(namespace) }).WithTimeout(200 * time.Millisecond).Should(Equal(2))
+}
+
+func TestSlowEventHandlerShouldNotBlockSubsequentEvents(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	w := newTestWeeder(ctx, watchClient, nil)
+	defer w.cancelFn()
+
+	// the handler blocks on the first pod till it is released, which simulates a delete that is stuck in a backoff.
+	release := make(chan struct{})
+	defer close(release)
+	handledPods := make(chan string, 10)
+	go newPodWatcher(w, namespace, testPodSelectorGroup, func(_ context.Context, _ logr.Logger, _ client.Client, targetPod *v1.Pod) error {
+		if targetPod.Name == "slow-pod" {
+			<-release
+		}
+		handledPods <- targetPod.Name
+		return nil
+	}).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+
+	for _, name := range []string{"slow-pod", "kube-controller-manager", "machine-controller-manager"} {
+		_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, newTestPod(name, namespace), metav1.CreateOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Eventually(handledPods).Within(time.Second).Should(Receive(Equal("kube-controller-manager")))
+	g.Eventually(handledPods).Within(time.Second).Should(Receive(Equal("machine-controller-manager")))
+	g.Consistently(handledPods).WithTimeout(100*time.Millisecond).ShouldNot(Receive(), "handling of the slow pod should still be blocked")
+}
+
+func TestEventsForPendingPodShouldBeDeduplicated(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	w := newTestWeeder(ctx, watchClient, nil)
+	defer w.cancelFn()
+
+	release := make(chan struct{})
+	var handledCount sync.Map
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, func(_ context.Context, _ logr.Logger, _ client.Client, targetPod *v1.Pod) error {
+		if targetPod.Name == "slow-pod" {
+			<-release
+		}
+		count, _ := handledCount.LoadOrStore(targetPod.Name, &atomic.Int32{})
+		count.(*atomic.Int32).Add(1)
+		return nil
+	})
+	// with a single worker which is blocked by the slow pod, all events for the other pod remain pending.
+	pw.numWorkers = 1
+	go pw.watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+
+	_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, newTestPod("slow-pod", namespace), metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Eventually(func() int { return pw.queue.Len() }).Within(time.Second).Should(BeZero(), "slow pod should be picked up by the worker")
+	pod := newTestPod("kube-controller-manager", namespace)
+	pod, err = watchClient.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	for i := 0; i < 3; i++ {
+		pod.Status.Message = fmt.Sprintf("update-%d", i)
+		pod, err = watchClient.CoreV1().Pods(namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Eventually(func() int { return pw.queue.Len() }).Within(time.Second).Should(Equal(1))
+	close(release)
+
+	handledCountOf := func(name string) int32 {
+		count, ok := handledCount.Load(name)
+		if !ok {
+			return 0
+		}
+		return count.(*atomic.Int32).Load()
+	}
+	g.Eventually(func() int32 { return handledCountOf("kube-controller-manager") }).Within(time.Second).Should(Equal(int32(1)))
+	g.Consistently(func() int32 { return handledCountOf("kube-controller-manager") }).WithTimeout(100 * time.Millisecond).Should(Equal(int32(1)))
+}
+
+func TestFailedEventHandlingShouldBeRetried(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	w := newTestWeeder(ctx, watchClient, nil)
+	defer w.cancelFn()
+
+	var attempts atomic.Int32
+	go newPodWatcher(w, namespace, testPodSelectorGroup, func(_ context.Context, _ logr.Logger, _ client.Client, _ *v1.Pod) error {
+		attempts.Add(1)
+		return fmt.Errorf("test error")
+	}).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+
+	_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, newTestPod("kube-controller-manager", namespace), metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Eventually(attempts.Load).Within(time.Second).Should(Equal(int32(maxEventHandlerRetries + 1)))
+	g.Consistently(attempts.Load).WithTimeout(100*time.Millisecond).Should(Equal(int32(maxEventHandlerRetries+1)), "handling should not be retried beyond the maximum number of retries")
+}
+
+func TestPodToBeInspectedAgainShouldBeRequeuedWithoutFurtherEvent(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	w := newTestWeeder(ctx, watchClient, nil)
+	defer w.cancelFn()
+
+	var inspections atomic.Int32
+	go newPodWatcher(w, namespace, testPodSelectorGroup, func(_ context.Context, _ logr.Logger, _ client.Client, _ *v1.Pod) error {
+		inspections.Add(1)
+		return &requeueAfterError{after: 10 * time.Millisecond}
+	}).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+
+	_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, newTestPod("kube-controller-manager", namespace), metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+	// unlike a failure, inspecting a pod again is not bounded by the maximum number of retries.
+	g.Eventually(inspections.Load).Within(2 * time.Second).Should(BeNumerically(">", maxEventHandlerRetries+1))
+}
+
+func TestPodWatcherShouldGiveUpCreatingWatchAfterBudget(t *testing.T) {
+	g := NewWithT(t)
+	watchClient := fake.NewSimpleClientset()
+	var attempts atomic.Int32
+	watchClient.PrependWatchReactor("pods", func(_ k8stesting.Action) (bool, watch.Interface, error) {
+		attempts.Add(1)
+		return true, nil, apierrors.NewServiceUnavailable("kube-apiserver is unavailable")
+	})
+	w := newTestWeeder(context.Background(), watchClient, nil)
+	defer w.cancelFn()
+
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, (&recordingEventHandler{namespaces: make(map[string]int)}).handle)
+	pw.watchCreationBackoff = util.Backoff{Initial: 10 * time.Millisecond, Factor: 2, Cap: 20 * time.Millisecond, Budget: 100 * time.Millisecond}
+	done := make(chan struct{})
+	go func() {
+		pw.watch()
+		close(done)
+	}()
+	g.Eventually(done).Within(time.Second).Should(BeClosed(), "podWatcher should stop once the budget for creating a watch has been used up")
+	// attempts are made after 0, 10, 30, 50, 70, 90 and 100 milliseconds.
+	g.Expect(attempts.Load()).To(BeNumerically("<=", 7), "watch creation should back off between attempts")
+	g.Expect(w.ctx.Err()).ToNot(HaveOccurred(), "weeder should continue to run when a podWatcher gives up")
+}
+
+func TestReconnectDelayShouldGrowForShortLivedWatchesAndReset(t *testing.T) {
+	g := NewWithT(t)
+	w := newTestWeeder(context.Background(), fake.NewSimpleClientset(), nil)
+	defer w.cancelFn()
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, (&recordingEventHandler{namespaces: make(map[string]int)}).handle)
+	pw.watchReconnectBackoff = util.Backoff{Initial: time.Second, Factor: 2, Cap: 5 * time.Second}
+	pw.watchReconnectResetPeriod = time.Minute
+
+	g.Expect(pw.reconnectDelay(time.Second)).To(Equal(time.Second))
+	g.Expect(pw.reconnectDelay(time.Second)).To(Equal(2 * time.Second))
+	g.Expect(pw.reconnectDelay(time.Second)).To(Equal(4 * time.Second))
+	g.Expect(pw.reconnectDelay(time.Second)).To(Equal(5*time.Second), "delay should be capped")
+	g.Expect(pw.reconnectDelay(time.Minute)).To(BeZero(), "watch which has been open for the reset period should be recreated immediately")
+	g.Expect(pw.reconnectDelay(time.Second)).To(Equal(time.Second), "delay should start over after a long-lived watch")
+}
+
+func TestPodWatcherShouldBackOffWhenWatchIsRepeatedlyClosed(t *testing.T) {
+	g := NewWithT(t)
+	watchClient := fake.NewSimpleClientset()
+	var (
+		mu          sync.Mutex
+		createdAt   []time.Time
+		numCreation = 5
+	)
+	watchClient.PrependWatchReactor("pods", func(_ k8stesting.Action) (bool, watch.Interface, error) {
+		mu.Lock()
+		createdAt = append(createdAt, time.Now())
+		mu.Unlock()
+		// the watch is closed right after its creation, as it is by a flapping API server.
+		fakeWatch := watch.NewFake()
+		fakeWatch.Stop()
+		return true, fakeWatch, nil
+	})
+	w := newTestWeeder(context.Background(), watchClient, nil)
+	defer w.cancelFn()
+
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, (&recordingEventHandler{namespaces: make(map[string]int)}).handle)
+	pw.watchReconnectBackoff = util.Backoff{Initial: 10 * time.Millisecond, Factor: 2, Cap: time.Second}
+	go pw.watch()
+	creations := func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(createdAt)
+	}
+	g.Eventually(creations).Within(2 * time.Second).Should(HaveLen(numCreation))
+	w.cancelFn()
+
+	observed := creations()
+	for i := 1; i < numCreation; i++ {
+		g.Expect(observed[i].Sub(observed[i-1])).To(BeNumerically(">=", pw.watchReconnectBackoff.Interval(i)), "reconnect %d should have been delayed by the backoff", i)
+	}
+}
+
+// logRecorder captures the log lines of a funcr logger which can be written to concurrently.
+type logRecorder struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (r *logRecorder) logger() logr.Logger {
+	return funcr.New(func(_, args string) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.logs = append(r.logs, args)
+	}, funcr.Options{})
+}
+
+func (r *logRecorder) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.logs)
+}
+
+func TestWeederLogsShouldCarryServiceAndWatchFields(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	pod := newCrashLoopingPod(nil)
+	crClient := fakeclient.NewClientBuilder().WithObjects(pod).Build()
+	recorder := &logRecorder{}
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, watchClient, nil, testEp, recorder.logger())
+	defer w.cancelFn()
+
+	go newPodWatcher(w, namespace, testPodSelectorGroup, w.shootPodIfNecessary).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+	_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Eventually(recorder.lines).Within(time.Second).Should(ContainElement(SatisfyAll(
+		ContainSubstring("Deleting pod"),
+		ContainSubstring(fmt.Sprintf(`"serviceNamespace"="%s"`, namespace)),
+		ContainSubstring(fmt.Sprintf(`"service"="%s"`, epName)),
+		ContainSubstring(fmt.Sprintf(`"watchedNamespace"="%s"`, namespace)),
+		ContainSubstring(fmt.Sprintf(`"selector"="%s"`, testPodSelector.String())),
+		ContainSubstring(fmt.Sprintf(`"podName"="%s"`, pod.Name)),
+	)))
+}
+
+func TestWeederShouldOnlyLogPodWhichWouldBeDeletedInDryRun(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient, watchEstablished := newWatchNotifyingClientset()
+	pod := newCrashLoopingPod(nil)
+	crClient := fakeclient.NewClientBuilder().WithObjects(pod).Build()
+	recorder := &logRecorder{}
+	config := *testWeederConfig
+	config.DryRun = true
+	w := NewWeeder(ctx, namespace, &config, crClient, watchClient, nil, testEp, recorder.logger())
+	defer w.cancelFn()
+
+	go newPodWatcher(w, namespace, testPodSelectorGroup, w.shootPodIfNecessary).watch()
+	g.Eventually(watchEstablished).Within(time.Second).Should(Receive())
+	_, err := watchClient.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Eventually(recorder.lines).Within(time.Second).Should(ContainElement(SatisfyAll(
+		ContainSubstring("Dry run, not deleting pod which would have been deleted"),
+		ContainSubstring(`"dryRun"=true`),
+		ContainSubstring(fmt.Sprintf(`"podName"="%s"`, pod.Name)),
+	)))
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted in dry run")
+	g.Expect(recorder.lines()).ToNot(ContainElement(ContainSubstring("Deleting pod")))
+}
+
+func TestNewWeederShouldFallBackToDefaultLogger(t *testing.T) {
+	g := NewWithT(t)
+	w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, fake.NewSimpleClientset(), nil, testEp, logr.Logger{})
+	defer w.cancelFn()
+	g.Expect(w.logger.GetSink()).ToNot(BeNil())
+}
+
+// newWatchNotifyingClientset creates a fake clientset which sends the namespace of every established pod watch to the returned channel.
+func newWatchNotifyingClientset() (*fake.Clientset, chan string) {
+	watchClient := fake.NewSimpleClientset()
+	watchEstablished := make(chan string, 2)
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		podWatch, err := watchClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err == nil {
+			watchEstablished <- action.GetNamespace()
+		}
+		return true, podWatch, err
+	})
+	return watchClient, watchEstablished
+}
+
+// countingWatch is a watch.Interface which keeps track of the number of open watches.
+type countingWatch struct {
+	watch.Interface
+	openWatches *atomic.Int32
+	stopOnce    sync.Once
+}
+
+func (c *countingWatch) Stop() {
+	c.stopOnce.Do(func() { c.openWatches.Add(-1) })
+	c.Interface.Stop()
+}
+
+func TestRepeatedReplacementOfWeederShouldNotAccumulatePodWatches(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	watchClient := fake.NewSimpleClientset()
+	var openWatches atomic.Int32
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		podWatch, err := watchClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		openWatches.Add(1)
+		return true, &countingWatch{Interface: podWatch, openWatches: &openWatches}, nil
+	})
+	mgr := NewManager()
+	defer mgr.UnregisterAll()
+
+	const numTransitions = 5
+	var registrations []Registration
+	for i := 0; i < numTransitions; i++ {
+		w := newTestWeeder(ctx, watchClient, []string{additionalNamespace})
+		g.Expect(mgr.Register(*w)).To(BeTrue())
+		wr, _ := mgr.GetWeederRegistration(createKey(*w))
+		registrations = append(registrations, wr)
+		go w.Run()
+		g.Eventually(openWatches.Load).Within(time.Second).Should(Equal(int32(2)), "only the watches of the latest weeder should be open")
+	}
+	g.Consistently(openWatches.Load).WithTimeout(200*time.Millisecond).Should(Equal(int32(2)), "replaced weeders should not leave watches behind")
+	for _, wr := range registrations[:numTransitions-1] {
+		g.Expect(wr.IsClosed()).To(BeTrue(), "replaced weeder should be closed")
+		g.Expect(wr.Stopped()).To(BeClosed(), "pod watchers of a replaced weeder should have stopped")
+	}
+
+	mgr.UnregisterAll()
+	g.Eventually(registrations[numTransitions-1].Stopped()).Within(time.Second).Should(BeClosed())
+	g.Expect(openWatches.Load()).To(BeZero(), "all watches should be closed once the weeder has been unregistered")
+}
+
+func TestCreateK8sWatchShouldCountFailedAndSuccessfulAttempts(t *testing.T) {
+	g := NewWithT(t)
+	const numFailures = 2
+	watchClient := fake.NewSimpleClientset()
+	var numAttempts atomic.Int32
+	watchClient.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		if numAttempts.Add(1) <= numFailures {
+			return true, nil, apierrors.NewServiceUnavailable("api server is not reachable")
+		}
+		return false, nil, nil
+	})
+	w := newTestWeeder(context.Background(), watchClient, nil)
+	defer w.cancelFn()
+	failuresBefore := promtestutil.ToFloat64(watchCreateAttemptsTotal.WithLabelValues(watchCreateResultFailure))
+	successesBefore := promtestutil.ToFloat64(watchCreateAttemptsTotal.WithLabelValues(watchCreateResultSuccess))
+
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, nil)
+	pw.watchCreationBackoff = util.Backoff{Initial: time.Millisecond, Factor: 1}
+	g.Expect(pw.createK8sWatch(w.ctx)).To(BeTrue())
+	defer pw.close()
+	g.Expect(promtestutil.ToFloat64(watchCreateAttemptsTotal.WithLabelValues(watchCreateResultFailure)) - failuresBefore).To(Equal(float64(numFailures)))
+	g.Expect(promtestutil.ToFloat64(watchCreateAttemptsTotal.WithLabelValues(watchCreateResultSuccess)) - successesBefore).To(Equal(float64(1)))
+}
+
+func TestCreateK8sWatchShouldCloseWatchCreatedWhileContextIsCancelled(t *testing.T) {
+	g := NewWithT(t)
+	watchClient := fake.NewSimpleClientset()
+	fakeWatch := watch.NewFake()
+	w := newTestWeeder(context.Background(), watchClient, nil)
+	defer w.cancelFn()
+	watchClient.PrependWatchReactor("pods", func(_ k8stesting.Action) (bool, watch.Interface, error) {
+		// the weeder is cancelled after the watch has been created but before it is consumed.
+		w.cancelFn()
+		return true, fakeWatch, nil
+	})
+
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, nil)
+	g.Expect(pw.createK8sWatch(w.ctx)).To(BeFalse(), "a watch created while the context has been cancelled should not be consumed")
+	g.Expect(fakeWatch.IsStopped()).To(BeTrue(), "the watch should have been closed right away")
+	g.Expect(pw.k8sWatch).To(BeNil())
+}
+
+func TestK8sWatchShouldBeStoppedOnceContextIsCancelledBeforeItIsConsumed(t *testing.T) {
+	g := NewWithT(t)
+	watchClient := fake.NewSimpleClientset()
+	fakeWatch := watch.NewFake()
+	watchClient.PrependWatchReactor("pods", func(_ k8stesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatch, nil
+	})
+	w := newTestWeeder(context.Background(), watchClient, nil)
+	defer w.cancelFn()
+
+	pw := newPodWatcher(w, namespace, testPodSelectorGroup, nil)
+	g.Expect(pw.createK8sWatch(w.ctx)).To(BeTrue())
+	// the context is cancelled before the watch loop is entered, hence the loop never closes the watch.
+	w.cancelFn()
+	g.Eventually(fakeWatch.IsStopped).Within(time.Second).Should(BeTrue(), "the watch should be stopped once the context has been cancelled")
+	pw.close()
+}
@@ -6,81 +6,423 @@ package weeder
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	wapi "github.com/gardener/dependency-watchdog/api/weeder"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-const crashLoopBackOff = "CrashLoopBackOff"
+const (
+	crashLoopBackOff = "CrashLoopBackOff"
+	// podWeededEventReason is the reason of the event which is recorded for every pod that has been deleted by the weeder.
+	podWeededEventReason = "PodWeeded"
+	// podWouldBeWeededEventReason is the reason of the event which is recorded in dry-run mode for every pod that would have been deleted.
+	podWouldBeWeededEventReason = "PodWouldBeWeeded"
+	// deploymentRestartedEventReason is the reason of the event which is recorded for every Deployment that has been restarted by the weeder.
+	deploymentRestartedEventReason = "DeploymentRestarted"
+	// deploymentWouldBeRestartedEventReason is the reason of the event which is recorded in dry-run mode for every Deployment that would have been restarted.
+	deploymentWouldBeRestartedEventReason = "DeploymentWouldBeRestarted"
+)
 
 // Weeder represents an actor which will be responsible for watching dependent pods and weeding them out if they
-// are in CrashLoopBackOff.
+// are in CrashLoopBackOff or, if configured, have restarted more often than the restart count threshold or have
+// been running without being ready for longer than the not ready threshold.
 type Weeder struct {
-	namespace          string
-	endpoints          *v1.Endpoints
-	ctrlClient         client.Client
-	watchClient        kubernetes.Interface
-	dependantSelectors wapi.DependantSelectors
-	ctx                context.Context
-	cancelFn           context.CancelFunc
-	logger             logr.Logger
+	namespace             string
+	endpoints             *v1.Endpoints
+	ctrlClient            client.Client
+	watchClient           kubernetes.Interface
+	podRemediators        []PodRemediator
+	dependantSelectors    wapi.DependantSelectors
+	restartCountThreshold *int32
+	// requireAllContainersCrashLooping if true only weeds a pod in CrashLoopBackOff once all of its containers are crash-looping.
+	requireAllContainersCrashLooping bool
+	// maxPodsPerTransition if set caps the number of pods which are weeded by the weeder.
+	maxPodsPerTransition *int32
+	// weedingCooldown if positive is the duration after a pod has been weeded by a previous weeder of the service during which
+	// the weeder does not weed any pod.
+	weedingCooldown time.Duration
+	// observe if true only logs and counts the pods which need weeding instead of remediating them.
+	observe  bool
+	ctx      context.Context
+	cancelFn context.CancelFunc
+	logger   logr.Logger
+	// notReadyThreshold if positive is the duration after which a running pod which is not ready is weeded.
+	notReadyThreshold time.Duration
+	// startedAt is the time at which the endpoint of the service has become ready, i.e. when the weeder has been created.
+	startedAt time.Time
+	clock     clock.PassiveClock
+	// generation tracks the pod watchers started by Run. It is shared by all copies of the weeder as the Manager registers a copy.
+	generation *weederGeneration
+}
+
+// weederGeneration tracks the pod watchers of a weeder, which replaces the weeder previously started for the same service, if any.
+type weederGeneration struct {
+	mu sync.Mutex
+	// predecessorStopped is closed once all pod watchers of the replaced weeder have stopped.
+	predecessorStopped <-chan struct{}
+	watchers           sync.WaitGroup
+	// stopped is closed once Run has returned, at which point all pod watchers of the weeder have stopped.
+	stopped  chan struct{}
+	stopOnce sync.Once
+	// weededPods is the number of pods which are being or have been weeded by the pod watchers of the weeder.
+	weededPods int32
+	// history is shared with the replaced weeder, so that it spans all weeders of the service.
+	history *weedingHistory
+}
+
+// weedingHistory records when a pod of a service has last been weeded and by which weeder.
+type weedingHistory struct {
+	mu           sync.Mutex
+	lastWeededAt time.Time
+	lastWeededBy *weederGeneration
+}
+
+func newWeederGeneration() *weederGeneration {
+	return &weederGeneration{stopped: make(chan struct{}), history: &weedingHistory{}}
+}
+
+// replaces records that the weeder replaces a weeder whose pod watchers have all stopped once predecessorStopped is closed
+// and which has recorded its weeding in history.
+func (g *weederGeneration) replaces(predecessorStopped <-chan struct{}, history *weedingHistory) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.predecessorStopped = predecessorStopped
+	g.history = history
+}
+
+func (g *weederGeneration) predecessor() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.predecessorStopped
+}
+
+// reserveWeeding reserves the weeding of a pod, it returns false if maxPods pods are already being or have been weeded.
+// A nil maxPods does not cap the number of weeded pods.
+func (g *weederGeneration) reserveWeeding(maxPods *int32) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if maxPods != nil && g.weededPods >= *maxPods {
+		return false
+	}
+	g.weededPods++
+	return true
+}
+
+// releaseWeeding releases a reservation of reserveWeeding for a pod which has not been weeded.
+func (g *weederGeneration) releaseWeeding() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.weededPods--
+}
+
+// remainingCooldown returns for how much longer the weeder must not weed any pod, as a previous weeder of the service has
+// weeded a pod less than cooldown ago. Pods weeded by the weeder itself do not start a cooldown for it.
+func (g *weederGeneration) remainingCooldown(cooldown time.Duration, now time.Time) time.Duration {
+	g.mu.Lock()
+	h := g.history
+	g.mu.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cooldown <= 0 || h.lastWeededBy == nil || h.lastWeededBy == g {
+		return 0
+	}
+	return cooldown - now.Sub(h.lastWeededAt)
+}
+
+// recordWeeding records that the weeder has weeded a pod at the given time.
+func (g *weederGeneration) recordWeeding(now time.Time) {
+	g.mu.Lock()
+	h := g.history
+	g.mu.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastWeededAt = now
+	h.lastWeededBy = g
+}
+
+func (g *weederGeneration) markStopped() {
+	g.stopOnce.Do(func() { close(g.stopped) })
+}
+
+// weederOption configures a Weeder.
+type weederOption func(w *Weeder)
+
+// WithPodRemediators replaces the remediation of dependant pods which need weeding, deleting them by default, with the given
+// remediators. If none are given then the default is retained.
+func WithPodRemediators(remediators ...PodRemediator) weederOption {
+	return func(w *Weeder) {
+		if len(remediators) > 0 {
+			w.podRemediators = remediators
+		}
+	}
+}
+
+// withClock replaces the real clock of the weeder, which allows tests to measure the not ready duration of pods and the
+// weeding cooldown with a fake clock.
+func withClock(clock clock.PassiveClock) weederOption {
+	return func(w *Weeder) {
+		w.clock = clock
+	}
 }
 
 // NewWeeder creates a new Weeder for a service/endpoint.
-func NewWeeder(parentCtx context.Context, namespace string, config *wapi.Config, ctrlClient client.Client, seedClient kubernetes.Interface, ep *v1.Endpoints, logger logr.Logger) *Weeder {
-	wLogger := logger.WithValues("weederRunning", true, "watchDuration", (*config.WatchDuration).String())
+// Every pod that is deleted by the weeder is recorded as an event via the eventRecorder, if one is provided.
+// If the config enables dry-run mode then pods are neither deleted nor passed to any of the configured remediators,
+// every pod which would have been deleted is only logged and recorded as an event instead. Likewise, if the service is in
+// observe mode then every pod which would have been weeded is only logged and counted in the observed pods metric. If the
+// config enables restarting the owning Deployment then the Deployment of a pod is restarted instead of deleting the pod,
+// unless custom remediators are given.
+// The logs of the weeder and of its pod watchers carry the namespace and the name of the service. If the logger has not
+// been set up then the controller-runtime logger is used.
+func NewWeeder(parentCtx context.Context, namespace string, config *wapi.Config, ctrlClient client.Client, seedClient kubernetes.Interface, eventRecorder record.EventRecorder, ep *v1.Endpoints, logger logr.Logger, opts ...weederOption) *Weeder {
+	if logger.GetSink() == nil {
+		logger = logf.Log.WithName("weeder")
+	}
+	wLogger := logger.WithValues("weederRunning", true, "watchDuration", (*config.WatchDuration).String(), "serviceNamespace", namespace, "service", ep.Name)
+	if config.DryRun {
+		wLogger = wLogger.WithValues("dryRun", true)
+	}
 	ctx, cancelFn := context.WithTimeout(parentCtx, config.WatchDuration.Duration)
 	dependantSelectors := config.ServicesAndDependantSelectors[ep.Name]
-	return &Weeder{
-		namespace:          namespace,
-		endpoints:          ep,
-		ctrlClient:         ctrlClient,
-		watchClient:        seedClient,
-		dependantSelectors: dependantSelectors,
-		ctx:                ctx,
-		cancelFn:           cancelFn,
-		logger:             wLogger,
+	observe := dependantSelectors.Mode == wapi.WeedingModeObserve
+	if observe {
+		wLogger = wLogger.WithValues("mode", wapi.WeedingModeObserve)
+	}
+	var notReadyThreshold time.Duration
+	if config.NotReadyThreshold != nil {
+		notReadyThreshold = config.NotReadyThreshold.Duration
 	}
+	var weedingCooldown time.Duration
+	if config.WeedingCooldown != nil {
+		weedingCooldown = config.WeedingCooldown.Duration
+	}
+	w := &Weeder{
+		namespace:                        namespace,
+		endpoints:                        ep,
+		ctrlClient:                       ctrlClient,
+		watchClient:                      seedClient,
+		dependantSelectors:               dependantSelectors,
+		restartCountThreshold:            config.RestartCountThreshold,
+		requireAllContainersCrashLooping: config.RequireAllContainersCrashLooping,
+		maxPodsPerTransition:             config.MaxPodsPerTransition,
+		weedingCooldown:                  weedingCooldown,
+		observe:                          observe,
+		notReadyThreshold:                notReadyThreshold,
+		clock:                            clock.RealClock{},
+		ctx:                              ctx,
+		cancelFn:                         cancelFn,
+		logger:                           wLogger,
+		generation:                       newWeederGeneration(),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.startedAt = w.clock.Now()
+	if config.DryRun || len(w.podRemediators) == 0 {
+		w.podRemediators = []PodRemediator{newDefaultPodRemediator(eventRecorder, w.clock, config.RestartOwningDeployment, config.DryRun)}
+	}
+	return w
 }
 
-// Run runs the Weeder which will intern create one go-routine for dependents identified by respective PodSelector.
+// Run runs the Weeder which will intern create one go-routine for dependents identified by respective PodSelector
+// in the namespace of the service and in each of the additional namespaces configured for it. PodSelectors which might
+// match the same pods share a go-routine, so that every pod is only handled once, see groupSelectors.
+// If the weeder replaces a weeder of the same service, see Manager.Register, then no pod watcher is started before all pod
+// watchers of the replaced weeder have stopped, so that repeated transitions of the endpoint do not accumulate watchers.
+// Run returns once the context of the weeder is done and all of its pod watchers have stopped.
 func (w *Weeder) Run() {
-	for _, ps := range w.dependantSelectors.PodSelectors {
-		go newPodWatcher(w, ps, shootPodIfNecessary).watch()
+	defer w.generation.markStopped()
+	if predecessorStopped := w.generation.predecessor(); predecessorStopped != nil {
+		select {
+		case <-predecessorStopped:
+		case <-w.ctx.Done():
+			return
+		}
+	}
+	for _, ns := range w.watchedNamespaces() {
+		for _, sg := range w.watchedSelectorGroups() {
+			pw := newPodWatcher(w, ns, sg, w.shootPodIfNecessary)
+			w.generation.watchers.Add(1)
+			go func() {
+				defer w.generation.watchers.Done()
+				pw.watch()
+			}()
+		}
 	}
 	// weeder should wait till the context expires
 	<-w.ctx.Done()
+	w.generation.watchers.Wait()
+}
+
+// watchedNamespaces returns the de-duplicated list of namespaces in which dependant pods should be watched.
+// The namespace of the service is always the first entry.
+func (w *Weeder) watchedNamespaces() []string {
+	namespaces := []string{w.namespace}
+	for _, ns := range w.dependantSelectors.AdditionalNamespaces {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
 }
 
-func shootPodIfNecessary(ctx context.Context, log logr.Logger, crClient client.Client, targetPod *v1.Pod) error {
-	if !shouldDeletePod(targetPod) {
+// watchedSelectorGroups returns the groups of PodSelectors for which dependant pods should be watched, one watch per group.
+func (w *Weeder) watchedSelectorGroups() []selectorGroup {
+	return groupSelectors(w.dependantSelectors.PodSelectors)
+}
+
+// shootPodIfNecessary remediates the pod with every remediator of the weeder if it needs weeding. The remediators are
+// invoked in order, the first one which fails or skips the pod aborts the remediation, a skipped pod does not count towards
+// maxPodsPerTransition and does not start a weeding cooldown. In observe mode the pod is only logged and counted
+// instead, it counts towards maxPodsPerTransition but does not start a weeding cooldown. Once the weeder has weeded maxPodsPerTransition pods,
+// any further pod which needs weeding is skipped, it is left to the weeder of a subsequent transition of the endpoint. Within the
+// weeding cooldown after a previous weeder of the service has weeded a pod, every pod which needs weeding is skipped as well.
+func (w *Weeder) shootPodIfNecessary(ctx context.Context, log logr.Logger, crClient client.Client, targetPod *v1.Pod) error {
+	reason, ok := w.weedingReason(targetPod)
+	if !ok {
+		if notReadyFor, notReady := w.notReadyDuration(targetPod); notReady {
+			// the pod does not cause another event while it stays not ready, it is therefore inspected again once it has reached the threshold.
+			return &requeueAfterError{after: w.notReadyThreshold - notReadyFor}
+		}
 		return nil
 	}
-	log.Info("Deleting pod", "namespace", targetPod.Namespace, "podName", targetPod.Name)
-	return crClient.Delete(ctx, targetPod)
+	if remaining := w.generation.remainingCooldown(w.weedingCooldown, w.clock.Now()); remaining > 0 {
+		log.Info("Skipping pod as a pod of the service has been weeded within the weeding cooldown", "podName", targetPod.Name, "reason", reason, "remainingCooldown", remaining)
+		return nil
+	}
+	if !w.generation.reserveWeeding(w.maxPodsPerTransition) {
+		log.Info("Skipping pod as the maximum number of pods weeded per transition has been reached", "podName", targetPod.Name, "reason", reason, "maxPodsPerTransition", *w.maxPodsPerTransition)
+		return nil
+	}
+	if w.observe {
+		log.Info("Observe mode, not weeding pod which would have been weeded", "podName", targetPod.Name, "reason", reason)
+		observedPodsTotal.WithLabelValues(w.namespace, w.endpoints.Name).Inc()
+		return nil
+	}
+	remediation := PodRemediation{Pod: targetPod, Reason: reason, ServiceNamespace: w.namespace, Service: w.endpoints.Name}
+	for _, remediator := range w.podRemediators {
+		if err := remediator.Remediate(ctx, log, crClient, remediation); err != nil {
+			w.generation.releaseWeeding()
+			if errors.Is(err, ErrPodRemediationSkipped) {
+				return nil
+			}
+			return err
+		}
+	}
+	w.generation.recordWeeding(w.clock.Now())
+	return nil
+}
+
+// weedingReason checks if a pod should be deleted for quicker recovery and returns a description of why. A pod can be
+// deleted only if it is not marked for deletion and is currently in CrashLoopBackOff state, i.e. any or, if configured,
+// all of its containers are in CrashLoopBackOff, or, if a restart count
+// threshold is configured, has a container which has restarted more often than the threshold or, if a not ready threshold
+// is configured, has all of its containers running but has not been ready for at least the threshold.
+func (w *Weeder) weedingReason(pod *v1.Pod) (string, bool) {
+	if pod.DeletionTimestamp != nil {
+		return "", false
+	}
+	if containers, ok := w.crashLoopingContainers(pod.Status); ok {
+		return fmt.Sprintf("in %s (containers: %s)", crashLoopBackOff, strings.Join(containers, ", ")), true
+	}
+	if w.restartCountThreshold != nil && hasContainerExceedingRestartCount(pod.Status, *w.restartCountThreshold) {
+		return fmt.Sprintf("with a container restart count above %d", *w.restartCountThreshold), true
+	}
+	if notReadyFor, notReady := w.notReadyDuration(pod); notReady && notReadyFor >= w.notReadyThreshold {
+		return fmt.Sprintf("not ready for longer than %s", w.notReadyThreshold), true
+	}
+	return "", false
+}
+
+// notReadyDuration returns for how long a pod whose containers are all running has not been ready, if a not ready threshold
+// is configured. Only the time since the endpoint of the service has become ready is counted, as the pod could not have
+// become ready while the service was unavailable.
+func (w *Weeder) notReadyDuration(pod *v1.Pod) (time.Duration, bool) {
+	if w.notReadyThreshold <= 0 || pod.DeletionTimestamp != nil || !areAllContainersRunning(pod.Status) {
+		return 0, false
+	}
+	readyCondition := getPodReadyCondition(pod.Status)
+	if readyCondition == nil || readyCondition.Status != v1.ConditionFalse {
+		return 0, false
+	}
+	notReadySince := w.startedAt
+	if readyCondition.LastTransitionTime.After(notReadySince) {
+		notReadySince = readyCondition.LastTransitionTime.Time
+	}
+	return w.clock.Since(notReadySince), true
 }
 
-// shouldDeletePod checks if a pod should be deleted for quicker recovery. A pod can be deleted
-// only if it is not marked for deletion and is currently in CrashLoopBackOff state
-func shouldDeletePod(pod *v1.Pod) bool {
-	podNotMarkedForDeletion := pod.DeletionTimestamp == nil
-	return podNotMarkedForDeletion && isPodInCrashloopBackoff(pod.Status)
+// requeueAfterError is returned by a podEventHandler for a pod which has to be inspected again after a delay, even if no
+// further event is received for it.
+type requeueAfterError struct {
+	after time.Duration
 }
 
-// isPodInCrashloopBackoff checks if any container in a pod is in CrashLoopBackOff
-func isPodInCrashloopBackoff(status v1.PodStatus) bool {
+func (e *requeueAfterError) Error() string {
+	return fmt.Sprintf("pod has to be inspected again after %s", e.after)
+}
+
+// crashLoopingContainers returns the names of the containers of a pod which are in CrashLoopBackOff and whether the pod
+// is therefore considered to be in CrashLoopBackOff, which requires any or, if configured, all of its containers to be.
+func (w *Weeder) crashLoopingContainers(status v1.PodStatus) ([]string, bool) {
+	var containers []string
 	for _, containerStatus := range status.ContainerStatuses {
 		if isContainerInCrashLoopBackOff(containerStatus.State) {
-			return true
+			containers = append(containers, containerStatus.Name)
 		}
 	}
-	return false
+	if w.requireAllContainersCrashLooping {
+		return containers, len(containers) > 0 && len(containers) == len(status.ContainerStatuses)
+	}
+	return containers, len(containers) > 0
 }
 
 // isContainerInCrashLoopBackOff checks if a container is in CrashLoopBackOff
 func isContainerInCrashLoopBackOff(containerState v1.ContainerState) bool {
 	return containerState.Waiting != nil && containerState.Waiting.Reason == crashLoopBackOff
 }
+
+// areAllContainersRunning checks if a pod is running and all of its containers are running
+func areAllContainersRunning(status v1.PodStatus) bool {
+	if status.Phase != v1.PodRunning || len(status.ContainerStatuses) == 0 {
+		return false
+	}
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Running == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// getPodReadyCondition returns the Ready condition of a pod, or nil if the pod does not have one
+func getPodReadyCondition(status v1.PodStatus) *v1.PodCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == v1.PodReady {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// hasContainerExceedingRestartCount checks if any container in a pod has restarted more often than the threshold
+func hasContainerExceedingRestartCount(status v1.PodStatus, threshold int32) bool {
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.RestartCount > threshold {
+			return true
+		}
+	}
+	return false
+}
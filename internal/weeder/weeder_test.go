@@ -0,0 +1,730 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !kind_tests
+
+package weeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	wapi "github.com/gardener/dependency-watchdog/api/weeder"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	testclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testReplicaSetName = "kube-controller-manager-7d4b9c8f6"
+
+func TestShootPodIfNecessaryShouldRecordEventOnOwner(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: testReplicaSetName, UID: types.UID("rs-uid"), Controller: pointer.Bool(true)})
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	recorder.IncludeObject = true
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "crash looping pod should have been deleted")
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(v1.EventTypeNormal),
+		ContainSubstring(podWeededEventReason),
+		ContainSubstring(pod.Name),
+		ContainSubstring(epName),
+		ContainSubstring("kind=ReplicaSet"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldRecordEventOnPodWithoutOwner(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	recorder.IncludeObject = true
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(podWeededEventReason),
+		ContainSubstring("kind=Pod"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldNotRecordEventForHealthyPod(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "healthy pod should not be deleted")
+	g.Expect(recorder.Events).ToNot(Receive())
+}
+
+func TestShootPodIfNecessaryShouldDeletePodExceedingRestartCountThreshold(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	// the container has just been restarted and is therefore momentarily not in CrashLoopBackOff
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	pod.Status.ContainerStatuses[0].RestartCount = 6
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.RestartCountThreshold = pointer.Int32(5)
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "pod exceeding the restart count threshold should have been deleted")
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(podWeededEventReason),
+		ContainSubstring("restart count above 5"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldNotDeletePodWithinRestartCountThreshold(t *testing.T) {
+	table := []struct {
+		description           string
+		restartCountThreshold *int32
+		restartCount          int32
+	}{
+		{"no restart count threshold configured", nil, 100},
+		{"restart count equal to threshold", pointer.Int32(5), 5},
+		{"restart count below threshold", pointer.Int32(5), 2},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			pod := newCrashLoopingPod(nil)
+			pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+			pod.Status.ContainerStatuses[0].RestartCount = entry.restartCount
+			crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			config := *testWeederConfig
+			config.RestartCountThreshold = entry.restartCountThreshold
+			w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+			defer w.cancelFn()
+
+			g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+			g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted")
+			g.Expect(recorder.Events).ToNot(Receive())
+		})
+	}
+}
+
+// recordingPodRemediator records every remediation it is invoked with.
+type recordingPodRemediator struct {
+	remediations []PodRemediation
+}
+
+func (r *recordingPodRemediator) Remediate(_ context.Context, _ logr.Logger, _ client.Client, remediation PodRemediation) error {
+	r.remediations = append(r.remediations, remediation)
+	return nil
+}
+
+func TestShootPodIfNecessaryShouldInvokeCustomPodRemediatorInsteadOfDeletingPod(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	remediator := &recordingPodRemediator{}
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, recorder, testEp, logr.Discard(), WithPodRemediators(remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(remediator.remediations).To(HaveLen(1))
+	g.Expect(remediator.remediations[0].Pod.Name).To(Equal(pod.Name))
+	g.Expect(remediator.remediations[0].Reason).To(Equal("in CrashLoopBackOff (containers: kube-controller-manager)"))
+	g.Expect(remediator.remediations[0].ServiceNamespace).To(Equal(namespace))
+	g.Expect(remediator.remediations[0].Service).To(Equal(epName))
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted by a custom remediator")
+	g.Expect(recorder.Events).ToNot(Receive())
+}
+
+func TestShootPodIfNecessaryShouldNotInvokePodRemediatorForHealthyPod(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	remediator := &recordingPodRemediator{}
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, nil, testEp, logr.Discard(), WithPodRemediators(remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(remediator.remediations).To(BeEmpty())
+}
+
+func TestShootPodIfNecessaryShouldInvokePodRemediatorsInOrderAndStopAtFirstFailure(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	var invocations []string
+	failing := PodRemediatorFunc(func(_ context.Context, _ logr.Logger, _ client.Client, _ PodRemediation) error {
+		invocations = append(invocations, "failing")
+		return errors.New("remediation failed")
+	})
+	remediator := &recordingPodRemediator{}
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, nil, testEp, logr.Discard(), WithPodRemediators(failing, remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(MatchError("remediation failed"))
+	g.Expect(invocations).To(Equal([]string{"failing"}))
+	g.Expect(remediator.remediations).To(BeEmpty(), "remediators after a failing one should not be invoked")
+}
+
+func TestShootPodIfNecessaryShouldCombineDeletePodRemediatorWithCustomPodRemediator(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	remediator := &recordingPodRemediator{}
+	w := NewWeeder(ctx, namespace, testWeederConfig, crClient, nil, nil, testEp, logr.Discard(), WithPodRemediators(remediator, NewDeletePodRemediator(recorder)))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(remediator.remediations).To(HaveLen(1))
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "crash looping pod should have been deleted")
+	g.Expect(recorder.Events).To(Receive(ContainSubstring(podWeededEventReason)))
+}
+
+func TestShootPodIfNecessaryShouldOnlyDeletePodInEnforceMode(t *testing.T) {
+	tests := []struct {
+		name              string
+		mode              wapi.WeedingMode
+		expectPodDeleted  bool
+		expectedObserved  float64
+		expectedEventsLen int
+	}{
+		{name: "observe mode should never delete the pod but count it", mode: wapi.WeedingModeObserve, expectedObserved: 1},
+		{name: "enforce mode should delete the pod", mode: wapi.WeedingModeEnforce, expectPodDeleted: true, expectedEventsLen: 1},
+		{name: "unset mode should enforce weeding", expectPodDeleted: true, expectedEventsLen: 1},
+	}
+	for _, entry := range tests {
+		t.Run(entry.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			pod := newCrashLoopingPod(nil)
+			crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			config := *testWeederConfig
+			ds := testServicesAndDependantSelectors[epName]
+			ds.Mode = entry.mode
+			config.ServicesAndDependantSelectors = map[string]wapi.DependantSelectors{epName: ds}
+			w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+			defer w.cancelFn()
+			observedBefore := promtestutil.ToFloat64(observedPodsTotal.WithLabelValues(namespace, epName))
+
+			g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+			g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(Equal(entry.expectPodDeleted))
+			g.Expect(promtestutil.ToFloat64(observedPodsTotal.WithLabelValues(namespace, epName)) - observedBefore).To(Equal(entry.expectedObserved))
+			g.Expect(recorder.Events).To(HaveLen(entry.expectedEventsLen), "no event should be recorded in observe mode")
+		})
+	}
+}
+
+func TestShootPodIfNecessaryShouldNotStartWeedingCooldownInObserveMode(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	remediator := &recordingPodRemediator{}
+	config := *testWeederConfig
+	config.WeedingCooldown = &metav1.Duration{Duration: time.Hour}
+	config.ServicesAndDependantSelectors = map[string]wapi.DependantSelectors{epName: {PodSelectors: testServicesAndDependantSelectors[epName].PodSelectors, Mode: wapi.WeedingModeObserve}}
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, nil, testEp, logr.Discard(), WithPodRemediators(remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(remediator.remediations).To(BeEmpty(), "remediators should not be invoked in observe mode")
+	g.Expect(w.generation.history.lastWeededBy).To(BeNil(), "an observed pod should not be recorded as weeded")
+}
+
+func TestShootPodIfNecessaryShouldNotDeletePodInDryRun(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: testReplicaSetName, UID: types.UID("rs-uid"), Controller: pointer.Bool(true)})
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	recorder.IncludeObject = true
+	remediator := &recordingPodRemediator{}
+	config := *testWeederConfig
+	config.DryRun = true
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard(), WithPodRemediators(remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted in dry run")
+	g.Expect(remediator.remediations).To(BeEmpty(), "custom remediators should not be invoked in dry run")
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(podWouldBeWeededEventReason),
+		ContainSubstring("Would have deleted pod"),
+		ContainSubstring(pod.Name),
+		ContainSubstring("kind=ReplicaSet"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldDeletePodNotReadyForLongerThanThreshold(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	now := time.Now()
+	pod := newRunningNotReadyPod(now.Add(-10 * time.Minute))
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.NotReadyThreshold = &metav1.Duration{Duration: time.Minute}
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+	w.startedAt = now.Add(-2 * time.Minute)
+	w.clock = testclock.NewFakePassiveClock(now)
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "pod not ready for longer than the threshold should have been deleted")
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(podWeededEventReason),
+		ContainSubstring("not ready for longer than 1m0s"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldRequeuePodNotReadyWithinThreshold(t *testing.T) {
+	now := time.Now()
+	table := []struct {
+		description   string
+		notReadySince time.Time
+		startedAt     time.Time
+		expectedAfter time.Duration
+	}{
+		{"pod has turned not ready after the endpoint", now.Add(-20 * time.Second), now.Add(-time.Minute), 40 * time.Second},
+		{"pod has been not ready before the endpoint", now.Add(-time.Hour), now.Add(-45 * time.Second), 15 * time.Second},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			pod := newRunningNotReadyPod(entry.notReadySince)
+			crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			config := *testWeederConfig
+			config.NotReadyThreshold = &metav1.Duration{Duration: time.Minute}
+			w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+			defer w.cancelFn()
+			w.startedAt = entry.startedAt
+			w.clock = testclock.NewFakePassiveClock(now)
+
+			err := w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)
+			var requeueErr *requeueAfterError
+			g.Expect(errors.As(err, &requeueErr)).To(BeTrue(), "pod not ready within the threshold should be inspected again")
+			g.Expect(requeueErr.after).To(Equal(entry.expectedAfter))
+			g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted")
+			g.Expect(recorder.Events).ToNot(Receive())
+		})
+	}
+}
+
+func TestNotReadyDurationShouldBeMeasuredFromStartOfWeederOnItsClock(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	pod := newRunningNotReadyPod(start.Add(-time.Hour))
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	config := *testWeederConfig
+	config.NotReadyThreshold = &metav1.Duration{Duration: time.Minute}
+	clk := testclock.NewFakePassiveClock(start)
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, record.NewFakeRecorder(1), testEp, logr.Discard(), withClock(clk))
+	defer w.cancelFn()
+	g.Expect(w.startedAt).To(Equal(start))
+
+	clk.SetTime(start.Add(20 * time.Second))
+	err := w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)
+	var requeueErr *requeueAfterError
+	g.Expect(errors.As(err, &requeueErr)).To(BeTrue(), "pod not ready within the threshold should be inspected again")
+	g.Expect(requeueErr.after).To(Equal(40*time.Second), "the pod should only be counted as not ready since the start of the weeder")
+
+	clk.SetTime(start.Add(time.Minute))
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "pod not ready for the threshold since the start of the weeder should have been deleted")
+}
+
+func TestShootPodIfNecessaryShouldIgnoreNotReadyPodUnlessAllContainersAreRunning(t *testing.T) {
+	table := []struct {
+		description       string
+		notReadyThreshold *metav1.Duration
+		mutateFn          func(pod *v1.Pod)
+	}{
+		{"no not ready threshold configured", nil, func(_ *v1.Pod) {}},
+		{"container is waiting", &metav1.Duration{Duration: time.Minute}, func(pod *v1.Pod) {
+			pod.Status.ContainerStatuses[0].State = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+		}},
+		{"pod is ready", &metav1.Duration{Duration: time.Minute}, func(pod *v1.Pod) {
+			pod.Status.Conditions[0].Status = v1.ConditionTrue
+		}},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			now := time.Now()
+			pod := newRunningNotReadyPod(now.Add(-time.Hour))
+			entry.mutateFn(pod)
+			crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			config := *testWeederConfig
+			config.NotReadyThreshold = entry.notReadyThreshold
+			w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+			defer w.cancelFn()
+			w.startedAt = now.Add(-time.Hour)
+			w.clock = testclock.NewFakePassiveClock(now)
+
+			g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+			g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted")
+			g.Expect(recorder.Events).ToNot(Receive())
+		})
+	}
+}
+
+func TestShootPodIfNecessaryShouldRestartOwningDeployment(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment, replicaSet, pod := newCrashLoopingDeploymentPod(time.Now())
+	crClient := fake.NewClientBuilder().WithObjects(deployment, replicaSet, pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.RestartOwningDeployment = true
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted if its deployment is restarted")
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Annotations).To(HaveKey(restartedAtAnnotationKey))
+	restartedAt, ok := deploymentRestartedAt(deployment)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(restartedAt).To(BeTemporally("~", time.Now(), 5*time.Second))
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(deploymentRestartedEventReason),
+		ContainSubstring("Restarted deployment "+namespace+"/"+deployment.Name),
+		ContainSubstring(pod.Name),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldNotRestartDeploymentRestartedSincePodCreation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment, replicaSet, pod := newCrashLoopingDeploymentPod(time.Now().Add(-time.Hour))
+	restartedAt := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	deployment.Spec.Template.Annotations = map[string]string{restartedAtAnnotationKey: restartedAt}
+	crClient := fake.NewClientBuilder().WithObjects(deployment, replicaSet, pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.RestartOwningDeployment = true
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(restartedAtAnnotationKey, restartedAt))
+	g.Expect(recorder.Events).To(BeEmpty())
+}
+
+func TestShootPodIfNecessaryShouldNotCountPodsOfDeploymentRestartedByWeeder(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	clk := testclock.NewFakePassiveClock(time.Now().Truncate(time.Second))
+	deployment, replicaSet, restartingPod := newCrashLoopingDeploymentPod(clk.Now().Add(-time.Hour))
+	olderPod := restartingPod.DeepCopy()
+	olderPod.Name = restartingPod.Name + "-older"
+	// the restartedAt annotation and the creation timestamp of a pod only have a precision of a second.
+	podOfRestart := restartingPod.DeepCopy()
+	podOfRestart.Name = restartingPod.Name + "-restart"
+	podOfRestart.CreationTimestamp = metav1.NewTime(clk.Now())
+	crClient := fake.NewClientBuilder().WithObjects(deployment, replicaSet, restartingPod, olderPod, podOfRestart).Build()
+	recorder := record.NewFakeRecorder(3)
+	config := *testWeederConfig
+	config.RestartOwningDeployment = true
+	config.MaxPodsPerTransition = pointer.Int32(2)
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard(), withClock(clk))
+	defer w.cancelFn()
+
+	for _, pod := range []*v1.Pod{restartingPod, olderPod, podOfRestart} {
+		g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	}
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(restartedAtAnnotationKey, clk.Now().UTC().Format(time.RFC3339)), "restart should be taken from the clock of the weeder")
+	g.Expect(recorder.Events).To(HaveLen(1), "deployment should only be restarted once")
+	g.Expect(w.generation.weededPods).To(Equal(int32(1)), "pods skipped as their deployment has already been restarted should not count towards maxPodsPerTransition")
+}
+
+func TestShootPodIfNecessaryShouldDeletePodWithoutOwningDeploymentInsteadOfRestartingIt(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "etcd-events", UID: types.UID("sts-uid"), Controller: pointer.Bool(true)})
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.RestartOwningDeployment = true
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "pod which is not owned by a deployment should have been deleted")
+	g.Expect(recorder.Events).To(Receive(ContainSubstring(podWeededEventReason)))
+}
+
+func TestShootPodIfNecessaryShouldNotRestartDeploymentInDryRun(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	deployment, replicaSet, pod := newCrashLoopingDeploymentPod(time.Now())
+	crClient := fake.NewClientBuilder().WithObjects(deployment, replicaSet, pod).Build()
+	recorder := record.NewFakeRecorder(1)
+	config := *testWeederConfig
+	config.RestartOwningDeployment = true
+	config.DryRun = true
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Annotations).ToNot(HaveKey(restartedAtAnnotationKey), "deployment should not be restarted in dry run")
+	g.Expect(recorder.Events).To(Receive(SatisfyAll(
+		ContainSubstring(deploymentWouldBeRestartedEventReason),
+		ContainSubstring("Would have restarted deployment"),
+	)))
+}
+
+func TestShootPodIfNecessaryShouldHonourCrashLoopingContainersOfMultiContainerPod(t *testing.T) {
+	table := []struct {
+		description                      string
+		requireAllContainersCrashLooping bool
+		crashLoopingContainers           []string
+		expectWeeded                     bool
+	}{
+		{"any: a single crash looping container", false, []string{"sidecar"}, true},
+		{"any: all containers crash looping", false, []string{"kube-controller-manager", "sidecar"}, true},
+		{"any: no container crash looping", false, nil, false},
+		{"all: a single crash looping container", true, []string{"sidecar"}, false},
+		{"all: all containers crash looping", true, []string{"kube-controller-manager", "sidecar"}, true},
+		{"all: no container crash looping", true, nil, false},
+	}
+	for _, entry := range table {
+		t.Run(entry.description, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			pod := newMultiContainerPod(entry.crashLoopingContainers...)
+			crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			config := *testWeederConfig
+			config.RequireAllContainersCrashLooping = entry.requireAllContainersCrashLooping
+			w := NewWeeder(ctx, namespace, &config, crClient, nil, recorder, testEp, logr.Discard())
+			defer w.cancelFn()
+
+			g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+			if !entry.expectWeeded {
+				g.Expect(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{})).To(Succeed(), "pod should not be deleted")
+				g.Expect(recorder.Events).ToNot(Receive())
+				return
+			}
+			g.Expect(apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))).To(BeTrue(), "pod should have been deleted")
+			g.Expect(recorder.Events).To(Receive(ContainSubstring("containers: " + strings.Join(entry.crashLoopingContainers, ", "))))
+		})
+	}
+}
+
+func TestShootPodIfNecessaryShouldNotWeedMorePodsThanMaxPodsPerTransition(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	var pods []*v1.Pod
+	var objects []client.Object
+	for i := 0; i < 5; i++ {
+		pod := newCrashLoopingPod(nil)
+		pod.Name = fmt.Sprintf("%s-%d", pod.Name, i)
+		pods = append(pods, pod)
+		objects = append(objects, pod)
+	}
+	crClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	config := *testWeederConfig
+	config.MaxPodsPerTransition = pointer.Int32(2)
+	countRemainingPods := func() int {
+		podList := &v1.PodList{}
+		g.Expect(crClient.List(ctx, podList)).To(Succeed())
+		return len(podList.Items)
+	}
+
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, nil, testEp, logr.Discard())
+	defer w.cancelFn()
+	for _, pod := range pods {
+		g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	}
+	g.Expect(countRemainingPods()).To(Equal(3), "only maxPodsPerTransition pods should have been weeded")
+
+	// the pods which have been skipped are weeded by the weeder of the next transition.
+	next := NewWeeder(ctx, namespace, &config, crClient, nil, nil, testEp, logr.Discard())
+	defer next.cancelFn()
+	for _, pod := range pods[2:] {
+		g.Expect(next.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	}
+	g.Expect(countRemainingPods()).To(Equal(1))
+}
+
+func TestShootPodIfNecessaryShouldNotCountPodWhoseRemediationFailedTowardsMaxPodsPerTransition(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	crClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	config := *testWeederConfig
+	config.MaxPodsPerTransition = pointer.Int32(1)
+	var remediationErr = errors.New("remediation failed")
+	var numRemediations int
+	remediator := PodRemediatorFunc(func(_ context.Context, _ logr.Logger, _ client.Client, _ PodRemediation) error {
+		numRemediations++
+		return remediationErr
+	})
+	w := NewWeeder(ctx, namespace, &config, crClient, nil, nil, testEp, logr.Discard(), WithPodRemediators(remediator))
+	defer w.cancelFn()
+
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(MatchError(remediationErr))
+	remediationErr = nil
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), crClient, pod)).To(Succeed())
+	g.Expect(numRemediations).To(Equal(2), "a pod whose remediation has failed should not use up the cap")
+}
+
+func TestShootPodIfNecessaryShouldNotWeedWithinWeedingCooldownOfPreviousTransition(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	var pods []*v1.Pod
+	var objects []client.Object
+	for i := 0; i < 4; i++ {
+		pod := newCrashLoopingPod(nil)
+		pod.Name = fmt.Sprintf("%s-%d", pod.Name, i)
+		pods = append(pods, pod)
+		objects = append(objects, pod)
+	}
+	crClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	config := *testWeederConfig
+	config.WeedingCooldown = &metav1.Duration{Duration: 10 * time.Minute}
+	clk := testclock.NewFakePassiveClock(time.Now())
+	mgr := NewManager()
+	defer mgr.UnregisterAll()
+	startWeeder := func() *Weeder {
+		w := NewWeeder(ctx, namespace, &config, crClient, nil, nil, testEp, logr.Discard())
+		w.clock = clk
+		mgr.Register(*w)
+		return w
+	}
+	isWeeded := func(pod *v1.Pod) bool {
+		return apierrors.IsNotFound(crClient.Get(ctx, client.ObjectKeyFromObject(pod), &v1.Pod{}))
+	}
+
+	first := startWeeder()
+	g.Expect(first.shootPodIfNecessary(ctx, logr.Discard(), crClient, pods[0])).To(Succeed())
+	g.Expect(first.shootPodIfNecessary(ctx, logr.Discard(), crClient, pods[1])).To(Succeed())
+	g.Expect(isWeeded(pods[0]) && isWeeded(pods[1])).To(BeTrue(), "the weeder which has started the cooldown should not be affected by it")
+
+	clk.SetTime(clk.Now().Add(5 * time.Minute))
+	second := startWeeder()
+	g.Expect(second.shootPodIfNecessary(ctx, logr.Discard(), crClient, pods[2])).To(Succeed())
+	g.Expect(isWeeded(pods[2])).To(BeFalse(), "a second transition within the weeding cooldown should not weed")
+
+	clk.SetTime(clk.Now().Add(5 * time.Minute))
+	third := startWeeder()
+	g.Expect(third.shootPodIfNecessary(ctx, logr.Discard(), crClient, pods[2])).To(Succeed())
+	g.Expect(isWeeded(pods[2])).To(BeTrue(), "a transition after the weeding cooldown should weed")
+
+	clk.SetTime(clk.Now().Add(time.Minute))
+	fourth := startWeeder()
+	g.Expect(fourth.shootPodIfNecessary(ctx, logr.Discard(), crClient, pods[3])).To(Succeed())
+	g.Expect(isWeeded(pods[3])).To(BeFalse(), "weeding after the cooldown should start a new cooldown")
+}
+
+// newCrashLoopingDeploymentPod creates a Deployment, its ReplicaSet and a crash looping pod of the ReplicaSet created at podCreated.
+func newCrashLoopingDeploymentPod(podCreated time.Time) (*appsv1.Deployment, *appsv1.ReplicaSet, *v1.Pod) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-controller-manager", Namespace: namespace, UID: types.UID("deploy-uid")},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            testReplicaSetName,
+			Namespace:       namespace,
+			UID:             types.UID("rs-uid"),
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: deployment.Name, UID: deployment.UID, Controller: pointer.Bool(true)}},
+		},
+	}
+	pod := newCrashLoopingPod(&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: replicaSet.Name, UID: replicaSet.UID, Controller: pointer.Bool(true)})
+	pod.CreationTimestamp = metav1.NewTime(podCreated)
+	return deployment, replicaSet, pod
+}
+
+func newCrashLoopingPod(owner *metav1.OwnerReference) *v1.Pod {
+	pod := &v1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-controller-manager-7d4b9c8f6-x2x4z",
+			Namespace: namespace,
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "kube-controller-manager", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: crashLoopBackOff}}},
+			},
+		},
+	}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+// newMultiContainerPod creates a running pod with a kube-controller-manager and a sidecar container of which the named
+// ones are in CrashLoopBackOff.
+func newMultiContainerPod(crashLoopingContainers ...string) *v1.Pod {
+	pod := newCrashLoopingPod(nil)
+	pod.Status.Phase = v1.PodRunning
+	pod.Status.ContainerStatuses = nil
+	for _, name := range []string{"kube-controller-manager", "sidecar"} {
+		state := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+		if slices.Contains(crashLoopingContainers, name) {
+			state = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: crashLoopBackOff}}
+		}
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, v1.ContainerStatus{Name: name, State: state})
+	}
+	return pod
+}
+
+// newRunningNotReadyPod creates a pod whose containers are all running but which has not been ready since notReadySince.
+func newRunningNotReadyPod(notReadySince time.Time) *v1.Pod {
+	pod := newCrashLoopingPod(nil)
+	pod.Status.Phase = v1.PodRunning
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	pod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodReady, Status: v1.ConditionFalse, Reason: "ReadinessGatesNotReady", LastTransitionTime: metav1.NewTime(notReadySince)},
+	}
+	return pod
+}
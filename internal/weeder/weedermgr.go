@@ -7,12 +7,15 @@ package weeder
 import (
 	"context"
 	"sync"
+
+	"github.com/go-logr/logr"
 )
 
 // Manager provides a single point for registering and unregistering weeders
 type Manager interface {
 	// Register registers a weeder with the manager. If a weeder with a key identified by `createKey`
-	// exists then it will close it and replace it with the new weeder.
+	// exists then it will close it and replace it with the new weeder. The new weeder does not start any pod
+	// watcher before all pod watchers of the replaced weeder have stopped.
 	Register(weeder Weeder) bool
 	// Unregister checks if there is an existing weeder with the key. If it is found then it will close the weeder
 	// and remove it from the manager.
@@ -21,25 +24,45 @@ type Manager interface {
 	UnregisterAll()
 	// GetWeederRegistration returns a weederRegistration which will give access to the context and the cancelFn to the caller.
 	GetWeederRegistration(key string) (Registration, bool)
+	// Shutdown closes and unregisters all weeders. It logs a summary of the number of weeders that have been
+	// unregistered and the number of pod watches which were still open and have been closed as a result.
+	Shutdown(logger logr.Logger)
+	// GetPodRemediators returns the remediators with which the weeders managed by the manager remediate dependant pods.
+	// If it is empty then the weeders delete the pods.
+	GetPodRemediators() []PodRemediator
 }
 
 // Registration provides a handle to check if a weeder has been closed and to also close the weeder.
 type Registration interface {
 	// IsClosed return true if a weeder is closed else returns false.
 	IsClosed() bool
-	// Close closes the weeder.
+	// Close closes the weeder. It can be called repeatedly.
 	Close()
+	// Stopped returns a channel which is closed once the weeder has run and all of its pod watchers have stopped.
+	Stopped() <-chan struct{}
+	// EndpointsResourceVersion returns the resource version of the endpoints for which the weeder has been started.
+	EndpointsResourceVersion() string
 }
 
+// weederManager guards the weeder registrations with a RWMutex as the reconciles of the endpoints of different services register,
+// unregister and look them up concurrently. The pod remediators are only set when the manager is created and are not guarded.
 type weederManager struct {
-	sync.Mutex
-	weeders map[string]weederRegistration
+	sync.RWMutex
+	weeders        map[string]weederRegistration
+	podRemediators []PodRemediator
 }
 
 // weederRegistration captures the handle to manage a weeder
 type weederRegistration struct {
 	ctx      context.Context
 	cancelFn context.CancelFunc
+	stopped  <-chan struct{}
+	// history records the weeding of all weeders of the service, it is passed on to the weeder replacing this one.
+	history *weedingHistory
+	// numWatches is the number of pod watches started by the weeder.
+	numWatches int
+	// endpointsResourceVersion is the resource version of the endpoints for which the weeder has been started.
+	endpointsResourceVersion string
 }
 
 func (wr weederRegistration) IsClosed() bool {
@@ -55,8 +78,18 @@ func (wr weederRegistration) Close() {
 	wr.cancelFn()
 }
 
+func (wr weederRegistration) Stopped() <-chan struct{} {
+	return wr.stopped
+}
+
+func (wr weederRegistration) EndpointsResourceVersion() string {
+	return wr.endpointsResourceVersion
+}
+
 // Register registers the new weeder. If the weeder with the same key (see `createKey` function) exists
-// then it will close the registration (if not already closed) which cancels the weeder.
+// then it will close the registration (if not already closed) which cancels the weeder, and the new weeder will wait
+// for the pod watchers of the cancelled weeder to stop before it starts its own. The new weeder takes over the weeding history
+// of the cancelled weeder, which spans the weeding cooldown across transitions of the endpoint.
 // It will then create a new weeder registration which will replace the existing weeder registration.
 func (wm *weederManager) Register(weeder Weeder) bool {
 	wm.Lock()
@@ -66,19 +99,42 @@ func (wm *weederManager) Register(weeder Weeder) bool {
 		if !wr.IsClosed() {
 			wr.Close()
 		}
+		weeder.generation.replaces(wr.stopped, wr.history)
 	}
 	wm.weeders[key] = weederRegistration{
-		ctx:      weeder.ctx,
-		cancelFn: weeder.cancelFn,
+		ctx:                      weeder.ctx,
+		cancelFn:                 weeder.cancelFn,
+		stopped:                  weeder.generation.stopped,
+		history:                  weeder.generation.history,
+		numWatches:               len(weeder.watchedNamespaces()) * len(weeder.watchedSelectorGroups()),
+		endpointsResourceVersion: weeder.endpoints.ResourceVersion,
 	}
 	return true
 }
 
+type managerOption func(wm *weederManager)
+
+// WithRemediators sets custom remediators which replace the deletion of dependant pods by the weeders of the manager. To run
+// a custom action in addition to deleting the pods, include the remediator created by NewDeletePodRemediator.
+func WithRemediators(remediators ...PodRemediator) managerOption {
+	return func(wm *weederManager) {
+		wm.podRemediators = remediators
+	}
+}
+
 // NewManager creates a new manager for weeders.
-func NewManager() Manager {
-	return &weederManager{
+func NewManager(opts ...managerOption) Manager {
+	wm := &weederManager{
 		weeders: make(map[string]weederRegistration),
 	}
+	for _, opt := range opts {
+		opt(wm)
+	}
+	return wm
+}
+
+func (wm *weederManager) GetPodRemediators() []PodRemediator {
+	return wm.podRemediators
 }
 
 func (wm *weederManager) Unregister(key string) bool {
@@ -93,16 +149,37 @@ func (wm *weederManager) Unregister(key string) bool {
 }
 
 func (wm *weederManager) UnregisterAll() {
-	for key := range wm.weeders {
-		_ = wm.Unregister(key)
+	wm.Lock()
+	defer wm.Unlock()
+	for key, wr := range wm.weeders {
+		delete(wm.weeders, key)
+		wr.Close()
 	}
 }
 
 func (wm *weederManager) GetWeederRegistration(key string) (Registration, bool) {
+	wm.RLock()
+	defer wm.RUnlock()
 	wr, ok := wm.weeders[key]
 	return wr, ok
 }
 
+func (wm *weederManager) Shutdown(logger logr.Logger) {
+	wm.Lock()
+	defer wm.Unlock()
+	var weedersUnregistered, watchesClosed int
+	for key, wr := range wm.weeders {
+		// watches of a weeder whose registration is already closed have stopped on their own.
+		if !wr.IsClosed() {
+			watchesClosed += wr.numWatches
+		}
+		delete(wm.weeders, key)
+		wr.Close()
+		weedersUnregistered++
+	}
+	logger.Info("Weeder manager has been shut down", "weedersUnregistered", weedersUnregistered, "watchesClosed", watchesClosed)
+}
+
 // createKey creates a key to uniquely identify a weeder
 func createKey(w Weeder) string {
 	return w.namespace + "/" + w.endpoints.Name
@@ -8,11 +8,14 @@ package weeder
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	v12 "github.com/gardener/dependency-watchdog/api/weeder"
 	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,7 +52,7 @@ func TestRegisterNewWeederAndIsNotClosed(t *testing.T) {
 	mgr, tearDownTest := setupMgrTest(t)
 	defer tearDownTest(mgr)
 
-	w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, testEp, logr.Discard())
+	w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard())
 	g.Expect(w).ShouldNot(BeNil(), "NewWeeder should have returned a non nil weeder")
 	g.Expect(mgr.Register(*w)).To(BeTrue(), "mgr.Register should register a new weeder")
 
@@ -66,13 +69,13 @@ func TestRegisterWeederWithSameKeyShouldReplaceOldEntry(t *testing.T) {
 	mgr, tearDownTest := setupMgrTest(t)
 	defer tearDownTest(mgr)
 
-	w1 := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, testEp, logr.Discard())
+	w1 := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard())
 	g.Expect(mgr.Register(*w1)).To(BeTrue(), "mgr.Register should register the first weeder")
 	key := createKey(*w1)
 	foundWeederRegistration1, _ := mgr.GetWeederRegistration(key)
 	g.Expect(foundWeederRegistration1.IsClosed()).To(BeFalse(), "First Registered weeder should be alive")
 
-	w2 := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, testEp, logr.Discard())
+	w2 := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard())
 	g.Expect(mgr.Register(*w2)).To(BeTrue(), "mgr.Register should register the second weeder")
 	foundWeederRegistration2, _ := mgr.GetWeederRegistration(key)
 
@@ -87,7 +90,7 @@ func TestUnregisterExistingWeederShouldCloseItAndRemoveItFromManager(t *testing.
 	mgr, tearDownTest := setupMgrTest(t)
 	defer tearDownTest(mgr)
 
-	w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, testEp, logr.Discard())
+	w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard())
 	g.Expect(mgr.Register(*w)).To(BeTrue(), "mgr.Register should register the first weeder")
 	key := createKey(*w)
 	foundWeederRegistration, _ := mgr.GetWeederRegistration(key)
@@ -101,6 +104,98 @@ func TestUnregisterExistingWeederShouldCloseItAndRemoveItFromManager(t *testing.
 	t.Log("De-registering a existing weeder succeeded")
 }
 
+// TestConcurrentRegistrationOfSameService is meant to be run with -race. The weeders of the same service are registered, replaced
+// and unregistered concurrently, while the registration of the service is looked up by a goroutine of its own.
+func TestConcurrentRegistrationOfSameService(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+	const numGoroutines, numIterations = 8, 50
+
+	weeders := make([][]*Weeder, numGoroutines)
+	for i := range weeders {
+		for j := 0; j < numIterations; j++ {
+			weeders[i] = append(weeders[i], NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard()))
+		}
+	}
+	key := createKey(*weeders[0][0])
+	var wg sync.WaitGroup
+	for i := range weeders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j, w := range weeders[i] {
+				if !mgr.Register(*w) {
+					t.Errorf("mgr.Register should always register the weeder of a service")
+				}
+				if j%3 == 2 {
+					mgr.Unregister(key)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < numGoroutines*numIterations; j++ {
+			_, _ = mgr.GetWeederRegistration(key)
+		}
+	}()
+	wg.Wait()
+
+	registration, registered := mgr.GetWeederRegistration(key)
+	var notClosed []*Weeder
+	for i := range weeders {
+		for _, w := range weeders[i] {
+			if w.ctx.Err() == nil {
+				notClosed = append(notClosed, w)
+			}
+		}
+	}
+	if !registered {
+		g.Expect(notClosed).To(BeEmpty(), "every weeder of an unregistered service should be closed")
+		return
+	}
+	g.Expect(registration.IsClosed()).To(BeFalse(), "the registered weeder should not be closed")
+	g.Expect(notClosed).To(HaveLen(1), "every weeder but the registered one should be closed")
+	g.Expect(registration.(weederRegistration).ctx).To(BeIdenticalTo(notClosed[0].ctx))
+}
+
+// TestConcurrentRegistrationOfDifferentServices is meant to be run with -race. Every service is registered and unregistered
+// by a goroutine of its own.
+func TestConcurrentRegistrationOfDifferentServices(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+	const numServices, numIterations = 8, 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < numServices; i++ {
+		ep := testEp.DeepCopy()
+		ep.Name = epName + "-" + strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numIterations; j++ {
+				w := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, ep, logr.Discard())
+				mgr.Register(*w)
+				if registration, ok := mgr.GetWeederRegistration(createKey(*w)); !ok || registration.IsClosed() {
+					t.Errorf("the weeder of service %s should be registered and not be closed", ep.Name)
+				}
+				if j%2 == 1 && (!mgr.Unregister(createKey(*w)) || w.ctx.Err() == nil) {
+					t.Errorf("the weeder of service %s should be unregistered and closed", ep.Name)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < numServices; i++ {
+		_, ok := mgr.GetWeederRegistration(namespace + "/" + epName + "-" + strconv.Itoa(i))
+		g.Expect(ok).To(BeFalse(), "every service should have been unregistered last")
+	}
+}
+
 func TestUnregisterNonExistingWeederShouldNotFail(t *testing.T) {
 	g := NewWithT(t)
 	mgr, tearDownTest := setupMgrTest(t)
@@ -109,3 +204,47 @@ func TestUnregisterNonExistingWeederShouldNotFail(t *testing.T) {
 	g.Expect(mgr.Unregister("random-key")).To(BeFalse(), "mgr.Unregister should return false for non existing weeder")
 	t.Log("De-registering a non-existing weeder did not fail")
 }
+
+func TestShutdownShouldCloseAllWeedersAndLogSummary(t *testing.T) {
+	g := NewWithT(t)
+	mgr, tearDownTest := setupMgrTest(t)
+	defer tearDownTest(mgr)
+
+	w1 := NewWeeder(context.Background(), namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard())
+	g.Expect(mgr.Register(*w1)).To(BeTrue(), "mgr.Register should register a new weeder")
+	w2 := NewWeeder(context.Background(), "bingo", testWeederConfig, nil, nil, nil, testEp, logr.Discard())
+	g.Expect(mgr.Register(*w2)).To(BeTrue(), "mgr.Register should register a new weeder")
+	w3 := NewWeeder(context.Background(), "zingo", testWeederConfig, nil, nil, nil, testEp, logr.Discard())
+	g.Expect(mgr.Register(*w3)).To(BeTrue(), "mgr.Register should register a new weeder")
+	// the watches of an already closed weeder have already stopped and should not be counted.
+	w3.cancelFn()
+
+	var logs []string
+	logger := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+	mgr.Shutdown(logger)
+
+	_, ok := mgr.GetWeederRegistration(createKey(*w1))
+	g.Expect(ok).To(BeFalse(), "mgr.Shutdown should unregister all weeders")
+	g.Expect(w1.ctx.Err()).To(HaveOccurred(), "mgr.Shutdown should close all weeders")
+	g.Expect(w2.ctx.Err()).To(HaveOccurred(), "mgr.Shutdown should close all weeders")
+	g.Expect(logs).To(ContainElement(SatisfyAll(
+		ContainSubstring(`"weedersUnregistered"=3`),
+		ContainSubstring(`"watchesClosed"=2`),
+	)))
+}
+
+func TestManagerShouldProvideConfiguredPodRemediators(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(NewManager().GetPodRemediators()).To(BeEmpty())
+
+	remediator := &recordingPodRemediator{}
+	mgr := NewManager(WithRemediators(remediator))
+	g.Expect(mgr.GetPodRemediators()).To(ConsistOf(remediator))
+
+	ctx := context.Background()
+	pod := newCrashLoopingPod(nil)
+	w := NewWeeder(ctx, namespace, testWeederConfig, nil, nil, nil, testEp, logr.Discard(), WithPodRemediators(mgr.GetPodRemediators()...))
+	defer w.cancelFn()
+	g.Expect(w.shootPodIfNecessary(ctx, logr.Discard(), nil, pod)).To(Succeed())
+	g.Expect(remediator.remediations).To(HaveLen(1))
+}